@@ -16,6 +16,7 @@ import (
 	"github.com/spf13/pflag"
 
 	"github.com/Nybkox/lazyopenconnect/pkg/app"
+	"github.com/Nybkox/lazyopenconnect/pkg/cli"
 	"github.com/Nybkox/lazyopenconnect/pkg/controllers/helpers"
 	"github.com/Nybkox/lazyopenconnect/pkg/daemon"
 	"github.com/Nybkox/lazyopenconnect/pkg/presentation"
@@ -36,11 +37,13 @@ func init() {
 	pflag.BoolVar(&debug, "debug", false, "Enable debug logging in daemon")
 	pflag.BoolVarP(&showHelp, "help", "h", false, "Show help")
 	pflag.BoolVarP(&showVersion, "version", "v", false, "Show version")
+	pflag.CommandLine.SetInterspersed(false)
 	pflag.Parse()
 }
 
 func main() {
 	version.Current = buildVersion
+	cli.Debug = debug
 
 	if showHelp {
 		printHelp()
@@ -56,6 +59,7 @@ func main() {
 	if len(args) > 0 {
 		switch args[0] {
 		case "daemon":
+			args = parseDaemonFlags(args)
 			if len(args) > 1 && args[1] == "run" {
 				if err := daemon.Run(debug); err != nil {
 					fmt.Fprintf(os.Stderr, "Daemon error: %v\n", err)
@@ -65,9 +69,48 @@ func main() {
 			}
 			handleDaemonCmd(args[1:])
 			return
+		case "connect":
+			cli.Connect(args[1:])
+			return
+		case "disconnect":
+			cli.Disconnect(args[1:])
+			return
+		case "status":
+			cli.Status(args[1:])
+			return
+		case "logs":
+			cli.Logs(args[1:])
+			return
+		case "history":
+			cli.History(args[1:])
+			return
+		case "conn":
+			cli.Conn(args[1:])
+			return
+		case "import":
+			cli.Import(args[1:])
+			return
+		case "export":
+			cli.Export(args[1:])
+			return
+		case "doctor":
+			cli.Doctor(args[1:])
+			return
+		case "killswitch":
+			cli.KillSwitch(args[1:])
+			return
+		case "completion":
+			cli.Completion(args[1:])
+			return
+		case "__complete":
+			cli.Complete(args[1:])
+			return
 		case "update":
 			handleUpdate()
 			return
+		case "service":
+			cli.Service(args[1:])
+			return
 		case "uninstall":
 			handleUninstall()
 			return
@@ -92,64 +135,8 @@ func main() {
 		}
 	}
 
-	var conn net.Conn
-	var result daemon.HelloResult
-
-	for attempt := 0; attempt < 2; attempt++ {
-		status := daemon.DaemonStatus(socketPath)
-		switch status {
-		case daemon.DaemonInaccessible:
-			fmt.Println("Daemon socket is not accessible, restarting with sudo...")
-			if err := stopAndRespawnDaemon(socketPath); err != nil {
-				fmt.Fprintf(os.Stderr, "Failed to restart daemon: %v\n", err)
-				os.Exit(1)
-			}
-			ready := daemon.WaitForDaemonStart(socketPath, 3*time.Second)
-			if ready != daemon.DaemonReachable {
-				printInaccessibleDaemonHint()
-				os.Exit(1)
-			}
-		case daemon.DaemonNotRunning:
-			if err := daemon.SpawnDaemon(daemon.SpawnConfig{
-				Debug:       debug,
-				Interactive: true,
-				Stdin:       os.Stdin,
-				Stdout:      os.Stdout,
-				Stderr:      os.Stderr,
-			}); err != nil {
-				fmt.Fprintf(os.Stderr, "Failed to start daemon: %v\n", err)
-				os.Exit(1)
-			}
-			ready := daemon.WaitForDaemonStart(socketPath, 2*time.Second)
-			if ready != daemon.DaemonReachable {
-				if ready == daemon.DaemonInaccessible {
-					printInaccessibleDaemonHint()
-				} else {
-					fmt.Fprintln(os.Stderr, "Failed to start daemon: daemon did not become ready")
-				}
-				os.Exit(1)
-			}
-		}
-
-		result, err = daemon.ConnectAndHello(socketPath, version.Current, 3*time.Second)
-		if err != nil {
-			var mismatchErr *daemon.VersionMismatchError
-			if errors.As(err, &mismatchErr) {
-				if attempt == 0 {
-					fmt.Fprintf(os.Stderr, "%s, restarting...\n", mismatchErr.Error())
-					time.Sleep(500 * time.Millisecond)
-					continue
-				}
-				fmt.Fprintln(os.Stderr, "Failed to restart daemon with matching version")
-				os.Exit(1)
-			}
-			fmt.Fprintf(os.Stderr, "Failed to connect to daemon: %v\n", err)
-			os.Exit(1)
-		}
-
-		conn = result.Conn
-		break
-	}
+	result := cli.AttachDaemon(socketPath)
+	conn := result.Conn
 	defer conn.Close()
 
 	cfg, err := helpers.LoadConfig()
@@ -163,11 +150,12 @@ func main() {
 		Config: *cfg,
 	})
 
+	daemon.WriteMsg(conn, daemon.TakeOwnershipCmd{Type: "take_ownership"})
 	daemon.WriteMsg(conn, daemon.GetStateCmd{Type: "get_state"})
 
 	a := app.New(cfg)
 	a.RenderView = presentation.Render
-	a.ConnectToDaemon(result.Conn)
+	a.ConnectToDaemon(result)
 
 	p := tea.NewProgram(a, tea.WithAltScreen())
 
@@ -177,49 +165,6 @@ func main() {
 	}
 }
 
-func stopAndRespawnDaemon(socketPath string) error {
-	exe, err := os.Executable()
-	if err != nil {
-		return err
-	}
-
-	stopArgs := []string{exe, "daemon", "stop", "all"}
-	stopCmd := exec.Command("sudo", stopArgs...)
-	stopCmd.Stdin = os.Stdin
-	stopCmd.Stdout = os.Stdout
-	stopCmd.Stderr = os.Stderr
-	if err := stopCmd.Run(); err != nil {
-		return err
-	}
-
-	deadline := time.Now().Add(2 * time.Second)
-	for time.Now().Before(deadline) {
-		if daemon.DaemonStatus(socketPath) == daemon.DaemonNotRunning {
-			break
-		}
-		time.Sleep(100 * time.Millisecond)
-	}
-
-	if err := daemon.SpawnDaemon(daemon.SpawnConfig{
-		Debug:       debug,
-		Interactive: true,
-		Stdin:       os.Stdin,
-		Stdout:      os.Stdout,
-		Stderr:      os.Stderr,
-	}); err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func printInaccessibleDaemonHint() {
-	fmt.Fprintln(os.Stderr, "Daemon is running but not accessible for this user.")
-	fmt.Fprintln(os.Stderr, "This usually means an older root-owned daemon socket.")
-	fmt.Fprintln(os.Stderr, "Fix once with: sudo lazyopenconnect daemon stop all")
-	fmt.Fprintln(os.Stderr, "Then run: lazyopenconnect")
-}
-
 func shutdownDaemon(socketPath string) {
 	_ = daemon.RequestShutdown(socketPath)
 }
@@ -236,6 +181,18 @@ Commands:
   daemon stop     Stop the background daemon
   daemon stop all Stop all matching stale daemons
   daemon status   Check if daemon is running
+  connect <name>  Connect without the TUI (answers prompts on the terminal)
+  disconnect      Disconnect one connection, or all of them
+  status          Show VPN status (--json or --format for scripts)
+  logs            Print a connection's VPN log (-f to follow)
+  history         Show past sessions and how they ended (--summary per connection)
+  conn            Manage connections (list, show, add, edit, rm)
+  import          Import connections (nm, anyconnect, globalprotect, bundle)
+  export          Export connections as a shareable bundle (no secrets)
+  doctor          Diagnose openconnect, sudo, daemon, keychain and network setup
+  killswitch off  Remove the kill switch rules and restore network access
+  service         Run the daemon as a systemd service (install, uninstall, status)
+  completion      Print shell completion script (bash, zsh, fish)
   update          Check for and install updates
   uninstall       Remove lazyopenconnect
 
@@ -244,6 +201,17 @@ Flags:`)
 	fmt.Println("\nRequires sudo to run openconnect.")
 }
 
+func parseDaemonFlags(args []string) []string {
+	fs := pflag.NewFlagSet("daemon", pflag.ContinueOnError)
+	fs.BoolVar(&debug, "debug", debug, "Enable debug logging in daemon")
+	if err := fs.Parse(args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	cli.Debug = debug
+	return append([]string{args[0]}, fs.Args()...)
+}
+
 func handleDaemonCmd(args []string) {
 	if len(args) < 1 {
 		fmt.Println("Usage: lazyopenconnect daemon <start|stop all|status>")
@@ -621,6 +589,10 @@ func handleUninstall() {
 		}
 	}
 
+	if err := helpers.RemoveService(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
 	if err := helpers.RemoveBinary(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
@@ -23,6 +23,9 @@ type App struct {
 	viewport     viewport.Model
 	input        textinput.Model
 	spinnerFrame int
+	spinning     bool
+	requestIDs   bool
+	requests     map[string]*pendingRequest
 }
 
 func New(cfg *models.Config) *App {
@@ -34,6 +37,7 @@ func New(cfg *models.Config) *App {
 		Keys:     DefaultKeyMap(),
 		viewport: vp,
 		input:    ti,
+		requests: make(map[string]*pendingRequest),
 	}
 }
 
@@ -2,11 +2,12 @@ package app
 
 import (
 	"bufio"
-	"net"
+	"fmt"
 
 	tea "github.com/charmbracelet/bubbletea"
 
 	"github.com/Nybkox/lazyopenconnect/pkg/daemon"
+	"github.com/Nybkox/lazyopenconnect/pkg/ui"
 )
 
 type DaemonMsg struct {
@@ -31,7 +32,74 @@ func (a *App) SendToDaemon(msg any) {
 	}
 }
 
-func (a *App) ConnectToDaemon(conn net.Conn) {
-	a.DaemonConn = conn
-	a.DaemonReader = bufio.NewReader(conn)
+func (a *App) ConnectToDaemon(result daemon.HelloResult) {
+	a.DaemonConn = result.Conn
+	a.DaemonReader = result.Reader
+	if a.DaemonReader == nil {
+		a.DaemonReader = bufio.NewReader(result.Conn)
+	}
+	a.useRequestIDs(result.Hello)
+}
+
+// pendingRequest is a command sent with an ID and still waiting for its
+// result. Once acked, its outcome also shows up as events, so a failure
+// is not reported twice and the timeout no longer applies.
+type pendingRequest struct {
+	label string
+	acked bool
+}
+
+// useRequestIDs forgets requests sent to a previous daemon and decides
+// whether new commands carry IDs.
+func (a *App) useRequestIDs(hello daemon.HelloResponse) {
+	a.requestIDs = hello.Supports(daemon.CapRequestIDs)
+	a.requests = make(map[string]*pendingRequest)
+}
+
+// newRequest returns the ID for a command described by label, or "" when
+// the daemon does not answer requests.
+func (a *App) newRequest(label string) string {
+	if !a.requestIDs {
+		return ""
+	}
+	id := daemon.NewRequestID()
+	a.requests[id] = &pendingRequest{label: label}
+	return id
+}
+
+func (a *App) handleDaemonAck(msg daemon.AckMsg) (tea.Model, tea.Cmd) {
+	if req := a.requests[msg.ID]; req != nil {
+		req.acked = true
+	}
+	return a, WaitForDaemonMsg(a.DaemonReader)
+}
+
+// handleDaemonResult reports a failed request and asks for the state, since
+// the TUI may already show what it expected to happen.
+func (a *App) handleDaemonResult(msg daemon.ResultMsg) (tea.Model, tea.Cmd) {
+	req := a.requests[msg.ID]
+	if req == nil {
+		return a, WaitForDaemonMsg(a.DaemonReader)
+	}
+	delete(a.requests, msg.ID)
+
+	if !msg.OK {
+		if !req.acked {
+			a.appendOutput(ui.LogError(fmt.Sprintf("%s failed [%s]: %s", req.label, msg.Code, msg.Message)))
+		}
+		a.SendToDaemon(daemon.GetStateCmd{Type: "get_state"})
+	}
+	return a, WaitForDaemonMsg(a.DaemonReader)
+}
+
+func (a *App) handleRequestTimeout(msg requestTimeoutMsg) (tea.Model, tea.Cmd) {
+	req := a.requests[msg.ID]
+	if req == nil || req.acked {
+		return a, nil
+	}
+	delete(a.requests, msg.ID)
+
+	a.appendOutput(ui.LogError(fmt.Sprintf("%s: no answer from daemon after %s", req.label, requestTimeout)))
+	a.SendToDaemon(daemon.GetStateCmd{Type: "get_state"})
+	return a, nil
 }
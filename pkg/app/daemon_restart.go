@@ -16,6 +16,7 @@ import (
 type daemonRestartedMsg struct {
 	Conn   net.Conn
 	Reader *bufio.Reader
+	Hello  daemon.HelloResponse
 }
 
 type daemonRestartFailedMsg struct {
@@ -45,7 +46,7 @@ func (a *App) restartDaemonCmd() tea.Cmd {
 		if err != nil {
 			return daemonRestartFailedMsg{Err: err}
 		}
-		return daemonRestartedMsg{Conn: result.Conn, Reader: result.Reader}
+		return daemonRestartedMsg{Conn: result.Conn, Reader: result.Reader, Hello: result.Hello}
 	}
 }
 
@@ -74,6 +74,13 @@ func (a *App) showSettingsForm() (tea.Model, tea.Cmd) {
 }
 
 func (a *App) showExportLogsForm() (tea.Model, tea.Cmd) {
+	if a.State.LogConnID == "" {
+		a.State.OutputLines = append(a.State.OutputLines, ui.LogError("[Export failed: no connection log shown]"))
+		a.viewport.SetContent(a.renderOutput())
+		a.viewport.GotoBottom()
+		return a, nil
+	}
+
 	data := helpers.NewExportFormData()
 	form := helpers.NewExportLogsForm(data, a.formWidth())
 
@@ -193,14 +200,17 @@ func (a *App) handleFormComplete() (tea.Model, tea.Cmd) {
 
 	case FormSettings:
 		data := a.State.FormData.(*helpers.SettingsFormData)
-		a.State.Config.Settings = *data.ToSettings()
+		settings := data.ToSettings()
+		// Hooks are only set in the config file.
+		settings.Hooks = a.State.Config.Settings.Hooks
+		a.State.Config.Settings = *settings
 
 		a.saveConfig()
 		a.syncConfigToDaemon()
 
 	case FormExportLogs:
 		data := a.State.FormData.(*helpers.ExportFormData)
-		if err := helpers.CopyVpnLogToPath(data.Path, data.StripANSI); err != nil {
+		if err := helpers.CopyVpnLogToPath(a.State.LogConnID, data.Path, data.StripANSI); err != nil {
 			a.State.OutputLines = append(a.State.OutputLines, ui.LogError("[Export failed: "+err.Error()+"]"))
 		} else {
 			a.State.OutputLines = append(a.State.OutputLines, ui.LogSuccess("[Logs exported to "+data.Path+"]"))
@@ -208,6 +218,17 @@ func (a *App) handleFormComplete() (tea.Model, tea.Cmd) {
 		a.viewport.SetContent(a.renderOutput())
 		a.viewport.GotoBottom()
 
+	case FormImport:
+		data := a.State.FormData.(*helpers.ImportFormData)
+		return a, loadImportCandidates(data.Source, data.Path)
+
+	case FormImportSelect:
+		data := a.State.FormData.(*helpers.ImportSelectData)
+		a.importConnections(data.SelectedCandidates(), data.Skipped, data.Options())
+
+	case FormExportBundle:
+		a.exportBundle(a.State.FormData.(*helpers.ExportBundleData))
+
 	case FormUpdateNotice:
 		return a.handleUpdateFormComplete()
 	}
@@ -271,6 +292,7 @@ func NewUpdateNoticeForm(newVersion string, data *UpdateNoticeData, isHomebrew b
 func (a *App) syncConfigToDaemon() {
 	a.SendToDaemon(daemon.ConfigUpdateCmd{
 		Type:   "config_update",
+		ID:     a.newRequest("Config update"),
 		Config: *a.State.Config,
 	})
 }
@@ -0,0 +1,86 @@
+package app
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/controllers/helpers"
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+	"github.com/Nybkox/lazyopenconnect/pkg/ui"
+)
+
+type historyLoadedMsg struct {
+	Records []models.HistoryRecord
+	Err     error
+}
+
+func loadHistory() tea.Cmd {
+	return func() tea.Msg {
+		path, err := helpers.HistoryPath()
+		if err != nil {
+			return historyLoadedMsg{Err: err}
+		}
+		records, err := helpers.LoadHistory(path)
+		return historyLoadedMsg{Records: records, Err: err}
+	}
+}
+
+// showHistory opens the history view for the selected connection.
+func (a *App) showHistory() (tea.Model, tea.Cmd) {
+	a.State.HistoryConnID = ""
+	if conn := a.State.SelectedConnection(); conn != nil {
+		a.State.HistoryConnID = conn.ID
+	}
+	a.State.HistoryScroll = 0
+	return a, loadHistory()
+}
+
+func (a *App) handleHistoryLoaded(msg historyLoadedMsg) (tea.Model, tea.Cmd) {
+	if msg.Err != nil {
+		a.appendOutput(ui.LogError("[Failed to read session history: " + msg.Err.Error() + "]"))
+		return a, nil
+	}
+	a.State.HistoryRecords = msg.Records
+	a.State.ShowingHistory = true
+	return a, nil
+}
+
+func (a *App) handleHistoryKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, a.Keys.Cancel), key.Matches(msg, a.Keys.History), key.Matches(msg, a.Keys.Detach):
+		a.State.ShowingHistory = false
+		a.State.HistoryRecords = nil
+		return a, nil
+	case key.Matches(msg, a.Keys.Quit):
+		return a.handleQuit()
+	case key.Matches(msg, a.Keys.HistoryAll):
+		if a.State.HistoryConnID != "" {
+			a.State.HistoryConnID = ""
+		} else if conn := a.State.SelectedConnection(); conn != nil {
+			a.State.HistoryConnID = conn.ID
+		}
+		a.State.HistoryScroll = 0
+		return a, nil
+	case key.Matches(msg, a.Keys.ScrollUp):
+		if a.State.HistoryScroll > 0 {
+			a.State.HistoryScroll--
+		}
+		return a, nil
+	case key.Matches(msg, a.Keys.ScrollDown):
+		a.State.HistoryScroll++
+		return a, nil
+	case key.Matches(msg, a.Keys.ScrollToTop):
+		a.State.HistoryScroll = 0
+		return a, nil
+	case key.Matches(msg, a.Keys.ScrollToBottom):
+		a.State.HistoryScroll = 999
+		return a, nil
+	case key.Matches(msg, a.Keys.PageUp):
+		a.State.HistoryScroll = max(a.State.HistoryScroll-5, 0)
+		return a, nil
+	case key.Matches(msg, a.Keys.PageDown):
+		a.State.HistoryScroll += 5
+		return a, nil
+	}
+	return a, nil
+}
@@ -0,0 +1,138 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/controllers/helpers"
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+	"github.com/Nybkox/lazyopenconnect/pkg/ui"
+)
+
+type importCandidatesMsg struct {
+	Source     helpers.ImportSource
+	Path       string
+	Candidates []helpers.ImportCandidate
+	Skipped    []helpers.ImportSkipped
+	Err        error
+}
+
+func loadImportCandidates(source helpers.ImportSource, path string) tea.Cmd {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		path = source.DefaultPath()
+	}
+	return func() tea.Msg {
+		candidates, skipped, err := helpers.ReadImportCandidates(source, path)
+		return importCandidatesMsg{Source: source, Path: path, Candidates: candidates, Skipped: skipped, Err: err}
+	}
+}
+
+func (a *App) showImportForm() (tea.Model, tea.Cmd) {
+	data := helpers.NewImportFormData()
+	form := helpers.NewImportForm(data, a.formWidth())
+
+	a.State.ActiveForm = form
+	a.State.FormKind = FormImport
+	a.State.FormData = data
+
+	return a, form.Init()
+}
+
+func (a *App) handleImportCandidates(msg importCandidatesMsg) (tea.Model, tea.Cmd) {
+	if msg.Err != nil {
+		a.appendOutput(ui.LogError("[Import failed: " + msg.Err.Error() + "]"))
+		return a, nil
+	}
+
+	if len(msg.Candidates) == 0 {
+		a.appendOutput(ui.LogWarning("[No connections found in " + msg.Path + "]"))
+		a.appendImportSkipped(msg.Skipped)
+		return a, nil
+	}
+
+	conflicts := helpers.FindImportConflicts(a.State.Config, msg.Candidates)
+	data := helpers.NewImportSelectData(msg.Source, msg.Candidates, msg.Skipped, conflicts)
+	form := helpers.NewImportSelectForm(data, a.formWidth())
+
+	a.State.ActiveForm = form
+	a.State.FormKind = FormImportSelect
+	a.State.FormData = data
+
+	return a, form.Init()
+}
+
+func (a *App) importConnections(candidates []helpers.ImportCandidate, skipped []helpers.ImportSkipped, opts helpers.ImportOptions) {
+	report := helpers.ApplyImport(a.State.Config, candidates, opts)
+	if len(report.Added)+len(report.Updated) > 0 {
+		helpers.SaveImportedPasswords(a.State.Config, &report)
+		a.saveConfig()
+		a.syncConfigToDaemon()
+	}
+
+	for _, conn := range report.Added {
+		a.appendOutput(ui.LogOK("+ " + conn.Name + " (" + conn.Host + ")"))
+	}
+	for _, conn := range report.Updated {
+		a.appendOutput(ui.LogOK("~ " + conn.Name + " (" + conn.Host + ")"))
+	}
+	a.appendImportSkipped(append(skipped, report.Skipped...))
+	for _, warning := range report.Warnings {
+		a.appendOutput(ui.LogWarning("[" + warning + "]"))
+	}
+	a.appendOutput(ui.LogSuccess(fmt.Sprintf("[Imported: %d new, %d updated, %d skipped]", len(report.Added), len(report.Updated), len(skipped)+len(report.Skipped))))
+}
+
+func (a *App) appendImportSkipped(skipped []helpers.ImportSkipped) {
+	for _, skip := range skipped {
+		a.appendOutput(ui.LogFail("- " + skip.Name + ": " + skip.Reason))
+	}
+}
+
+func (a *App) showExportBundleForm() (tea.Model, tea.Cmd) {
+	conn := a.State.SelectedConnection()
+	if conn == nil {
+		return a, nil
+	}
+
+	data := helpers.NewExportBundleData(conn.ID)
+	form := helpers.NewExportBundleForm(data, a.State.Config.Connections, a.formWidth())
+
+	a.State.ActiveForm = form
+	a.State.FormKind = FormExportBundle
+	a.State.FormData = data
+
+	return a, form.Init()
+}
+
+func (a *App) exportBundle(data *helpers.ExportBundleData) {
+	selected := make(map[string]bool, len(data.Selected))
+	for _, id := range data.Selected {
+		selected[id] = true
+	}
+	var conns []models.Connection
+	for _, conn := range a.State.Config.Connections {
+		if selected[conn.ID] {
+			conns = append(conns, conn)
+		}
+	}
+	bundle := helpers.NewBundle(conns)
+
+	if data.AsURI {
+		if err := helpers.CopyBundleURIToClipboard(bundle); err != nil {
+			a.appendOutput(ui.LogError("[Share failed: " + err.Error() + "]"))
+			return
+		}
+		a.appendOutput(ui.LogSuccess(fmt.Sprintf("[Copied %d connection(s) to clipboard as a lazyopenconnect:// URI]", len(conns))))
+		return
+	}
+
+	path := strings.TrimSpace(data.Path)
+	if err := helpers.WriteBundleFile(path, bundle); err != nil {
+		a.appendOutput(ui.LogError("[Share failed: " + err.Error() + "]"))
+		return
+	}
+	a.appendOutput(ui.LogSuccess(fmt.Sprintf("[Exported %d connection(s) to %s]", len(conns), path)))
+}
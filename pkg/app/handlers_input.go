@@ -27,12 +27,15 @@ func (a *App) updateOutput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 		a.State.ClearLogsPending = false
 		a.clearOutputLogs()
-		a.SendToDaemon(daemon.ClearLogsCmd{Type: "clear_logs"})
-		return a, nil
+		id := a.newRequest("Clear logs")
+		a.SendToDaemon(daemon.ClearLogsCmd{Type: "clear_logs", ID: id, ConnID: a.State.LogConnID})
+		return a, scheduleRequestTimeout(id)
 	case key.Matches(msg, a.Keys.Export):
 		return a.showExportLogsForm()
 	case key.Matches(msg, a.Keys.CopyLogs):
-		if err := helpers.CopyVpnLogToClipboard(); err != nil {
+		if a.State.LogConnID == "" {
+			a.State.OutputLines = append(a.State.OutputLines, ui.LogError("[Copy failed: no connection log shown]"))
+		} else if err := helpers.CopyVpnLogToClipboard(a.State.LogConnID); err != nil {
 			a.State.OutputLines = append(a.State.OutputLines, ui.LogError("[Copy failed: "+err.Error()+"]"))
 		} else {
 			a.State.OutputLines = append(a.State.OutputLines, ui.LogSuccess("[Logs copied to clipboard]"))
@@ -60,6 +63,9 @@ func (a *App) updateOutput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 func (a *App) clearOutputLogs() {
 	a.State.OutputLines = []string{}
 	a.State.TotalLogLines = 0
+	if sess := a.State.Sessions[a.State.LogConnID]; sess != nil {
+		sess.TotalLogLines = 0
+	}
 	a.State.LogLoadedFrom = 0
 	a.State.LogLoadedTo = 0
 	a.viewport.SetContent(a.renderOutput())
@@ -72,8 +78,9 @@ func (a *App) updateInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			value := a.input.Value()
 
 			a.SendToDaemon(daemon.InputCmd{
-				Type:  "input",
-				Value: value,
+				Type:   "input",
+				ConnID: a.State.PromptConnID,
+				Value:  value,
 			})
 
 			displayValue := value
@@ -85,7 +92,10 @@ func (a *App) updateInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			a.input.SetValue("")
 			a.input.EchoMode = textinput.EchoNormal
 			a.State.IsPasswordPrompt = false
-			a.State.Status = StatusConnecting
+			if sess := a.State.Sessions[a.State.PromptConnID]; sess != nil {
+				sess.Status = StatusConnecting
+			}
+			a.State.PromptConnID = ""
 
 			a.viewport.SetContent(a.renderOutput())
 			a.viewport.GotoBottom()
@@ -9,6 +9,10 @@ import (
 const MaxLoadedLines = 1000
 
 func (a *App) handleLogRange(msg daemon.LogRangeMsg) (tea.Model, tea.Cmd) {
+	if msg.ConnID != a.State.LogConnID {
+		return a, WaitForDaemonMsg(a.DaemonReader)
+	}
+
 	a.State.TotalLogLines = msg.TotalLines
 	a.State.LogLoadedFrom = msg.From
 	a.State.OutputLines = append([]string(nil), msg.Lines...)
@@ -19,7 +23,38 @@ func (a *App) handleLogRange(msg daemon.LogRangeMsg) (tea.Model, tea.Cmd) {
 }
 
 func (a *App) requestLogs(from, to int) {
-	a.SendToDaemon(daemon.GetLogsCmd{Type: "get_logs", From: from, To: to})
+	if a.State.LogConnID == "" {
+		return
+	}
+	a.SendToDaemon(daemon.GetLogsCmd{Type: "get_logs", ConnID: a.State.LogConnID, From: from, To: to})
+}
+
+// showLogsFor switches the Output pane to connID's log and loads its tail.
+func (a *App) showLogsFor(connID string) {
+	a.State.LogConnID = connID
+	a.State.OutputLines = []string{}
+	a.State.TotalLogLines = 0
+	a.State.LogLoadedFrom = 0
+	a.State.LogLoadedTo = 0
+	if sess := a.State.Sessions[connID]; sess != nil {
+		a.State.TotalLogLines = sess.TotalLogLines
+	}
+	a.viewport.SetContent(a.renderOutput())
+	a.viewport.GotoBottom()
+
+	if a.State.TotalLogLines > 0 {
+		a.requestLogs(max(0, a.State.TotalLogLines-MaxLoadedLines), a.State.TotalLogLines)
+	}
+}
+
+// followSelection shows the log of the selected connection when it has a
+// running tunnel.
+func (a *App) followSelection() {
+	conn := a.State.SelectedConnection()
+	if conn == nil || conn.ID == a.State.LogConnID || a.State.Sessions[conn.ID] == nil {
+		return
+	}
+	a.showLogsFor(conn.ID)
 }
 
 func (a *App) shouldFetchLogs() (bool, int, int) {
@@ -74,10 +74,16 @@ func (a *App) updateConnections(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return a.cleanup()
 	case key.Matches(msg, a.Keys.New):
 		return a.showNewConnForm()
+	case key.Matches(msg, a.Keys.Import):
+		return a.showImportForm()
+	case key.Matches(msg, a.Keys.Export):
+		return a.showExportBundleForm()
 	case key.Matches(msg, a.Keys.Edit):
 		return a.showEditConnForm()
 	case key.Matches(msg, a.Keys.Delete):
 		return a.showDeleteConfirm()
+	case key.Matches(msg, a.Keys.History):
+		return a.showHistory()
 	}
 	return a, nil
 }
@@ -1,6 +1,8 @@
 package app
 
 import (
+	"time"
+
 	tea "github.com/charmbracelet/bubbletea"
 
 	"github.com/Nybkox/lazyopenconnect/pkg/controllers/helpers"
@@ -14,7 +16,7 @@ func (a *App) connect() (tea.Model, tea.Cmd) {
 		return a, nil
 	}
 
-	if a.State.Status != StatusDisconnected {
+	if a.State.Sessions[conn.ID] != nil {
 		return a, nil
 	}
 
@@ -29,8 +31,8 @@ func (a *App) connect() (tea.Model, tea.Cmd) {
 		}
 	}
 
-	a.State.Status = StatusConnecting
-	a.State.ActiveConnID = conn.ID
+	a.State.Sessions[conn.ID] = &Session{Status: StatusConnecting}
+	a.State.LogConnID = conn.ID
 	a.State.OutputLines = []string{}
 	a.State.TotalLogLines = 0
 	a.State.LogLoadedFrom = 0
@@ -40,61 +42,75 @@ func (a *App) connect() (tea.Model, tea.Cmd) {
 	}
 	a.viewport.SetContent(a.renderOutput())
 
+	a.SendToDaemon(daemon.TakeOwnershipCmd{Type: "take_ownership", Force: true})
+	id := a.newRequest("Connect to " + conn.Name)
 	a.SendToDaemon(daemon.ConnectCmd{
 		Type:     "connect",
+		ID:       id,
 		ConnID:   conn.ID,
 		Password: password,
 	})
 
-	return a, tea.Batch(spinnerTick(), scheduleConnectionTimeout())
+	return a, tea.Batch(a.startSpinner(), scheduleRequestTimeout(id))
 }
 
-func (a *App) handleConnectionTimeout() (tea.Model, tea.Cmd) {
-	if a.State.Status != StatusConnecting {
+// disconnect stops the selected connection's tunnel, or the external
+// openconnect when that is what the selected connection points at.
+func (a *App) disconnect() (tea.Model, tea.Cmd) {
+	conn := a.State.SelectedConnection()
+	if conn == nil {
 		return a, nil
 	}
+	if a.State.Sessions[conn.ID] != nil {
+		return a.disconnectSession(conn.ID)
+	}
+	if a.State.IsExternal(conn) {
+		return a.disconnectExternal()
+	}
+	return a, nil
+}
 
-	a.State.OutputLines = append(a.State.OutputLines,
-		ui.LogError("Connection timed out after 30s"))
+func (a *App) disconnectSession(connID string) (tea.Model, tea.Cmd) {
+	sess := a.State.Sessions[connID]
+	if sess == nil {
+		return a, nil
+	}
+
+	name := a.State.ConnectionName(connID)
+	if sess.Status == StatusReconnecting {
+		delete(a.State.Sessions, connID)
+		a.State.OutputLines = append(a.State.OutputLines, "--- Reconnect cancelled: "+name+" ---")
+	} else {
+		a.State.OutputLines = append(a.State.OutputLines, "--- Disconnecting "+name+" ---")
+	}
 	a.viewport.SetContent(a.renderOutput())
 	a.viewport.GotoBottom()
 
-	a.SendToDaemon(daemon.DisconnectCmd{Type: "disconnect"})
+	id := a.newRequest("Disconnect " + name)
+	a.SendToDaemon(daemon.DisconnectCmd{Type: "disconnect", ID: id, ConnID: connID})
 
-	return a, nil
+	return a, scheduleRequestTimeout(id)
 }
 
-func (a *App) disconnect() (tea.Model, tea.Cmd) {
-	if a.State.Status == StatusReconnecting {
-		a.State.Status = StatusDisconnected
-		a.State.ReconnectAttempts = 0
-		a.State.ReconnectConnID = ""
-		a.State.ActiveConnID = ""
-		a.State.OutputLines = append(a.State.OutputLines, "--- Reconnect cancelled ---")
-		a.viewport.SetContent(a.renderOutput())
-		a.viewport.GotoBottom()
-		a.SendToDaemon(daemon.DisconnectCmd{Type: "disconnect"})
-		return a, nil
-	}
-
-	if a.State.Status == StatusDisconnected {
+func (a *App) disconnectExternal() (tea.Model, tea.Cmd) {
+	if !a.State.HasExternal() {
 		return a, nil
 	}
 
-	a.State.ReconnectConnID = ""
-
-	a.State.OutputLines = append(a.State.OutputLines, "--- Disconnecting ---")
+	a.State.OutputLines = append(a.State.OutputLines, "--- Disconnecting external openconnect ---")
 	a.viewport.SetContent(a.renderOutput())
 	a.viewport.GotoBottom()
 
-	a.SendToDaemon(daemon.DisconnectCmd{Type: "disconnect"})
+	id := a.newRequest("Disconnect external openconnect")
+	a.SendToDaemon(daemon.DisconnectCmd{Type: "disconnect", ID: id, External: true})
 
-	return a, nil
+	return a, scheduleRequestTimeout(id)
 }
 
 func (a *App) cleanup() (tea.Model, tea.Cmd) {
-	a.SendToDaemon(daemon.CleanupCmd{Type: "cleanup"})
-	return a, nil
+	id := a.newRequest("Cleanup")
+	a.SendToDaemon(daemon.CleanupCmd{Type: "cleanup", ID: id})
+	return a, scheduleRequestTimeout(id)
 }
 
 func (a *App) renderOutput() string {
@@ -106,13 +122,67 @@ func (a *App) renderOutput() string {
 }
 
 func (a *App) handleDaemonReconnecting(msg daemon.ReconnectingMsg) (tea.Model, tea.Cmd) {
-	a.State.Status = StatusReconnecting
-	a.State.ActiveConnID = msg.ConnID
-	a.State.ReconnectAttempts = msg.Attempt
+	sess := a.State.Sessions[msg.ConnID]
+	if sess == nil {
+		sess = &Session{}
+		a.State.Sessions[msg.ConnID] = sess
+	}
+	sess.Status = StatusReconnecting
+	sess.IP = ""
+	sess.PID = 0
+	sess.Traffic = nil
+	sess.Health = nil
+	sess.ReconnectAttempts = msg.Attempt
+	sess.ReconnectMax = msg.Max
+
+	return a, tea.Batch(a.startSpinner(), WaitForDaemonMsg(a.DaemonReader))
+}
 
-	if msg.Attempt == 0 {
-		return a, tea.Batch(spinnerTick(), WaitForDaemonMsg(a.DaemonReader))
+func (a *App) handleDaemonStats(msg daemon.StatsMsg) (tea.Model, tea.Cmd) {
+	sess := a.State.Sessions[msg.ConnID]
+	if sess == nil {
+		return a, WaitForDaemonMsg(a.DaemonReader)
 	}
 
+	var rates []float64
+	if sess.Traffic != nil {
+		rates = sess.Traffic.Rates
+	}
+	rates = append(rates, msg.RxRate+msg.TxRate)
+	if len(rates) > maxRateSamples {
+		rates = rates[len(rates)-maxRateSamples:]
+	}
+
+	sess.Traffic = &Traffic{
+		RxBytes:  msg.RxBytes,
+		TxBytes:  msg.TxBytes,
+		RxErrors: msg.RxErrors,
+		TxErrors: msg.TxErrors,
+		RxRate:   msg.RxRate,
+		TxRate:   msg.TxRate,
+		Rates:    rates,
+	}
 	return a, WaitForDaemonMsg(a.DaemonReader)
 }
+
+func (a *App) handleDaemonHealth(msg daemon.HealthMsg) (tea.Model, tea.Cmd) {
+	if sess := a.State.Sessions[msg.ConnID]; sess != nil {
+		sess.Health = &Health{
+			OK:        msg.OK,
+			RTT:       time.Duration(msg.RTTMs * float64(time.Millisecond)),
+			Error:     msg.Error,
+			Failures:  msg.Failures,
+			Threshold: msg.Threshold,
+		}
+	}
+	return a, WaitForDaemonMsg(a.DaemonReader)
+}
+
+// startSpinner starts the spinner unless it is already ticking.
+func (a *App) startSpinner() tea.Cmd {
+	if a.spinning {
+		return nil
+	}
+	a.spinning = true
+	return spinnerTick()
+}
@@ -3,14 +3,17 @@ package app
 import "github.com/charmbracelet/bubbles/key"
 
 type KeyMap struct {
-	Quit       key.Binding
-	Detach     key.Binding
-	FocusPane1 key.Binding
-	FocusPane2 key.Binding
-	FocusPane3 key.Binding
-	FocusPane4 key.Binding
-	FocusPane5 key.Binding
-	TabFocus   key.Binding
+	Quit   key.Binding
+	Detach key.Binding
+	// TakePrompts makes this client answer prompts, taking them from
+	// another attached client.
+	TakePrompts key.Binding
+	FocusPane1  key.Binding
+	FocusPane2  key.Binding
+	FocusPane3  key.Binding
+	FocusPane4  key.Binding
+	FocusPane5  key.Binding
+	TabFocus    key.Binding
 
 	Up            key.Binding
 	Down          key.Binding
@@ -21,6 +24,7 @@ type KeyMap struct {
 	Edit          key.Binding
 	Delete        key.Binding
 	New           key.Binding
+	Import        key.Binding
 	Settings      key.Binding
 
 	ScrollUp       key.Binding
@@ -41,6 +45,9 @@ type KeyMap struct {
 
 	Help key.Binding
 
+	History    key.Binding
+	HistoryAll key.Binding
+
 	Search   key.Binding
 	MoveUp   key.Binding
 	MoveDown key.Binding
@@ -48,14 +55,15 @@ type KeyMap struct {
 
 func DefaultKeyMap() KeyMap {
 	return KeyMap{
-		Quit:       key.NewBinding(key.WithKeys("Q")),
-		Detach:     key.NewBinding(key.WithKeys("q", "ctrl+c")),
-		FocusPane1: key.NewBinding(key.WithKeys("1")),
-		FocusPane2: key.NewBinding(key.WithKeys("2")),
-		FocusPane3: key.NewBinding(key.WithKeys("3")),
-		FocusPane4: key.NewBinding(key.WithKeys("4")),
-		FocusPane5: key.NewBinding(key.WithKeys("5")),
-		TabFocus:   key.NewBinding(key.WithKeys("tab")),
+		Quit:        key.NewBinding(key.WithKeys("Q")),
+		Detach:      key.NewBinding(key.WithKeys("q", "ctrl+c")),
+		TakePrompts: key.NewBinding(key.WithKeys("o")),
+		FocusPane1:  key.NewBinding(key.WithKeys("1")),
+		FocusPane2:  key.NewBinding(key.WithKeys("2")),
+		FocusPane3:  key.NewBinding(key.WithKeys("3")),
+		FocusPane4:  key.NewBinding(key.WithKeys("4")),
+		FocusPane5:  key.NewBinding(key.WithKeys("5")),
+		TabFocus:    key.NewBinding(key.WithKeys("tab")),
 
 		Up:            key.NewBinding(key.WithKeys("k", "up")),
 		Down:          key.NewBinding(key.WithKeys("j", "down")),
@@ -66,6 +74,7 @@ func DefaultKeyMap() KeyMap {
 		Edit:          key.NewBinding(key.WithKeys("e")),
 		Delete:        key.NewBinding(key.WithKeys("x")),
 		New:           key.NewBinding(key.WithKeys("n")),
+		Import:        key.NewBinding(key.WithKeys("i")),
 		Settings:      key.NewBinding(key.WithKeys("s")),
 
 		ScrollUp:       key.NewBinding(key.WithKeys("k", "up")),
@@ -86,6 +95,9 @@ func DefaultKeyMap() KeyMap {
 
 		Help: key.NewBinding(key.WithKeys("?")),
 
+		History:    key.NewBinding(key.WithKeys("h")),
+		HistoryAll: key.NewBinding(key.WithKeys("a")),
+
 		Search:   key.NewBinding(key.WithKeys("/")),
 		MoveUp:   key.NewBinding(key.WithKeys("K")),
 		MoveDown: key.NewBinding(key.WithKeys("J")),
@@ -14,13 +14,19 @@ func spinnerTick() tea.Cmd {
 	})
 }
 
-type connectionTimeoutMsg struct{}
+type requestTimeoutMsg struct {
+	ID string
+}
 
-const connectionTimeout = 30 * time.Second
+// requestTimeout is how long a request may go without an ack or result.
+const requestTimeout = 10 * time.Second
 
-func scheduleConnectionTimeout() tea.Cmd {
-	return tea.Tick(connectionTimeout, func(time.Time) tea.Msg {
-		return connectionTimeoutMsg{}
+func scheduleRequestTimeout(id string) tea.Cmd {
+	if id == "" {
+		return nil
+	}
+	return tea.Tick(requestTimeout, func(time.Time) tea.Msg {
+		return requestTimeoutMsg{ID: id}
 	})
 }
 
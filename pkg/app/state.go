@@ -1,7 +1,9 @@
 package app
 
 import (
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/huh"
 
@@ -40,23 +42,82 @@ const (
 	FormDeleteConfirm
 	FormExportLogs
 	FormUpdateNotice
+	FormImport
+	FormImportSelect
+	FormExportBundle
 )
 
+// Session is a tunnel run by the daemon, as last reported to this client.
+type Session struct {
+	Status            ConnStatus
+	IP                string
+	PID               int
+	Tunnel            string
+	ConnectedAt       time.Time
+	ReconnectAttempts int
+	// ReconnectMax is the attempt limit of the running reconnect, 0 when
+	// unlimited.
+	ReconnectMax  int
+	TotalLogLines int
+	// Deadline is when the daemon gives up connecting or waiting for an
+	// answer; zero when it waits indefinitely.
+	Deadline time.Time
+	// IncludeRoutes and ExcludeRoutes are the split routes the daemon
+	// applied.
+	IncludeRoutes []string
+	ExcludeRoutes []string
+	// Traffic is nil until the daemon sent the first stats sample.
+	Traffic *Traffic
+	// Health is nil until the first health probe of this run reported.
+	Health *Health
+}
+
+// maxRateSamples is how much throughput history the Status pane keeps.
+const maxRateSamples = 32
+
+// Traffic is the last stats sample of a connected tunnel.
+type Traffic struct {
+	RxBytes  uint64
+	TxBytes  uint64
+	RxErrors uint64
+	TxErrors uint64
+	RxRate   float64
+	TxRate   float64
+	// Rates holds recent combined throughput in bytes per second, oldest
+	// first.
+	Rates []float64
+}
+
+// Health is the last health probe of a connected tunnel.
+type Health struct {
+	OK        bool
+	RTT       time.Duration
+	Error     string
+	Failures  int
+	Threshold int
+}
+
 type State struct {
 	Config *models.Config
 
-	Selected         int
-	ActiveConnID     string
-	Status           ConnStatus
-	IP               string
-	PID              int
+	Selected int
+	// Sessions holds the daemon's tunnels keyed by connection ID.
+	Sessions         map[string]*Session
 	IsPasswordPrompt bool
-	ExternalHost     string
+	// PromptConnID is the connection whose prompt the Input pane answers.
+	PromptConnID string
+	ExternalHost string
+	ExternalPID  int
+	// KillSwitch is set while the daemon's kill switch blocks traffic
+	// outside the tunnels.
+	KillSwitch bool
+	// IsOwner reports whether this client receives and answers prompts.
+	IsOwner  bool
+	Quitting bool
 
-	ReconnectAttempts int
-	ReconnectConnID   string
-
-	FocusedPane        FocusedPane
+	FocusedPane FocusedPane
+	// LogConnID is the connection whose log the Output pane shows.
+	LogConnID          string
 	OutputLines        []string
 	TotalLogLines      int
 	LogLoadedFrom      int
@@ -85,6 +146,13 @@ type State struct {
 	ShowingHelp bool
 	HelpScroll  int
 
+	ShowingHistory bool
+	HistoryScroll  int
+	// HistoryConnID limits the history view to one connection; empty
+	// shows every connection.
+	HistoryConnID  string
+	HistoryRecords []models.HistoryRecord
+
 	FilterActive  bool
 	FilterText    string
 	FilterIndices []int
@@ -94,7 +162,7 @@ func NewState(cfg *models.Config) *State {
 	return &State{
 		Config:      cfg,
 		Selected:    0,
-		Status:      StatusDisconnected,
+		Sessions:    make(map[string]*Session),
 		FocusedPane: PaneConnections,
 		OutputLines: []string{},
 	}
@@ -112,8 +180,66 @@ func (s *State) FindConnectionByID(id string) *models.Connection {
 	return nil
 }
 
-func (s *State) ActiveConnection() *models.Connection {
-	return s.FindConnectionByID(s.ActiveConnID)
+// SessionIDs returns the connection IDs of all sessions, sorted.
+func (s *State) SessionIDs() []string {
+	ids := make([]string, 0, len(s.Sessions))
+	for id := range s.Sessions {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// StatusConnID is the session the Status pane describes: the one whose log
+// is shown, else the first one.
+func (s *State) StatusConnID() string {
+	if s.Sessions[s.LogConnID] != nil {
+		return s.LogConnID
+	}
+	if ids := s.SessionIDs(); len(ids) > 0 {
+		return ids[0]
+	}
+	return ""
+}
+
+// Busy reports whether any session is connecting, reconnecting or counting
+// down to a deadline.
+func (s *State) Busy() bool {
+	for _, sess := range s.Sessions {
+		if sess.Status == StatusConnecting || sess.Status == StatusReconnecting || !sess.Deadline.IsZero() {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *State) HasExternal() bool {
+	return s.ExternalPID != 0
+}
+
+// IsExternal reports whether conn is the one an external openconnect is
+// connected to.
+func (s *State) IsExternal(conn *models.Connection) bool {
+	return s.HasExternal() && conn != nil && conn.Host == s.ExternalHost
+}
+
+// ConnectionName returns the saved name for connID, falling back to the ID.
+func (s *State) ConnectionName(connID string) string {
+	if conn := s.FindConnectionByID(connID); conn != nil {
+		return conn.Name
+	}
+	return connID
+}
+
+// VisibleHistory returns the history records the view shows, newest first.
+func (s *State) VisibleHistory() []models.HistoryRecord {
+	var records []models.HistoryRecord
+	for i := len(s.HistoryRecords) - 1; i >= 0; i-- {
+		if s.HistoryConnID == "" || s.HistoryRecords[i].ConnID == s.HistoryConnID {
+			records = append(records, s.HistoryRecords[i])
+		}
+	}
+	return records
 }
 
 func (s *State) RealIndex(selected int) int {
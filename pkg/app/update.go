@@ -3,6 +3,7 @@ package app
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textinput"
@@ -67,12 +68,18 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case UpdateCheckMsg:
 		return a.handleUpdateCheck(msg)
 
-	case connectionTimeoutMsg:
-		return a.handleConnectionTimeout()
+	case requestTimeoutMsg:
+		return a.handleRequestTimeout(msg)
 
 	case UpdatePerformedMsg:
 		return a.handleUpdatePerformed(msg)
 
+	case importCandidatesMsg:
+		return a.handleImportCandidates(msg)
+
+	case historyLoadedMsg:
+		return a.handleHistoryLoaded(msg)
+
 	case tea.KeyMsg:
 		return a.handleKeyMsg(msg)
 	}
@@ -101,17 +108,25 @@ func (a *App) handleDaemonMsg(msg DaemonMsg) (tea.Model, tea.Cmd) {
 	case "connected":
 		return handleTypedDaemonMsg(a, msg.Raw, a.handleDaemonConnected)
 	case "disconnected":
-		return a.handleDaemonDisconnectedEvent()
+		return handleTypedDaemonMsg(a, msg.Raw, a.handleDaemonDisconnectedEvent)
 	case "error":
 		return handleTypedDaemonMsg(a, msg.Raw, a.handleDaemonError)
-	case "kicked":
-		return a.handleKicked()
+	case "ownership":
+		return handleTypedDaemonMsg(a, msg.Raw, a.handleOwnership)
 	case "reconnecting":
 		return handleTypedDaemonMsg(a, msg.Raw, a.handleDaemonReconnecting)
+	case "stats":
+		return handleTypedDaemonMsg(a, msg.Raw, a.handleDaemonStats)
+	case "health":
+		return handleTypedDaemonMsg(a, msg.Raw, a.handleDaemonHealth)
 	case "cleanup_step":
 		return handleTypedDaemonMsg(a, msg.Raw, a.handleDaemonCleanupStep)
 	case "cleanup_done":
 		return a.handleDaemonCleanupDone()
+	case "ack":
+		return handleTypedDaemonMsg(a, msg.Raw, a.handleDaemonAck)
+	case "result":
+		return handleTypedDaemonMsg(a, msg.Raw, a.handleDaemonResult)
 	}
 
 	return a, WaitForDaemonMsg(a.DaemonReader)
@@ -132,7 +147,7 @@ func handleTypedDaemonMsg[T any](a *App, msg daemon.IncomingMsg, handler func(T)
 func (a *App) handleHelloResponse(msg daemon.HelloResponse) (tea.Model, tea.Cmd) {
 	if !msg.Compatible {
 		a.State.OutputLines = append(a.State.OutputLines,
-			ui.LogError("Daemon version mismatch. Please restart the daemon."))
+			ui.LogError("Daemon speaks an incompatible protocol. Please restart the daemon."))
 		a.viewport.SetContent(a.renderOutput())
 		return a, tea.Quit
 	}
@@ -148,21 +163,66 @@ func (a *App) handleHelloResponse(msg daemon.HelloResponse) (tea.Model, tea.Cmd)
 }
 
 func (a *App) handleDaemonState(msg daemon.StateMsg) (tea.Model, tea.Cmd) {
-	a.State.Status = ConnStatus(msg.Status)
-	a.State.ActiveConnID = msg.ActiveConnID
-	a.State.IP = msg.IP
-	a.State.PID = msg.PID
+	sessions := make(map[string]*Session, len(msg.Sessions))
+	for _, ss := range msg.Sessions {
+		sess := &Session{
+			Status:        ConnStatus(ss.Status),
+			IP:            ss.IP,
+			PID:           ss.PID,
+			Tunnel:        ss.Tunnel,
+			TotalLogLines: ss.TotalLogLines,
+			IncludeRoutes: ss.IncludeRoutes,
+			ExcludeRoutes: ss.ExcludeRoutes,
+		}
+		if ss.ConnectedAt != 0 {
+			sess.ConnectedAt = time.Unix(ss.ConnectedAt, 0)
+		}
+		if ss.Deadline != 0 {
+			sess.Deadline = time.Unix(ss.Deadline, 0)
+		}
+		if prev := a.State.Sessions[ss.ConnID]; prev != nil {
+			sess.ReconnectAttempts = prev.ReconnectAttempts
+			sess.ReconnectMax = prev.ReconnectMax
+			if sess.Status == StatusConnected {
+				sess.Traffic = prev.Traffic
+				sess.Health = prev.Health
+			}
+		}
+		sessions[ss.ConnID] = sess
+	}
+	a.State.Sessions = sessions
 	a.State.ExternalHost = msg.ExternalHost
-	a.State.TotalLogLines = msg.TotalLogLines
-	if a.State.TotalLogLines > 0 {
-		from := max(0, a.State.TotalLogLines-MaxLoadedLines)
-		a.requestLogs(from, a.State.TotalLogLines)
+	a.State.ExternalPID = msg.ExternalPID
+	a.State.KillSwitch = msg.KillSwitch
+
+	if a.State.LogConnID == "" && len(msg.Sessions) > 0 {
+		a.State.LogConnID = msg.Sessions[0].ConnID
+	}
+	if sess := sessions[a.State.LogConnID]; sess != nil && sess.TotalLogLines != a.State.TotalLogLines {
+		a.State.TotalLogLines = sess.TotalLogLines
+		if a.State.TotalLogLines > 0 {
+			from := max(0, a.State.TotalLogLines-MaxLoadedLines)
+			a.requestLogs(from, a.State.TotalLogLines)
+		}
 	}
 
+	if a.State.Busy() {
+		return a, tea.Batch(a.startSpinner(), WaitForDaemonMsg(a.DaemonReader))
+	}
 	return a, WaitForDaemonMsg(a.DaemonReader)
 }
 
 func (a *App) handleDaemonLog(msg daemon.LogMsg) (tea.Model, tea.Cmd) {
+	if sess := a.State.Sessions[msg.ConnID]; sess != nil {
+		sess.TotalLogLines = msg.LineNumber + 1
+	}
+	if a.State.LogConnID == "" {
+		a.showLogsFor(msg.ConnID)
+	}
+	if msg.ConnID != a.State.LogConnID {
+		return a, WaitForDaemonMsg(a.DaemonReader)
+	}
+
 	a.State.TotalLogLines = msg.LineNumber + 1
 
 	if msg.LineNumber == a.State.LogLoadedTo {
@@ -185,7 +245,16 @@ func (a *App) handleDaemonLog(msg daemon.LogMsg) (tea.Model, tea.Cmd) {
 }
 
 func (a *App) handleDaemonPrompt(msg daemon.PromptMsg) (tea.Model, tea.Cmd) {
-	a.State.Status = StatusPrompting
+	sess := a.State.Sessions[msg.ConnID]
+	if sess == nil {
+		sess = &Session{}
+		a.State.Sessions[msg.ConnID] = sess
+	}
+	sess.Status = StatusPrompting
+	a.State.PromptConnID = msg.ConnID
+	if msg.ConnID != a.State.LogConnID {
+		a.showLogsFor(msg.ConnID)
+	}
 	a.State.FocusedPane = PaneInput
 
 	a.State.IsPasswordPrompt = msg.IsPassword
@@ -200,23 +269,44 @@ func (a *App) handleDaemonPrompt(msg daemon.PromptMsg) (tea.Model, tea.Cmd) {
 }
 
 func (a *App) handleDaemonConnected(msg daemon.ConnectedMsg) (tea.Model, tea.Cmd) {
-	a.State.Status = StatusConnected
-	a.State.ReconnectAttempts = 0
-	a.State.ReconnectConnID = ""
+	sess := a.State.Sessions[msg.ConnID]
+	if sess == nil {
+		sess = &Session{}
+		a.State.Sessions[msg.ConnID] = sess
+	}
+	sess.Status = StatusConnected
+	sess.ReconnectAttempts = 0
+	sess.Deadline = time.Time{}
+	sess.Tunnel = msg.Tunnel
 
 	if msg.IP != "" {
-		a.State.IP = msg.IP
+		sess.IP = msg.IP
 	}
 	if msg.PID != 0 {
-		a.State.PID = msg.PID
+		sess.PID = msg.PID
 	}
 
 	return a, WaitForDaemonMsg(a.DaemonReader)
 }
 
-func (a *App) handleDaemonDisconnectedEvent() (tea.Model, tea.Cmd) {
-	if a.State.Status == StatusQuitting {
-		if a.State.Config.Settings.AutoCleanup {
+func (a *App) handleDaemonDisconnectedEvent(msg daemon.DisconnectedMsg) (tea.Model, tea.Cmd) {
+	name := "external openconnect"
+	if msg.ConnID == "" {
+		a.State.ExternalHost = ""
+		a.State.ExternalPID = 0
+	} else {
+		name = a.State.ConnectionName(msg.ConnID)
+		delete(a.State.Sessions, msg.ConnID)
+		if a.State.PromptConnID == msg.ConnID {
+			a.clearPrompt()
+		}
+	}
+
+	if a.State.Quitting {
+		if len(a.State.Sessions) > 0 || a.State.HasExternal() {
+			return a, WaitForDaemonMsg(a.DaemonReader)
+		}
+		if msg.ConnID != "" && a.State.Config.Settings.AutoCleanup {
 			return a, WaitForDaemonMsg(a.DaemonReader)
 		}
 		if a.DaemonConn != nil {
@@ -227,33 +317,61 @@ func (a *App) handleDaemonDisconnectedEvent() (tea.Model, tea.Cmd) {
 		return a, tea.Quit
 	}
 
-	a.State.Status = StatusDisconnected
-	a.State.ActiveConnID = ""
-	a.State.IP = ""
-	a.State.PID = 0
-	a.State.ReconnectAttempts = 0
-	a.State.ReconnectConnID = ""
-	a.State.OutputLines = append(a.State.OutputLines, "--- Disconnected ---")
+	a.State.OutputLines = append(a.State.OutputLines, "--- Disconnected "+name+" ---")
 	a.viewport.SetContent(a.renderOutput())
 	a.viewport.GotoBottom()
 
 	return a, WaitForDaemonMsg(a.DaemonReader)
 }
 
+// clearPrompt drops a pending prompt, e.g. when its session ended or this
+// client lost ownership.
+func (a *App) clearPrompt() {
+	a.State.PromptConnID = ""
+	a.State.IsPasswordPrompt = false
+	a.input.Blur()
+	a.input.SetValue("")
+	a.input.EchoMode = textinput.EchoNormal
+	if a.State.FocusedPane == PaneInput {
+		a.State.FocusedPane = PaneConnections
+	}
+}
+
 func (a *App) handleDaemonError(msg daemon.ErrorMsg) (tea.Model, tea.Cmd) {
-	a.State.OutputLines = append(a.State.OutputLines,
-		ui.LogError(fmt.Sprintf("Error [%s]: %s", msg.Code, msg.Message)))
+	line := fmt.Sprintf("Error [%s]: %s", msg.Code, msg.Message)
+	if msg.ConnID != "" {
+		line = a.State.ConnectionName(msg.ConnID) + ": " + line
+	}
+	a.State.OutputLines = append(a.State.OutputLines, ui.LogError(line))
 	a.viewport.SetContent(a.renderOutput())
 	a.viewport.GotoBottom()
 
 	return a, WaitForDaemonMsg(a.DaemonReader)
 }
 
-func (a *App) handleKicked() (tea.Model, tea.Cmd) {
-	a.State.OutputLines = append(a.State.OutputLines,
-		ui.LogWarning("Another client connected. Exiting..."))
-	a.viewport.SetContent(a.renderOutput())
-	return a, tea.Quit
+func (a *App) handleOwnership(msg daemon.OwnershipMsg) (tea.Model, tea.Cmd) {
+	// The owner detached; claim the prompts unless another client is
+	// quicker.
+	if msg.Free {
+		a.State.IsOwner = false
+		a.SendToDaemon(daemon.TakeOwnershipCmd{Type: "take_ownership"})
+		return a, WaitForDaemonMsg(a.DaemonReader)
+	}
+
+	wasOwner := a.State.IsOwner
+	a.State.IsOwner = msg.Owner
+
+	if !msg.Owner && (wasOwner || msg.Reason != "") {
+		a.State.OutputLines = append(a.State.OutputLines,
+			ui.LogWarning(fmt.Sprintf("Not answering prompts: %s (press o to take them)", msg.Reason)))
+		if a.State.PromptConnID != "" {
+			a.clearPrompt()
+		}
+		a.viewport.SetContent(a.renderOutput())
+		a.viewport.GotoBottom()
+	}
+
+	return a, WaitForDaemonMsg(a.DaemonReader)
 }
 
 func (a *App) handleDaemonDisconnected() (tea.Model, tea.Cmd) {
@@ -288,9 +406,10 @@ func (a *App) handleWindowSize(msg tea.WindowSizeMsg) (tea.Model, tea.Cmd) {
 
 func (a *App) handleSpinnerTick() (tea.Model, tea.Cmd) {
 	a.spinnerFrame++
-	if a.State.Status == StatusConnecting || a.State.Status == StatusReconnecting {
+	if a.State.Busy() {
 		return a, spinnerTick()
 	}
+	a.spinning = false
 	return a, nil
 }
 
@@ -306,7 +425,7 @@ func (a *App) handleDaemonCleanupDone() (tea.Model, tea.Cmd) {
 	a.viewport.SetContent(a.renderOutput())
 	a.viewport.GotoBottom()
 
-	if a.State.Status == StatusQuitting {
+	if a.State.Quitting {
 		if a.DaemonConn != nil {
 			a.DaemonConn.Close()
 			a.DaemonConn = nil
@@ -361,8 +480,6 @@ func (a *App) handleRestartDaemon() (tea.Model, tea.Cmd) {
 
 	a.State.RestartPending = false
 	a.State.RestartingDaemon = true
-	a.State.ReconnectConnID = ""
-	a.State.ReconnectAttempts = 0
 	a.State.OutputLines = append(a.State.OutputLines,
 		ui.LogWarning("[Restarting daemon...]"))
 	a.viewport.SetContent(a.renderOutput())
@@ -373,12 +490,7 @@ func (a *App) handleRestartDaemon() (tea.Model, tea.Cmd) {
 func (a *App) handleDaemonRestarted(msg daemonRestartedMsg) (tea.Model, tea.Cmd) {
 	a.State.RestartingDaemon = false
 	a.State.RestartPending = false
-	a.State.Status = StatusDisconnected
-	a.State.ActiveConnID = ""
-	a.State.IP = ""
-	a.State.PID = 0
-	a.State.ReconnectAttempts = 0
-	a.State.ReconnectConnID = ""
+	a.resetSessions()
 	a.State.TotalLogLines = 0
 	a.State.LogLoadedFrom = 0
 	a.State.LogLoadedTo = 0
@@ -388,11 +500,14 @@ func (a *App) handleDaemonRestarted(msg daemonRestartedMsg) (tea.Model, tea.Cmd)
 
 	a.DaemonConn = msg.Conn
 	a.DaemonReader = msg.Reader
+	a.useRequestIDs(msg.Hello)
 
+	a.State.IsOwner = false
 	a.SendToDaemon(daemon.ConfigUpdateCmd{
 		Type:   "config_update",
 		Config: *a.State.Config,
 	})
+	a.SendToDaemon(daemon.TakeOwnershipCmd{Type: "take_ownership"})
 	a.SendToDaemon(daemon.GetStateCmd{Type: "get_state"})
 	return a, WaitForDaemonMsg(a.DaemonReader)
 }
@@ -400,12 +515,7 @@ func (a *App) handleDaemonRestarted(msg daemonRestartedMsg) (tea.Model, tea.Cmd)
 func (a *App) handleDaemonRestartFailed(msg daemonRestartFailedMsg) (tea.Model, tea.Cmd) {
 	a.State.RestartingDaemon = false
 	a.State.RestartPending = false
-	a.State.Status = StatusDisconnected
-	a.State.ActiveConnID = ""
-	a.State.IP = ""
-	a.State.PID = 0
-	a.State.ReconnectAttempts = 0
-	a.State.ReconnectConnID = ""
+	a.resetSessions()
 	a.DaemonConn = nil
 	a.DaemonReader = nil
 	errMsg := "unknown error"
@@ -423,6 +533,16 @@ func (a *App) handleDaemonRestartFailed(msg daemonRestartFailedMsg) (tea.Model,
 	return a, nil
 }
 
+// resetSessions forgets every tunnel, after the daemon went away.
+func (a *App) resetSessions() {
+	a.State.Sessions = make(map[string]*Session)
+	a.State.LogConnID = ""
+	a.State.PromptConnID = ""
+	a.State.ExternalHost = ""
+	a.State.ExternalPID = 0
+	a.State.KillSwitch = false
+}
+
 func (a *App) handleDetach() (tea.Model, tea.Cmd) {
 	if a.DaemonConn != nil {
 		a.DaemonConn.Close()
@@ -430,10 +550,24 @@ func (a *App) handleDetach() (tea.Model, tea.Cmd) {
 	return a, tea.Quit
 }
 
+// filtering reports whether keys go to the connection search.
+func (a *App) filtering() bool {
+	return a.State.FilterActive && a.State.FocusedPane == PaneConnections
+}
+
+// takePrompts makes this client the one that answers prompts, taking them
+// from whichever client holds them.
+func (a *App) takePrompts() (tea.Model, tea.Cmd) {
+	if a.State.IsOwner {
+		return a, nil
+	}
+	a.SendToDaemon(daemon.TakeOwnershipCmd{Type: "take_ownership", Force: true})
+	return a, nil
+}
+
 func (a *App) handleQuit() (tea.Model, tea.Cmd) {
-	switch a.State.Status {
-	case StatusConnected, StatusConnecting, StatusPrompting, StatusExternal, StatusReconnecting:
-		a.State.Status = StatusQuitting
+	if len(a.State.Sessions) > 0 || a.State.HasExternal() {
+		a.State.Quitting = true
 		a.SendToDaemon(daemon.DisconnectCmd{Type: "disconnect"})
 		return a, WaitForDaemonMsg(a.DaemonReader)
 	}
@@ -447,6 +581,9 @@ func (a *App) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	if a.State.ShowingHelp {
 		return a.handleHelpKeys(msg)
 	}
+	if a.State.ShowingHistory {
+		return a.handleHistoryKeys(msg)
+	}
 
 	if a.State.FocusedPane == PaneInput {
 		if key.Matches(msg, a.Keys.TabFocus) {
@@ -474,6 +611,10 @@ func (a *App) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case key.Matches(msg, a.Keys.Detach):
 		return a.handleDetach()
 
+	// While searching connections, "o" is part of the search text.
+	case key.Matches(msg, a.Keys.TakePrompts) && !a.filtering():
+		return a.takePrompts()
+
 	case key.Matches(msg, a.Keys.TabFocus):
 		a.cycleFocus()
 		if a.State.FocusedPane == PaneInput {
@@ -520,13 +661,16 @@ func (a *App) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			return a.cleanup()
 		}
 		if key.Matches(msg, a.Keys.Disconnect) {
-			if a.State.Status == StatusExternal || a.State.Status == StatusConnected || a.State.Status == StatusReconnecting {
-				return a.disconnect()
+			if connID := a.State.StatusConnID(); connID != "" {
+				return a.disconnectSession(connID)
 			}
+			return a.disconnectExternal()
 		}
 		return a, nil
 	case PaneConnections:
-		return a.updateConnections(msg)
+		model, cmd := a.updateConnections(msg)
+		a.followSelection()
+		return model, cmd
 	case PaneSettings:
 		return a.updateSettings(msg)
 	case PaneOutput:
@@ -0,0 +1,314 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/controllers/helpers"
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+)
+
+const completionUsage = "completion <bash|zsh|fish>"
+
+type argKind int
+
+const (
+	argNone argKind = iota
+	argConnection
+	// argConnections completes connection names at every position.
+	argConnections
+	// argFile prints nothing so the shell falls back to file names.
+	argFile
+)
+
+// command describes the CLI surface for shell completion. Keep it in sync
+// with the dispatch in main.go.
+type command struct {
+	name        string
+	subcommands []command
+	flags       func() *pflag.FlagSet
+	args        argKind
+}
+
+func commands() command {
+	return command{
+		flags: globalFlags,
+		subcommands: []command{
+			{name: "daemon", flags: daemonFlags, subcommands: []command{
+				{name: "start"},
+				{name: "stop", subcommands: []command{{name: "all"}}},
+				{name: "status"},
+			}},
+			{name: "connect", flags: connectFlags, args: argConnection},
+			{name: "disconnect", flags: disconnectFlags, args: argConnection},
+			{name: "status", flags: statusFlags},
+			{name: "logs", flags: logsFlags, args: argConnection},
+			{name: "history", flags: historyFlags, args: argConnection},
+			{name: "conn", subcommands: []command{
+				{name: "list", flags: connListFlags},
+				{name: "show", flags: connShowFlags, args: argConnection},
+				{name: "add", flags: connAddFlags},
+				{name: "edit", flags: connEditFlags, args: argConnection},
+				{name: "rm", flags: connRmFlags, args: argConnection},
+			}},
+			{name: "import", subcommands: []command{
+				{name: "nm", flags: importNMFlags},
+				{name: "anyconnect", flags: importXMLFlags, args: argFile},
+				{name: "globalprotect", flags: importXMLFlags, args: argFile},
+				{name: "bundle", flags: importBundleFlags, args: argFile},
+			}},
+			{name: "export", flags: exportFlags, args: argConnections},
+			{name: "doctor"},
+			{name: "killswitch", subcommands: []command{
+				{name: "off", flags: killSwitchFlags},
+			}},
+			{name: "service", subcommands: []command{
+				{name: "install", flags: serviceInstallFlags},
+				{name: "uninstall"},
+				{name: "status"},
+			}},
+			{name: "completion", subcommands: []command{
+				{name: "bash"},
+				{name: "zsh"},
+				{name: "fish"},
+			}},
+			{name: "update"},
+			{name: "uninstall", flags: uninstallFlags},
+		},
+	}
+}
+
+func globalFlags() *pflag.FlagSet {
+	fs := newFlagSet("lazyopenconnect")
+	fs.Bool("debug", false, "Enable debug logging in daemon")
+	fs.BoolP("help", "h", false, "Show help")
+	fs.BoolP("version", "v", false, "Show version")
+	return fs
+}
+
+func daemonFlags() *pflag.FlagSet {
+	fs := newFlagSet("daemon")
+	fs.Bool("debug", false, "Enable debug logging in daemon")
+	return fs
+}
+
+func uninstallFlags() *pflag.FlagSet {
+	fs := newFlagSet("uninstall")
+	fs.Bool("purge", false, "Remove config, keychain entries, and PATH export without prompting")
+	fs.Bool("keep-config", false, "Only remove binary, keep config and keychain")
+	return fs
+}
+
+func Completion(args []string) {
+	if len(args) != 1 {
+		usageError(completionUsage)
+	}
+
+	switch args[0] {
+	case "bash":
+		io.WriteString(os.Stdout, bashCompletion)
+	case "zsh":
+		io.WriteString(os.Stdout, zshCompletion)
+	case "fish":
+		io.WriteString(os.Stdout, fishCompletion)
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unsupported shell %q\n", args[0])
+		usageError(completionUsage)
+	}
+}
+
+// Complete prints completion candidates for the given words, one per line.
+// The last word is the one being completed. It backs the shell scripts
+// printed by Completion, which fall back to file names when nothing is
+// printed.
+func Complete(args []string) {
+	for _, candidate := range complete(args, loadConnectionsForCompletion) {
+		fmt.Println(candidate)
+	}
+}
+
+func loadConnectionsForCompletion() []models.Connection {
+	cfg, err := helpers.LoadConfig()
+	if err != nil {
+		return nil
+	}
+	return cfg.Connections
+}
+
+func complete(words []string, connections func() []models.Connection) []string {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	current := words[len(words)-1]
+
+	cmd := commands()
+	positional := 0
+	var pendingFlag *pflag.Flag
+
+	for _, word := range words[:len(words)-1] {
+		if pendingFlag != nil {
+			pendingFlag = nil
+			continue
+		}
+		if strings.HasPrefix(word, "-") {
+			pendingFlag = flagNeedingValue(cmd, word)
+			continue
+		}
+		if sub, ok := findSubcommand(cmd, word); ok {
+			cmd = sub
+			positional = 0
+			continue
+		}
+		positional++
+	}
+
+	if pendingFlag != nil {
+		return completeFlagValue(pendingFlag, current)
+	}
+
+	if strings.HasPrefix(current, "-") {
+		return completeFlags(cmd, current)
+	}
+
+	var candidates []string
+	if len(cmd.subcommands) > 0 {
+		for _, sub := range cmd.subcommands {
+			if strings.HasPrefix(sub.name, current) {
+				candidates = append(candidates, sub.name)
+			}
+		}
+		return candidates
+	}
+
+	if (cmd.args == argConnection && positional == 0) || cmd.args == argConnections {
+		for _, conn := range connections() {
+			if strings.HasPrefix(conn.Name, current) {
+				candidates = append(candidates, conn.Name)
+			}
+		}
+		if current != "" {
+			for _, conn := range connections() {
+				if strings.HasPrefix(conn.ID, current) {
+					candidates = append(candidates, conn.ID)
+				}
+			}
+		}
+	}
+	return candidates
+}
+
+func findSubcommand(cmd command, name string) (command, bool) {
+	for _, sub := range cmd.subcommands {
+		if sub.name == name {
+			return sub, true
+		}
+	}
+	return command{}, false
+}
+
+// flagNeedingValue returns the flag named by word when its value is passed
+// as the next word.
+func flagNeedingValue(cmd command, word string) *pflag.Flag {
+	if cmd.flags == nil || strings.Contains(word, "=") {
+		return nil
+	}
+	fs := cmd.flags()
+	var flag *pflag.Flag
+	if strings.HasPrefix(word, "--") {
+		flag = fs.Lookup(strings.TrimPrefix(word, "--"))
+	} else if len(word) == 2 {
+		flag = fs.ShorthandLookup(word[1:])
+	}
+	if flag == nil || flag.NoOptDefVal != "" {
+		return nil
+	}
+	return flag
+}
+
+func completeFlagValue(flag *pflag.Flag, current string) []string {
+	var candidates []string
+	switch flag.Name {
+	case "protocol":
+		for _, p := range models.Protocols {
+			if strings.HasPrefix(p.Name, current) {
+				candidates = append(candidates, p.Name)
+			}
+		}
+	case "on-conflict":
+		for _, mode := range helpers.ConflictModes {
+			if strings.HasPrefix(string(mode), current) {
+				candidates = append(candidates, string(mode))
+			}
+		}
+	}
+	return candidates
+}
+
+func completeFlags(cmd command, current string) []string {
+	if cmd.flags == nil {
+		return nil
+	}
+	var candidates []string
+	cmd.flags().VisitAll(func(flag *pflag.Flag) {
+		if name := "--" + flag.Name; strings.HasPrefix(name, current) {
+			candidates = append(candidates, name)
+		}
+	})
+	return candidates
+}
+
+const bashCompletion = `# bash completion for lazyopenconnect
+_lazyopenconnect() {
+    local IFS=$'\n'
+    local candidates
+    candidates=($("${COMP_WORDS[0]}" __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+    COMPREPLY=()
+    local candidate
+    for candidate in "${candidates[@]}"; do
+        COMPREPLY+=("$(printf '%q' "$candidate")")
+    done
+}
+complete -o default -F _lazyopenconnect lazyopenconnect lzcon
+`
+
+const zshCompletion = `#compdef lazyopenconnect lzcon
+_lazyopenconnect() {
+    local out
+    out=$("${words[1]}" __complete "${(@)words[2,CURRENT]}" 2>/dev/null)
+    if [[ -z $out ]]; then
+        _files
+        return
+    fi
+    local -a candidates
+    candidates=("${(@f)out}")
+    compadd -a candidates
+}
+
+if [[ "${funcstack[1]}" == "_lazyopenconnect" ]]; then
+    _lazyopenconnect "$@"
+else
+    compdef _lazyopenconnect lazyopenconnect lzcon
+fi
+`
+
+const fishCompletion = `# fish completion for lazyopenconnect
+function __lazyopenconnect_complete
+    set -l tokens (commandline -opc)
+    set -l cmd $tokens[1]
+    set -e tokens[1]
+    set -l current (commandline -ct)
+    set -l candidates ($cmd __complete $tokens "$current" 2>/dev/null)
+    if test (count $candidates) -eq 0
+        __fish_complete_path "$current"
+        return
+    end
+    printf '%s\n' $candidates
+end
+
+complete -c lazyopenconnect -f -a '(__lazyopenconnect_complete)'
+complete -c lzcon -f -a '(__lazyopenconnect_complete)'
+`
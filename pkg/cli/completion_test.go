@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+)
+
+func TestComplete(t *testing.T) {
+	connections := func() []models.Connection {
+		return []models.Connection{
+			{ID: "3f2a9c1e-0000", Name: "Work", Host: "vpn.work.com"},
+			{ID: "7b11d0aa-0000", Name: "Berlin Office", Host: "vpn.berlin.example"},
+		}
+	}
+
+	tests := []struct {
+		name  string
+		words []string
+		want  []string
+	}{
+		{name: "top level", words: []string{"con"}, want: []string{"connect", "conn"}},
+		{name: "daemon subcommands", words: []string{"daemon", "st"}, want: []string{"start", "stop", "status"}},
+		{name: "connection names", words: []string{"connect", ""}, want: []string{"Work", "Berlin Office"}},
+		{name: "connection id prefix", words: []string{"connect", "3f"}, want: []string{"3f2a9c1e-0000"}},
+		{name: "connection after flag value", words: []string{"connect", "--timeout", "10s", "B"}, want: []string{"Berlin Office"}},
+		{name: "only first positional", words: []string{"connect", "Work", ""}, want: nil},
+		{name: "nested connection arg", words: []string{"conn", "edit", "W"}, want: []string{"Work"}},
+		{name: "flags", words: []string{"logs", "--n"}, want: []string{"--no-color"}},
+		{name: "flag value", words: []string{"conn", "add", "--protocol", "a"}, want: []string{"anyconnect", "array"}},
+		{name: "global flag before command", words: []string{"--debug", "daemon", "s"}, want: []string{"start", "stop", "status"}},
+		{name: "every positional", words: []string{"export", "Work", "B"}, want: []string{"Berlin Office"}},
+		{name: "conflict mode", words: []string{"import", "bundle", "--on-conflict", "r"}, want: []string{"rename"}},
+		{name: "optional connection", words: []string{"disconnect", "W"}, want: []string{"Work"}},
+		{name: "service flags", words: []string{"service", "install", "--s"}, want: []string{"--socket"}},
+		{name: "no args", words: []string{"status", ""}, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := complete(tt.words, connections)
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("complete(%q) = %q, want %q", tt.words, got, tt.want)
+			}
+		})
+	}
+}
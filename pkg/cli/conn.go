@@ -0,0 +1,685 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/google/uuid"
+	"github.com/spf13/pflag"
+	"github.com/zalando/go-keyring"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/controllers/helpers"
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+)
+
+const (
+	connUsage     = "conn <list|show|add|edit|rm> [options]"
+	connListUsage = "conn list [--json]"
+	connShowUsage = "conn show <name|id> [--json]"
+	connAddUsage  = "conn add --name <name> --host <host> [options]"
+	connEditUsage = "conn edit <name|id> [options]"
+	connRmUsage   = "conn rm <name|id>"
+)
+
+func connListFlags() *pflag.FlagSet {
+	fs := newFlagSet("conn list")
+	fs.Bool("json", false, "Print connections as JSON")
+	return fs
+}
+
+func connShowFlags() *pflag.FlagSet {
+	fs := newFlagSet("conn show")
+	fs.Bool("json", false, "Print connection as JSON")
+	return fs
+}
+
+func connAddFlags() *pflag.FlagSet {
+	fs := newFlagSet("conn add")
+	addConnFieldFlags(fs, "fortinet")
+	fs.Bool("json", false, "Print the created connection as JSON")
+	return fs
+}
+
+func connEditFlags() *pflag.FlagSet {
+	fs := newFlagSet("conn edit")
+	addConnFieldFlags(fs, "")
+	fs.Bool("no-password", false, "Remove the saved password from the keychain")
+	fs.Bool("json", false, "Print the updated connection as JSON")
+	return fs
+}
+
+func connRmFlags() *pflag.FlagSet {
+	return newFlagSet("conn rm")
+}
+
+func addConnFieldFlags(fs *pflag.FlagSet, defaultProtocol string) {
+	fs.String("name", "", "Display name")
+	fs.String("protocol", defaultProtocol, "Protocol ("+strings.Join(protocolNames(), ", ")+")")
+	fs.String("host", "", "VPN server host")
+	fs.String("username", "", "Username")
+	fs.String("server-cert", "", "SHA256 pin passed as --servercert")
+	fs.String("flags", "", "Additional openconnect flags")
+	fs.Bool("password-stdin", false, "Read the password from stdin and save it to the keychain")
+	fs.String("password-file", "", "File the daemon reads the password from (mode 600; empty to unset)")
+	fs.Bool("auto-connect", false, "Connect when the daemon starts")
+	fs.String("health-check", "", "Probe inside the VPN: tcp:host:port, icmp:host or dns:name@server (empty to unset)")
+	fs.Int("health-interval", 0, "Seconds between health probes (0 = default)")
+	fs.Int("health-failures", 0, "Failed health probes in a row before reconnecting (0 = default)")
+	fs.Int("reconnect-attempts", 0, "Reconnect tries for this connection (0 = unlimited)")
+	fs.String("reconnect-backoff", "", "Seconds to wait after each failed try, e.g. \"2 5 10\"")
+	fs.Int("reconnect-jitter", 0, "Spread each wait by up to this percent")
+	fs.Int("network-wait", 0, "Seconds to wait for the server to be reachable")
+	fs.String("reconnect-on", "", "Events that reconnect: "+strings.Join(models.ReconnectTriggers, ",")+" (empty for none)")
+	fs.Bool("reconnect-default", false, "Drop this connection's reconnect overrides")
+	fs.StringArray("hook", nil, "Run a command at an event, as EVENT=COMMAND (empty COMMAND to unset); events: "+strings.Join(models.HookEvents, ","))
+	fs.Int("hook-timeout", 0, "Seconds each hook may run (0 = default)")
+	fs.String("include-routes", "", "CIDRs to send through the tunnel, comma-separated (empty to unset)")
+	fs.String("exclude-routes", "", "CIDRs to keep off the tunnel, comma-separated (empty to unset)")
+	fs.Bool("no-default-route", false, "Ignore the server's default route; only split routes use the tunnel")
+	fs.String("dns-domains", "", "Only resolve these domains through the tunnel, comma-separated; ~domain routes without searching (empty to unset)")
+	fs.String("dns-servers", "", "DNS servers for --dns-domains, comma-separated (empty for the VPN's)")
+	fs.Bool("kill-switch", false, "Block traffic outside the tunnel while it is up or reconnecting")
+}
+
+func protocolNames() []string {
+	names := make([]string, 0, len(models.Protocols))
+	for _, p := range models.Protocols {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+func Conn(args []string) {
+	if len(args) == 0 {
+		usageError(connUsage)
+	}
+
+	switch args[0] {
+	case "list", "ls":
+		connList(args[1:])
+	case "show":
+		connShow(args[1:])
+	case "add":
+		connAdd(args[1:])
+	case "edit":
+		connEdit(args[1:])
+	case "rm", "remove":
+		connRm(args[1:])
+	case "-h", "--help":
+		fmt.Println("Usage: lazyopenconnect " + connUsage)
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown conn command %q\n", args[0])
+		usageError(connUsage)
+	}
+}
+
+func connList(args []string) {
+	fs := connListFlags()
+	parseFlags(fs, args, connListUsage)
+	if fs.NArg() != 0 {
+		usageError(connListUsage)
+	}
+	asJSON, _ := fs.GetBool("json")
+
+	cfg := mustLoadConfig()
+	if asJSON {
+		printJSON(cfg.Connections)
+		return
+	}
+
+	if len(cfg.Connections) == 0 {
+		fmt.Println("No connections")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tPROTOCOL\tHOST\tUSERNAME")
+	for _, conn := range cfg.Connections {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", shortID(conn.ID), conn.Name, conn.Protocol, conn.Host, conn.Username)
+	}
+	w.Flush()
+}
+
+func connShow(args []string) {
+	fs := connShowFlags()
+	parseFlags(fs, args, connShowUsage)
+	if fs.NArg() != 1 {
+		usageError(connShowUsage)
+	}
+	asJSON, _ := fs.GetBool("json")
+
+	cfg := mustLoadConfig()
+	conn := mustResolveConnection(cfg, fs.Arg(0))
+	if asJSON {
+		printJSON(conn)
+		return
+	}
+	printConnection(conn)
+}
+
+func connAdd(args []string) {
+	fs := connAddFlags()
+	parseFlags(fs, args, connAddUsage)
+	if fs.NArg() != 0 {
+		usageError(connAddUsage)
+	}
+	asJSON, _ := fs.GetBool("json")
+
+	cfg := mustLoadConfig()
+
+	conn := models.Connection{ID: uuid.New().String()}
+	conn.Protocol, _ = fs.GetString("protocol")
+	if err := applyConnFlags(fs, &conn); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	if conn.Name == "" || conn.Host == "" {
+		fmt.Fprintln(os.Stderr, "Error: --name and --host are required")
+		usageError(connAddUsage)
+	}
+	if err := checkDuplicateName(cfg, conn.Name, ""); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if passwordStdin, _ := fs.GetBool("password-stdin"); passwordStdin {
+		mustSavePasswordFromStdin(conn.ID)
+		conn.HasPassword = true
+	}
+
+	cfg.Connections = append(cfg.Connections, conn)
+	mustSaveConfig(cfg)
+
+	if asJSON {
+		printJSON(conn)
+		return
+	}
+	fmt.Printf("Added %s (%s)\n", conn.Name, shortID(conn.ID))
+}
+
+func connEdit(args []string) {
+	fs := connEditFlags()
+	parseFlags(fs, args, connEditUsage)
+	if fs.NArg() != 1 {
+		usageError(connEditUsage)
+	}
+	asJSON, _ := fs.GetBool("json")
+	passwordStdin, _ := fs.GetBool("password-stdin")
+	noPassword, _ := fs.GetBool("no-password")
+	if passwordStdin && noPassword {
+		fmt.Fprintln(os.Stderr, "Error: --password-stdin and --no-password are mutually exclusive")
+		os.Exit(2)
+	}
+
+	cfg := mustLoadConfig()
+	conn := mustResolveConnection(cfg, fs.Arg(0))
+
+	updated := *conn
+	if err := applyConnFlags(fs, &updated); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	if updated.Name == "" || updated.Host == "" {
+		fmt.Fprintln(os.Stderr, "Error: name and host cannot be empty")
+		os.Exit(2)
+	}
+	if err := checkDuplicateName(cfg, updated.Name, updated.ID); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch {
+	case passwordStdin:
+		mustSavePasswordFromStdin(updated.ID)
+		updated.HasPassword = true
+	case noPassword:
+		if err := helpers.DeletePassword(updated.ID); err != nil && !errors.Is(err, keyring.ErrNotFound) {
+			fmt.Fprintf(os.Stderr, "Failed to remove password from keychain: %v\n", err)
+			os.Exit(1)
+		}
+		updated.HasPassword = false
+	}
+
+	*conn = updated
+	mustSaveConfig(cfg)
+
+	if asJSON {
+		printJSON(updated)
+		return
+	}
+	fmt.Printf("Updated %s (%s)\n", updated.Name, shortID(updated.ID))
+}
+
+func connRm(args []string) {
+	fs := connRmFlags()
+	parseFlags(fs, args, connRmUsage)
+	if fs.NArg() != 1 {
+		usageError(connRmUsage)
+	}
+
+	cfg := mustLoadConfig()
+	conn := *mustResolveConnection(cfg, fs.Arg(0))
+
+	if conn.HasPassword {
+		if err := helpers.DeletePassword(conn.ID); err != nil && !errors.Is(err, keyring.ErrNotFound) {
+			fmt.Fprintf(os.Stderr, "Warning: failed to remove password from keychain: %v\n", err)
+		}
+	}
+
+	for i := range cfg.Connections {
+		if cfg.Connections[i].ID == conn.ID {
+			cfg.Connections = append(cfg.Connections[:i], cfg.Connections[i+1:]...)
+			break
+		}
+	}
+	mustSaveConfig(cfg)
+
+	fmt.Printf("Removed %s (%s)\n", conn.Name, shortID(conn.ID))
+}
+
+// applyConnFlags copies the connection field flags that were set on the
+// command line into conn.
+func applyConnFlags(fs *pflag.FlagSet, conn *models.Connection) error {
+	fields := []struct {
+		flag  string
+		field *string
+	}{
+		{"name", &conn.Name},
+		{"protocol", &conn.Protocol},
+		{"host", &conn.Host},
+		{"username", &conn.Username},
+		{"server-cert", &conn.ServerCert},
+		{"flags", &conn.Flags},
+		{"password-file", &conn.PasswordFile},
+		{"health-check", &conn.HealthCheck},
+	}
+	for _, f := range fields {
+		if !fs.Changed(f.flag) {
+			continue
+		}
+		value, _ := fs.GetString(f.flag)
+		*f.field = strings.TrimSpace(value)
+	}
+	if fs.Changed("auto-connect") {
+		conn.AutoConnect, _ = fs.GetBool("auto-connect")
+	}
+	if fs.Changed("kill-switch") {
+		conn.KillSwitch, _ = fs.GetBool("kill-switch")
+	}
+	if fs.Changed("health-interval") {
+		conn.HealthInterval, _ = fs.GetInt("health-interval")
+	}
+	if fs.Changed("health-failures") {
+		conn.HealthFailures, _ = fs.GetInt("health-failures")
+	}
+	if conn.HealthInterval < 0 || conn.HealthFailures < 0 {
+		return errors.New("--health-interval and --health-failures must not be negative")
+	}
+	if conn.HealthCheck != "" {
+		if _, err := helpers.ParseHealthCheck(conn.HealthCheck); err != nil {
+			return fmt.Errorf("invalid health check %q: %w", conn.HealthCheck, err)
+		}
+	}
+	if err := applyReconnectFlags(fs, conn); err != nil {
+		return err
+	}
+	if err := applyHookFlags(fs, conn); err != nil {
+		return err
+	}
+	if err := applySplitFlags(fs, conn); err != nil {
+		return err
+	}
+	if err := applyDNSFlags(fs, conn); err != nil {
+		return err
+	}
+
+	// The daemon resolves the path from its own working directory.
+	if conn.PasswordFile != "" && !filepath.IsAbs(conn.PasswordFile) {
+		abs, err := filepath.Abs(conn.PasswordFile)
+		if err != nil {
+			return fmt.Errorf("resolve password file: %w", err)
+		}
+		conn.PasswordFile = abs
+	}
+
+	if !models.IsSupportedProtocol(conn.Protocol) {
+		return fmt.Errorf("unsupported protocol %q (use one of: %s)", conn.Protocol, strings.Join(protocolNames(), ", "))
+	}
+	return nil
+}
+
+// applyReconnectFlags updates the connection's reconnect policy overrides.
+func applyReconnectFlags(fs *pflag.FlagSet, conn *models.Connection) error {
+	if reset, _ := fs.GetBool("reconnect-default"); reset {
+		conn.Reconnect = nil
+	}
+
+	policy := models.ReconnectPolicy{}
+	if conn.Reconnect != nil {
+		policy = *conn.Reconnect
+	}
+	if fs.Changed("reconnect-attempts") {
+		n, _ := fs.GetInt("reconnect-attempts")
+		if n < 0 {
+			return errors.New("--reconnect-attempts must not be negative")
+		}
+		policy.MaxAttempts = n
+		if n == 0 {
+			policy.MaxAttempts = models.UnlimitedAttempts
+		}
+	}
+	if fs.Changed("reconnect-backoff") {
+		value, _ := fs.GetString("reconnect-backoff")
+		backoff, err := helpers.ParseBackoff(value)
+		if err != nil {
+			return fmt.Errorf("invalid --reconnect-backoff: %w", err)
+		}
+		policy.Backoff = backoff
+	}
+	if fs.Changed("reconnect-jitter") {
+		n, _ := fs.GetInt("reconnect-jitter")
+		if n < 0 || n > 100 {
+			return errors.New("--reconnect-jitter must be from 0 to 100")
+		}
+		policy.Jitter = float64(n) / 100
+	}
+	if fs.Changed("network-wait") {
+		n, _ := fs.GetInt("network-wait")
+		if n < 0 {
+			return errors.New("--network-wait must not be negative")
+		}
+		policy.NetworkWait = n
+	}
+	if fs.Changed("reconnect-on") {
+		value, _ := fs.GetString("reconnect-on")
+		on := map[string]bool{}
+		for _, reason := range strings.Split(value, ",") {
+			if reason = strings.TrimSpace(reason); reason == "" {
+				continue
+			}
+			if !slices.Contains(models.ReconnectTriggers, reason) {
+				return fmt.Errorf("unknown --reconnect-on event %q (use %s)", reason, strings.Join(models.ReconnectTriggers, ", "))
+			}
+			on[reason] = true
+		}
+		// Explicit either way, so the connection does not inherit them.
+		for _, reason := range models.ReconnectTriggers {
+			enabled := on[reason]
+			policy.SetReconnectsOn(reason, &enabled)
+		}
+	}
+
+	if reflect.ValueOf(policy).IsZero() {
+		conn.Reconnect = nil
+	} else {
+		conn.Reconnect = &policy
+	}
+	return nil
+}
+
+// applyHookFlags sets the connection's hook commands.
+func applyHookFlags(fs *pflag.FlagSet, conn *models.Connection) error {
+	hooks, _ := fs.GetStringArray("hook")
+	for _, hook := range hooks {
+		event, command, ok := strings.Cut(hook, "=")
+		if !ok {
+			return fmt.Errorf("invalid --hook %q (use EVENT=COMMAND)", hook)
+		}
+		if !conn.Hooks.SetCommand(strings.TrimSpace(event), strings.TrimSpace(command)) {
+			return fmt.Errorf("unknown --hook event %q (use %s)", event, strings.Join(models.HookEvents, ", "))
+		}
+	}
+	if fs.Changed("hook-timeout") {
+		n, _ := fs.GetInt("hook-timeout")
+		if n < 0 {
+			return errors.New("--hook-timeout must not be negative")
+		}
+		conn.Hooks.Timeout = n
+	}
+	return nil
+}
+
+// applySplitFlags sets the connection's split tunnel routes.
+func applySplitFlags(fs *pflag.FlagSet, conn *models.Connection) error {
+	routes := []struct {
+		flag  string
+		field *[]string
+	}{
+		{"include-routes", &conn.IncludeRoutes},
+		{"exclude-routes", &conn.ExcludeRoutes},
+	}
+	for _, r := range routes {
+		if !fs.Changed(r.flag) {
+			continue
+		}
+		value, _ := fs.GetString(r.flag)
+		parsed, err := helpers.ParseRoutes(value)
+		if err != nil {
+			return fmt.Errorf("--%s: %w", r.flag, err)
+		}
+		*r.field = parsed
+	}
+	if fs.Changed("no-default-route") {
+		conn.NoDefaultRoute, _ = fs.GetBool("no-default-route")
+	}
+	return nil
+}
+
+// applyDNSFlags sets the connection's split DNS.
+func applyDNSFlags(fs *pflag.FlagSet, conn *models.Connection) error {
+	if fs.Changed("dns-domains") {
+		value, _ := fs.GetString("dns-domains")
+		domains, err := helpers.ParseDNSDomains(value)
+		if err != nil {
+			return fmt.Errorf("--dns-domains: %w", err)
+		}
+		conn.DNSDomains = domains
+	}
+	if fs.Changed("dns-servers") {
+		value, _ := fs.GetString("dns-servers")
+		servers, err := helpers.ParseDNSServers(value)
+		if err != nil {
+			return fmt.Errorf("--dns-servers: %w", err)
+		}
+		conn.DNSServers = servers
+	}
+	return nil
+}
+
+// describeSplitDNS summarizes which names a connection resolves through
+// the tunnel.
+func describeSplitDNS(conn *models.Connection) string {
+	if !conn.SplitDNS() {
+		return "server DNS"
+	}
+	servers := "the VPN's servers"
+	if len(conn.DNSServers) > 0 {
+		servers = helpers.FormatList(conn.DNSServers)
+	}
+	return helpers.FormatList(conn.DNSDomains) + " via " + servers
+}
+
+// describeSplitTunnel summarizes the routes a connection changes.
+func describeSplitTunnel(conn *models.Connection) string {
+	if !conn.SplitTunnel() {
+		return "server routes"
+	}
+	var parts []string
+	if conn.NoDefaultRoute {
+		parts = append(parts, "no default route")
+	}
+	if len(conn.IncludeRoutes) > 0 {
+		parts = append(parts, "include "+helpers.FormatList(conn.IncludeRoutes))
+	}
+	if len(conn.ExcludeRoutes) > 0 {
+		parts = append(parts, "exclude "+helpers.FormatList(conn.ExcludeRoutes))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// describeHooks lists a connection's hook commands.
+func describeHooks(hooks models.Hooks) string {
+	var parts []string
+	for _, event := range models.HookEvents {
+		if command := hooks.Command(event); command != "" {
+			parts = append(parts, event+": "+command)
+		}
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, "; ") + fmt.Sprintf(" (timeout %s)", hooks.RunTimeout())
+}
+
+// describeReconnect summarizes a connection's reconnect overrides.
+func describeReconnect(policy *models.ReconnectPolicy) string {
+	if policy == nil {
+		return "from settings"
+	}
+	var parts []string
+	switch {
+	case policy.MaxAttempts == models.UnlimitedAttempts:
+		parts = append(parts, "unlimited attempts")
+	case policy.MaxAttempts > 0:
+		parts = append(parts, fmt.Sprintf("%d attempts", policy.MaxAttempts))
+	}
+	if len(policy.Backoff) > 0 {
+		parts = append(parts, "backoff "+helpers.FormatBackoff(policy.Backoff)+"s")
+	}
+	if policy.Jitter > 0 {
+		parts = append(parts, fmt.Sprintf("±%.0f%%", policy.Jitter*100))
+	}
+	if policy.NetworkWait > 0 {
+		parts = append(parts, fmt.Sprintf("network wait %ds", policy.NetworkWait))
+	}
+	if policy.OnWake != nil || policy.OnExit != nil || policy.OnHealth != nil || policy.OnNetworkChange != nil {
+		on := "none"
+		var reasons []string
+		for _, reason := range models.ReconnectTriggers {
+			if policy.ReconnectsOn(reason) {
+				reasons = append(reasons, reason)
+			}
+		}
+		if len(reasons) > 0 {
+			on = strings.Join(reasons, ",")
+		}
+		parts = append(parts, "on "+on)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func checkDuplicateName(cfg *models.Config, name, selfID string) error {
+	for _, conn := range cfg.Connections {
+		if conn.ID != selfID && strings.EqualFold(conn.Name, name) {
+			return fmt.Errorf("a connection named %q already exists", conn.Name)
+		}
+	}
+	return nil
+}
+
+func mustLoadConfig() *models.Config {
+	cfg, err := helpers.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+	return cfg
+}
+
+func mustSaveConfig(cfg *models.Config) {
+	if err := helpers.SaveConfig(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to save config: %v\n", err)
+		os.Exit(1)
+	}
+	pushConfig(cfg)
+}
+
+func mustResolveConnection(cfg *models.Config, query string) *models.Connection {
+	conn, err := resolveConnection(cfg, query)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return conn
+}
+
+func mustSavePasswordFromStdin(connID string) {
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read password from stdin: %v\n", err)
+		os.Exit(1)
+	}
+	password := strings.TrimRight(string(data), "\r\n")
+	if password == "" {
+		fmt.Fprintln(os.Stderr, "Error: empty password on stdin")
+		os.Exit(1)
+	}
+	if err := helpers.SetPassword(connID, password); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to save password to keychain: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func printConnection(conn *models.Connection) {
+	password := "not saved"
+	switch {
+	case conn.PasswordFile != "":
+		password = "read from " + conn.PasswordFile
+	case conn.HasPassword:
+		password = "saved in keychain"
+	}
+	autoConnect := "no"
+	if conn.AutoConnect {
+		autoConnect = "yes"
+	}
+	killSwitch := "off"
+	if conn.KillSwitch {
+		killSwitch = "on"
+	}
+	health := "none"
+	if conn.HealthCheck != "" {
+		health = fmt.Sprintf("%s every %s, reconnect after %d failures",
+			conn.HealthCheck, conn.HealthEvery(), conn.HealthThreshold())
+	}
+	rows := [][2]string{
+		{"ID", conn.ID},
+		{"Name", conn.Name},
+		{"Protocol", conn.Protocol},
+		{"Host", conn.Host},
+		{"Username", conn.Username},
+		{"Password", password},
+		{"Server cert", conn.ServerCert},
+		{"Flags", conn.Flags},
+		{"Auto-connect", autoConnect},
+		{"Health check", health},
+		{"Reconnect", describeReconnect(conn.Reconnect)},
+		{"Hooks", describeHooks(conn.Hooks)},
+		{"Routes", describeSplitTunnel(conn)},
+		{"DNS", describeSplitDNS(conn)},
+		{"Kill switch", killSwitch},
+	}
+	for _, row := range rows {
+		fmt.Printf("%-12s %s\n", row[0]+":", row[1])
+	}
+}
+
+func printJSON(v any) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
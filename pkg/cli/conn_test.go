@@ -0,0 +1,285 @@
+package cli
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/controllers/helpers"
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+)
+
+func TestApplyConnFlagsOnlyChangesSetFlags(t *testing.T) {
+	fs := connEditFlags()
+	if err := fs.Parse([]string{"--host", " vpn2.example.com ", "--protocol", "gp"}); err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	conn := models.Connection{ID: "1", Name: "Work", Protocol: "fortinet", Host: "vpn.example.com", Username: "alice"}
+	if err := applyConnFlags(fs, &conn); err != nil {
+		t.Fatalf("applyConnFlags returned error: %v", err)
+	}
+
+	if conn.Host != "vpn2.example.com" {
+		t.Fatalf("Host = %q, want %q", conn.Host, "vpn2.example.com")
+	}
+	if conn.Protocol != "gp" {
+		t.Fatalf("Protocol = %q, want %q", conn.Protocol, "gp")
+	}
+	if conn.Name != "Work" || conn.Username != "alice" {
+		t.Fatalf("unset fields changed: %+v", conn)
+	}
+}
+
+func TestApplyConnFlagsAutoConnectAndPasswordFile(t *testing.T) {
+	t.Chdir(t.TempDir())
+	fs := connEditFlags()
+	if err := fs.Parse([]string{"--auto-connect", "--password-file", "secret"}); err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	conn := models.Connection{ID: "1", Name: "Work", Protocol: "fortinet", Host: "vpn.example.com"}
+	if err := applyConnFlags(fs, &conn); err != nil {
+		t.Fatalf("applyConnFlags returned error: %v", err)
+	}
+
+	if !conn.AutoConnect {
+		t.Fatal("AutoConnect was not set")
+	}
+	if !filepath.IsAbs(conn.PasswordFile) || filepath.Base(conn.PasswordFile) != "secret" {
+		t.Fatalf("PasswordFile = %q, want an absolute path to secret", conn.PasswordFile)
+	}
+}
+
+func TestApplyConnFlagsHealthCheck(t *testing.T) {
+	fs := connEditFlags()
+	if err := fs.Parse([]string{"--health-check", "icmp:10.0.0.1", "--health-failures", "5"}); err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	conn := models.Connection{ID: "1", Name: "Work", Protocol: "fortinet", Host: "vpn.example.com", HealthInterval: 10}
+	if err := applyConnFlags(fs, &conn); err != nil {
+		t.Fatalf("applyConnFlags returned error: %v", err)
+	}
+	if conn.HealthCheck != "icmp:10.0.0.1" || conn.HealthFailures != 5 || conn.HealthInterval != 10 {
+		t.Fatalf("health fields = %q/%d/%d, want icmp:10.0.0.1/5/10", conn.HealthCheck, conn.HealthFailures, conn.HealthInterval)
+	}
+
+	fs = connEditFlags()
+	if err := fs.Parse([]string{"--health-check", "ping 10.0.0.1"}); err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if err := applyConnFlags(fs, &conn); err == nil {
+		t.Fatal("expected error for an invalid health check")
+	}
+}
+
+func TestApplyConnFlagsRejectsUnknownProtocol(t *testing.T) {
+	fs := connAddFlags()
+	if err := fs.Parse([]string{"--protocol", "wireguard"}); err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	conn := models.Connection{Protocol: "fortinet"}
+	if err := applyConnFlags(fs, &conn); err == nil {
+		t.Fatal("expected error for unsupported protocol")
+	}
+}
+
+func TestCheckDuplicateName(t *testing.T) {
+	cfg := &models.Config{
+		Connections: []models.Connection{
+			{ID: "1", Name: "Work", Host: "vpn.work.com"},
+			{ID: "2", Name: "Home", Host: "vpn.home.com"},
+		},
+	}
+
+	if err := checkDuplicateName(cfg, "work", ""); err == nil {
+		t.Fatal("expected error for duplicate name")
+	}
+	if err := checkDuplicateName(cfg, "Work", "1"); err != nil {
+		t.Fatalf("renaming a connection to its own name returned error: %v", err)
+	}
+	if err := checkDuplicateName(cfg, "Lab", ""); err != nil {
+		t.Fatalf("unique name returned error: %v", err)
+	}
+}
+
+func TestApplyConnFlagsReconnectPolicy(t *testing.T) {
+	fs := connEditFlags()
+	args := []string{"--reconnect-attempts", "0", "--reconnect-backoff", "1,3 30", "--reconnect-jitter", "20", "--reconnect-on", "exit,health"}
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	conn := models.Connection{ID: "1", Name: "Work", Protocol: "fortinet", Host: "vpn.example.com"}
+	if err := applyConnFlags(fs, &conn); err != nil {
+		t.Fatalf("applyConnFlags returned error: %v", err)
+	}
+
+	policy := conn.Reconnect
+	if policy == nil {
+		t.Fatal("Reconnect override was not set")
+	}
+	if policy.MaxAttempts != models.UnlimitedAttempts || policy.Jitter != 0.2 {
+		t.Fatalf("MaxAttempts/Jitter = %d/%v, want unlimited/0.2", policy.MaxAttempts, policy.Jitter)
+	}
+	if got := helpers.FormatBackoff(policy.Backoff); got != "1 3 30" {
+		t.Fatalf("Backoff = %q, want %q", got, "1 3 30")
+	}
+	if policy.ReconnectsOn(models.EndWake) || !policy.ReconnectsOn(models.EndExit) || !policy.ReconnectsOn(models.EndHealth) {
+		t.Fatalf("triggers = %v/%v/%v, want wake off, exit and health on", policy.OnWake, policy.OnExit, policy.OnHealth)
+	}
+	want := "unlimited attempts, backoff 1 3 30s, ±20%, on exit,health"
+	if got := describeReconnect(policy); got != want {
+		t.Fatalf("describeReconnect = %q, want %q", got, want)
+	}
+
+	fs = connEditFlags()
+	if err := fs.Parse([]string{"--reconnect-default"}); err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if err := applyConnFlags(fs, &conn); err != nil {
+		t.Fatalf("applyConnFlags returned error: %v", err)
+	}
+	if conn.Reconnect != nil {
+		t.Fatalf("Reconnect = %+v after --reconnect-default, want nil", conn.Reconnect)
+	}
+}
+
+func TestApplyConnFlagsRejectsInvalidReconnectPolicy(t *testing.T) {
+	for _, args := range [][]string{
+		{"--reconnect-on", "sleep"},
+		{"--reconnect-backoff", "soon"},
+		{"--reconnect-jitter", "150"},
+	} {
+		fs := connEditFlags()
+		if err := fs.Parse(args); err != nil {
+			t.Fatalf("Parse returned error: %v", err)
+		}
+		conn := models.Connection{ID: "1", Name: "Work", Protocol: "fortinet", Host: "vpn.example.com"}
+		if err := applyConnFlags(fs, &conn); err == nil {
+			t.Fatalf("applyConnFlags(%v) succeeded", args)
+		}
+	}
+}
+
+func TestApplyConnFlagsHooks(t *testing.T) {
+	fs := connEditFlags()
+	args := []string{"--hook", "post_connect=mount /mnt/share", "--hook", "pre_disconnect = umount /mnt/share", "--hook-timeout", "60"}
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	conn := models.Connection{ID: "1", Name: "Work", Protocol: "fortinet", Host: "vpn.example.com"}
+	conn.Hooks.PreConnect = "kinit -R"
+	if err := applyConnFlags(fs, &conn); err != nil {
+		t.Fatalf("applyConnFlags returned error: %v", err)
+	}
+
+	want := "pre_connect: kinit -R; post_connect: mount /mnt/share; pre_disconnect: umount /mnt/share (timeout 1m0s)"
+	if got := describeHooks(conn.Hooks); got != want {
+		t.Fatalf("describeHooks = %q, want %q", got, want)
+	}
+
+	fs = connEditFlags()
+	if err := fs.Parse([]string{"--hook", "pre_connect="}); err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if err := applyConnFlags(fs, &conn); err != nil {
+		t.Fatalf("applyConnFlags returned error: %v", err)
+	}
+	if conn.Hooks.PreConnect != "" {
+		t.Fatalf("PreConnect = %q, want it unset", conn.Hooks.PreConnect)
+	}
+}
+
+func TestApplyConnFlagsRejectsInvalidHooks(t *testing.T) {
+	for _, args := range [][]string{
+		{"--hook", "mount /mnt/share"},
+		{"--hook", "on_connect=true"},
+		{"--hook-timeout", "-5"},
+	} {
+		fs := connEditFlags()
+		if err := fs.Parse(args); err != nil {
+			t.Fatalf("Parse returned error: %v", err)
+		}
+		conn := models.Connection{ID: "1", Name: "Work", Protocol: "fortinet", Host: "vpn.example.com"}
+		if err := applyConnFlags(fs, &conn); err == nil {
+			t.Fatalf("applyConnFlags(%v) succeeded", args)
+		}
+	}
+}
+
+func TestApplyConnFlagsSplitTunnel(t *testing.T) {
+	fs := connEditFlags()
+	args := []string{"--include-routes", "10.0.0.0/8, 172.16.0.1/12", "--exclude-routes", "192.168.1.0/24", "--no-default-route"}
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	conn := models.Connection{ID: "1", Name: "Work", Protocol: "fortinet", Host: "vpn.example.com"}
+	if err := applyConnFlags(fs, &conn); err != nil {
+		t.Fatalf("applyConnFlags returned error: %v", err)
+	}
+
+	want := "no default route; include 10.0.0.0/8, 172.16.0.0/12; exclude 192.168.1.0/24"
+	if got := describeSplitTunnel(&conn); got != want {
+		t.Fatalf("describeSplitTunnel = %q, want %q", got, want)
+	}
+
+	fs = connEditFlags()
+	if err := fs.Parse([]string{"--include-routes", "10.0.0.0/33"}); err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if err := applyConnFlags(fs, &conn); err == nil {
+		t.Fatal("applyConnFlags accepted an invalid route")
+	}
+}
+
+func TestApplyConnFlagsSplitDNS(t *testing.T) {
+	fs := connEditFlags()
+	if err := fs.Parse([]string{"--dns-domains", "corp.example,~lab.example", "--dns-servers", "10.0.0.53"}); err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	conn := models.Connection{ID: "1", Name: "Work", Protocol: "fortinet", Host: "vpn.example.com"}
+	if err := applyConnFlags(fs, &conn); err != nil {
+		t.Fatalf("applyConnFlags returned error: %v", err)
+	}
+
+	want := "corp.example, ~lab.example via 10.0.0.53"
+	if got := describeSplitDNS(&conn); got != want {
+		t.Fatalf("describeSplitDNS = %q, want %q", got, want)
+	}
+
+	fs = connEditFlags()
+	if err := fs.Parse([]string{"--dns-domains", "../etc"}); err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if err := applyConnFlags(fs, &conn); err == nil {
+		t.Fatal("applyConnFlags accepted an invalid DNS domain")
+	}
+}
+
+func TestApplyConnFlagsKillSwitch(t *testing.T) {
+	conn := models.Connection{ID: "1", Name: "Work", Protocol: "fortinet", Host: "vpn.example.com"}
+	for _, tc := range []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"--kill-switch"}, true},
+		{[]string{"--auto-connect"}, true},
+		{[]string{"--kill-switch=false"}, false},
+	} {
+		fs := connEditFlags()
+		if err := fs.Parse(tc.args); err != nil {
+			t.Fatalf("Parse returned error: %v", err)
+		}
+		if err := applyConnFlags(fs, &conn); err != nil {
+			t.Fatalf("applyConnFlags returned error: %v", err)
+		}
+		if conn.KillSwitch != tc.want {
+			t.Fatalf("after %v KillSwitch = %v, want %v", tc.args, conn.KillSwitch, tc.want)
+		}
+	}
+}
@@ -0,0 +1,367 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/pflag"
+	"golang.org/x/term"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/controllers/helpers"
+	"github.com/Nybkox/lazyopenconnect/pkg/daemon"
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+)
+
+const (
+	connectUsage    = "connect <name|id> [options]"
+	disconnectUsage = "disconnect [<name|id>] [options]"
+)
+
+// legacyConnectTimeout applies when neither --timeout nor the daemon bounds
+// the wait.
+const legacyConnectTimeout = 30 * time.Second
+
+func connectFlags() *pflag.FlagSet {
+	fs := newFlagSet("connect")
+	fs.Duration("timeout", 0, "Give up if the tunnel is not up within this time (default: the daemon's connect timeout)")
+	fs.BoolP("quiet", "q", false, "Do not print openconnect output")
+	return fs
+}
+
+func disconnectFlags() *pflag.FlagSet {
+	fs := newFlagSet("disconnect")
+	fs.Duration("timeout", 15*time.Second, "Give up waiting for the daemon after this time")
+	return fs
+}
+
+func Connect(args []string) {
+	fs := connectFlags()
+	parseFlags(fs, args, connectUsage)
+	if fs.NArg() != 1 {
+		usageError(connectUsage)
+	}
+	timeout, _ := fs.GetDuration("timeout")
+	quiet, _ := fs.GetBool("quiet")
+
+	cfg, err := helpers.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+
+	conn, err := resolveConnection(cfg, fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var password string
+	if conn.HasPassword {
+		storedPassword, err := helpers.GetPassword(conn.ID)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to read saved password; daemon will prompt if needed.")
+		} else {
+			password = storedPassword
+		}
+	}
+
+	result := AttachDaemon(mustSocketPath())
+	defer result.Conn.Close()
+
+	daemon.WriteMsg(result.Conn, daemon.ConfigUpdateCmd{
+		Type:   "config_update",
+		Config: *cfg,
+	})
+	daemon.WriteMsg(result.Conn, daemon.TakeOwnershipCmd{Type: "take_ownership", Force: true})
+	connect := daemon.ConnectCmd{
+		Type:     "connect",
+		ConnID:   conn.ID,
+		Password: password,
+	}
+	if result.Hello.Supports(daemon.CapRequestIDs) {
+		connect.ID = daemon.NewRequestID()
+	}
+	daemon.WriteMsg(result.Conn, connect)
+	if timeout == 0 && !result.Hello.Supports(daemon.CapTimeouts) {
+		timeout = legacyConnectTimeout
+	}
+
+	restoreTerm := saveTerminalState()
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		restoreTerm()
+		fmt.Fprintln(os.Stderr, "\nInterrupted, disconnecting...")
+		daemon.WriteMsg(result.Conn, daemon.DisconnectCmd{Type: "disconnect", ConnID: conn.ID})
+		os.Exit(130)
+	}()
+
+	if err := waitForConnected(result, conn, connect.ID, timeout, quiet); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// waitForConnected follows conn's session until its tunnel is up or the
+// connect request identified by requestID fails. Events for other tunnels
+// run by the same daemon are ignored. A zero timeout leaves giving up to
+// the daemon.
+func waitForConnected(result daemon.HelloResult, conn *models.Connection, requestID string, timeout time.Duration, quiet bool) error {
+	stdin := bufio.NewReader(os.Stdin)
+	deadline := clientDeadline(timeout)
+
+	for {
+		_ = result.Conn.SetReadDeadline(deadline)
+		msg, err := daemon.ReadMsg(result.Reader)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				daemon.WriteMsg(result.Conn, daemon.DisconnectCmd{Type: "disconnect", ConnID: conn.ID, Reason: models.EndTimeout})
+				return &codeError{code: "connect_timeout", message: fmt.Sprintf("connection timed out after %s", timeout)}
+			}
+			return fmt.Errorf("lost connection to daemon: %w", err)
+		}
+
+		switch msg.Type {
+		case "log":
+			var logMsg daemon.LogMsg
+			if err := msg.Decode(&logMsg); err == nil && logMsg.ConnID == conn.ID && !quiet {
+				fmt.Fprintln(os.Stderr, logMsg.Line)
+			}
+
+		case "prompt":
+			var prompt daemon.PromptMsg
+			if err := msg.Decode(&prompt); err != nil {
+				return fmt.Errorf("decode daemon prompt: %w", err)
+			}
+			if prompt.ConnID != conn.ID {
+				continue
+			}
+			_ = result.Conn.SetReadDeadline(time.Time{})
+			answer, err := readPromptAnswer(stdin, prompt.IsPassword)
+			if err != nil {
+				daemon.WriteMsg(result.Conn, daemon.DisconnectCmd{Type: "disconnect", ConnID: conn.ID})
+				return &codeError{code: "prompt_unanswered", message: err.Error()}
+			}
+			daemon.WriteMsg(result.Conn, daemon.InputCmd{Type: "input", ConnID: conn.ID, Value: answer})
+			deadline = clientDeadline(timeout)
+
+		case "reconnecting":
+			var reconnecting daemon.ReconnectingMsg
+			if err := msg.Decode(&reconnecting); err == nil && reconnecting.ConnID == conn.ID && reconnecting.Attempt > 0 {
+				fmt.Fprintf(os.Stderr, "Reconnecting (attempt %d/%d)...\n", reconnecting.Attempt, reconnecting.Max)
+			}
+
+		case "connected":
+			var connected daemon.ConnectedMsg
+			if err := msg.Decode(&connected); err != nil {
+				return fmt.Errorf("decode daemon message: %w", err)
+			}
+			if connected.ConnID != conn.ID {
+				continue
+			}
+			if connected.IP != "" {
+				fmt.Printf("Connected to %s (%s)\n", conn.Name, connected.IP)
+			} else {
+				fmt.Printf("Connected to %s\n", conn.Name)
+			}
+			return nil
+
+		case "disconnected":
+			var disconnected daemon.DisconnectedMsg
+			if err := msg.Decode(&disconnected); err == nil && disconnected.ConnID == conn.ID {
+				return fmt.Errorf("connection to %s failed", conn.Name)
+			}
+
+		case "error":
+			var errMsg daemon.ErrorMsg
+			if err := msg.Decode(&errMsg); err != nil {
+				return fmt.Errorf("decode daemon error: %w", err)
+			}
+			if errMsg.ConnID != "" && errMsg.ConnID != conn.ID {
+				continue
+			}
+			return &codeError{code: errMsg.Code, message: errMsg.Message}
+
+		case "result":
+			var res daemon.ResultMsg
+			if err := msg.Decode(&res); err != nil {
+				return fmt.Errorf("decode daemon result: %w", err)
+			}
+			if requestID != "" && res.ID == requestID && !res.OK {
+				return &codeError{code: res.Code, message: res.Message}
+			}
+
+		case "ownership":
+			var ownership daemon.OwnershipMsg
+			if err := msg.Decode(&ownership); err != nil {
+				continue
+			}
+			if ownership.Free {
+				daemon.WriteMsg(result.Conn, daemon.TakeOwnershipCmd{Type: "take_ownership"})
+			} else if !ownership.Owner && !quiet {
+				fmt.Fprintf(os.Stderr, "Prompts are answered elsewhere now: %s\n", ownership.Reason)
+			}
+		}
+	}
+}
+
+// clientDeadline is when the client stops waiting on its own, zero for
+// never.
+func clientDeadline(timeout time.Duration) time.Time {
+	if timeout == 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(timeout)
+}
+
+type codeError struct {
+	code    string
+	message string
+}
+
+func (e *codeError) Error() string {
+	return fmt.Sprintf("[%s] %s", e.code, e.message)
+}
+
+func readPromptAnswer(stdin *bufio.Reader, isPassword bool) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if isPassword && term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, "> ")
+		value, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		return string(value), nil
+	}
+
+	if term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, "> ")
+	}
+	line, err := stdin.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		return "", fmt.Errorf("no answer on stdin")
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func saveTerminalState() func() {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return func() {}
+	}
+	state, err := term.GetState(fd)
+	if err != nil {
+		return func() {}
+	}
+	return func() {
+		_ = term.Restore(fd, state)
+	}
+}
+
+// Disconnect stops the named connection's tunnel, or every tunnel and an
+// external openconnect when no connection is given.
+func Disconnect(args []string) {
+	fs := disconnectFlags()
+	parseFlags(fs, args, disconnectUsage)
+	if fs.NArg() > 1 {
+		usageError(disconnectUsage)
+	}
+	timeout, _ := fs.GetDuration("timeout")
+
+	var target *models.Connection
+	if fs.NArg() == 1 {
+		target = mustResolveConnection(mustLoadConfig(), fs.Arg(0))
+	}
+
+	result, err := dialRunningDaemon(mustSocketPath())
+	if err != nil {
+		if errors.Is(err, errDaemonNotRunning) {
+			fmt.Println("Not connected (daemon is not running)")
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Failed to connect to daemon: %v\n", err)
+		os.Exit(1)
+	}
+	defer result.Conn.Close()
+
+	daemon.WriteMsg(result.Conn, daemon.GetStateCmd{Type: "get_state"})
+	msg, err := readUntil(result, "state", timeout, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read daemon state: %v\n", err)
+		os.Exit(1)
+	}
+	var state daemon.StateMsg
+	if err := msg.Decode(&state); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to decode daemon state: %v\n", err)
+		os.Exit(1)
+	}
+
+	// pending holds the connection IDs still to report disconnected; the
+	// external openconnect reports with an empty ID.
+	pending := make(map[string]bool)
+	for _, sess := range state.Sessions {
+		if target == nil || sess.ConnID == target.ID {
+			pending[sess.ConnID] = true
+		}
+	}
+	if target == nil && state.ExternalPID != 0 {
+		pending[""] = true
+	}
+
+	if len(pending) == 0 {
+		if target != nil {
+			fmt.Printf("%s is not connected\n", target.Name)
+		} else {
+			fmt.Println("Not connected")
+		}
+		return
+	}
+
+	cmd := daemon.DisconnectCmd{Type: "disconnect"}
+	if target != nil {
+		cmd.ConnID = target.ID
+	}
+	if result.Hello.Supports(daemon.CapRequestIDs) {
+		cmd.ID = daemon.NewRequestID()
+	}
+	daemon.WriteMsg(result.Conn, cmd)
+
+	if cmd.ID != "" {
+		if err := awaitResult(result, cmd.ID, timeout); err != nil {
+			var codeErr *codeError
+			if !errors.As(err, &codeErr) {
+				err = &codeError{code: "disconnect_failed", message: err.Error()}
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("Disconnected")
+		return
+	}
+
+	// Daemons without request IDs only report each tunnel going down.
+	deadline := time.Now().Add(timeout)
+	for len(pending) > 0 {
+		msg, err := readUntil(result, "disconnected", max(time.Until(deadline), time.Millisecond), nil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: [disconnect_failed] %v\n", err)
+			os.Exit(1)
+		}
+		var disconnected daemon.DisconnectedMsg
+		if err := msg.Decode(&disconnected); err == nil {
+			delete(pending, disconnected.ConnID)
+		}
+	}
+	fmt.Println("Disconnected")
+}
@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+)
+
+// resolveConnection finds a connection by exact ID, case-insensitive name or
+// unique ID prefix.
+func resolveConnection(cfg *models.Config, query string) (*models.Connection, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, fmt.Errorf("connection name or ID required")
+	}
+
+	for i := range cfg.Connections {
+		if cfg.Connections[i].ID == query {
+			return &cfg.Connections[i], nil
+		}
+	}
+
+	var matches []*models.Connection
+	for i := range cfg.Connections {
+		if strings.EqualFold(cfg.Connections[i].Name, query) {
+			matches = append(matches, &cfg.Connections[i])
+		}
+	}
+	if len(matches) == 0 {
+		for i := range cfg.Connections {
+			if strings.HasPrefix(cfg.Connections[i].ID, query) {
+				matches = append(matches, &cfg.Connections[i])
+			}
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("connection %q not found", query)
+	case 1:
+		return matches[0], nil
+	default:
+		return nil, fmt.Errorf("%q matches %d connections; use the connection ID", query, len(matches))
+	}
+}
@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+)
+
+func TestResolveConnection(t *testing.T) {
+	cfg := &models.Config{
+		Connections: []models.Connection{
+			{ID: "3f2a9c1e-0000", Name: "Work", Host: "vpn.work.com"},
+			{ID: "7b11d0aa-0000", Name: "Berlin", Host: "vpn.berlin.example"},
+			{ID: "7b22e0bb-0000", Name: "Berlin", Host: "vpn2.berlin.example"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		wantID  string
+		wantErr bool
+	}{
+		{name: "exact id", query: "7b11d0aa-0000", wantID: "7b11d0aa-0000"},
+		{name: "name is case-insensitive", query: "work", wantID: "3f2a9c1e-0000"},
+		{name: "unique id prefix", query: "3f2a", wantID: "3f2a9c1e-0000"},
+		{name: "ambiguous name", query: "Berlin", wantErr: true},
+		{name: "ambiguous id prefix", query: "7b", wantErr: true},
+		{name: "unknown", query: "home", wantErr: true},
+		{name: "empty", query: "  ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := resolveConnection(cfg, tt.query)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got connection %q", conn.ID)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveConnection returned error: %v", err)
+			}
+			if conn.ID != tt.wantID {
+				t.Fatalf("ID = %q, want %q", conn.ID, tt.wantID)
+			}
+		})
+	}
+}
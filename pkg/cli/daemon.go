@@ -0,0 +1,263 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/daemon"
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+	"github.com/Nybkox/lazyopenconnect/pkg/version"
+)
+
+var Debug bool
+
+var (
+	errDaemonNotRunning   = errors.New("daemon is not running")
+	errDaemonInaccessible = errors.New("daemon is running but not accessible for this user")
+)
+
+func mustSocketPath() string {
+	socketPath, err := daemon.SocketPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to get socket path: %v\n", err)
+		os.Exit(1)
+	}
+	return socketPath
+}
+
+// AttachDaemon starts or restarts the daemon as needed and returns a
+// connection that has completed the hello handshake. It exits on failure.
+func AttachDaemon(socketPath string) daemon.HelloResult {
+	var result daemon.HelloResult
+	var err error
+
+	for attempt := 0; attempt < 2; attempt++ {
+		status := daemon.DaemonStatus(socketPath)
+		switch status {
+		case daemon.DaemonInaccessible:
+			fmt.Println("Daemon socket is not accessible, restarting with sudo...")
+			if err := stopAndRespawnDaemon(socketPath); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to restart daemon: %v\n", err)
+				os.Exit(1)
+			}
+			ready := daemon.WaitForDaemonStart(socketPath, 3*time.Second)
+			if ready != daemon.DaemonReachable {
+				PrintInaccessibleDaemonHint()
+				os.Exit(1)
+			}
+		case daemon.DaemonNotRunning:
+			if err := daemon.SpawnDaemon(daemon.SpawnConfig{
+				Debug:       Debug,
+				Interactive: true,
+				Stdin:       os.Stdin,
+				Stdout:      os.Stdout,
+				Stderr:      os.Stderr,
+			}); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to start daemon: %v\n", err)
+				os.Exit(1)
+			}
+			ready := daemon.WaitForDaemonStart(socketPath, 2*time.Second)
+			if ready != daemon.DaemonReachable {
+				if ready == daemon.DaemonInaccessible {
+					PrintInaccessibleDaemonHint()
+				} else {
+					fmt.Fprintln(os.Stderr, "Failed to start daemon: daemon did not become ready")
+				}
+				os.Exit(1)
+			}
+		}
+
+		result, err = daemon.ConnectAndHello(socketPath, version.Current, 3*time.Second)
+		if err != nil {
+			var mismatchErr *daemon.VersionMismatchError
+			if errors.As(err, &mismatchErr) {
+				if attempt == 0 {
+					if !confirmDaemonRestart(mismatchErr) {
+						os.Exit(1)
+					}
+					// An idle daemon may already be stopping; a busy one
+					// only stops once the user agreed.
+					_ = daemon.RequestShutdown(socketPath)
+					// Shutting down disconnects every tunnel, which can
+					// take a few seconds each.
+					daemon.WaitForDaemonStop(socketPath, 10*time.Second)
+					continue
+				}
+				fmt.Fprintln(os.Stderr, "Failed to restart daemon with matching version")
+				os.Exit(1)
+			}
+			fmt.Fprintf(os.Stderr, "Failed to connect to daemon: %v\n", err)
+			os.Exit(1)
+		}
+
+		break
+	}
+
+	return result
+}
+
+// confirmDaemonRestart decides whether an incompatible daemon may be
+// replaced. An idle daemon is restarted right away; one carrying tunnels or
+// serving other clients, such as an open TUI, is only restarted after the
+// user agreed on a terminal.
+func confirmDaemonRestart(mismatch *daemon.VersionMismatchError) bool {
+	if mismatch.Tunnels == 0 && mismatch.Clients == 0 {
+		fmt.Fprintf(os.Stderr, "%s, restarting...\n", mismatch.Error())
+		return true
+	}
+
+	fmt.Fprintf(os.Stderr, "%s.\n", mismatch.Error())
+	if mismatch.Tunnels > 0 {
+		fmt.Fprintf(os.Stderr, "Restarting the daemon disconnects %d VPN tunnel(s).\n", mismatch.Tunnels)
+	}
+	if mismatch.Clients > 0 {
+		fmt.Fprintf(os.Stderr, "Restarting the daemon detaches %d other client(s).\n", mismatch.Clients)
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Fprintln(os.Stderr, "Error: run `lazyopenconnect daemon stop` when the daemon may go down")
+		return false
+	}
+
+	fmt.Fprint(os.Stderr, "Restart now? [y/N] ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true
+	}
+	fmt.Fprintln(os.Stderr, "Keeping the running daemon")
+	return false
+}
+
+// dialRunningDaemon connects to an already running daemon without spawning one.
+func dialRunningDaemon(socketPath string) (daemon.HelloResult, error) {
+	switch daemon.DaemonStatus(socketPath) {
+	case daemon.DaemonNotRunning:
+		return daemon.HelloResult{}, errDaemonNotRunning
+	case daemon.DaemonInaccessible:
+		return daemon.HelloResult{}, errDaemonInaccessible
+	}
+	return daemon.ConnectAndHello(socketPath, version.Current, 3*time.Second)
+}
+
+func stopAndRespawnDaemon(socketPath string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	stopArgs := []string{exe, "daemon", "stop", "all"}
+	stopCmd := exec.Command("sudo", stopArgs...)
+	stopCmd.Stdin = os.Stdin
+	stopCmd.Stdout = os.Stdout
+	stopCmd.Stderr = os.Stderr
+	if err := stopCmd.Run(); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if daemon.DaemonStatus(socketPath) == daemon.DaemonNotRunning {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	if err := daemon.SpawnDaemon(daemon.SpawnConfig{
+		Debug:       Debug,
+		Interactive: true,
+		Stdin:       os.Stdin,
+		Stdout:      os.Stdout,
+		Stderr:      os.Stderr,
+	}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func PrintInaccessibleDaemonHint() {
+	fmt.Fprintln(os.Stderr, "Daemon is running but not accessible for this user.")
+	fmt.Fprintln(os.Stderr, "This usually means an older root-owned daemon socket.")
+	fmt.Fprintln(os.Stderr, "Fix once with: sudo lazyopenconnect daemon stop all")
+	fmt.Fprintln(os.Stderr, "Then run: lazyopenconnect")
+}
+
+// readUntil reads daemon messages until one of the wanted type arrives.
+// Every other message is passed to onOther when it is non-nil.
+func readUntil(result daemon.HelloResult, msgType string, timeout time.Duration, onOther func(daemon.IncomingMsg)) (daemon.IncomingMsg, error) {
+	if timeout > 0 {
+		_ = result.Conn.SetReadDeadline(time.Now().Add(timeout))
+		defer result.Conn.SetReadDeadline(time.Time{})
+	}
+	for {
+		msg, err := daemon.ReadMsg(result.Reader)
+		if err != nil {
+			return daemon.IncomingMsg{}, err
+		}
+		if msg.Type == msgType {
+			return msg, nil
+		}
+		if onOther != nil {
+			onOther(msg)
+		}
+	}
+}
+
+// awaitResult waits for the result of request id. Other messages,
+// including its ack, are skipped. A failed request is returned as a
+// codeError.
+func awaitResult(result daemon.HelloResult, id string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		msg, err := readUntil(result, "result", max(time.Until(deadline), time.Millisecond), nil)
+		if err != nil {
+			return err
+		}
+		if msg.ID != id {
+			continue
+		}
+		var res daemon.ResultMsg
+		if err := msg.Decode(&res); err != nil {
+			return fmt.Errorf("decode daemon result: %w", err)
+		}
+		if !res.OK {
+			return &codeError{code: res.Code, message: res.Message}
+		}
+		return nil
+	}
+}
+
+// pushConfig sends cfg to a running daemon so it picks up changes made
+// outside the TUI. It never spawns a daemon.
+func pushConfig(cfg *models.Config) {
+	result, err := dialRunningDaemon(mustSocketPath())
+	if err != nil {
+		if !errors.Is(err, errDaemonNotRunning) {
+			fmt.Fprintf(os.Stderr, "Warning: config saved but not sent to daemon: %v\n", err)
+		}
+		return
+	}
+	defer result.Conn.Close()
+
+	cmd := daemon.ConfigUpdateCmd{Type: "config_update", Config: *cfg}
+	if result.Hello.Supports(daemon.CapRequestIDs) {
+		cmd.ID = daemon.NewRequestID()
+	}
+	err = daemon.WriteMsg(result.Conn, cmd)
+	if err == nil && cmd.ID != "" {
+		err = awaitResult(result, cmd.ID, 2*time.Second)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: config saved but not sent to daemon: %v\n", err)
+	}
+}
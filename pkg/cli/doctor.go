@@ -0,0 +1,326 @@
+package cli
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/controllers/helpers"
+	"github.com/Nybkox/lazyopenconnect/pkg/daemon"
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+)
+
+const doctorUsage = "doctor"
+
+type checkStatus string
+
+const (
+	checkPass checkStatus = "ok"
+	checkWarn checkStatus = "warn"
+	checkFail checkStatus = "fail"
+)
+
+type doctorCheck struct {
+	name   string
+	status checkStatus
+	detail string
+	hint   string
+}
+
+// doctorEnv is what the checks learned so far; later checks build on it.
+type doctorEnv struct {
+	cfg           *models.Config
+	daemonRunning bool
+	interfaces    []net.Interface
+}
+
+// Doctor checks everything lazyopenconnect depends on and prints one line
+// per check, so the output can be pasted into a support request.
+func Doctor(args []string) {
+	fs := newFlagSet("doctor")
+	parseFlags(fs, args, doctorUsage)
+	if fs.NArg() != 0 {
+		usageError(doctorUsage)
+	}
+
+	env := &doctorEnv{cfg: models.NewConfig()}
+	checks := []func(*doctorEnv) doctorCheck{
+		checkConfig,
+		checkOpenconnect,
+		checkSudo,
+		checkDaemon,
+		checkKeychain,
+		checkDNS,
+		checkNetInterface,
+		checkTunnelInterfaces,
+	}
+
+	failed, warned := 0, 0
+	for _, check := range checks {
+		result := check(env)
+		fmt.Printf("[%-4s] %-12s %s\n", result.status, result.name, result.detail)
+		if result.hint != "" && result.status != checkPass {
+			fmt.Printf("%-20sfix: %s\n", "", result.hint)
+		}
+		switch result.status {
+		case checkFail:
+			failed++
+		case checkWarn:
+			warned++
+		}
+	}
+
+	fmt.Printf("\n%d check(s): %d warning(s), %d failure(s)\n", len(checks), warned, failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func checkConfig(env *doctorEnv) doctorCheck {
+	cfg, err := helpers.LoadConfig()
+	if err != nil {
+		return doctorCheck{
+			name:   "config",
+			status: checkFail,
+			detail: err.Error(),
+			hint:   "fix or move away ~/.config/lazyopenconnect/config.json",
+		}
+	}
+	env.cfg = cfg
+	return doctorCheck{name: "config", status: checkPass, detail: fmt.Sprintf("%d connection(s)", len(cfg.Connections))}
+}
+
+func checkOpenconnect(env *doctorEnv) doctorCheck {
+	path, err := exec.LookPath("openconnect")
+	if err != nil {
+		return doctorCheck{
+			name:   "openconnect",
+			status: checkFail,
+			detail: "not found in PATH",
+			hint:   "install openconnect (apt/dnf/pacman install openconnect, or brew install openconnect)",
+		}
+	}
+
+	out, _ := exec.Command(path, "--version").CombinedOutput()
+	ver, protocols := parseOpenconnectVersion(string(out))
+	if ver == "" {
+		ver = "unknown version"
+	}
+	detail := fmt.Sprintf("%s (%s)", ver, path)
+	if len(protocols) == 0 {
+		return doctorCheck{name: "openconnect", status: checkPass, detail: detail}
+	}
+	detail += ", protocols: " + strings.Join(protocols, " ")
+
+	var missing []string
+	for _, conn := range env.cfg.Connections {
+		if !slices.Contains(protocols, conn.Protocol) && !slices.Contains(missing, conn.Protocol) {
+			missing = append(missing, conn.Protocol)
+		}
+	}
+	if len(missing) > 0 {
+		return doctorCheck{
+			name:   "openconnect",
+			status: checkWarn,
+			detail: detail,
+			hint:   "configured protocol(s) not supported by this build: " + strings.Join(missing, ", ") + "; upgrade openconnect",
+		}
+	}
+	return doctorCheck{name: "openconnect", status: checkPass, detail: detail}
+}
+
+// parseOpenconnectVersion extracts the version and the supported protocols
+// from `openconnect --version` output.
+func parseOpenconnectVersion(output string) (string, []string) {
+	var ver string
+	var protocols []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if rest, ok := strings.CutPrefix(line, "OpenConnect version "); ok {
+			ver = rest
+		}
+		if rest, ok := strings.CutPrefix(line, "Supported protocols:"); ok {
+			for _, p := range strings.Split(rest, ",") {
+				if fields := strings.Fields(p); len(fields) > 0 {
+					protocols = append(protocols, fields[0])
+				}
+			}
+		}
+	}
+	return ver, protocols
+}
+
+func checkSudo(_ *doctorEnv) doctorCheck {
+	if os.Geteuid() == 0 {
+		return doctorCheck{name: "sudo", status: checkPass, detail: "running as root"}
+	}
+	if _, err := exec.LookPath("sudo"); err != nil {
+		return doctorCheck{
+			name:   "sudo",
+			status: checkFail,
+			detail: "sudo not found; the daemon needs root to run openconnect",
+			hint:   "install sudo or start the daemon as root: lazyopenconnect daemon start",
+		}
+	}
+	if exec.Command("sudo", "-n", "true").Run() == nil {
+		return doctorCheck{name: "sudo", status: checkPass, detail: "available (no password needed)"}
+	}
+	return doctorCheck{name: "sudo", status: checkPass, detail: "available (asks for a password when the daemon starts)"}
+}
+
+func checkDaemon(env *doctorEnv) doctorCheck {
+	socketPath, err := daemon.SocketPath()
+	if err != nil {
+		return doctorCheck{name: "daemon", status: checkFail, detail: err.Error()}
+	}
+
+	switch daemon.DaemonStatus(socketPath) {
+	case daemon.DaemonReachable:
+		env.daemonRunning = true
+		return doctorCheck{name: "daemon", status: checkPass, detail: "running (" + socketPath + ")"}
+	case daemon.DaemonInaccessible:
+		env.daemonRunning = true
+		return doctorCheck{
+			name:   "daemon",
+			status: checkFail,
+			detail: "running but the socket is not accessible for this user",
+			hint:   "sudo lazyopenconnect daemon stop all",
+		}
+	}
+
+	files := daemon.InspectFiles(socketPath)
+	if !files.Stale() {
+		return doctorCheck{name: "daemon", status: checkPass, detail: "not running (starts on demand)"}
+	}
+
+	var leftovers []string
+	if files.SocketExists {
+		leftovers = append(leftovers, "socket "+files.SocketPath)
+	}
+	if files.Pid != 0 && !files.PidAlive {
+		leftovers = append(leftovers, fmt.Sprintf("pid file %s (pid %d is gone)", files.PidPath, files.Pid))
+	}
+	if files.LockHeld {
+		leftovers = append(leftovers, "lock "+files.LockPath+" held by an unresponsive process")
+	}
+	return doctorCheck{
+		name:   "daemon",
+		status: checkWarn,
+		detail: "not running, stale " + strings.Join(leftovers, ", "),
+		hint:   "sudo lazyopenconnect daemon stop all",
+	}
+}
+
+func checkKeychain(_ *doctorEnv) doctorCheck {
+	if err := helpers.CheckKeychain(); err != nil {
+		hint := "unlock your login keychain"
+		if runtime.GOOS == "linux" {
+			hint = "run a Secret Service provider (gnome-keyring, KWallet or KeePassXC) and unlock it"
+		}
+		return doctorCheck{
+			name:   "keychain",
+			status: checkFail,
+			detail: "unavailable: " + err.Error(),
+			hint:   hint + "; passwords will be prompted for until then",
+		}
+	}
+	return doctorCheck{name: "keychain", status: checkPass, detail: "available"}
+}
+
+func checkDNS(env *doctorEnv) doctorCheck {
+	backend := helpers.DNSBackend()
+	servers, err := helpers.DetectDNSServers(env.cfg.Settings.WifiInterface)
+	if err != nil {
+		return doctorCheck{
+			name:   "dns",
+			status: checkWarn,
+			detail: backend + ", failed to read servers: " + err.Error(),
+			hint:   "check that " + backend + " is readable; cleanup cannot restore DNS otherwise",
+		}
+	}
+	if len(servers) == 0 {
+		return doctorCheck{
+			name:   "dns",
+			status: checkWarn,
+			detail: backend + ", no DNS servers configured",
+			hint:   "set a DNS server in Settings or fix your network configuration",
+		}
+	}
+	return doctorCheck{name: "dns", status: checkPass, detail: backend + ", servers: " + strings.Join(servers, " ")}
+}
+
+func checkNetInterface(env *doctorEnv) doctorCheck {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return doctorCheck{name: "interfaces", status: checkFail, detail: err.Error()}
+	}
+	env.interfaces = ifaces
+
+	names := make([]string, 0, len(ifaces))
+	for _, iface := range ifaces {
+		names = append(names, iface.Name)
+	}
+	detected := "detected: " + strings.Join(names, " ")
+
+	want := env.cfg.Settings.NetInterface
+	if want == "" {
+		defaultIface, err := helpers.DetectDefaultInterface()
+		if err != nil || defaultIface == "" {
+			return doctorCheck{
+				name:   "interfaces",
+				status: checkWarn,
+				detail: "no default route; " + detected,
+				hint:   "connect to a network, or set Network interface in Settings",
+			}
+		}
+		return doctorCheck{name: "interfaces", status: checkPass, detail: defaultIface + " (default route); " + detected}
+	}
+
+	if !slices.Contains(names, want) {
+		return doctorCheck{
+			name:   "interfaces",
+			status: checkWarn,
+			detail: "configured network interface " + want + " not found; " + detected,
+			hint:   "update Network interface in Settings",
+		}
+	}
+	return doctorCheck{name: "interfaces", status: checkPass, detail: want + " (configured); " + detected}
+}
+
+// checkTunnelInterfaces flags tun devices left behind by a VPN that is no
+// longer managed by a daemon. macOS is skipped since the system itself
+// keeps several utun devices up.
+func checkTunnelInterfaces(env *doctorEnv) doctorCheck {
+	tunnel := env.cfg.Settings.TunnelInterface
+	if tunnel == "" {
+		tunnel = models.DefaultTunnelInterface()
+	}
+	if runtime.GOOS != "linux" {
+		return doctorCheck{name: "tunnel", status: checkPass, detail: tunnel + " (leftover check skipped on " + runtime.GOOS + ")"}
+	}
+
+	var tuns []string
+	for _, iface := range env.interfaces {
+		if strings.HasPrefix(iface.Name, "tun") {
+			tuns = append(tuns, iface.Name)
+		}
+	}
+
+	switch {
+	case len(tuns) == 0:
+		return doctorCheck{name: "tunnel", status: checkPass, detail: tunnel + " (no tun devices present)"}
+	case env.daemonRunning:
+		return doctorCheck{name: "tunnel", status: checkPass, detail: "tun devices up: " + strings.Join(tuns, " ")}
+	default:
+		return doctorCheck{
+			name:   "tunnel",
+			status: checkWarn,
+			detail: "leftover tun devices while no daemon is running: " + strings.Join(tuns, " "),
+			hint:   "run cleanup (c) in the TUI, or: sudo ip link delete " + tuns[0],
+		}
+	}
+}
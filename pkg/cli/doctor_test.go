@@ -0,0 +1,28 @@
+package cli
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseOpenconnectVersion(t *testing.T) {
+	output := `OpenConnect version v9.12-1
+Using GnuTLS 3.8.3. Features present: TPMv2, PKCS#11, RSA software token, HOTP software token, TOTP software token, Yubikey OATH, System keys, DTLS, ESP
+Supported protocols: anyconnect (default), nc, gp, pulse, f5, fortinet, array
+Default vpnc-script (override with --script):
+ /usr/share/vpnc-scripts/vpnc-script
+`
+
+	ver, protocols := parseOpenconnectVersion(output)
+	if ver != "v9.12-1" {
+		t.Fatalf("version = %q, want %q", ver, "v9.12-1")
+	}
+	want := []string{"anyconnect", "nc", "gp", "pulse", "f5", "fortinet", "array"}
+	if !slices.Equal(protocols, want) {
+		t.Fatalf("protocols = %q, want %q", protocols, want)
+	}
+
+	if ver, protocols := parseOpenconnectVersion("openconnect: command failed"); ver != "" || protocols != nil {
+		t.Fatalf("got %q, %q for unrelated output", ver, protocols)
+	}
+}
@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/pflag"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/controllers/helpers"
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+)
+
+const exportUsage = "export [<name|id>...] [--uri] [-o <file>]"
+
+func exportFlags() *pflag.FlagSet {
+	fs := newFlagSet("export")
+	fs.Bool("uri", false, "Print a lazyopenconnect:// URI instead of JSON")
+	fs.StringP("output", "o", "", "Write the bundle to a file instead of stdout")
+	return fs
+}
+
+// Export prints a shareable bundle of the given connections, or of all of
+// them. Passwords and IDs are never included.
+func Export(args []string) {
+	fs := exportFlags()
+	parseFlags(fs, args, exportUsage)
+	asURI, _ := fs.GetBool("uri")
+	output, _ := fs.GetString("output")
+
+	cfg := mustLoadConfig()
+	conns := cfg.Connections
+	if fs.NArg() > 0 {
+		conns = make([]models.Connection, 0, fs.NArg())
+		seen := make(map[string]bool)
+		for _, query := range fs.Args() {
+			conn := mustResolveConnection(cfg, query)
+			if !seen[conn.ID] {
+				seen[conn.ID] = true
+				conns = append(conns, *conn)
+			}
+		}
+	}
+	if len(conns) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: no connections to export")
+		os.Exit(1)
+	}
+
+	bundle := helpers.NewBundle(conns)
+	var data []byte
+	if asURI {
+		uri, err := bundle.URI()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to encode bundle: %v\n", err)
+			os.Exit(1)
+		}
+		data = []byte(uri + "\n")
+	} else {
+		var err error
+		if data, err = bundle.JSON(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to encode bundle: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if output == "" {
+		os.Stdout.Write(data)
+		return
+	}
+	if err := os.WriteFile(output, data, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write %s: %v\n", output, err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d connection(s) to %s\n", len(conns), output)
+}
@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/pflag"
+)
+
+func newFlagSet(name string) *pflag.FlagSet {
+	fs := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	fs.SortFlags = false
+	return fs
+}
+
+// parseFlags parses args into fs, printing usage on --help and exiting on
+// invalid input.
+func parseFlags(fs *pflag.FlagSet, args []string, usage string) {
+	fs.Usage = func() {
+		fmt.Println("Usage: lazyopenconnect " + usage)
+		if fs.HasFlags() {
+			fmt.Println()
+			fmt.Println("Options:")
+			fs.PrintDefaults()
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			os.Exit(0)
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintln(os.Stderr, "Usage: lazyopenconnect "+usage)
+		os.Exit(2)
+	}
+}
+
+func usageError(usage string) {
+	fmt.Fprintln(os.Stderr, "Usage: lazyopenconnect "+usage)
+	os.Exit(2)
+}
@@ -0,0 +1,220 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/controllers/helpers"
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+)
+
+const historyUsage = "history [<name|id>] [-n N] [--since DURATION] [--summary] [--json]"
+
+func historyFlags() *pflag.FlagSet {
+	fs := newFlagSet("history")
+	fs.IntP("lines", "n", 20, "Show the last N sessions (0 = all)")
+	fs.Duration("since", 0, "Only sessions that ended within this time (e.g. 168h)")
+	fs.Bool("summary", false, "Print sessions, drops and uptime per connection")
+	fs.Bool("json", false, "Print records as JSON")
+	return fs
+}
+
+// History prints the daemon's session journal.
+func History(args []string) {
+	fs := historyFlags()
+	parseFlags(fs, args, historyUsage)
+	if fs.NArg() > 1 {
+		usageError(historyUsage)
+	}
+	lines, _ := fs.GetInt("lines")
+	since, _ := fs.GetDuration("since")
+	summary, _ := fs.GetBool("summary")
+	asJSON, _ := fs.GetBool("json")
+	if lines < 0 || since < 0 {
+		fmt.Fprintln(os.Stderr, "Error: -n and --since must not be negative")
+		os.Exit(2)
+	}
+
+	cfg := mustLoadConfig()
+	var connID string
+	if fs.NArg() == 1 {
+		connID = mustResolveConnection(cfg, fs.Arg(0)).ID
+	}
+
+	path, err := helpers.HistoryPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to locate history: %v\n", err)
+		os.Exit(1)
+	}
+	records, err := helpers.LoadHistory(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read history: %v\n", err)
+		os.Exit(1)
+	}
+
+	var cutoff time.Time
+	if since > 0 {
+		cutoff = time.Now().Add(-since)
+	}
+	records = filterHistory(records, connID, cutoff)
+
+	// Records keep the name at the time; show renamed connections as they
+	// are called now.
+	names := make(map[string]string, len(cfg.Connections))
+	for _, conn := range cfg.Connections {
+		names[conn.ID] = conn.Name
+	}
+	for i := range records {
+		if name, ok := names[records[i].ConnID]; ok {
+			records[i].Name = name
+		}
+	}
+
+	if summary {
+		summaries := helpers.SummarizeHistory(records)
+		if asJSON {
+			printJSON(historySummariesJSON(summaries))
+			return
+		}
+		printHistorySummary(summaries)
+		return
+	}
+
+	if lines > 0 && len(records) > lines {
+		records = records[len(records)-lines:]
+	}
+	if asJSON {
+		if records == nil {
+			records = []models.HistoryRecord{}
+		}
+		printJSON(records)
+		return
+	}
+	printHistory(records)
+}
+
+// filterHistory keeps the records of connID (all when empty) that ended
+// after cutoff.
+func filterHistory(records []models.HistoryRecord, connID string, cutoff time.Time) []models.HistoryRecord {
+	var kept []models.HistoryRecord
+	for _, rec := range records {
+		if connID != "" && rec.ConnID != connID {
+			continue
+		}
+		if rec.EndedAt.Before(cutoff) {
+			continue
+		}
+		kept = append(kept, rec)
+	}
+	return kept
+}
+
+func printHistory(records []models.HistoryRecord) {
+	if len(records) == 0 {
+		fmt.Println("No sessions recorded")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STARTED\tCONNECTION\tUPTIME\tENDED BY\tRETRIES\tIP\tTUNNEL\tLAST ERROR")
+	for _, rec := range records {
+		uptime := "-"
+		if rec.Connected() {
+			uptime = rec.Uptime().Round(time.Second).String()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			rec.StartedAt.Local().Format("2006-01-02 15:04"),
+			historyName(rec.ConnID, rec.Name),
+			uptime,
+			rec.EndReason,
+			rec.ReconnectAttempts,
+			orDash(rec.IP),
+			orDash(rec.Tunnel),
+			truncate(rec.LastError, 60),
+		)
+	}
+	w.Flush()
+}
+
+// historySummaryJSON uses the naming of the records it totals.
+type historySummaryJSON struct {
+	ConnID        string     `json:"connId"`
+	Name          string     `json:"name,omitempty"`
+	Sessions      int        `json:"sessions"`
+	Drops         int        `json:"drops"`
+	Failed        int        `json:"failed"`
+	UptimeSeconds int64      `json:"uptimeSeconds"`
+	LastDrop      *time.Time `json:"lastDrop,omitempty"`
+}
+
+func historySummariesJSON(summaries []helpers.HistorySummary) []historySummaryJSON {
+	out := make([]historySummaryJSON, 0, len(summaries))
+	for _, sum := range summaries {
+		entry := historySummaryJSON{
+			ConnID:        sum.ConnID,
+			Name:          sum.Name,
+			Sessions:      sum.Sessions,
+			Drops:         sum.Drops,
+			Failed:        sum.Failed,
+			UptimeSeconds: int64(sum.Uptime.Seconds()),
+		}
+		if !sum.LastDrop.IsZero() {
+			lastDrop := sum.LastDrop
+			entry.LastDrop = &lastDrop
+		}
+		out = append(out, entry)
+	}
+	return out
+}
+
+func printHistorySummary(summaries []helpers.HistorySummary) {
+	if len(summaries) == 0 {
+		fmt.Println("No sessions recorded")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CONNECTION\tSESSIONS\tDROPS\tFAILED\tUPTIME\tLAST DROP")
+	for _, sum := range summaries {
+		lastDrop := "-"
+		if !sum.LastDrop.IsZero() {
+			lastDrop = sum.LastDrop.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\t%s\n",
+			historyName(sum.ConnID, sum.Name),
+			sum.Sessions,
+			sum.Drops,
+			sum.Failed,
+			sum.Uptime.Round(time.Second),
+			lastDrop,
+		)
+	}
+	w.Flush()
+}
+
+// historyName names a connection that may have been deleted since.
+func historyName(connID, name string) string {
+	if name != "" {
+		return name
+	}
+	return shortID(connID)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
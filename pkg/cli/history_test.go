@@ -0,0 +1,28 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+)
+
+func TestFilterHistory(t *testing.T) {
+	now := time.Now()
+	records := []models.HistoryRecord{
+		{ConnID: "berlin", EndedAt: now.Add(-48 * time.Hour)},
+		{ConnID: "work", EndedAt: now.Add(-time.Hour)},
+		{ConnID: "berlin", EndedAt: now.Add(-time.Hour)},
+	}
+
+	if got := filterHistory(records, "", time.Time{}); len(got) != 3 {
+		t.Fatalf("no filter kept %d records, want 3", len(got))
+	}
+	if got := filterHistory(records, "berlin", time.Time{}); len(got) != 2 {
+		t.Fatalf("connection filter kept %d records, want 2", len(got))
+	}
+	got := filterHistory(records, "berlin", now.Add(-24*time.Hour))
+	if len(got) != 1 || !got[0].EndedAt.Equal(records[2].EndedAt) {
+		t.Fatalf("since filter kept %+v, want the recent berlin record", got)
+	}
+}
@@ -0,0 +1,189 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"golang.org/x/term"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/controllers/helpers"
+)
+
+const (
+	importUsage              = "import <nm|anyconnect|globalprotect|bundle> [options]"
+	importNMUsage            = "import nm [--dir <path>] [--dry-run]"
+	importAnyConnectUsage    = "import anyconnect [<profile.xml|dir>] [--dry-run]"
+	importGlobalProtectUsage = "import globalprotect <portal-config.xml> [--dry-run]"
+	importBundleUsage        = "import bundle <file|lazyopenconnect://...> [--on-conflict skip|merge|rename] [--dry-run]"
+)
+
+func importNMFlags() *pflag.FlagSet {
+	fs := newFlagSet("import nm")
+	fs.String("dir", helpers.DefaultNMConnectionsDir, "NetworkManager keyfile directory")
+	fs.Bool("dry-run", false, "Show what would be imported without saving")
+	return fs
+}
+
+func importXMLFlags() *pflag.FlagSet {
+	fs := newFlagSet("import")
+	fs.Bool("dry-run", false, "Show what would be imported without saving")
+	return fs
+}
+
+func importBundleFlags() *pflag.FlagSet {
+	fs := newFlagSet("import bundle")
+	fs.String("on-conflict", "", "What to do with connections whose name or host exists: skip, merge or rename (asks when interactive)")
+	fs.Bool("dry-run", false, "Show what would be imported without saving")
+	return fs
+}
+
+func Import(args []string) {
+	if len(args) == 0 {
+		usageError(importUsage)
+	}
+
+	switch args[0] {
+	case "nm":
+		importNM(args[1:])
+	case "anyconnect":
+		importXML(helpers.ImportAnyConnect, args[1:], importAnyConnectUsage, false)
+	case "globalprotect", "gp":
+		importXML(helpers.ImportGlobalProtect, args[1:], importGlobalProtectUsage, true)
+	case "bundle":
+		importBundle(args[1:])
+	case "-h", "--help":
+		fmt.Println("Usage: lazyopenconnect " + importUsage)
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown import source %q\n", args[0])
+		usageError(importUsage)
+	}
+}
+
+func importNM(args []string) {
+	fs := importNMFlags()
+	parseFlags(fs, args, importNMUsage)
+	if fs.NArg() != 0 {
+		usageError(importNMUsage)
+	}
+	dir, _ := fs.GetString("dir")
+	dryRun, _ := fs.GetBool("dry-run")
+
+	candidates, skipped := mustReadImportCandidates(helpers.ImportNetworkManager, dir)
+	runImport(candidates, skipped, helpers.ImportNetworkManager.Options(), dryRun)
+}
+
+func importXML(source helpers.ImportSource, args []string, usage string, pathRequired bool) {
+	fs := importXMLFlags()
+	parseFlags(fs, args, usage)
+	if fs.NArg() > 1 || (pathRequired && fs.NArg() == 0) {
+		usageError(usage)
+	}
+	dryRun, _ := fs.GetBool("dry-run")
+
+	candidates, skipped := mustReadImportCandidates(source, fs.Arg(0))
+	runImport(candidates, skipped, source.Options(), dryRun)
+}
+
+func importBundle(args []string) {
+	fs := importBundleFlags()
+	parseFlags(fs, args, importBundleUsage)
+	if fs.NArg() != 1 {
+		usageError(importBundleUsage)
+	}
+	dryRun, _ := fs.GetBool("dry-run")
+	onConflict, _ := fs.GetString("on-conflict")
+
+	candidates, skipped := mustReadImportCandidates(helpers.ImportBundle, fs.Arg(0))
+
+	opts := helpers.ImportBundle.Options()
+	if onConflict != "" {
+		mode, err := helpers.ParseConflictMode(onConflict)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
+		opts.OnConflict = mode
+	} else if conflicts := helpers.FindImportConflicts(mustLoadConfig(), candidates); len(conflicts) > 0 {
+		opts.OnConflict = mustAskConflictMode(conflicts)
+	}
+
+	runImport(candidates, skipped, opts, dryRun)
+}
+
+// mustAskConflictMode lists conflicts and asks how to resolve them. Without a
+// terminal there is nobody to ask, so it exits and points at --on-conflict.
+func mustAskConflictMode(conflicts []helpers.ImportConflict) helpers.ConflictMode {
+	fmt.Fprintf(os.Stderr, "%d connection(s) already exist:\n", len(conflicts))
+	for _, c := range conflicts {
+		fmt.Fprintf(os.Stderr, "  %s (%s) <-> %s (%s)\n", c.Candidate.Connection.Name, c.Candidate.Connection.Host, c.Existing.Name, c.Existing.Host)
+	}
+
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Fprintln(os.Stderr, "Error: pass --on-conflict skip, merge or rename")
+		os.Exit(1)
+	}
+
+	stdin := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Fprint(os.Stderr, "[m]erge into existing, [r]ename and add, or [s]kip? ")
+		line, err := stdin.ReadString('\n')
+		if err != nil {
+			os.Exit(1)
+		}
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "m", "merge":
+			return helpers.ConflictMerge
+		case "r", "rename":
+			return helpers.ConflictRename
+		case "s", "skip":
+			return helpers.ConflictSkip
+		}
+	}
+}
+
+func mustReadImportCandidates(source helpers.ImportSource, path string) ([]helpers.ImportCandidate, []helpers.ImportSkipped) {
+	candidates, skipped, err := helpers.ReadImportCandidates(source, path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read %s: %v\n", source.Label(), err)
+		os.Exit(1)
+	}
+	return candidates, skipped
+}
+
+func runImport(candidates []helpers.ImportCandidate, skipped []helpers.ImportSkipped, opts helpers.ImportOptions, dryRun bool) {
+	cfg := mustLoadConfig()
+	report := helpers.ApplyImport(cfg, candidates, opts)
+	report.Skipped = append(skipped, report.Skipped...)
+
+	if !dryRun && len(report.Added)+len(report.Updated) > 0 {
+		helpers.SaveImportedPasswords(cfg, &report)
+		mustSaveConfig(cfg)
+	}
+
+	printImportReport(report, dryRun)
+}
+
+func printImportReport(report helpers.ImportReport, dryRun bool) {
+	for _, conn := range report.Added {
+		fmt.Printf("+ %s (%s, %s)\n", conn.Name, conn.Protocol, conn.Host)
+	}
+	for _, conn := range report.Updated {
+		fmt.Printf("~ %s (%s, %s)\n", conn.Name, conn.Protocol, conn.Host)
+	}
+	for _, skip := range report.Skipped {
+		fmt.Printf("- %s: %s\n", skip.Name, skip.Reason)
+	}
+	for _, warning := range report.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+
+	summary := fmt.Sprintf("%d new, %d updated, %d skipped", len(report.Added), len(report.Updated), len(report.Skipped))
+	if dryRun {
+		fmt.Println("Dry run: " + summary)
+		return
+	}
+	fmt.Println("Imported: " + summary)
+}
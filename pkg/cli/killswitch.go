@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/controllers/helpers"
+	"github.com/Nybkox/lazyopenconnect/pkg/daemon"
+)
+
+const killSwitchUsage = "killswitch off"
+
+func killSwitchFlags() *pflag.FlagSet {
+	fs := newFlagSet("killswitch")
+	fs.Duration("timeout", 15*time.Second, "Give up waiting for the daemon after this time")
+	return fs
+}
+
+// KillSwitch is the escape hatch for a kill switch left blocking the
+// network. The daemon removes its rules when it runs; otherwise they are
+// removed here, which needs root.
+func KillSwitch(args []string) {
+	fs := killSwitchFlags()
+	parseFlags(fs, args, killSwitchUsage)
+	if fs.NArg() != 1 || fs.Arg(0) != "off" {
+		usageError(killSwitchUsage)
+	}
+	timeout, _ := fs.GetDuration("timeout")
+
+	result, err := dialRunningDaemon(mustSocketPath())
+	switch {
+	case err == nil && result.Hello.Supports(daemon.CapKillSwitch):
+		defer result.Conn.Close()
+		cmd := daemon.KillSwitchOffCmd{Type: "killswitch_off", ID: daemon.NewRequestID()}
+		daemon.WriteMsg(result.Conn, cmd)
+		if err := awaitResult(result, cmd.ID, timeout); err != nil {
+			var codeErr *codeError
+			if !errors.As(err, &codeErr) {
+				err = &codeError{code: "killswitch_failed", message: err.Error()}
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("Kill switch off")
+		return
+	case err == nil:
+		result.Conn.Close()
+	case !errors.Is(err, errDaemonNotRunning):
+		fmt.Fprintf(os.Stderr, "Warning: daemon not reachable (%v), removing the rules directly\n", err)
+	}
+
+	disableKillSwitchLocally()
+}
+
+// disableKillSwitchLocally removes kill switch rules no daemon manages,
+// e.g. after the daemon crashed.
+func disableKillSwitchLocally() {
+	mustBeRoot(killSwitchUsage)
+	if !helpers.KillSwitchActive() {
+		fmt.Println("Kill switch is not on")
+		return
+	}
+	if err := helpers.DisableKillSwitch(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: [killswitch_failed] %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Kill switch off")
+}
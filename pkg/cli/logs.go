@@ -0,0 +1,203 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/controllers/helpers"
+	"github.com/Nybkox/lazyopenconnect/pkg/daemon"
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+)
+
+const logsUsage = "logs [<name|id>] [-f] [--no-color] [--since-line N]"
+
+func logsFlags() *pflag.FlagSet {
+	fs := newFlagSet("logs")
+	fs.BoolP("follow", "f", false, "Keep printing new lines until interrupted")
+	fs.Bool("no-color", false, "Strip ANSI color codes from output")
+	fs.Int("since-line", 0, "Start at this line number (0 = beginning of the log)")
+	return fs
+}
+
+func Logs(args []string) {
+	fs := logsFlags()
+	parseFlags(fs, args, logsUsage)
+	if fs.NArg() > 1 {
+		usageError(logsUsage)
+	}
+	follow, _ := fs.GetBool("follow")
+	noColor, _ := fs.GetBool("no-color")
+	sinceLine, _ := fs.GetInt("since-line")
+	if sinceLine < 0 {
+		fmt.Fprintln(os.Stderr, "Error: --since-line must not be negative")
+		os.Exit(2)
+	}
+
+	printLine := func(line string) {
+		if noColor {
+			line = helpers.StripANSI(line)
+		}
+		fmt.Println(line)
+	}
+
+	var connID string
+	if fs.NArg() == 1 {
+		connID = mustResolveConnection(mustLoadConfig(), fs.Arg(0)).ID
+	}
+
+	result, err := dialRunningDaemon(mustSocketPath())
+	if err != nil {
+		if errors.Is(err, errDaemonNotRunning) && !follow {
+			if connID == "" {
+				connID = helpers.LatestVpnLogConnID()
+			}
+			if err := printLogFile(connID, sinceLine, printLine); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to read VPN log: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+		if errors.Is(err, errDaemonInaccessible) {
+			PrintInaccessibleDaemonHint()
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Failed to connect to daemon: %v\n", err)
+		os.Exit(1)
+	}
+	defer result.Conn.Close()
+
+	if connID == "" {
+		connID, err = defaultLogConnID(result)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	daemon.WriteMsg(result.Conn, daemon.GetLogsCmd{Type: "get_logs", ConnID: connID, From: sinceLine, To: math.MaxInt32})
+
+	// Live lines can race ahead of the log_range reply, so hold on to them
+	// until we know where the history ends.
+	var early []daemon.LogMsg
+	msg, err := readUntil(result, "log_range", 5*time.Second, func(other daemon.IncomingMsg) {
+		var logMsg daemon.LogMsg
+		if other.Type == "log" && other.Decode(&logMsg) == nil && logMsg.ConnID == connID {
+			early = append(early, logMsg)
+		}
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read logs from daemon: %v\n", err)
+		os.Exit(1)
+	}
+
+	var logRange daemon.LogRangeMsg
+	if err := msg.Decode(&logRange); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to decode logs: %v\n", err)
+		os.Exit(1)
+	}
+	for _, line := range logRange.Lines {
+		printLine(line)
+	}
+
+	if !follow {
+		return
+	}
+
+	next := max(logRange.TotalLines, sinceLine)
+	handleLog := func(logMsg daemon.LogMsg) {
+		if logMsg.LineNumber == 0 && next > 0 {
+			// The daemon starts a fresh log on every connect and on clear.
+			next = 0
+		}
+		if logMsg.LineNumber < next {
+			return
+		}
+		printLine(logMsg.Line)
+		next = logMsg.LineNumber + 1
+	}
+	for _, logMsg := range early {
+		handleLog(logMsg)
+	}
+
+	for {
+		msg, err := daemon.ReadMsg(result.Reader)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				fmt.Fprintln(os.Stderr, "Daemon stopped")
+			} else {
+				fmt.Fprintf(os.Stderr, "Lost connection to daemon: %v\n", err)
+			}
+			os.Exit(1)
+		}
+
+		if msg.Type == "log" {
+			var logMsg daemon.LogMsg
+			if err := msg.Decode(&logMsg); err == nil && logMsg.ConnID == connID {
+				handleLog(logMsg)
+			}
+		}
+	}
+}
+
+// defaultLogConnID picks the log to show when none was named: the only
+// running tunnel, else the most recently written log.
+func defaultLogConnID(result daemon.HelloResult) (string, error) {
+	daemon.WriteMsg(result.Conn, daemon.GetStateCmd{Type: "get_state"})
+	msg, err := readUntil(result, "state", 5*time.Second, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to read daemon state: %w", err)
+	}
+	var state daemon.StateMsg
+	if err := msg.Decode(&state); err != nil {
+		return "", fmt.Errorf("failed to decode daemon state: %w", err)
+	}
+
+	switch len(state.Sessions) {
+	case 0:
+		return helpers.LatestVpnLogConnID(), nil
+	case 1:
+		return state.Sessions[0].ConnID, nil
+	}
+
+	cfg, err := helpers.LoadConfig()
+	if err != nil {
+		cfg = models.NewConfig()
+	}
+	names := make([]string, 0, len(state.Sessions))
+	for _, sess := range state.Sessions {
+		name := sess.ConnID
+		for _, conn := range cfg.Connections {
+			if conn.ID == sess.ConnID {
+				name = conn.Name
+				break
+			}
+		}
+		names = append(names, name)
+	}
+	return "", fmt.Errorf("%d tunnels are running, name one: %s", len(names), strings.Join(names, ", "))
+}
+
+func printLogFile(connID string, sinceLine int, printLine func(string)) error {
+	if connID == "" {
+		return nil
+	}
+	content, err := helpers.ReadVpnLog(connID)
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if content == "" || sinceLine >= len(lines) {
+		return nil
+	}
+	for _, line := range lines[sinceLine:] {
+		printLine(line)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+
+	"github.com/spf13/pflag"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/controllers/helpers"
+	"github.com/Nybkox/lazyopenconnect/pkg/daemon"
+)
+
+const (
+	serviceUsage          = "service <install|uninstall|status> [options]"
+	serviceInstallUsage   = "service install [--socket] [--debug]"
+	serviceUninstallUsage = "service uninstall"
+	serviceStatusUsage    = "service status"
+)
+
+func serviceInstallFlags() *pflag.FlagSet {
+	fs := newFlagSet("service install")
+	fs.Bool("socket", false, "Start the daemon on first use through a .socket unit instead of at boot")
+	fs.Bool("debug", false, "Enable debug logging in the daemon")
+	return fs
+}
+
+// Service manages the systemd units that run the daemon.
+func Service(args []string) {
+	if len(args) == 0 {
+		usageError(serviceUsage)
+	}
+
+	switch args[0] {
+	case "install":
+		serviceInstall(args[1:])
+	case "uninstall":
+		serviceUninstall(args[1:])
+	case "status":
+		serviceStatus(args[1:])
+	case "-h", "--help":
+		fmt.Println("Usage: lazyopenconnect " + serviceUsage)
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown service command %q\n", args[0])
+		usageError(serviceUsage)
+	}
+}
+
+func serviceInstall(args []string) {
+	fs := serviceInstallFlags()
+	parseFlags(fs, args, serviceInstallUsage)
+	if fs.NArg() != 0 {
+		usageError(serviceInstallUsage)
+	}
+	socket, _ := fs.GetBool("socket")
+	debug, _ := fs.GetBool("debug")
+
+	mustSupportService()
+	mustBeRoot("service install")
+
+	exe, err := os.Executable()
+	if err == nil {
+		exe, err = filepath.EvalSymlinks(exe)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot determine executable path: %v\n", err)
+		os.Exit(1)
+	}
+
+	cfg := helpers.ServiceConfig{
+		Executable: exe,
+		Debug:      debug,
+		Socket:     socket,
+		SocketPath: mustSocketPath(),
+		User:       sudoUser(),
+	}
+
+	// A daemon started with sudo keeps the socket; systemd takes over once
+	// it is stopped, so its tunnels are not dropped here.
+	running := daemon.DaemonStatus(cfg.SocketPath) != daemon.DaemonNotRunning && !helpers.ServiceInstalled()
+	if err := helpers.InstallService(cfg, !running); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to install service: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Installed %s\n", helpers.ServiceUnitPath())
+	if socket {
+		fmt.Printf("Installed %s\n", helpers.SocketUnitPath())
+		fmt.Println("The daemon starts when a client connects; auto-connect runs then, not at boot.")
+	} else {
+		fmt.Println("The daemon starts at boot.")
+	}
+	if running {
+		fmt.Println("A daemon is already running; systemd takes over after: lazyopenconnect daemon stop")
+	}
+}
+
+func serviceUninstall(args []string) {
+	fs := newFlagSet("service uninstall")
+	parseFlags(fs, args, serviceUninstallUsage)
+	if fs.NArg() != 0 {
+		usageError(serviceUninstallUsage)
+	}
+
+	mustSupportService()
+	if state := helpers.GetServiceState(); !state.ServiceInstalled && !state.SocketInstalled {
+		fmt.Println("Service is not installed")
+		return
+	}
+	mustBeRoot("service uninstall")
+
+	if err := helpers.RemoveService(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to remove service: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func serviceStatus(args []string) {
+	fs := newFlagSet("service status")
+	parseFlags(fs, args, serviceStatusUsage)
+	if fs.NArg() != 0 {
+		usageError(serviceStatusUsage)
+	}
+
+	if err := helpers.ServiceSupported(); err != nil {
+		fmt.Printf("Service: unavailable (%v)\n", err)
+		return
+	}
+
+	state := helpers.GetServiceState()
+	if state.ServiceInstalled {
+		fmt.Printf("Service: %s, %s (%s)\n", state.ServiceEnabled, state.ServiceActive, helpers.ServiceUnitPath())
+	} else {
+		fmt.Println("Service: not installed")
+	}
+	if state.SocketInstalled {
+		fmt.Printf("Socket:  %s, %s (%s)\n", state.SocketEnabled, state.SocketActive, helpers.SocketUnitPath())
+	} else {
+		fmt.Println("Socket:  not installed")
+	}
+}
+
+func mustSupportService() {
+	if err := helpers.ServiceSupported(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func mustBeRoot(command string) {
+	if os.Geteuid() != 0 {
+		fmt.Fprintf(os.Stderr, "Error: %s needs root\nRun: sudo lazyopenconnect %s\n", command, command)
+		os.Exit(1)
+	}
+}
+
+// sudoUser is the user who ran sudo, whose config the daemon serves. Nil
+// when run as root directly.
+func sudoUser() *user.User {
+	name := os.Getenv("SUDO_USER")
+	if name == "" || name == "root" {
+		return nil
+	}
+	u, err := user.Lookup(name)
+	if err != nil {
+		return nil
+	}
+	return u
+}
@@ -0,0 +1,246 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/controllers/helpers"
+	"github.com/Nybkox/lazyopenconnect/pkg/daemon"
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+)
+
+const statusUsage = "status [--json | --format <template>]"
+
+// statusReport describes the first tunnel at the top level, so scripts
+// written for a single connection keep working; Sessions lists them all.
+type statusReport struct {
+	Daemon         string          `json:"daemon"`
+	DaemonVersion  string          `json:"daemon_version,omitempty"`
+	Status         string          `json:"status"`
+	ConnID         string          `json:"conn_id,omitempty"`
+	Connection     string          `json:"connection,omitempty"`
+	IP             string          `json:"ip,omitempty"`
+	PID            int             `json:"pid,omitempty"`
+	Tunnel         string          `json:"tunnel,omitempty"`
+	ExternalHost   string          `json:"external_host,omitempty"`
+	ConnectedSince *time.Time      `json:"connected_since,omitempty"`
+	UptimeSeconds  int64           `json:"uptime_seconds,omitempty"`
+	Sessions       []sessionReport `json:"sessions"`
+}
+
+type sessionReport struct {
+	Status         string     `json:"status"`
+	ConnID         string     `json:"conn_id"`
+	Connection     string     `json:"connection,omitempty"`
+	IP             string     `json:"ip,omitempty"`
+	PID            int        `json:"pid,omitempty"`
+	Tunnel         string     `json:"tunnel,omitempty"`
+	ConnectedSince *time.Time `json:"connected_since,omitempty"`
+	UptimeSeconds  int64      `json:"uptime_seconds,omitempty"`
+}
+
+func statusFlags() *pflag.FlagSet {
+	fs := newFlagSet("status")
+	fs.Bool("json", false, "Print status as JSON")
+	fs.String("format", "", "Format output using a Go template (e.g. '{{.Status}} {{.IP}}')")
+	fs.Duration("timeout", 3*time.Second, "Give up waiting for the daemon after this time")
+	return fs
+}
+
+func Status(args []string) {
+	fs := statusFlags()
+	parseFlags(fs, args, statusUsage)
+	if fs.NArg() != 0 {
+		usageError(statusUsage)
+	}
+	asJSON, _ := fs.GetBool("json")
+	format, _ := fs.GetString("format")
+	timeout, _ := fs.GetDuration("timeout")
+
+	if asJSON && format != "" {
+		fmt.Fprintln(os.Stderr, "Error: --json and --format are mutually exclusive")
+		os.Exit(2)
+	}
+
+	var tmpl *template.Template
+	if format != "" {
+		var err error
+		tmpl, err = template.New("status").Parse(format)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid --format template: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	report, err := queryStatus(timeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to query daemon: %v\n", err)
+	}
+
+	switch {
+	case asJSON:
+		printJSON(report)
+	case tmpl != nil:
+		if err := tmpl.Execute(os.Stdout, report); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println()
+	default:
+		printStatusReport(report)
+	}
+
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+func queryStatus(timeout time.Duration) (statusReport, error) {
+	report := statusReport{
+		Daemon:   "not_running",
+		Status:   models.StatusDisconnected.String(),
+		Sessions: []sessionReport{},
+	}
+
+	result, err := dialRunningDaemon(mustSocketPath())
+	if err != nil {
+		switch {
+		case errors.Is(err, errDaemonNotRunning):
+			return report, nil
+		case errors.Is(err, errDaemonInaccessible):
+			report.Daemon = "inaccessible"
+		default:
+			report.Daemon = "unreachable"
+		}
+		report.Status = "unknown"
+		return report, err
+	}
+	defer result.Conn.Close()
+
+	report.Daemon = "running"
+	report.DaemonVersion = result.Hello.Version
+
+	daemon.WriteMsg(result.Conn, daemon.GetStateCmd{Type: "get_state"})
+	msg, err := readUntil(result, "state", timeout, nil)
+	if err != nil {
+		report.Status = "unknown"
+		return report, err
+	}
+
+	var state daemon.StateMsg
+	if err := msg.Decode(&state); err != nil {
+		report.Status = "unknown"
+		return report, err
+	}
+
+	cfg, err := helpers.LoadConfig()
+	if err != nil {
+		cfg = models.NewConfig()
+	}
+	connectionName := func(match func(models.Connection) bool) string {
+		for _, conn := range cfg.Connections {
+			if match(conn) {
+				return conn.Name
+			}
+		}
+		return ""
+	}
+
+	for _, ss := range state.Sessions {
+		sess := sessionReport{
+			Status:     models.ConnStatus(ss.Status).String(),
+			ConnID:     ss.ConnID,
+			Connection: connectionName(func(c models.Connection) bool { return c.ID == ss.ConnID }),
+			IP:         ss.IP,
+			PID:        ss.PID,
+			Tunnel:     ss.Tunnel,
+		}
+		if ss.ConnectedAt > 0 {
+			since := time.Unix(ss.ConnectedAt, 0)
+			sess.ConnectedSince = &since
+			sess.UptimeSeconds = int64(time.Since(since).Seconds())
+		}
+		report.Sessions = append(report.Sessions, sess)
+	}
+
+	report.ExternalHost = state.ExternalHost
+	if len(report.Sessions) > 0 {
+		first := report.Sessions[0]
+		report.Status = first.Status
+		report.ConnID = first.ConnID
+		report.Connection = first.Connection
+		report.IP = first.IP
+		report.PID = first.PID
+		report.Tunnel = first.Tunnel
+		report.ConnectedSince = first.ConnectedSince
+		report.UptimeSeconds = first.UptimeSeconds
+	} else if state.ExternalPID != 0 {
+		report.Status = models.StatusExternal.String()
+		report.PID = state.ExternalPID
+		report.Connection = connectionName(func(c models.Connection) bool { return c.Host == state.ExternalHost })
+	}
+
+	return report, nil
+}
+
+func printStatusReport(report statusReport) {
+	var lines [][2]string
+	if len(report.Sessions) > 1 {
+		for _, sess := range report.Sessions {
+			name := sess.Connection
+			if name == "" {
+				name = sess.ConnID
+			}
+			detail := sess.Status
+			if sess.IP != "" {
+				detail += ", " + sess.IP
+			}
+			if sess.Tunnel != "" {
+				detail += " on " + sess.Tunnel
+			}
+			if sess.ConnectedSince != nil {
+				detail += ", up " + (time.Duration(sess.UptimeSeconds) * time.Second).String()
+			}
+			lines = append(lines, [2]string{name, detail})
+		}
+		if report.ExternalHost != "" {
+			lines = append(lines, [2]string{"External host", report.ExternalHost})
+		}
+	} else {
+		lines = append(lines, [2]string{"Status", report.Status})
+		if report.Connection != "" {
+			lines = append(lines, [2]string{"Connection", report.Connection})
+		}
+		if report.ExternalHost != "" {
+			lines = append(lines, [2]string{"External host", report.ExternalHost})
+		}
+		if report.IP != "" {
+			lines = append(lines, [2]string{"IP", report.IP})
+		}
+		if report.Tunnel != "" {
+			lines = append(lines, [2]string{"Tunnel", report.Tunnel})
+		}
+		if report.PID != 0 {
+			lines = append(lines, [2]string{"PID", fmt.Sprint(report.PID)})
+		}
+		if report.ConnectedSince != nil {
+			uptime := (time.Duration(report.UptimeSeconds) * time.Second).String()
+			lines = append(lines, [2]string{"Connected since", report.ConnectedSince.Format(time.DateTime) + " (" + uptime + ")"})
+		}
+	}
+	daemonLine := strings.ReplaceAll(report.Daemon, "_", " ")
+	if report.DaemonVersion != "" {
+		daemonLine += " (" + report.DaemonVersion + ")"
+	}
+	lines = append(lines, [2]string{"Daemon", daemonLine})
+
+	for _, line := range lines {
+		fmt.Printf("%-16s %s\n", line[0]+":", line[1])
+	}
+}
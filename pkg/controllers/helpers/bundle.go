@@ -0,0 +1,199 @@
+package helpers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/atotto/clipboard"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+)
+
+// BundleVersion is the newest bundle format this build understands.
+const BundleVersion = 1
+
+const BundleURIScheme = "lazyopenconnect"
+
+// Bundle is a shareable set of connections. It carries no IDs, passwords or
+// keychain state; importers assign fresh IDs.
+type Bundle struct {
+	Version     int                `json:"version"`
+	Connections []BundleConnection `json:"connections"`
+}
+
+type BundleConnection struct {
+	Name       string `json:"name"`
+	Protocol   string `json:"protocol"`
+	Host       string `json:"host"`
+	Username   string `json:"username,omitempty"`
+	ServerCert string `json:"serverCert,omitempty"`
+	Flags      string `json:"flags,omitempty"`
+}
+
+func NewBundle(conns []models.Connection) Bundle {
+	bundle := Bundle{Version: BundleVersion, Connections: make([]BundleConnection, 0, len(conns))}
+	for _, conn := range conns {
+		bundle.Connections = append(bundle.Connections, BundleConnection{
+			Name:       conn.Name,
+			Protocol:   conn.Protocol,
+			Host:       conn.Host,
+			Username:   conn.Username,
+			ServerCert: conn.ServerCert,
+			Flags:      conn.Flags,
+		})
+	}
+	return bundle
+}
+
+func (b Bundle) JSON() ([]byte, error) {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// URI encodes the bundle as lazyopenconnect://import?bundle=<base64url JSON>
+// so it can be pasted into chat.
+func (b Bundle) URI() (string, error) {
+	data, err := json.Marshal(b)
+	if err != nil {
+		return "", err
+	}
+	u := url.URL{
+		Scheme:   BundleURIScheme,
+		Host:     "import",
+		RawQuery: url.Values{"bundle": {base64.RawURLEncoding.EncodeToString(data)}}.Encode(),
+	}
+	return u.String(), nil
+}
+
+// ParseBundle decodes a bundle from JSON or from a lazyopenconnect:// URI.
+func ParseBundle(data []byte) (Bundle, error) {
+	if text := string(data); IsBundleURI(text) {
+		decoded, err := decodeBundleURI(strings.TrimSpace(text))
+		if err != nil {
+			return Bundle{}, err
+		}
+		data = decoded
+	}
+
+	var bundle Bundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return Bundle{}, fmt.Errorf("parse bundle: %w", err)
+	}
+	if bundle.Version < 1 {
+		return Bundle{}, fmt.Errorf("bundle has no version")
+	}
+	if bundle.Version > BundleVersion {
+		return Bundle{}, fmt.Errorf("bundle version %d is newer than supported (%d); update lazyopenconnect", bundle.Version, BundleVersion)
+	}
+	return bundle, nil
+}
+
+func decodeBundleURI(text string) ([]byte, error) {
+	u, err := url.Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse bundle URI: %w", err)
+	}
+	encoded := u.Query().Get("bundle")
+	if u.Host != "import" || encoded == "" {
+		return nil, fmt.Errorf("not a bundle URI")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("decode bundle URI: %w", err)
+	}
+	return data, nil
+}
+
+// Candidates returns the bundle's connections as import candidates.
+// Entries without a host or with an unknown protocol are skipped.
+func (b Bundle) Candidates() ([]ImportCandidate, []ImportSkipped) {
+	var candidates []ImportCandidate
+	var skipped []ImportSkipped
+	for _, entry := range b.Connections {
+		conn := models.Connection{
+			Name:       strings.TrimSpace(entry.Name),
+			Protocol:   strings.TrimSpace(entry.Protocol),
+			Host:       strings.TrimSpace(entry.Host),
+			Username:   strings.TrimSpace(entry.Username),
+			ServerCert: strings.TrimSpace(entry.ServerCert),
+			Flags:      strings.TrimSpace(entry.Flags),
+		}
+		if conn.Name == "" {
+			conn.Name = conn.Host
+		}
+
+		switch {
+		case conn.Host == "":
+			skipped = append(skipped, ImportSkipped{Name: conn.Name, Reason: "no host"})
+		case !models.IsSupportedProtocol(conn.Protocol):
+			skipped = append(skipped, ImportSkipped{Name: conn.Name, Reason: fmt.Sprintf("unsupported protocol %q", conn.Protocol)})
+		default:
+			candidates = append(candidates, ImportCandidate{Connection: conn})
+		}
+	}
+	return candidates, skipped
+}
+
+// ReadBundle reads a bundle from a file, or from pathOrURI itself when it is
+// a lazyopenconnect:// URI.
+func ReadBundle(pathOrURI string) ([]ImportCandidate, []ImportSkipped, error) {
+	data := []byte(pathOrURI)
+	source := ""
+	if !IsBundleURI(pathOrURI) {
+		var err error
+		if data, err = os.ReadFile(pathOrURI); err != nil {
+			return nil, nil, err
+		}
+		source = pathOrURI
+	}
+
+	bundle, err := ParseBundle(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	candidates, skipped := bundle.Candidates()
+	for i := range candidates {
+		candidates[i].Source = source
+	}
+	return candidates, skipped, nil
+}
+
+func IsBundleURI(s string) bool {
+	return strings.HasPrefix(strings.TrimSpace(s), BundleURIScheme+"://")
+}
+
+// DefaultBundlePath returns the default path for exporting a bundle.
+func DefaultBundlePath() string {
+	home, err := GetHomeDir()
+	if err != nil {
+		return filepath.Join("/tmp", "lazyopenconnect", "connections.json")
+	}
+	return filepath.Join(home, "lazyopenconnect-connections.json")
+}
+
+func WriteBundleFile(path string, bundle Bundle) error {
+	data, err := bundle.JSON()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func CopyBundleURIToClipboard(bundle Bundle) error {
+	uri, err := bundle.URI()
+	if err != nil {
+		return err
+	}
+	return clipboard.WriteAll(uri)
+}
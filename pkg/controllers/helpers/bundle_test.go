@@ -0,0 +1,80 @@
+package helpers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+)
+
+func TestBundleRoundTrip(t *testing.T) {
+	conns := []models.Connection{
+		{ID: "1", Name: "Work", Protocol: "gp", Host: "vpn.example.com", Username: "alice", HasPassword: true, ServerCert: "pin-sha256:abc=", Flags: "--no-dtls"},
+		{ID: "2", Name: "Lab", Protocol: "anyconnect", Host: "lab.example.com"},
+	}
+	bundle := NewBundle(conns)
+
+	data, err := bundle.JSON()
+	if err != nil {
+		t.Fatalf("JSON returned error: %v", err)
+	}
+	for _, secret := range []string{`"id"`, "hasPassword"} {
+		if strings.Contains(string(data), secret) {
+			t.Fatalf("bundle JSON contains %s:\n%s", secret, data)
+		}
+	}
+
+	uri, err := bundle.URI()
+	if err != nil {
+		t.Fatalf("URI returned error: %v", err)
+	}
+	if !IsBundleURI(uri) {
+		t.Fatalf("URI = %q, want lazyopenconnect:// scheme", uri)
+	}
+
+	for _, input := range [][]byte{data, []byte(uri + "\n")} {
+		parsed, err := ParseBundle(input)
+		if err != nil {
+			t.Fatalf("ParseBundle returned error: %v", err)
+		}
+		candidates, skipped := parsed.Candidates()
+		if len(candidates) != 2 || len(skipped) != 0 {
+			t.Fatalf("candidates = %+v, skipped = %+v", candidates, skipped)
+		}
+		got := candidates[0].Connection
+		want := models.Connection{Name: "Work", Protocol: "gp", Host: "vpn.example.com", Username: "alice", ServerCert: "pin-sha256:abc=", Flags: "--no-dtls"}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("candidate = %+v, want %+v", got, want)
+		}
+	}
+}
+
+func TestParseBundleRejectsNewerVersion(t *testing.T) {
+	if _, err := ParseBundle([]byte(`{"version": 99, "connections": []}`)); err == nil {
+		t.Fatal("expected an error for a newer bundle version")
+	}
+	if _, err := ParseBundle([]byte(`{"connections": []}`)); err == nil {
+		t.Fatal("expected an error for a bundle without version")
+	}
+}
+
+func TestApplyImportRenamesConflicts(t *testing.T) {
+	cfg := &models.Config{
+		Connections: []models.Connection{
+			{ID: "1", Name: "Work", Protocol: "gp", Host: "vpn.example.com"},
+		},
+	}
+	candidates := []ImportCandidate{
+		{Connection: models.Connection{Name: "Work", Protocol: "gp", Host: "other.example.com"}},
+	}
+
+	if conflicts := FindImportConflicts(cfg, candidates); len(conflicts) != 1 || conflicts[0].Existing.ID != "1" {
+		t.Fatalf("conflicts = %+v, want Work", conflicts)
+	}
+
+	report := ApplyImport(cfg, candidates, ImportOptions{OnConflict: ConflictRename})
+	if len(report.Added) != 1 || report.Added[0].Name != "Work (2)" || report.Added[0].ID == "1" {
+		t.Fatalf("Added = %+v, want renamed copy with a new ID", report.Added)
+	}
+}
@@ -20,7 +20,10 @@ type CleanupResult struct {
 }
 
 func RunCleanupSteps(snap *NetworkSnapshot) []CleanupResult {
-	steps := PlatformCleanupSteps(snap)
+	return runSteps(PlatformCleanupSteps(snap))
+}
+
+func runSteps(steps []CleanupStep) []CleanupResult {
 	var results []CleanupResult
 
 	for _, step := range steps {
@@ -37,6 +40,15 @@ func RunCleanupSteps(snap *NetworkSnapshot) []CleanupResult {
 	return results
 }
 
+// RunTunnelCleanupSteps only tears down the tunnel interface of snap and
+// undoes its split routes and DNS. It is used while other tunnels are
+// still up, so the default route and system DNS are left alone.
+func RunTunnelCleanupSteps(snap *NetworkSnapshot) []CleanupResult {
+	steps := PlatformRouteCleanupSteps(snap)
+	steps = append(steps, PlatformSplitDNSCleanupSteps(snap)...)
+	return runSteps(append(steps, PlatformTunnelCleanupSteps(snap.TunnelInterface)...))
+}
+
 func FormatCleanupResults(results []CleanupResult) []string {
 	var lines []string
 	for _, r := range results {
@@ -3,6 +3,8 @@
 package helpers
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 )
@@ -28,15 +30,9 @@ func PlatformCleanupSteps(snap *NetworkSnapshot) []CleanupStep {
 		dns = "1.1.1.1 1.0.0.1"
 	}
 
-	var steps []CleanupStep
-
-	steps = append(steps, CleanupStep{
-		Name: "Killing tunnel interface (" + tunnelIface + ")",
-		Cmd:  "ifconfig " + tunnelIface + " down",
-		Fn: func() error {
-			return runCmd("ifconfig", tunnelIface, "down")
-		},
-	})
+	steps := PlatformRouteCleanupSteps(snap)
+	steps = append(steps, PlatformSplitDNSCleanupSteps(snap)...)
+	steps = append(steps, PlatformTunnelCleanupSteps(tunnelIface)...)
 
 	steps = append(steps, CleanupStep{
 		Name: "Flushing routes",
@@ -77,3 +73,100 @@ func PlatformCleanupSteps(snap *NetworkSnapshot) []CleanupStep {
 
 	return steps
 }
+
+// PlatformRouteCleanupSteps removes the split routes the tunnel of snap
+// added: includes on the tunnel, excludes via the physical gateway. Routes
+// its vpnc-script already removed are not an error.
+func PlatformRouteCleanupSteps(snap *NetworkSnapshot) []CleanupStep {
+	tunnelIface := snap.TunnelInterface
+	if tunnelIface == "" {
+		tunnelIface = "utun0"
+	}
+
+	var steps []CleanupStep
+	for _, route := range snap.IncludeRoutes {
+		steps = append(steps, deleteRouteStep(route, "-interface", tunnelIface))
+	}
+	for _, route := range snap.ExcludeRoutes {
+		if snap.DefaultGateway != "" && strings.Contains(route, ":") == strings.Contains(snap.DefaultGateway, ":") {
+			steps = append(steps, deleteRouteStep(route, snap.DefaultGateway))
+		} else {
+			steps = append(steps, deleteRouteStep(route))
+		}
+	}
+	return steps
+}
+
+// PlatformSplitDNSCleanupSteps removes the /etc/resolver files written for
+// the split DNS domains of snap. Files lazyopenconnect did not write stay.
+func PlatformSplitDNSCleanupSteps(snap *NetworkSnapshot) []CleanupStep {
+	var steps []CleanupStep
+	for _, raw := range snap.SplitDNSDomains {
+		domain, err := ParseDNSDomain(raw)
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(resolverDir, strings.TrimPrefix(domain, "~"))
+		steps = append(steps, CleanupStep{
+			Name: "Removing split DNS for " + strings.TrimPrefix(domain, "~"),
+			Cmd:  "rm " + path,
+			Fn: func() error {
+				data, err := os.ReadFile(path)
+				if os.IsNotExist(err) || (err == nil && !strings.HasPrefix(string(data), resolverMarker)) {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				return os.Remove(path)
+			},
+		})
+	}
+	if len(steps) > 0 {
+		steps = append(steps, CleanupStep{
+			Name: "Flushing DNS cache",
+			Cmd:  "dscacheutil -flushcache && killall -HUP mDNSResponder",
+			Fn: func() error {
+				runCmd("dscacheutil", "-flushcache")
+				return runCmd("killall", "-HUP", "mDNSResponder")
+			},
+		})
+	}
+	return steps
+}
+
+// deleteRouteStep deletes route through gateway, if given, so a route of
+// the same prefix that the tunnel did not add stays.
+func deleteRouteStep(route string, gateway ...string) CleanupStep {
+	args := []string{"-n", "delete", "-net", route}
+	if strings.Contains(route, ":") {
+		args = []string{"-n", "delete", "-inet6", "-net", route}
+	}
+	args = append(args, gateway...)
+	return CleanupStep{
+		Name: "Removing split route " + route,
+		Cmd:  "route " + strings.Join(args, " "),
+		Fn: func() error {
+			_ = runCmd("route", args...)
+			return nil
+		},
+	}
+}
+
+// PlatformTunnelCleanupSteps only brings the interface down; macOS has no
+// per-device route flush, so routes are left to the tunnel's own teardown.
+func PlatformTunnelCleanupSteps(tunnelIface string) []CleanupStep {
+	if tunnelIface == "" {
+		tunnelIface = "utun0"
+	}
+
+	return []CleanupStep{
+		{
+			Name: "Killing tunnel interface (" + tunnelIface + ")",
+			Cmd:  "ifconfig " + tunnelIface + " down",
+			Fn: func() error {
+				return runCmd("ifconfig", tunnelIface, "down")
+			},
+		},
+	}
+}
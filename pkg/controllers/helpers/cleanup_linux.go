@@ -3,7 +3,9 @@
 package helpers
 
 import (
+	"bytes"
 	"os"
+	"os/exec"
 	"strings"
 )
 
@@ -21,23 +23,9 @@ func PlatformCleanupSteps(snap *NetworkSnapshot) []CleanupStep {
 	gateway := snap.DefaultGateway
 	dns := strings.Join(snap.DNSServers, " ")
 
-	var steps []CleanupStep
-
-	steps = append(steps, CleanupStep{
-		Name: "Killing tunnel interface (" + tunnelIface + ")",
-		Cmd:  "ip link set " + tunnelIface + " down",
-		Fn: func() error {
-			return runCmd("ip", "link", "set", tunnelIface, "down")
-		},
-	})
-
-	steps = append(steps, CleanupStep{
-		Name: "Flushing VPN routes (" + tunnelIface + ")",
-		Cmd:  "ip route flush dev " + tunnelIface,
-		Fn: func() error {
-			return runCmd("ip", "route", "flush", "dev", tunnelIface)
-		},
-	})
+	steps := PlatformRouteCleanupSteps(snap)
+	steps = append(steps, PlatformSplitDNSCleanupSteps(snap)...)
+	steps = append(steps, PlatformTunnelCleanupSteps(tunnelIface)...)
 
 	if gateway != "" {
 		steps = append(steps, CleanupStep{
@@ -83,6 +71,103 @@ func PlatformCleanupSteps(snap *NetworkSnapshot) []CleanupStep {
 	return steps
 }
 
+func PlatformTunnelCleanupSteps(tunnelIface string) []CleanupStep {
+	if tunnelIface == "" {
+		tunnelIface = "tun0"
+	}
+
+	return []CleanupStep{
+		{
+			Name: "Killing tunnel interface (" + tunnelIface + ")",
+			Cmd:  "ip link set " + tunnelIface + " down",
+			Fn: func() error {
+				return runCmd("ip", "link", "set", tunnelIface, "down")
+			},
+		},
+		{
+			Name: "Flushing VPN routes (" + tunnelIface + ")",
+			Cmd:  "ip route flush dev " + tunnelIface,
+			Fn: func() error {
+				return runCmd("ip", "route", "flush", "dev", tunnelIface)
+			},
+		},
+	}
+}
+
+// PlatformRouteCleanupSteps removes the split routes the tunnel of snap
+// added: includes on the tunnel, excludes via the physical gateway. Routes
+// its vpnc-script already removed are skipped.
+func PlatformRouteCleanupSteps(snap *NetworkSnapshot) []CleanupStep {
+	tunnelIface := snap.TunnelInterface
+	if tunnelIface == "" {
+		tunnelIface = "tun0"
+	}
+
+	var steps []CleanupStep
+	for _, route := range snap.IncludeRoutes {
+		steps = append(steps, deleteRouteStep(route, "dev", tunnelIface))
+	}
+	for _, route := range snap.ExcludeRoutes {
+		switch {
+		case snap.DefaultGateway != "" && strings.Contains(route, ":") == strings.Contains(snap.DefaultGateway, ":"):
+			steps = append(steps, deleteRouteStep(route, "via", snap.DefaultGateway))
+		case snap.DefaultInterface != "":
+			steps = append(steps, deleteRouteStep(route, "dev", snap.DefaultInterface))
+		default:
+			steps = append(steps, deleteRouteStep(route))
+		}
+	}
+	return steps
+}
+
+// PlatformSplitDNSCleanupSteps reverts the split DNS setup of the tunnel
+// link. A link that is already gone took its DNS setup with it.
+func PlatformSplitDNSCleanupSteps(snap *NetworkSnapshot) []CleanupStep {
+	if len(snap.SplitDNSDomains) == 0 || !isSystemdResolved() {
+		return nil
+	}
+	tunnelIface := snap.TunnelInterface
+	if tunnelIface == "" {
+		tunnelIface = "tun0"
+	}
+
+	return []CleanupStep{{
+		Name: "Reverting split DNS (" + tunnelIface + ")",
+		Cmd:  "resolvectl revert " + tunnelIface,
+		Fn: func() error {
+			if _, err := os.Stat("/sys/class/net/" + tunnelIface); os.IsNotExist(err) {
+				return nil
+			}
+			if err := runCmd("resolvectl", "revert", tunnelIface); err != nil {
+				return err
+			}
+			return runCmd("resolvectl", "flush-caches")
+		},
+	}}
+}
+
+// deleteRouteStep deletes route, narrowed down by selector so a route of
+// the same prefix that the tunnel did not add stays.
+func deleteRouteStep(route string, selector ...string) CleanupStep {
+	family := "-4"
+	if strings.Contains(route, ":") {
+		family = "-6"
+	}
+	del := append([]string{family, "route", "del", route}, selector...)
+	show := append([]string{family, "route", "show", "exact", route}, selector...)
+	return CleanupStep{
+		Name: "Removing split route " + route,
+		Cmd:  "ip " + strings.Join(del, " "),
+		Fn: func() error {
+			out, err := exec.Command("ip", show...).Output()
+			if err == nil && len(bytes.TrimSpace(out)) == 0 {
+				return nil
+			}
+			return runCmd("ip", del...)
+		},
+	}
+}
+
 func writeResolvConf(servers []string) error {
 	var sb strings.Builder
 	for _, s := range servers {
@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"strconv"
 
 	"github.com/Nybkox/lazyopenconnect/pkg/models"
 )
@@ -91,5 +92,29 @@ func SaveConfig(cfg *models.Config) error {
 		return err
 	}
 
-	return os.WriteFile(path, data, 0o600)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return err
+	}
+
+	// Commands run through sudo (e.g. imports reading root-only files) must
+	// leave the config readable by the invoking user.
+	chownToSudoUser(dir, path)
+	return nil
+}
+
+func chownToSudoUser(paths ...string) {
+	if os.Geteuid() != 0 {
+		return
+	}
+	uid, err := strconv.Atoi(os.Getenv("SUDO_UID"))
+	if err != nil {
+		return
+	}
+	gid, err := strconv.Atoi(os.Getenv("SUDO_GID"))
+	if err != nil {
+		return
+	}
+	for _, path := range paths {
+		_ = os.Chown(path, uid, gid)
+	}
 }
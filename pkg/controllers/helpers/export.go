@@ -37,16 +37,18 @@ func DefaultExportPath() string {
 	return filepath.Join("/tmp", "lazyopenconnect", "exported-logs-"+timestamp+".log")
 }
 
-func VpnLogPath() (string, error) {
+// VpnLogPath returns the log file of the tunnel for connID. Every
+// connection keeps its own log so concurrent tunnels do not interleave.
+func VpnLogPath(connID string) (string, error) {
 	dir, err := GetConfigDir()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(dir, "vpn.log"), nil
+	return filepath.Join(dir, "vpn-"+connID+".log"), nil
 }
 
-func ReadVpnLog() (string, error) {
-	path, err := VpnLogPath()
+func ReadVpnLog(connID string) (string, error) {
+	path, err := VpnLogPath(connID)
 	if err != nil {
 		return "", err
 	}
@@ -61,8 +63,30 @@ func ReadVpnLog() (string, error) {
 	return string(data), nil
 }
 
-func CopyVpnLogToPath(destPath string, stripANSI bool) error {
-	content, err := ReadVpnLog()
+// LatestVpnLogConnID returns the connection whose log was written last, or
+// "" when there are no logs yet.
+func LatestVpnLogConnID() string {
+	dir, err := GetConfigDir()
+	if err != nil {
+		return ""
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, "vpn-*.log"))
+
+	var latest string
+	var latestMod time.Time
+	for _, path := range matches {
+		info, err := os.Stat(path)
+		if err != nil || !info.ModTime().After(latestMod) {
+			continue
+		}
+		latestMod = info.ModTime()
+		latest = strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), "vpn-"), ".log")
+	}
+	return latest
+}
+
+func CopyVpnLogToPath(connID, destPath string, stripANSI bool) error {
+	content, err := ReadVpnLog(connID)
 	if err != nil {
 		return err
 	}
@@ -79,8 +103,8 @@ func CopyVpnLogToPath(destPath string, stripANSI bool) error {
 	return os.WriteFile(destPath, []byte(content), 0o644)
 }
 
-func CopyVpnLogToClipboard() error {
-	content, err := ReadVpnLog()
+func CopyVpnLogToClipboard(connID string) error {
+	content, err := ReadVpnLog(connID)
 	if err != nil {
 		return err
 	}
@@ -3,6 +3,10 @@ package helpers
 import (
 	"errors"
 	"fmt"
+	"math"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/charmbracelet/huh"
@@ -17,13 +21,28 @@ func formTheme() *huh.Theme {
 }
 
 type ConnectionFormData struct {
-	Name       string
-	Protocol   string
-	Host       string
-	Username   string
-	Password   string
-	ServerCert string
-	Flags      string
+	Name         string
+	Protocol     string
+	Host         string
+	Username     string
+	Password     string
+	ServerCert   string
+	Flags        string
+	PasswordFile string
+	AutoConnect  bool
+	HealthCheck  string
+	// HealthInterval and HealthFailures are kept as typed; blank means the
+	// default.
+	HealthInterval string
+	HealthFailures string
+	// IncludeRoutes and ExcludeRoutes are comma-separated CIDRs.
+	IncludeRoutes  string
+	ExcludeRoutes  string
+	NoDefaultRoute bool
+	// DNSDomains and DNSServers are comma-separated too.
+	DNSDomains string
+	DNSServers string
+	KillSwitch bool
 }
 
 func NewConnectionFormData(conn *models.Connection) *ConnectionFormData {
@@ -33,28 +52,54 @@ func NewConnectionFormData(conn *models.Connection) *ConnectionFormData {
 		}
 	}
 	return &ConnectionFormData{
-		Name:       conn.Name,
-		Protocol:   conn.Protocol,
-		Host:       conn.Host,
-		Username:   conn.Username,
-		ServerCert: conn.ServerCert,
-		Flags:      conn.Flags,
+		Name:           conn.Name,
+		Protocol:       conn.Protocol,
+		Host:           conn.Host,
+		Username:       conn.Username,
+		ServerCert:     conn.ServerCert,
+		Flags:          conn.Flags,
+		PasswordFile:   conn.PasswordFile,
+		AutoConnect:    conn.AutoConnect,
+		HealthCheck:    conn.HealthCheck,
+		HealthInterval: optionalInt(conn.HealthInterval),
+		HealthFailures: optionalInt(conn.HealthFailures),
+		IncludeRoutes:  FormatList(conn.IncludeRoutes),
+		ExcludeRoutes:  FormatList(conn.ExcludeRoutes),
+		NoDefaultRoute: conn.NoDefaultRoute,
+		DNSDomains:     FormatList(conn.DNSDomains),
+		DNSServers:     FormatList(conn.DNSServers),
+		KillSwitch:     conn.KillSwitch,
 	}
 }
 
 func (d *ConnectionFormData) ToConnection(existing *models.Connection) *models.Connection {
 	passwordProvided := strings.TrimSpace(d.Password) != ""
 	conn := &models.Connection{
-		Name:        d.Name,
-		Protocol:    d.Protocol,
-		Host:        d.Host,
-		Username:    d.Username,
-		HasPassword: passwordProvided,
-		ServerCert:  d.ServerCert,
-		Flags:       d.Flags,
+		Name:           d.Name,
+		Protocol:       d.Protocol,
+		Host:           d.Host,
+		Username:       d.Username,
+		HasPassword:    passwordProvided,
+		ServerCert:     d.ServerCert,
+		Flags:          d.Flags,
+		PasswordFile:   normalizedValue(d.PasswordFile),
+		AutoConnect:    d.AutoConnect,
+		HealthCheck:    normalizedValue(d.HealthCheck),
+		NoDefaultRoute: d.NoDefaultRoute,
+		KillSwitch:     d.KillSwitch,
+	}
+	conn.IncludeRoutes, _ = ParseRoutes(d.IncludeRoutes)
+	conn.ExcludeRoutes, _ = ParseRoutes(d.ExcludeRoutes)
+	conn.DNSDomains, _ = ParseDNSDomains(d.DNSDomains)
+	conn.DNSServers, _ = ParseDNSServers(d.DNSServers)
+	if conn.HealthCheck != "" {
+		conn.HealthInterval, _ = strconv.Atoi(normalizedValue(d.HealthInterval))
+		conn.HealthFailures, _ = strconv.Atoi(normalizedValue(d.HealthFailures))
 	}
 	if existing != nil {
 		conn.ID = existing.ID
+		conn.Reconnect = existing.Reconnect
+		conn.Hooks = existing.Hooks
 		if !passwordProvided {
 			conn.HasPassword = existing.HasPassword
 		}
@@ -62,6 +107,51 @@ func (d *ConnectionFormData) ToConnection(existing *models.Connection) *models.C
 	return conn
 }
 
+func optionalInt(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// validateOptionalNumber accepts a blank value or a whole number from lo to
+// hi.
+func validateOptionalNumber(lo, hi int) func(string) error {
+	return func(s string) error {
+		if s = strings.TrimSpace(s); s == "" {
+			return nil
+		}
+		if n, err := strconv.Atoi(s); err != nil || n < lo || n > hi {
+			return fmt.Errorf("must be a whole number from %d to %d", lo, hi)
+		}
+		return nil
+	}
+}
+
+// validateOptionalCount accepts a blank value or a positive whole number.
+func validateOptionalCount(s string) error {
+	if s = strings.TrimSpace(s); s == "" {
+		return nil
+	}
+	if n, err := strconv.Atoi(s); err != nil || n < 1 {
+		return errors.New("must be a positive whole number")
+	}
+	return nil
+}
+
+func validateRoutes(s string) error {
+	_, err := ParseRoutes(s)
+	return err
+}
+
+func protocolOptions() []huh.Option[string] {
+	options := make([]huh.Option[string], 0, len(models.Protocols))
+	for _, p := range models.Protocols {
+		options = append(options, huh.NewOption(p.Label, p.Name))
+	}
+	return options
+}
+
 func NewConnectionForm(data *ConnectionFormData, width int, isEdit bool) *huh.Form {
 	title := "New Connection"
 	if isEdit {
@@ -82,15 +172,7 @@ func NewConnectionForm(data *ConnectionFormData, width int, isEdit bool) *huh.Fo
 
 			huh.NewSelect[string]().
 				Title("Protocol").
-				Options(
-					huh.NewOption("Fortinet", "fortinet"),
-					huh.NewOption("GlobalProtect", "gp"),
-					huh.NewOption("AnyConnect", "anyconnect"),
-					huh.NewOption("Juniper", "nc"),
-					huh.NewOption("Pulse", "pulse"),
-					huh.NewOption("F5", "f5"),
-					huh.NewOption("Array", "array"),
-				).
+				Options(protocolOptions()...).
 				Value(&data.Protocol),
 
 			huh.NewInput().
@@ -127,6 +209,99 @@ func NewConnectionForm(data *ConnectionFormData, width int, isEdit bool) *huh.Fo
 				Prompt("> ").
 				Value(&data.Flags).
 				Description("Additional openconnect flags"),
+
+			huh.NewInput().
+				Title("Password File").
+				Prompt("> ").
+				Value(&data.PasswordFile).
+				Description("Absolute path the daemon reads the password from (optional, mode 600)").
+				Validate(func(s string) error {
+					if s = strings.TrimSpace(s); s != "" && !filepath.IsAbs(s) {
+						return errors.New("must be an absolute path")
+					}
+					return nil
+				}),
+
+			huh.NewConfirm().
+				Title("Auto-connect").
+				Value(&data.AutoConnect).
+				Description("Connect when the daemon starts"),
+
+			huh.NewInput().
+				Title("Health Check").
+				Prompt("> ").
+				Value(&data.HealthCheck).
+				Description("tcp:host:port, icmp:host or dns:name@server inside the VPN (optional)").
+				Validate(func(s string) error {
+					if strings.TrimSpace(s) == "" {
+						return nil
+					}
+					_, err := ParseHealthCheck(s)
+					return err
+				}),
+		).Title(title).Description(" "),
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Health Interval").
+				Prompt("> ").
+				Value(&data.HealthInterval).
+				Placeholder(strconv.Itoa(models.DefaultHealthInterval)).
+				Description("Seconds between probes").
+				Validate(validateOptionalCount),
+
+			huh.NewInput().
+				Title("Health Failures").
+				Prompt("> ").
+				Value(&data.HealthFailures).
+				Placeholder(strconv.Itoa(models.DefaultHealthFailures)).
+				Description("Failed probes in a row before reconnecting").
+				Validate(validateOptionalCount),
+		).Title(title).Description(" ").
+			WithHideFunc(func() bool { return strings.TrimSpace(data.HealthCheck) == "" }),
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Include Routes").
+				Prompt("> ").
+				Value(&data.IncludeRoutes).
+				Description("CIDRs to send through the tunnel, comma-separated (optional)").
+				Validate(validateRoutes),
+
+			huh.NewInput().
+				Title("Exclude Routes").
+				Prompt("> ").
+				Value(&data.ExcludeRoutes).
+				Description("CIDRs to keep off the tunnel, comma-separated (optional)").
+				Validate(validateRoutes),
+
+			huh.NewConfirm().
+				Title("No Default Route").
+				Value(&data.NoDefaultRoute).
+				Description("Ignore the server's default route; only split routes use the tunnel"),
+
+			huh.NewInput().
+				Title("DNS Domains").
+				Prompt("> ").
+				Value(&data.DNSDomains).
+				Description("Only resolve these domains through the tunnel; ~domain to route without searching (optional)").
+				Validate(func(s string) error {
+					_, err := ParseDNSDomains(s)
+					return err
+				}),
+
+			huh.NewInput().
+				Title("DNS Servers").
+				Prompt("> ").
+				Value(&data.DNSServers).
+				Description("Servers for the DNS domains; blank uses the VPN's (optional)").
+				Validate(func(s string) error {
+					_, err := ParseDNSServers(s)
+					return err
+				}),
+
+			huh.NewConfirm().
+				Title("Kill Switch").
+				Value(&data.KillSwitch).
+				Description("Block traffic outside the tunnel while it is up or reconnecting"),
 		).Title(title).Description(" "),
 	).WithShowHelp(true).WithTheme(formTheme()).WithWidth(width)
 }
@@ -138,17 +313,48 @@ type SettingsFormData struct {
 	WifiInterface   string
 	NetInterface    string
 	TunnelInterface string
+
+	// The reconnect policy, kept as typed; blank means the default.
+	ReconnectAttempts string
+	ReconnectBackoff  string
+	ReconnectJitter   string
+	NetworkWait       string
+	ReconnectOn       []string
+
+	// Timeouts in seconds; blank means the default, -1 none.
+	ConnectTimeout   string
+	PromptTimeout    string
+	ReconnectTimeout string
 }
 
 func NewSettingsFormData(settings *models.Settings) *SettingsFormData {
-	return &SettingsFormData{
-		DNS:             settings.DNS,
-		Reconnect:       settings.Reconnect,
-		AutoCleanup:     settings.AutoCleanup,
-		WifiInterface:   settings.WifiInterface,
-		NetInterface:    settings.NetInterface,
-		TunnelInterface: settings.TunnelInterface,
+	policy := settings.ReconnectPolicy
+	data := &SettingsFormData{
+		DNS:              settings.DNS,
+		Reconnect:        settings.Reconnect,
+		AutoCleanup:      settings.AutoCleanup,
+		WifiInterface:    settings.WifiInterface,
+		NetInterface:     settings.NetInterface,
+		TunnelInterface:  settings.TunnelInterface,
+		ReconnectBackoff: FormatBackoff(policy.Backoff),
+		NetworkWait:      optionalInt(policy.NetworkWait),
+		ConnectTimeout:   optionalTimeout(settings.Timeouts.Connect),
+		PromptTimeout:    optionalTimeout(settings.Timeouts.Prompt),
+		ReconnectTimeout: optionalTimeout(settings.Timeouts.Reconnect),
+	}
+	switch {
+	case policy.MaxAttempts == models.UnlimitedAttempts:
+		data.ReconnectAttempts = "0"
+	case policy.MaxAttempts > 0:
+		data.ReconnectAttempts = strconv.Itoa(policy.MaxAttempts)
 	}
+	data.ReconnectJitter = optionalInt(int(math.Round(policy.Jitter * 100)))
+	for _, reason := range models.ReconnectTriggers {
+		if policy.ReconnectsOn(reason) {
+			data.ReconnectOn = append(data.ReconnectOn, reason)
+		}
+	}
+	return data
 }
 
 func (d *SettingsFormData) ToSettings() *models.Settings {
@@ -159,7 +365,49 @@ func (d *SettingsFormData) ToSettings() *models.Settings {
 		WifiInterface:   normalizedValue(d.WifiInterface),
 		NetInterface:    normalizedValue(d.NetInterface),
 		TunnelInterface: normalizedValue(d.TunnelInterface),
+		ReconnectPolicy: d.reconnectPolicy(),
+		Timeouts:        d.timeouts(),
+	}
+}
+
+// timeouts reads the timeout fields; the form validated them.
+func (d *SettingsFormData) timeouts() models.Timeouts {
+	var t models.Timeouts
+	t.Connect, _ = strconv.Atoi(normalizedValue(d.ConnectTimeout))
+	t.Prompt, _ = strconv.Atoi(normalizedValue(d.PromptTimeout))
+	t.Reconnect, _ = strconv.Atoi(normalizedValue(d.ReconnectTimeout))
+	return t
+}
+
+// optionalTimeout shows a timeout setting, blank for the default.
+func optionalTimeout(seconds int) string {
+	if seconds == models.NoTimeout {
+		return strconv.Itoa(seconds)
+	}
+	return optionalInt(seconds)
+}
+
+// reconnectPolicy reads the policy fields; the form validated them.
+func (d *SettingsFormData) reconnectPolicy() models.ReconnectPolicy {
+	var policy models.ReconnectPolicy
+	if n, err := strconv.Atoi(normalizedValue(d.ReconnectAttempts)); err == nil {
+		policy.MaxAttempts = n
+		if n == 0 {
+			policy.MaxAttempts = models.UnlimitedAttempts
+		}
 	}
+	policy.Backoff, _ = ParseBackoff(d.ReconnectBackoff)
+	if n, err := strconv.Atoi(normalizedValue(d.ReconnectJitter)); err == nil {
+		policy.Jitter = float64(n) / 100
+	}
+	policy.NetworkWait, _ = strconv.Atoi(normalizedValue(d.NetworkWait))
+	for _, reason := range models.ReconnectTriggers {
+		if !slices.Contains(d.ReconnectOn, reason) {
+			off := false
+			policy.SetReconnectsOn(reason, &off)
+		}
+	}
+	return policy
 }
 
 func normalizedValue(value string) string {
@@ -203,6 +451,78 @@ func NewSettingsForm(data *SettingsFormData, width int) *huh.Form {
 				Value(&data.TunnelInterface).
 				Description(fmt.Sprintf("Leave blank to auto-detect (common: %s)", models.DefaultTunnelInterface())),
 		).Title("Settings").Description(" "),
+		huh.NewGroup(
+			huh.NewMultiSelect[string]().
+				Title("Reconnect On").
+				Options(
+					huh.NewOption("Wake from sleep", models.EndWake),
+					huh.NewOption("openconnect exiting", models.EndExit),
+					huh.NewOption("Failed health checks", models.EndHealth),
+					huh.NewOption("Network change", models.EndNetworkChange),
+				).
+				Value(&data.ReconnectOn),
+
+			huh.NewInput().
+				Title("Max Attempts").
+				Prompt("> ").
+				Value(&data.ReconnectAttempts).
+				Placeholder(strconv.Itoa(models.DefaultReconnectAttempts)).
+				Description("Tries per reconnect, 0 for unlimited").
+				Validate(validateOptionalNumber(0, math.MaxInt32)),
+
+			huh.NewInput().
+				Title("Backoff").
+				Prompt("> ").
+				Value(&data.ReconnectBackoff).
+				Placeholder(FormatBackoff(models.DefaultReconnectBackoff)).
+				Description("Seconds to wait after each failed try; the last one repeats").
+				Validate(func(s string) error {
+					_, err := ParseBackoff(s)
+					return err
+				}),
+
+			huh.NewInput().
+				Title("Jitter").
+				Prompt("> ").
+				Value(&data.ReconnectJitter).
+				Placeholder("0").
+				Description("Spread each wait by up to this percent either way").
+				Validate(validateOptionalNumber(0, 100)),
+
+			huh.NewInput().
+				Title("Network Wait").
+				Prompt("> ").
+				Value(&data.NetworkWait).
+				Placeholder(strconv.Itoa(models.DefaultNetworkWait)).
+				Description("Seconds to wait for the VPN server to be reachable").
+				Validate(validateOptionalCount),
+		).Title("Reconnect Policy").Description(" ").
+			WithHideFunc(func() bool { return !data.Reconnect }),
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Connect Timeout").
+				Prompt("> ").
+				Value(&data.ConnectTimeout).
+				Placeholder(strconv.Itoa(models.DefaultConnectTimeout)).
+				Description("Seconds for the tunnel to come up, -1 to wait forever").
+				Validate(validateOptionalNumber(models.NoTimeout, math.MaxInt32)),
+
+			huh.NewInput().
+				Title("Prompt Timeout").
+				Prompt("> ").
+				Value(&data.PromptTimeout).
+				Placeholder(strconv.Itoa(models.DefaultPromptTimeout)).
+				Description("Seconds to answer a prompt, -1 to wait forever").
+				Validate(validateOptionalNumber(models.NoTimeout, math.MaxInt32)),
+
+			huh.NewInput().
+				Title("Reconnect Attempt Timeout").
+				Prompt("> ").
+				Value(&data.ReconnectTimeout).
+				Placeholder(strconv.Itoa(models.DefaultReconnectTimeout)).
+				Description("Seconds before a reconnect attempt counts as failed, -1 to wait forever").
+				Validate(validateOptionalNumber(models.NoTimeout, math.MaxInt32)),
+		).Title("Timeouts").Description(" "),
 	).WithShowHelp(true).WithTheme(formTheme()).WithWidth(width)
 }
 
@@ -246,3 +566,180 @@ func NewExportLogsForm(data *ExportFormData, width int) *huh.Form {
 		).Title("Export Logs").Description(" "),
 	).WithShowHelp(true).WithTheme(formTheme()).WithWidth(width)
 }
+
+type ImportFormData struct {
+	Source ImportSource
+	Path   string
+}
+
+func NewImportFormData() *ImportFormData {
+	return &ImportFormData{
+		Source: ImportNetworkManager,
+	}
+}
+
+func NewImportForm(data *ImportFormData, width int) *huh.Form {
+	sources := make([]huh.Option[ImportSource], 0, len(ImportSources))
+	for _, source := range ImportSources {
+		sources = append(sources, huh.NewOption(source.Label(), source))
+	}
+
+	return huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[ImportSource]().
+				Title("Source").
+				Options(sources...).
+				Value(&data.Source),
+			huh.NewInput().
+				Title("Path").
+				Prompt("> ").
+				Value(&data.Path).
+				PlaceholderFunc(func() string {
+					return data.Source.DefaultPath()
+				}, &data.Source).
+				DescriptionFunc(func() string {
+					switch data.Source {
+					case ImportNetworkManager:
+						return "Folder with vpn-type=openconnect keyfiles (usually needs sudo)"
+					case ImportAnyConnect:
+						return "Client profile .xml or a folder of profiles"
+					case ImportBundle:
+						return "Bundle .json file or a pasted lazyopenconnect:// URI"
+					default:
+						return "Portal configuration export (.xml)"
+					}
+				}, &data.Source).
+				Validate(func(s string) error {
+					if strings.TrimSpace(s) == "" && data.Source.DefaultPath() == "" {
+						return errRequired
+					}
+					return nil
+				}),
+		).Title("Import Connections").Description(" "),
+	).WithShowHelp(true).WithTheme(formTheme()).WithWidth(width)
+}
+
+type ImportSelectData struct {
+	Candidates []ImportCandidate
+	Skipped    []ImportSkipped
+	Selected   []int
+	Conflicts  []ImportConflict
+	OnConflict ConflictMode
+}
+
+func NewImportSelectData(source ImportSource, candidates []ImportCandidate, skipped []ImportSkipped, conflicts []ImportConflict) *ImportSelectData {
+	selected := make([]int, len(candidates))
+	for i := range candidates {
+		selected[i] = i
+	}
+	return &ImportSelectData{
+		Candidates: candidates,
+		Skipped:    skipped,
+		Selected:   selected,
+		Conflicts:  conflicts,
+		OnConflict: source.Options().OnConflict,
+	}
+}
+
+func (d *ImportSelectData) Options() ImportOptions {
+	return ImportOptions{OnConflict: d.OnConflict}
+}
+
+func (d *ImportSelectData) SelectedCandidates() []ImportCandidate {
+	selected := make([]ImportCandidate, 0, len(d.Selected))
+	for _, i := range d.Selected {
+		if i >= 0 && i < len(d.Candidates) {
+			selected = append(selected, d.Candidates[i])
+		}
+	}
+	return selected
+}
+
+func NewImportSelectForm(data *ImportSelectData, width int) *huh.Form {
+	options := make([]huh.Option[int], 0, len(data.Candidates))
+	for i, c := range data.Candidates {
+		label := fmt.Sprintf("%s (%s, %s)", c.Connection.Name, c.Connection.Protocol, c.Connection.Host)
+		options = append(options, huh.NewOption(label, i).Selected(true))
+	}
+
+	fields := []huh.Field{
+		huh.NewMultiSelect[int]().
+			Title("Connections").
+			Options(options...).
+			Value(&data.Selected),
+	}
+	if len(data.Conflicts) > 0 {
+		fields = append(fields, huh.NewSelect[ConflictMode]().
+			Title("Existing Connections").
+			Description(fmt.Sprintf("%d entries match a configured name or host", len(data.Conflicts))).
+			Options(
+				huh.NewOption("Skip", ConflictSkip),
+				huh.NewOption("Merge into existing", ConflictMerge),
+				huh.NewOption("Rename and add", ConflictRename),
+			).
+			Value(&data.OnConflict))
+	}
+
+	return huh.NewForm(
+		huh.NewGroup(fields...).Title("Import Connections").Description(" "),
+	).WithShowHelp(true).WithTheme(formTheme()).WithWidth(width)
+}
+
+type ExportBundleData struct {
+	Selected []string
+	AsURI    bool
+	Path     string
+}
+
+func NewExportBundleData(selectedID string) *ExportBundleData {
+	return &ExportBundleData{
+		Selected: []string{selectedID},
+		AsURI:    true,
+		Path:     DefaultBundlePath(),
+	}
+}
+
+func NewExportBundleForm(data *ExportBundleData, conns []models.Connection, width int) *huh.Form {
+	options := make([]huh.Option[string], 0, len(conns))
+	for _, conn := range conns {
+		label := fmt.Sprintf("%s (%s, %s)", conn.Name, conn.Protocol, conn.Host)
+		options = append(options, huh.NewOption(label, conn.ID))
+	}
+
+	return huh.NewForm(
+		huh.NewGroup(
+			huh.NewMultiSelect[string]().
+				Title("Connections").
+				Description("Passwords are never exported").
+				Options(options...).
+				Value(&data.Selected).
+				Validate(func(s []string) error {
+					if len(s) == 0 {
+						return errRequired
+					}
+					return nil
+				}),
+			huh.NewSelect[bool]().
+				Title("Format").
+				Options(
+					huh.NewOption("Copy lazyopenconnect:// URI to clipboard", true),
+					huh.NewOption("Save JSON file", false),
+				).
+				Value(&data.AsURI),
+		).Title("Share Connections").Description(" "),
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Export Path").
+				Prompt("> ").
+				Value(&data.Path).
+				Description("Path to save the bundle").
+				Validate(func(s string) error {
+					if strings.TrimSpace(s) == "" {
+						return errRequired
+					}
+					return nil
+				}),
+		).Title("Share Connections").Description(" ").
+			WithHideFunc(func() bool { return data.AsURI }),
+	).WithShowHelp(true).WithTheme(formTheme()).WithWidth(width)
+}
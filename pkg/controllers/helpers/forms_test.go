@@ -1,6 +1,7 @@
 package helpers
 
 import (
+	"slices"
 	"testing"
 
 	"github.com/Nybkox/lazyopenconnect/pkg/models"
@@ -75,3 +76,139 @@ func TestConnectionFormDataToConnectionKeepsExistingPassword(t *testing.T) {
 		t.Fatal("HasPassword should remain true when password input is blank")
 	}
 }
+
+func TestConnectionFormDataToConnectionHealthCheck(t *testing.T) {
+	data := &ConnectionFormData{
+		Name:           "Work",
+		Protocol:       "anyconnect",
+		Host:           "vpn.example.com",
+		HealthCheck:    " tcp:10.0.0.1:22 ",
+		HealthInterval: " 15 ",
+	}
+
+	conn := data.ToConnection(nil)
+
+	if conn.HealthCheck != "tcp:10.0.0.1:22" {
+		t.Fatalf("HealthCheck = %q, want %q", conn.HealthCheck, "tcp:10.0.0.1:22")
+	}
+	if conn.HealthInterval != 15 || conn.HealthFailures != 0 {
+		t.Fatalf("HealthInterval/HealthFailures = %d/%d, want 15/0", conn.HealthInterval, conn.HealthFailures)
+	}
+
+	data.HealthCheck = ""
+	if conn := data.ToConnection(nil); conn.HealthInterval != 0 {
+		t.Fatalf("HealthInterval = %d without a health check, want 0", conn.HealthInterval)
+	}
+}
+
+func TestValidateOptionalCount(t *testing.T) {
+	for _, s := range []string{"", "  ", "1", "30"} {
+		if err := validateOptionalCount(s); err != nil {
+			t.Fatalf("validateOptionalCount(%q) returned error: %v", s, err)
+		}
+	}
+	for _, s := range []string{"0", "-3", "1.5", "often"} {
+		if err := validateOptionalCount(s); err == nil {
+			t.Fatalf("validateOptionalCount(%q) succeeded", s)
+		}
+	}
+}
+
+func TestSettingsFormDataReconnectPolicy(t *testing.T) {
+	data := &SettingsFormData{
+		Reconnect:         true,
+		ReconnectAttempts: "0",
+		ReconnectBackoff:  " 1, 5 ",
+		ReconnectJitter:   "25",
+		ReconnectOn:       []string{models.EndExit, models.EndHealth, models.EndNetworkChange},
+	}
+
+	policy := data.ToSettings().ReconnectPolicy
+
+	if policy.MaxAttempts != models.UnlimitedAttempts {
+		t.Fatalf("MaxAttempts = %d, want unlimited", policy.MaxAttempts)
+	}
+	if len(policy.Backoff) != 2 || policy.Backoff[0] != 1 || policy.Backoff[1] != 5 {
+		t.Fatalf("Backoff = %v, want [1 5]", policy.Backoff)
+	}
+	if policy.Jitter != 0.25 || policy.NetworkWait != 0 {
+		t.Fatalf("Jitter/NetworkWait = %v/%d, want 0.25/0", policy.Jitter, policy.NetworkWait)
+	}
+	if policy.OnWake == nil || *policy.OnWake || policy.OnExit != nil || policy.OnHealth != nil || policy.OnNetworkChange != nil {
+		t.Fatalf("triggers = %v/%v/%v, want only wake turned off", policy.OnWake, policy.OnExit, policy.OnHealth)
+	}
+
+	back := NewSettingsFormData(&models.Settings{ReconnectPolicy: policy})
+	if back.ReconnectAttempts != "0" || back.ReconnectBackoff != "1 5" || back.ReconnectJitter != "25" {
+		t.Fatalf("form data = %+v, want the values typed", back)
+	}
+	if len(back.ReconnectOn) != 3 {
+		t.Fatalf("ReconnectOn = %v, want all but wake", back.ReconnectOn)
+	}
+}
+
+func TestSettingsFormDataDefaultPolicyIsZero(t *testing.T) {
+	data := NewSettingsFormData(&models.Settings{})
+
+	policy := data.ToSettings().ReconnectPolicy
+
+	if policy.MaxAttempts != 0 || policy.Backoff != nil || policy.OnWake != nil || policy.OnExit != nil || policy.OnHealth != nil || policy.OnNetworkChange != nil {
+		t.Fatalf("policy = %+v, want the zero policy", policy)
+	}
+}
+
+func TestSettingsFormDataTimeouts(t *testing.T) {
+	data := &SettingsFormData{ConnectTimeout: " 45 ", PromptTimeout: "-1"}
+
+	timeouts := data.ToSettings().Timeouts
+
+	if timeouts.Connect != 45 || timeouts.Prompt != models.NoTimeout || timeouts.Reconnect != 0 {
+		t.Fatalf("Timeouts = %+v, want 45/none/default", timeouts)
+	}
+
+	back := NewSettingsFormData(&models.Settings{Timeouts: timeouts})
+	if back.ConnectTimeout != "45" || back.PromptTimeout != "-1" || back.ReconnectTimeout != "" {
+		t.Fatalf("form data = %q/%q/%q, want the values typed", back.ConnectTimeout, back.PromptTimeout, back.ReconnectTimeout)
+	}
+}
+
+func TestParseBackoff(t *testing.T) {
+	got, err := ParseBackoff("2, 5 10")
+	if err != nil {
+		t.Fatalf("ParseBackoff returned error: %v", err)
+	}
+	if FormatBackoff(got) != "2 5 10" {
+		t.Fatalf("ParseBackoff = %v, want [2 5 10]", got)
+	}
+	if got, err := ParseBackoff("  "); err != nil || got != nil {
+		t.Fatalf("ParseBackoff(blank) = %v, %v; want nil, nil", got, err)
+	}
+	for _, s := range []string{"2 soon", "-1", "1.5"} {
+		if _, err := ParseBackoff(s); err == nil {
+			t.Fatalf("ParseBackoff(%q) succeeded", s)
+		}
+	}
+}
+
+func TestConnectionFormDataSplitTunnel(t *testing.T) {
+	existing := &models.Connection{
+		ID:             "conn-1",
+		IncludeRoutes:  []string{"10.0.0.0/8"},
+		ExcludeRoutes:  []string{"192.168.1.0/24"},
+		NoDefaultRoute: true,
+	}
+	data := NewConnectionFormData(existing)
+	if data.IncludeRoutes != "10.0.0.0/8" || data.ExcludeRoutes != "192.168.1.0/24" || !data.NoDefaultRoute {
+		t.Fatalf("form data = %+v, want the connection's split routes", data)
+	}
+
+	data.IncludeRoutes = "10.0.0.0/8, 172.16.5.1/12"
+	data.ExcludeRoutes = " "
+	conn := data.ToConnection(existing)
+	if !slices.Equal(conn.IncludeRoutes, []string{"10.0.0.0/8", "172.16.0.0/12"}) {
+		t.Fatalf("IncludeRoutes = %q", conn.IncludeRoutes)
+	}
+	if conn.ExcludeRoutes != nil || !conn.NoDefaultRoute {
+		t.Fatalf("ExcludeRoutes = %q, NoDefaultRoute = %v", conn.ExcludeRoutes, conn.NoDefaultRoute)
+	}
+}
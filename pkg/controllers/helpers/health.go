@@ -0,0 +1,137 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Health probe kinds.
+const (
+	HealthTCP  = "tcp"
+	HealthICMP = "icmp"
+	HealthDNS  = "dns"
+)
+
+var pingTimePattern = regexp.MustCompile(`time[=<]([0-9.]+) ?ms`)
+
+// HealthProbe checks that a target behind the tunnel answers.
+type HealthProbe struct {
+	Kind string
+	// Target is host:port for tcp, a host for icmp and the name to resolve
+	// for dns.
+	Target string
+	// Server is the host:port of the DNS server asked by dns probes; empty
+	// uses the system resolver.
+	Server string
+}
+
+// ParseHealthCheck parses a connection's health check: "tcp:host:port",
+// "icmp:host" or "dns:name[@server]".
+func ParseHealthCheck(spec string) (HealthProbe, error) {
+	kind, rest, ok := strings.Cut(strings.TrimSpace(spec), ":")
+	if !ok || rest == "" || strings.ContainsAny(rest, " \t") {
+		return HealthProbe{}, errors.New("use tcp:host:port, icmp:host or dns:name@server")
+	}
+
+	switch kind = strings.ToLower(kind); kind {
+	case HealthTCP:
+		host, port, err := net.SplitHostPort(rest)
+		if err != nil || host == "" || port == "" {
+			return HealthProbe{}, errors.New("tcp check needs host:port")
+		}
+		return HealthProbe{Kind: kind, Target: rest}, nil
+	case HealthICMP:
+		return HealthProbe{Kind: kind, Target: rest}, nil
+	case HealthDNS:
+		name, server, _ := strings.Cut(rest, "@")
+		if name == "" {
+			return HealthProbe{}, errors.New("dns check needs a name to resolve")
+		}
+		if server != "" {
+			if _, _, err := net.SplitHostPort(server); err != nil {
+				server = net.JoinHostPort(strings.Trim(server, "[]"), "53")
+			}
+		}
+		return HealthProbe{Kind: kind, Target: name, Server: server}, nil
+	}
+	return HealthProbe{}, fmt.Errorf("unknown check %q (use tcp, icmp or dns)", kind)
+}
+
+// Run probes the target once and returns the round-trip time.
+func (p HealthProbe) Run(timeout time.Duration) (time.Duration, error) {
+	switch p.Kind {
+	case HealthTCP:
+		start := time.Now()
+		conn, err := net.DialTimeout("tcp", p.Target, timeout)
+		if err != nil {
+			return 0, err
+		}
+		rtt := time.Since(start)
+		conn.Close()
+		return rtt, nil
+	case HealthICMP:
+		return ping(p.Target, timeout)
+	case HealthDNS:
+		return p.resolve(timeout)
+	}
+	return 0, fmt.Errorf("unknown check %q", p.Kind)
+}
+
+func (p HealthProbe) resolve(timeout time.Duration) (time.Duration, error) {
+	resolver := net.DefaultResolver
+	if p.Server != "" {
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, p.Server)
+			},
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := resolver.LookupHost(ctx, p.Target); err != nil {
+		return 0, err
+	}
+	return time.Since(start), nil
+}
+
+func ping(host string, timeout time.Duration) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout+time.Second)
+	defer cancel()
+
+	start := time.Now()
+	out, err := exec.CommandContext(ctx, "ping", pingArgs(host, timeout)...).CombinedOutput()
+	elapsed := time.Since(start)
+	if err != nil {
+		if ctx.Err() != nil {
+			return 0, fmt.Errorf("ping %s: timed out", host)
+		}
+		return 0, fmt.Errorf("ping %s: no reply", host)
+	}
+	return parsePingRTT(string(out), elapsed), nil
+}
+
+// parsePingRTT reads the reply time from ping output, falling back to
+// how long ping ran.
+func parsePingRTT(out string, fallback time.Duration) time.Duration {
+	m := pingTimePattern.FindStringSubmatch(out)
+	if m == nil {
+		return fallback
+	}
+	ms, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return fallback
+	}
+	return time.Duration(ms * float64(time.Millisecond))
+}
@@ -0,0 +1,13 @@
+//go:build darwin
+
+package helpers
+
+import (
+	"strconv"
+	"time"
+)
+
+func pingArgs(host string, timeout time.Duration) []string {
+	secs := max(int(timeout.Seconds()), 1)
+	return []string{"-n", "-c", "1", "-t", strconv.Itoa(secs), host}
+}
@@ -0,0 +1,70 @@
+package helpers
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestParseHealthCheck(t *testing.T) {
+	tests := []struct {
+		spec string
+		want HealthProbe
+	}{
+		{"tcp:10.0.0.1:22", HealthProbe{Kind: HealthTCP, Target: "10.0.0.1:22"}},
+		{"TCP:[fd00::1]:443", HealthProbe{Kind: HealthTCP, Target: "[fd00::1]:443"}},
+		{"icmp:10.0.0.1", HealthProbe{Kind: HealthICMP, Target: "10.0.0.1"}},
+		{"dns:intranet.corp", HealthProbe{Kind: HealthDNS, Target: "intranet.corp"}},
+		{"dns:intranet.corp@10.0.0.53", HealthProbe{Kind: HealthDNS, Target: "intranet.corp", Server: "10.0.0.53:53"}},
+		{"dns:intranet.corp@10.0.0.53:5353", HealthProbe{Kind: HealthDNS, Target: "intranet.corp", Server: "10.0.0.53:5353"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseHealthCheck(tt.spec)
+		if err != nil {
+			t.Fatalf("ParseHealthCheck(%q) returned error: %v", tt.spec, err)
+		}
+		if got != tt.want {
+			t.Fatalf("ParseHealthCheck(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestParseHealthCheckRejectsInvalid(t *testing.T) {
+	for _, spec := range []string{"", "10.0.0.1", "tcp:10.0.0.1", "icmp:", "dns:@10.0.0.53", "http:intranet", "icmp:a b"} {
+		if _, err := ParseHealthCheck(spec); err == nil {
+			t.Fatalf("ParseHealthCheck(%q) succeeded", spec)
+		}
+	}
+}
+
+func TestHealthProbeTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen returned error: %v", err)
+	}
+	addr := ln.Addr().String()
+
+	probe := HealthProbe{Kind: HealthTCP, Target: addr}
+	if _, err := probe.Run(time.Second); err != nil {
+		t.Fatalf("Run returned error with a listener: %v", err)
+	}
+
+	ln.Close()
+	if _, err := probe.Run(time.Second); err == nil {
+		t.Fatal("Run succeeded after the listener closed")
+	}
+}
+
+func TestParsePingRTT(t *testing.T) {
+	linux := "64 bytes from 10.0.0.1: icmp_seq=1 ttl=63 time=23.4 ms\n"
+	if got := parsePingRTT(linux, time.Second); got != 23400*time.Microsecond {
+		t.Fatalf("parsePingRTT(linux) = %v", got)
+	}
+	darwin := "64 bytes from 10.0.0.1: icmp_seq=0 ttl=63 time=7.125 ms\n"
+	if got := parsePingRTT(darwin, time.Second); got != 7125*time.Microsecond {
+		t.Fatalf("parsePingRTT(darwin) = %v", got)
+	}
+	if got := parsePingRTT("no reply", time.Second); got != time.Second {
+		t.Fatalf("parsePingRTT without a time = %v, want fallback", got)
+	}
+}
@@ -0,0 +1,166 @@
+package helpers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+)
+
+const (
+	historyFile = "history.jsonl"
+	// maxHistorySize triggers trimming the journal down to its newest
+	// keepHistoryRecords records.
+	maxHistorySize     = 1 << 20
+	keepHistoryRecords = 2000
+)
+
+var historyMu sync.Mutex
+
+// HistoryPath returns the session history journal, one JSON record per line.
+func HistoryPath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, historyFile), nil
+}
+
+// AppendHistory adds rec to the journal at path.
+func AppendHistory(path string, rec models.HistoryRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	info, statErr := f.Stat()
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	// The daemon runs as root; the user must still be able to read it.
+	chownToSudoUser(path)
+
+	if statErr == nil && info.Size() > maxHistorySize {
+		return trimHistory(path)
+	}
+	return nil
+}
+
+// trimHistory keeps the newest records. Called with historyMu held.
+func trimHistory(path string) error {
+	records, err := readHistory(path)
+	if err != nil {
+		return err
+	}
+	if len(records) > keepHistoryRecords {
+		records = records[len(records)-keepHistoryRecords:]
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, rec := range records {
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	chownToSudoUser(tmp)
+	return os.Rename(tmp, path)
+}
+
+// LoadHistory returns the journal at path, oldest first. A missing journal
+// is empty; lines that do not parse are skipped.
+func LoadHistory(path string) ([]models.HistoryRecord, error) {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	return readHistory(path)
+}
+
+func readHistory(path string) ([]models.HistoryRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []models.HistoryRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var rec models.HistoryRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil || rec.ConnID == "" {
+			continue
+		}
+		records = append(records, rec)
+	}
+	return records, scanner.Err()
+}
+
+// HistorySummary totals the history of one connection.
+type HistorySummary struct {
+	ConnID   string
+	Name     string
+	Sessions int
+	// Drops counts tunnels that were up and went down unasked.
+	Drops int
+	// Failed counts runs where the tunnel never came up.
+	Failed   int
+	Uptime   time.Duration
+	LastDrop time.Time
+}
+
+// SummarizeHistory totals records per connection, in order of first
+// appearance.
+func SummarizeHistory(records []models.HistoryRecord) []HistorySummary {
+	var summaries []HistorySummary
+	index := make(map[string]int)
+	for _, rec := range records {
+		i, ok := index[rec.ConnID]
+		if !ok {
+			i = len(summaries)
+			index[rec.ConnID] = i
+			summaries = append(summaries, HistorySummary{ConnID: rec.ConnID})
+		}
+
+		sum := &summaries[i]
+		if rec.Name != "" {
+			sum.Name = rec.Name
+		}
+		sum.Sessions++
+		sum.Uptime += rec.Uptime()
+		if !rec.Connected() {
+			sum.Failed++
+		}
+		if rec.Dropped() {
+			sum.Drops++
+			if rec.EndedAt.After(sum.LastDrop) {
+				sum.LastDrop = rec.EndedAt
+			}
+		}
+	}
+	return summaries
+}
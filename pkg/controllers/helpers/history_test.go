@@ -0,0 +1,95 @@
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+)
+
+func TestAppendAndLoadHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	first := models.HistoryRecord{ConnID: "berlin", StartedAt: start, ConnectedAt: start.Add(5 * time.Second), EndedAt: start.Add(time.Hour), EndReason: models.EndExit}
+	second := models.HistoryRecord{ConnID: "work", StartedAt: start, EndedAt: start.Add(30 * time.Second), EndReason: models.EndTimeout}
+	for _, rec := range []models.HistoryRecord{first, second} {
+		if err := AppendHistory(path, rec); err != nil {
+			t.Fatalf("AppendHistory returned error: %v", err)
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile returned error: %v", err)
+	}
+	_, _ = f.WriteString("not json\n")
+	f.Close()
+
+	records, err := LoadHistory(path)
+	if err != nil {
+		t.Fatalf("LoadHistory returned error: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("records = %d, want 2", len(records))
+	}
+	if records[0].ConnID != "berlin" || records[1].EndReason != models.EndTimeout {
+		t.Fatalf("records = %+v", records)
+	}
+	if got := records[0].Uptime(); got != time.Hour-5*time.Second {
+		t.Fatalf("Uptime = %v, want %v", got, time.Hour-5*time.Second)
+	}
+	if records[1].Connected() {
+		t.Fatal("record without connectedAt reports connected")
+	}
+}
+
+func TestLoadHistoryMissingFile(t *testing.T) {
+	records, err := LoadHistory(filepath.Join(t.TempDir(), "history.jsonl"))
+	if err != nil || records != nil {
+		t.Fatalf("LoadHistory = %v, %v, want nil, nil", records, err)
+	}
+}
+
+func TestSummarizeHistory(t *testing.T) {
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	up := func(connID, reason string, hours int) models.HistoryRecord {
+		return models.HistoryRecord{
+			ConnID:      connID,
+			Name:        connID,
+			StartedAt:   start,
+			ConnectedAt: start,
+			EndedAt:     start.Add(time.Duration(hours) * time.Hour),
+			EndReason:   reason,
+		}
+	}
+
+	records := []models.HistoryRecord{
+		up("berlin", models.EndExit, 1),
+		up("work", models.EndUserDisconnect, 8),
+		up("berlin", models.EndWake, 2),
+		{ConnID: "berlin", StartedAt: start, EndedAt: start, EndReason: models.EndReconnectFailed},
+		up("berlin", models.EndUserDisconnect, 3),
+	}
+
+	summaries := SummarizeHistory(records)
+	if len(summaries) != 2 {
+		t.Fatalf("summaries = %d, want 2", len(summaries))
+	}
+
+	berlin := summaries[0]
+	if berlin.ConnID != "berlin" || berlin.Sessions != 4 || berlin.Drops != 2 || berlin.Failed != 1 {
+		t.Fatalf("berlin = %+v, want 4 sessions, 2 drops, 1 failed", berlin)
+	}
+	if berlin.Uptime != 6*time.Hour {
+		t.Fatalf("berlin uptime = %v, want 6h", berlin.Uptime)
+	}
+	if !berlin.LastDrop.Equal(start.Add(2 * time.Hour)) {
+		t.Fatalf("berlin last drop = %v", berlin.LastDrop)
+	}
+	if summaries[1].Drops != 0 {
+		t.Fatalf("work drops = %d, want 0", summaries[1].Drops)
+	}
+}
@@ -0,0 +1,98 @@
+package helpers
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// ErrHookTimeout is returned by RunHook when the command overran its
+// timeout and was killed.
+var ErrHookTimeout = errors.New("hook timed out")
+
+// RunHook runs command with sh, adding env to the daemon's environment,
+// and passes each line it prints to output. A command still running after
+// timeout is killed along with everything it started.
+func RunHook(command string, env []string, timeout time.Duration, output func(string)) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", command)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	// Background children may keep the pipe open after the hook exited.
+	cmd.WaitDelay = time.Second
+
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scanner := bufio.NewScanner(pr)
+		for scanner.Scan() {
+			output(scanner.Text())
+		}
+		_, _ = io.Copy(io.Discard, pr)
+	}()
+
+	err := cmd.Run()
+	pw.Close()
+	<-done
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("%w after %s", ErrHookTimeout, timeout)
+	}
+	return err
+}
+
+// HookContext describes the tunnel a hook runs for.
+type HookContext struct {
+	Event    string
+	Reason   string
+	ConnID   string
+	ConnName string
+	Host     string
+	// Server is the VPN server's address as openconnect resolved it.
+	Server string
+	IP     string
+	Tunnel string
+	// DefaultGateway and DefaultInterface are the physical default route,
+	// not the VPN server.
+	DefaultGateway   string
+	DefaultInterface string
+}
+
+// Env returns the hook's environment variables; empty values are left
+// out.
+func (h HookContext) Env() []string {
+	vars := []struct{ name, value string }{
+		{"LAZYOPENCONNECT_EVENT", h.Event},
+		{"LAZYOPENCONNECT_REASON", h.Reason},
+		{"LAZYOPENCONNECT_CONN_ID", h.ConnID},
+		{"LAZYOPENCONNECT_CONN_NAME", h.ConnName},
+		{"LAZYOPENCONNECT_HOST", h.Host},
+		{"LAZYOPENCONNECT_SERVER", h.Server},
+		{"LAZYOPENCONNECT_IP", h.IP},
+		{"LAZYOPENCONNECT_TUNNEL", h.Tunnel},
+		{"LAZYOPENCONNECT_DEFAULT_GATEWAY", h.DefaultGateway},
+		{"LAZYOPENCONNECT_DEFAULT_INTERFACE", h.DefaultInterface},
+	}
+	env := make([]string, 0, len(vars))
+	for _, v := range vars {
+		if v.value != "" {
+			env = append(env, v.name+"="+v.value)
+		}
+	}
+	return env
+}
@@ -0,0 +1,51 @@
+package helpers
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRunHookStreamsOutput(t *testing.T) {
+	var lines []string
+	err := RunHook(`echo "up $LAZYOPENCONNECT_TUNNEL"; echo oops >&2`, []string{"LAZYOPENCONNECT_TUNNEL=tun1"}, 5*time.Second, func(line string) {
+		lines = append(lines, line)
+	})
+	if err != nil {
+		t.Fatalf("RunHook returned error: %v", err)
+	}
+	if !slices.Equal(lines, []string{"up tun1", "oops"}) {
+		t.Fatalf("output = %q, want [up tun1 oops]", lines)
+	}
+}
+
+func TestRunHookReportsFailure(t *testing.T) {
+	err := RunHook("exit 3", nil, 5*time.Second, func(string) {})
+	if err == nil || errors.Is(err, ErrHookTimeout) {
+		t.Fatalf("RunHook = %v, want the exit status", err)
+	}
+}
+
+func TestRunHookKillsOverrunningHook(t *testing.T) {
+	start := time.Now()
+	err := RunHook("sleep 5 & sleep 5", nil, 200*time.Millisecond, func(string) {})
+	if !errors.Is(err, ErrHookTimeout) {
+		t.Fatalf("RunHook = %v, want ErrHookTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Fatalf("RunHook returned after %v, want the hook killed", elapsed)
+	}
+}
+
+func TestHookContextEnvSkipsEmpty(t *testing.T) {
+	env := HookContext{Event: "post_connect", ConnID: "work", IP: "10.0.0.2"}.Env()
+	want := []string{
+		"LAZYOPENCONNECT_EVENT=post_connect",
+		"LAZYOPENCONNECT_CONN_ID=work",
+		"LAZYOPENCONNECT_IP=10.0.0.2",
+	}
+	if !slices.Equal(env, want) {
+		t.Fatalf("Env = %q, want %q", env, want)
+	}
+}
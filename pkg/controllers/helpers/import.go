@@ -0,0 +1,301 @@
+package helpers
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+)
+
+// ImportCandidate is a connection read from another VPN client's config,
+// before it has been added to ours.
+type ImportCandidate struct {
+	Connection models.Connection
+	Password   string
+	Source     string
+	Notes      []string
+}
+
+type ImportSkipped struct {
+	Name   string
+	Reason string
+}
+
+type ImportReport struct {
+	Added    []models.Connection
+	Updated  []models.Connection
+	Skipped  []ImportSkipped
+	Warnings []string
+
+	passwords map[string]string
+}
+
+type ImportSource string
+
+const (
+	ImportNetworkManager ImportSource = "nm"
+	ImportAnyConnect     ImportSource = "anyconnect"
+	ImportGlobalProtect  ImportSource = "globalprotect"
+	ImportBundle         ImportSource = "bundle"
+)
+
+var ImportSources = []ImportSource{ImportNetworkManager, ImportAnyConnect, ImportGlobalProtect, ImportBundle}
+
+func (s ImportSource) Label() string {
+	switch s {
+	case ImportNetworkManager:
+		return "NetworkManager"
+	case ImportAnyConnect:
+		return "AnyConnect profile"
+	case ImportGlobalProtect:
+		return "GlobalProtect portal config"
+	case ImportBundle:
+		return "connection bundle"
+	default:
+		return string(s)
+	}
+}
+
+// Options returns how candidates from this source are merged by default.
+// Client profiles are redistributed by IT, so re-importing them refreshes
+// the connections they created earlier. Bundles leave the choice to the
+// user.
+func (s ImportSource) Options() ImportOptions {
+	switch s {
+	case ImportAnyConnect, ImportGlobalProtect:
+		return ImportOptions{OnConflict: ConflictMerge}
+	default:
+		return ImportOptions{OnConflict: ConflictSkip}
+	}
+}
+
+// DefaultPath returns where the source is read from when no path is given,
+// or "" when a path is required.
+func (s ImportSource) DefaultPath() string {
+	switch s {
+	case ImportNetworkManager:
+		return DefaultNMConnectionsDir
+	case ImportAnyConnect:
+		return DefaultAnyConnectProfileDir
+	default:
+		return ""
+	}
+}
+
+// ReadImportCandidates reads candidates from path, falling back to
+// source.DefaultPath. For NetworkManager path is a keyfile directory; XML
+// sources accept a file or a directory of .xml files; bundles accept a file
+// or a lazyopenconnect:// URI.
+func ReadImportCandidates(source ImportSource, path string) ([]ImportCandidate, []ImportSkipped, error) {
+	if path == "" {
+		path = source.DefaultPath()
+	}
+	if path == "" {
+		return nil, nil, fmt.Errorf("a path to the %s is required", source.Label())
+	}
+
+	switch source {
+	case ImportNetworkManager:
+		return ReadNMConnections(path)
+	case ImportAnyConnect:
+		return readXMLImports(path, ParseAnyConnectProfile)
+	case ImportGlobalProtect:
+		return readXMLImports(path, ParseGlobalProtectConfig)
+	case ImportBundle:
+		return ReadBundle(path)
+	default:
+		return nil, nil, fmt.Errorf("unknown import source %q", source)
+	}
+}
+
+// ConflictMode decides what happens to an imported connection whose host or
+// name is already configured.
+type ConflictMode string
+
+const (
+	ConflictSkip   ConflictMode = "skip"
+	ConflictMerge  ConflictMode = "merge"
+	ConflictRename ConflictMode = "rename"
+)
+
+var ConflictModes = []ConflictMode{ConflictSkip, ConflictMerge, ConflictRename}
+
+func ParseConflictMode(s string) (ConflictMode, error) {
+	for _, mode := range ConflictModes {
+		if string(mode) == s {
+			return mode, nil
+		}
+	}
+	return "", fmt.Errorf("unknown conflict mode %q (use skip, merge or rename)", s)
+}
+
+type ImportOptions struct {
+	// OnConflict applies to candidates matching a configured connection by
+	// host, or by name for merge and rename. Skip only matches by host
+	// since the added copy is renamed anyway.
+	OnConflict ConflictMode
+}
+
+// ImportConflict pairs a candidate with the configured connection it
+// collides with.
+type ImportConflict struct {
+	Candidate ImportCandidate
+	Existing  models.Connection
+}
+
+// FindImportConflicts returns the candidates that match a configured
+// connection by host or name.
+func FindImportConflicts(cfg *models.Config, candidates []ImportCandidate) []ImportConflict {
+	var conflicts []ImportConflict
+	for _, c := range candidates {
+		if idx := findConflict(cfg, c.Connection, ConflictMerge); idx >= 0 {
+			conflicts = append(conflicts, ImportConflict{Candidate: c, Existing: cfg.Connections[idx]})
+		}
+	}
+	return conflicts
+}
+
+// ApplyImport appends candidates to cfg with fresh IDs. Candidates that
+// collide with a configured connection (or one added earlier in the batch)
+// are handled per opts.OnConflict. Passwords are not stored; call
+// SaveImportedPasswords afterwards.
+func ApplyImport(cfg *models.Config, candidates []ImportCandidate, opts ImportOptions) ImportReport {
+	report := ImportReport{passwords: make(map[string]string)}
+
+	for _, c := range candidates {
+		conn := c.Connection
+
+		// Renamed conflicts fall through and are added under a unique name.
+		if idx := findConflict(cfg, conn, opts.OnConflict); idx >= 0 && opts.OnConflict != ConflictRename {
+			existing := &cfg.Connections[idx]
+			switch {
+			case opts.OnConflict != ConflictMerge:
+				report.Skipped = append(report.Skipped, ImportSkipped{
+					Name:   conn.Name,
+					Reason: fmt.Sprintf("host %s already configured as %q", conn.Host, existing.Name),
+				})
+			case mergeImportedConnection(existing, conn):
+				report.Updated = append(report.Updated, *existing)
+			default:
+				report.Skipped = append(report.Skipped, ImportSkipped{
+					Name:   conn.Name,
+					Reason: fmt.Sprintf("unchanged (%q)", existing.Name),
+				})
+			}
+			continue
+		}
+
+		conn.ID = uuid.New().String()
+		conn.Name = uniqueConnectionName(cfg, conn.Name)
+		conn.HasPassword = c.Password != ""
+		if conn.HasPassword {
+			report.passwords[conn.ID] = c.Password
+		}
+
+		cfg.Connections = append(cfg.Connections, conn)
+		report.Added = append(report.Added, conn)
+
+		for _, note := range c.Notes {
+			report.Warnings = append(report.Warnings, conn.Name+": "+note)
+		}
+	}
+
+	return report
+}
+
+// mergeImportedConnection copies the imported fields into existing, keeping
+// its ID, name and password. It reports whether anything changed.
+func mergeImportedConnection(existing *models.Connection, imported models.Connection) bool {
+	before := *existing
+	existing.Host = imported.Host
+	existing.Protocol = imported.Protocol
+	if imported.Username != "" {
+		existing.Username = imported.Username
+	}
+	if imported.ServerCert != "" {
+		existing.ServerCert = imported.ServerCert
+	}
+	if imported.Flags != "" {
+		existing.Flags = imported.Flags
+	}
+	return !reflect.DeepEqual(*existing, before)
+}
+
+func findConnectionByHost(cfg *models.Config, host string) int {
+	key := normalizeHost(host)
+	for i, conn := range cfg.Connections {
+		if normalizeHost(conn.Host) == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func findConnectionByName(cfg *models.Config, name string) int {
+	for i, conn := range cfg.Connections {
+		if strings.EqualFold(conn.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+func findConflict(cfg *models.Config, conn models.Connection, mode ConflictMode) int {
+	idx := findConnectionByHost(cfg, conn.Host)
+	if idx < 0 && mode != ConflictSkip {
+		idx = findConnectionByName(cfg, conn.Name)
+	}
+	return idx
+}
+
+// SaveImportedPasswords stores the passwords of newly added connections in
+// the keychain. Connections whose password could not be stored are marked
+// as having none so the daemon prompts instead.
+func SaveImportedPasswords(cfg *models.Config, report *ImportReport) {
+	for i := range report.Added {
+		added := &report.Added[i]
+		password, ok := report.passwords[added.ID]
+		if !ok {
+			continue
+		}
+		if err := SetPassword(added.ID, password); err != nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("%s: failed to save password to keychain: %v", added.Name, err))
+			added.HasPassword = false
+			for j := range cfg.Connections {
+				if cfg.Connections[j].ID == added.ID {
+					cfg.Connections[j].HasPassword = false
+				}
+			}
+		}
+	}
+}
+
+func normalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	host = strings.TrimPrefix(host, "https://")
+	return strings.TrimRight(host, "/")
+}
+
+func uniqueConnectionName(cfg *models.Config, name string) string {
+	taken := func(candidate string) bool {
+		for _, conn := range cfg.Connections {
+			if strings.EqualFold(conn.Name, candidate) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !taken(name) {
+		return name
+	}
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s (%d)", name, i)
+		if !taken(candidate) {
+			return candidate
+		}
+	}
+}
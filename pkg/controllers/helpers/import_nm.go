@@ -0,0 +1,190 @@
+package helpers
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+)
+
+const DefaultNMConnectionsDir = "/etc/NetworkManager/system-connections"
+
+var errNotOpenconnect = errors.New("not an openconnect VPN profile")
+
+// nmFlagOptions maps NetworkManager-openconnect vpn data keys to the
+// openconnect flag they stand for.
+var nmFlagOptions = []struct {
+	key  string
+	flag string
+}{
+	{"usergroup", "--usergroup"},
+	{"cacert", "--cafile"},
+	{"usercert", "--certificate"},
+	{"userkey", "--sslkey"},
+	{"proxy", "--proxy"},
+	{"useragent", "--useragent"},
+	{"reported_os", "--os"},
+	{"mtu", "--mtu"},
+}
+
+// ReadNMConnections parses every openconnect keyfile in dir. Files that are
+// not openconnect profiles are ignored; unreadable ones are reported as
+// skipped.
+func ReadNMConnections(dir string) ([]ImportCandidate, []ImportSkipped, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var candidates []ImportCandidate
+	var skipped []ImportSkipped
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			reason := err.Error()
+			if errors.Is(err, os.ErrPermission) {
+				reason = "permission denied (run with sudo)"
+			}
+			skipped = append(skipped, ImportSkipped{Name: entry.Name(), Reason: reason})
+			continue
+		}
+
+		candidate, err := ParseNMKeyfile(data)
+		if errors.Is(err, errNotOpenconnect) {
+			continue
+		}
+		if err != nil {
+			skipped = append(skipped, ImportSkipped{Name: entry.Name(), Reason: err.Error()})
+			continue
+		}
+		candidate.Source = path
+		candidates = append(candidates, *candidate)
+	}
+
+	return candidates, skipped, nil
+}
+
+// ParseNMKeyfile maps a NetworkManager vpn-type=openconnect keyfile to a
+// connection.
+func ParseNMKeyfile(data []byte) (*ImportCandidate, error) {
+	sections := parseKeyfile(data)
+	connection := sections["connection"]
+	vpn := sections["vpn"]
+	secrets := sections["vpn-secrets"]
+
+	if connection["type"] != "vpn" || !strings.Contains(vpn["service-type"], "openconnect") {
+		return nil, errNotOpenconnect
+	}
+
+	host := vpn["gateway"]
+	if host == "" {
+		return nil, fmt.Errorf("no gateway set")
+	}
+
+	name := connection["id"]
+	if name == "" {
+		name = host
+	}
+
+	protocol := vpn["protocol"]
+	if protocol == "" {
+		protocol = "anyconnect"
+	}
+	if !models.IsSupportedProtocol(protocol) {
+		return nil, fmt.Errorf("unsupported protocol %q", protocol)
+	}
+
+	candidate := &ImportCandidate{
+		Connection: models.Connection{
+			Name:     name,
+			Protocol: protocol,
+			Host:     host,
+			Username: firstNonEmpty(vpn["username"], vpn["user-name"], secrets["form:main:username"]),
+		},
+		Password: firstNonEmpty(secrets["password"], secrets["form:main:password"]),
+	}
+
+	candidate.Connection.ServerCert = firstNonEmpty(vpn["servercert"], firstCertSig(vpn["certsigs"]))
+
+	var flags []string
+	for _, opt := range nmFlagOptions {
+		value := vpn[opt.key]
+		if value == "" {
+			continue
+		}
+		if strings.ContainsAny(value, " \t") {
+			candidate.Notes = append(candidate.Notes, fmt.Sprintf("%s %q contains spaces and was not imported", opt.key, value))
+			continue
+		}
+		flags = append(flags, opt.flag+"="+value)
+	}
+	if vpn["enable_csd_trojan"] == "yes" && vpn["csd_wrapper"] != "" {
+		flags = append(flags, "--csd-wrapper="+vpn["csd_wrapper"])
+	}
+	if vpn["pem_passphrase_fsid"] == "yes" {
+		flags = append(flags, "--key-password-from-fsid")
+	}
+	if vpn["disable_udp"] == "yes" {
+		flags = append(flags, "--no-dtls")
+	}
+	candidate.Connection.Flags = strings.Join(flags, " ")
+
+	return candidate, nil
+}
+
+func parseKeyfile(data []byte) map[string]map[string]string {
+	sections := make(map[string]map[string]string)
+	current := ""
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			current = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		if sections[current] == nil {
+			sections[current] = make(map[string]string)
+		}
+		sections[current][strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+
+	return sections
+}
+
+// firstCertSig returns the first accepted server certificate from the
+// tab-separated certsigs list NetworkManager keeps.
+func firstCertSig(value string) string {
+	for _, sig := range strings.FieldsFunc(value, func(r rune) bool { return r == '\t' || r == ',' }) {
+		if sig = strings.TrimSpace(sig); sig != "" {
+			return sig
+		}
+	}
+	return ""
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,246 @@
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+)
+
+const nmKeyfile = `[connection]
+id=Work VPN
+uuid=6f1c1f7e-4a0e-4b5e-9d55-5d4f0c7a1b2c
+type=vpn
+autoconnect=false
+
+[vpn]
+service-type=org.freedesktop.NetworkManager.openconnect
+gateway=vpn.example.com
+protocol=gp
+username=alice
+usergroup=portal
+cacert=/etc/ssl/corp-ca.pem
+servercert=pin-sha256:abc=
+useragent=Mozilla Foo
+enable_csd_trojan=no
+csd_wrapper=/usr/libexec/openconnect/hipreport.sh
+disable_udp=yes
+
+[vpn-secrets]
+form:main:password=hunter2
+
+[ipv4]
+method=auto
+`
+
+func TestParseNMKeyfile(t *testing.T) {
+	candidate, err := ParseNMKeyfile([]byte(nmKeyfile))
+	if err != nil {
+		t.Fatalf("ParseNMKeyfile returned error: %v", err)
+	}
+
+	conn := candidate.Connection
+	if conn.Name != "Work VPN" {
+		t.Fatalf("Name = %q, want %q", conn.Name, "Work VPN")
+	}
+	if conn.Host != "vpn.example.com" {
+		t.Fatalf("Host = %q, want %q", conn.Host, "vpn.example.com")
+	}
+	if conn.Protocol != "gp" {
+		t.Fatalf("Protocol = %q, want %q", conn.Protocol, "gp")
+	}
+	if conn.Username != "alice" {
+		t.Fatalf("Username = %q, want %q", conn.Username, "alice")
+	}
+	if conn.ServerCert != "pin-sha256:abc=" {
+		t.Fatalf("ServerCert = %q, want %q", conn.ServerCert, "pin-sha256:abc=")
+	}
+	wantFlags := "--usergroup=portal --cafile=/etc/ssl/corp-ca.pem --no-dtls"
+	if conn.Flags != wantFlags {
+		t.Fatalf("Flags = %q, want %q", conn.Flags, wantFlags)
+	}
+	if candidate.Password != "hunter2" {
+		t.Fatalf("Password = %q, want %q", candidate.Password, "hunter2")
+	}
+	if len(candidate.Notes) != 1 {
+		t.Fatalf("Notes = %q, want one note about useragent", candidate.Notes)
+	}
+}
+
+func TestParseNMKeyfileIgnoresOtherConnections(t *testing.T) {
+	wifi := "[connection]\nid=Home\ntype=wifi\n"
+	if _, err := ParseNMKeyfile([]byte(wifi)); err != errNotOpenconnect {
+		t.Fatalf("err = %v, want errNotOpenconnect", err)
+	}
+
+	wireguard := "[connection]\nid=WG\ntype=vpn\n\n[vpn]\nservice-type=org.freedesktop.NetworkManager.wireguard\n"
+	if _, err := ParseNMKeyfile([]byte(wireguard)); err != errNotOpenconnect {
+		t.Fatalf("err = %v, want errNotOpenconnect", err)
+	}
+}
+
+func TestReadNMConnections(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"work.nmconnection": nmKeyfile,
+		"wifi.nmconnection": "[connection]\nid=Home\ntype=wifi\n",
+		"bad.nmconnection":  "[connection]\nid=Broken\ntype=vpn\n\n[vpn]\nservice-type=org.freedesktop.NetworkManager.openconnect\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatalf("WriteFile returned error: %v", err)
+		}
+	}
+
+	candidates, skipped, err := ReadNMConnections(dir)
+	if err != nil {
+		t.Fatalf("ReadNMConnections returned error: %v", err)
+	}
+	if len(candidates) != 1 || candidates[0].Connection.Name != "Work VPN" {
+		t.Fatalf("candidates = %+v, want only Work VPN", candidates)
+	}
+	if len(skipped) != 1 || skipped[0].Name != "bad.nmconnection" {
+		t.Fatalf("skipped = %+v, want bad.nmconnection", skipped)
+	}
+}
+
+func TestApplyImportSkipsDuplicateHosts(t *testing.T) {
+	cfg := &models.Config{
+		Connections: []models.Connection{
+			{ID: "1", Name: "Work", Protocol: "gp", Host: "vpn.example.com"},
+		},
+	}
+	candidates := []ImportCandidate{
+		{Connection: models.Connection{Name: "Work VPN", Protocol: "gp", Host: "https://VPN.example.com/"}},
+		{Connection: models.Connection{Name: "Work", Protocol: "anyconnect", Host: "vpn2.example.com"}, Password: "secret"},
+		{Connection: models.Connection{Name: "Again", Protocol: "anyconnect", Host: "vpn2.example.com"}},
+	}
+
+	report := ApplyImport(cfg, candidates, ImportOptions{OnConflict: ConflictSkip})
+
+	if len(report.Added) != 1 {
+		t.Fatalf("Added = %+v, want 1 connection", report.Added)
+	}
+	added := report.Added[0]
+	if added.ID == "" {
+		t.Fatal("added connection has no ID")
+	}
+	if added.Name != "Work (2)" {
+		t.Fatalf("Name = %q, want %q", added.Name, "Work (2)")
+	}
+	if !added.HasPassword {
+		t.Fatal("HasPassword should be true when a password was imported")
+	}
+	if len(report.Skipped) != 2 {
+		t.Fatalf("Skipped = %+v, want 2 entries", report.Skipped)
+	}
+	if len(cfg.Connections) != 2 {
+		t.Fatalf("config has %d connections, want 2", len(cfg.Connections))
+	}
+}
+
+const anyConnectProfileXML = `<?xml version="1.0" encoding="UTF-8"?>
+<AnyConnectProfile xmlns="http://schemas.xmlsoap.org/encoding/">
+	<ClientInitialization>
+		<AutoUpdate>true</AutoUpdate>
+	</ClientInitialization>
+	<ServerList>
+		<HostEntry>
+			<HostName>Office</HostName>
+			<HostAddress>vpn.example.com</HostAddress>
+		</HostEntry>
+		<HostEntry>
+			<HostName>Engineering</HostName>
+			<HostAddress>vpn.example.com</HostAddress>
+			<UserGroup>eng</UserGroup>
+		</HostEntry>
+		<HostEntry>
+			<HostName>backup.example.com</HostName>
+		</HostEntry>
+	</ServerList>
+</AnyConnectProfile>
+`
+
+func TestParseAnyConnectProfile(t *testing.T) {
+	candidates, err := ParseAnyConnectProfile([]byte(anyConnectProfileXML))
+	if err != nil {
+		t.Fatalf("ParseAnyConnectProfile returned error: %v", err)
+	}
+
+	want := []models.Connection{
+		{Name: "Office", Protocol: "anyconnect", Host: "vpn.example.com"},
+		{Name: "Engineering", Protocol: "anyconnect", Host: "vpn.example.com/eng"},
+		{Name: "backup.example.com", Protocol: "anyconnect", Host: "backup.example.com"},
+	}
+	if len(candidates) != len(want) {
+		t.Fatalf("got %d candidates, want %d", len(candidates), len(want))
+	}
+	for i, c := range candidates {
+		if !reflect.DeepEqual(c.Connection, want[i]) {
+			t.Fatalf("candidate %d = %+v, want %+v", i, c.Connection, want[i])
+		}
+	}
+}
+
+func TestParseGlobalProtectConfig(t *testing.T) {
+	config := `<portal-config>
+	<gateways>
+		<external>
+			<list>
+				<entry name="gw-eu.example.com"><description>Europe</description></entry>
+				<entry name="gw-us.example.com"/>
+			</list>
+		</external>
+	</gateways>
+</portal-config>`
+
+	candidates, err := ParseGlobalProtectConfig([]byte(config))
+	if err != nil {
+		t.Fatalf("ParseGlobalProtectConfig returned error: %v", err)
+	}
+	if len(candidates) != 2 {
+		t.Fatalf("got %d candidates, want 2", len(candidates))
+	}
+	if got := candidates[0].Connection; got.Name != "Europe" || got.Host != "gw-eu.example.com" || got.Protocol != "gp" {
+		t.Fatalf("candidate 0 = %+v", got)
+	}
+	if got := candidates[1].Connection; got.Name != "gw-us.example.com" {
+		t.Fatalf("candidate 1 Name = %q, want host as name", got.Name)
+	}
+
+	if _, err := ParseGlobalProtectConfig([]byte("<portal-config/>")); err == nil {
+		t.Fatal("expected an error for a config without gateways")
+	}
+}
+
+func TestApplyImportUpdatesExisting(t *testing.T) {
+	cfg := &models.Config{
+		Connections: []models.Connection{
+			{ID: "1", Name: "Office", Protocol: "anyconnect", Host: "old.example.com", HasPassword: true},
+			{ID: "2", Name: "Lab", Protocol: "anyconnect", Host: "lab.example.com"},
+		},
+	}
+	candidates := []ImportCandidate{
+		{Connection: models.Connection{Name: "Office", Protocol: "anyconnect", Host: "vpn.example.com"}},
+		{Connection: models.Connection{Name: "Lab", Protocol: "anyconnect", Host: "lab.example.com"}},
+		{Connection: models.Connection{Name: "New", Protocol: "anyconnect", Host: "new.example.com"}},
+	}
+
+	report := ApplyImport(cfg, candidates, ImportOptions{OnConflict: ConflictMerge})
+
+	if len(report.Added) != 1 || report.Added[0].Name != "New" {
+		t.Fatalf("Added = %+v, want New", report.Added)
+	}
+	if len(report.Updated) != 1 || report.Updated[0].ID != "1" {
+		t.Fatalf("Updated = %+v, want Office", report.Updated)
+	}
+	if len(report.Skipped) != 1 || report.Skipped[0].Name != "Lab" {
+		t.Fatalf("Skipped = %+v, want unchanged Lab", report.Skipped)
+	}
+	office := cfg.Connections[0]
+	if office.Host != "vpn.example.com" || !office.HasPassword {
+		t.Fatalf("Office = %+v, want new host and kept password", office)
+	}
+}
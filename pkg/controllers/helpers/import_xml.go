@@ -0,0 +1,145 @@
+package helpers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+)
+
+const DefaultAnyConnectProfileDir = "/opt/cisco/anyconnect/profile"
+
+type anyConnectProfile struct {
+	HostEntries []struct {
+		HostName    string `xml:"HostName"`
+		HostAddress string `xml:"HostAddress"`
+		UserGroup   string `xml:"UserGroup"`
+	} `xml:"ServerList>HostEntry"`
+}
+
+// ParseAnyConnectProfile maps every ServerList HostEntry of a Cisco
+// AnyConnect client profile to a connection.
+func ParseAnyConnectProfile(data []byte) ([]ImportCandidate, error) {
+	var profile anyConnectProfile
+	if err := xml.Unmarshal(data, &profile); err != nil {
+		return nil, fmt.Errorf("parse AnyConnect profile: %w", err)
+	}
+	if len(profile.HostEntries) == 0 {
+		return nil, fmt.Errorf("no ServerList host entries found")
+	}
+
+	var candidates []ImportCandidate
+	for _, entry := range profile.HostEntries {
+		name := strings.TrimSpace(entry.HostName)
+		host := strings.TrimSpace(entry.HostAddress)
+		if host == "" {
+			host = name
+		}
+		if host == "" {
+			continue
+		}
+		if name == "" {
+			name = host
+		}
+		if group := strings.Trim(strings.TrimSpace(entry.UserGroup), "/"); group != "" {
+			host = strings.TrimRight(host, "/") + "/" + group
+		}
+
+		candidates = append(candidates, ImportCandidate{
+			Connection: models.Connection{
+				Name:     name,
+				Protocol: "anyconnect",
+				Host:     host,
+			},
+		})
+	}
+
+	return candidates, nil
+}
+
+type globalProtectGateway struct {
+	Name        string `xml:"name,attr"`
+	Description string `xml:"description"`
+}
+
+type globalProtectConfig struct {
+	External []globalProtectGateway `xml:"gateways>external>list>entry"`
+	Internal []globalProtectGateway `xml:"gateways>internal>list>entry"`
+}
+
+// ParseGlobalProtectConfig maps the gateways of a GlobalProtect portal
+// configuration export to connections.
+func ParseGlobalProtectConfig(data []byte) ([]ImportCandidate, error) {
+	var config globalProtectConfig
+	if err := xml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("parse GlobalProtect config: %w", err)
+	}
+
+	gateways := append(config.External, config.Internal...)
+	if len(gateways) == 0 {
+		return nil, fmt.Errorf("no gateways found")
+	}
+
+	var candidates []ImportCandidate
+	for _, gw := range gateways {
+		host := strings.TrimSpace(gw.Name)
+		if host == "" {
+			continue
+		}
+		name := strings.TrimSpace(gw.Description)
+		if name == "" {
+			name = host
+		}
+
+		candidates = append(candidates, ImportCandidate{
+			Connection: models.Connection{
+				Name:     name,
+				Protocol: "gp",
+				Host:     host,
+			},
+		})
+	}
+
+	return candidates, nil
+}
+
+// readXMLImports parses path, or every .xml file in it when it is a
+// directory.
+func readXMLImports(path string, parse func([]byte) ([]ImportCandidate, error)) ([]ImportCandidate, []ImportSkipped, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		files, err = filepath.Glob(filepath.Join(path, "*.xml"))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var candidates []ImportCandidate
+	var skipped []ImportSkipped
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			skipped = append(skipped, ImportSkipped{Name: filepath.Base(file), Reason: err.Error()})
+			continue
+		}
+		parsed, err := parse(data)
+		if err != nil {
+			skipped = append(skipped, ImportSkipped{Name: filepath.Base(file), Reason: err.Error()})
+			continue
+		}
+		for i := range parsed {
+			parsed[i].Source = file
+		}
+		candidates = append(candidates, parsed...)
+	}
+
+	return candidates, skipped, nil
+}
@@ -1,6 +1,13 @@
 package helpers
 
-import "github.com/zalando/go-keyring"
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/zalando/go-keyring"
+)
 
 const serviceName = "lazyopenconnect"
 
@@ -15,3 +22,36 @@ func SetPassword(connectionID, password string) error {
 func DeletePassword(connectionID string) error {
 	return keyring.Delete(serviceName, connectionID)
 }
+
+// ReadPasswordFile reads a password from the first line of path. Files
+// other users can read are refused.
+func ReadPasswordFile(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.Mode().Perm()&0o077 != 0 {
+		return "", fmt.Errorf("password file %s is accessible by other users (chmod 600 it)", path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	password, _, _ := strings.Cut(string(data), "\n")
+	password = strings.TrimRight(password, "\r")
+	if password == "" {
+		return "", fmt.Errorf("password file %s is empty", path)
+	}
+	return password, nil
+}
+
+// CheckKeychain reports whether the system keychain (Secret Service on
+// Linux) can be queried.
+func CheckKeychain() error {
+	_, err := keyring.Get(serviceName, "doctor-probe")
+	if err == nil || errors.Is(err, keyring.ErrNotFound) {
+		return nil
+	}
+	return err
+}
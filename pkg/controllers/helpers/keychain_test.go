@@ -0,0 +1,48 @@
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadPasswordFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "work.pass")
+	if err := os.WriteFile(path, []byte("s3cret\r\nignored\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+
+	password, err := ReadPasswordFile(path)
+	if err != nil {
+		t.Fatalf("ReadPasswordFile returned error: %v", err)
+	}
+	if password != "s3cret" {
+		t.Fatalf("password = %q, want %q", password, "s3cret")
+	}
+}
+
+func TestReadPasswordFileRejectsReadableByOthers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "work.pass")
+	if err := os.WriteFile(path, []byte("s3cret\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	if err := os.Chmod(path, 0o644); err != nil {
+		t.Fatalf("Chmod returned error: %v", err)
+	}
+
+	if _, err := ReadPasswordFile(path); err == nil {
+		t.Fatal("expected error for a password file readable by others")
+	}
+}
+
+func TestReadPasswordFileRejectsEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "work.pass")
+	if err := os.WriteFile(path, []byte("\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+
+	if _, err := ReadPasswordFile(path); err == nil {
+		t.Fatal("expected error for an empty password file")
+	}
+}
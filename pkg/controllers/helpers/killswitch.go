@@ -0,0 +1,75 @@
+package helpers
+
+import (
+	"net/netip"
+	"slices"
+)
+
+// KillSwitch is what the kill switch lets out besides loopback and DHCP:
+// the tunnel devices and the VPN servers they connect through.
+type KillSwitch struct {
+	Tunnels []string
+	Servers []string
+}
+
+// Equal reports whether k and other allow the same traffic.
+func (k KillSwitch) Equal(other KillSwitch) bool {
+	return slices.Equal(k.Tunnels, other.Tunnels) && slices.Equal(k.Servers, other.Servers)
+}
+
+// validTunnels returns the tunnel names safe to put into firewall rules.
+func (k KillSwitch) validTunnels() []string {
+	var tunnels []string
+	for _, name := range k.Tunnels {
+		if validInterfaceName(name) {
+			tunnels = append(tunnels, name)
+		}
+	}
+	return tunnels
+}
+
+// validServers returns the server addresses, split by family.
+func (k KillSwitch) validServers() (v4, v6 []string) {
+	for _, server := range k.Servers {
+		addr, err := netip.ParseAddr(server)
+		switch {
+		case err != nil:
+		case addr.Is4() || addr.Is4In6():
+			v4 = append(v4, addr.Unmap().String())
+		default:
+			v6 = append(v6, addr.WithZone("").String())
+		}
+	}
+	return v4, v6
+}
+
+func validInterfaceName(name string) bool {
+	if name == "" || len(name) > 15 {
+		return false
+	}
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '-' && r != '_' && r != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+// KillSwitchCleanupSteps removes the kill switch rules if they are in
+// place.
+func KillSwitchCleanupSteps() []CleanupStep {
+	if !KillSwitchActive() {
+		return nil
+	}
+	return []CleanupStep{{
+		Name: "Removing kill switch",
+		Cmd:  killSwitchDisableCmd,
+		Fn:   DisableKillSwitch,
+	}}
+}
+
+// RunKillSwitchCleanupSteps removes the kill switch rules, reporting like
+// the other cleanup steps.
+func RunKillSwitchCleanupSteps() []CleanupResult {
+	return runSteps(KillSwitchCleanupSteps())
+}
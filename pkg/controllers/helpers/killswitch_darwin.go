@@ -0,0 +1,83 @@
+//go:build darwin
+
+package helpers
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// killSwitchAnchor is loaded by the com.apple/* anchor of the stock
+// pf.conf, so the rules need no change to the main ruleset.
+const killSwitchAnchor = "com.apple/lazyopenconnect"
+
+const killSwitchDisableCmd = "pfctl -a " + killSwitchAnchor + " -F all"
+
+// pfTokenPath keeps the reference that enabled pf, so disabling drops
+// only that reference and leaves pf on for anyone else using it.
+const pfTokenPath = "/var/run/lazyopenconnect-pf.token"
+
+var pfTokenPattern = regexp.MustCompile(`Token : (\d+)`)
+
+// EnableKillSwitch loads, or replaces, the pf anchor that blocks all
+// outgoing traffic k does not allow, and enables pf.
+func EnableKillSwitch(k KillSwitch) error {
+	cmd := exec.Command("pfctl", "-a", killSwitchAnchor, "-f", "-")
+	cmd.Stdin = strings.NewReader(killSwitchRuleset(k))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("pfctl: %s", strings.TrimSpace(string(out)))
+	}
+
+	if _, err := os.Stat(pfTokenPath); err == nil {
+		return nil
+	}
+	out, err := exec.Command("pfctl", "-E").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("pfctl -E: %s", strings.TrimSpace(string(out)))
+	}
+	if match := pfTokenPattern.FindSubmatch(out); match != nil {
+		return os.WriteFile(pfTokenPath, match[1], 0o600)
+	}
+	return nil
+}
+
+// DisableKillSwitch flushes the anchor and releases the pf reference the
+// kill switch took.
+func DisableKillSwitch() error {
+	err := runCmd("pfctl", "-a", killSwitchAnchor, "-F", "all")
+	if token, readErr := os.ReadFile(pfTokenPath); readErr == nil {
+		_ = runCmd("pfctl", "-X", string(bytes.TrimSpace(token)))
+		_ = os.Remove(pfTokenPath)
+	}
+	return err
+}
+
+// KillSwitchActive reports whether the kill switch anchor has rules.
+func KillSwitchActive() bool {
+	out, err := exec.Command("pfctl", "-a", killSwitchAnchor, "-s", "rules").Output()
+	return err == nil && len(bytes.TrimSpace(out)) > 0
+}
+
+func killSwitchRuleset(k KillSwitch) string {
+	var b strings.Builder
+	b.WriteString("pass out quick on lo0 all\n")
+	for _, tunnel := range k.validTunnels() {
+		fmt.Fprintf(&b, "pass out quick on %s all\n", tunnel)
+	}
+	v4, v6 := k.validServers()
+	for _, server := range v4 {
+		fmt.Fprintf(&b, "pass out quick inet from any to %s\n", server)
+	}
+	for _, server := range v6 {
+		fmt.Fprintf(&b, "pass out quick inet6 from any to %s\n", server)
+	}
+	b.WriteString("pass out quick inet proto udp from any port 68 to any port 67\n")
+	b.WriteString("pass out quick inet6 proto udp from any port 546 to any port 547\n")
+	b.WriteString("pass out quick inet6 proto icmp6 icmp6-type { routersol, neighbrsol, neighbradv }\n")
+	b.WriteString("block drop out quick all\n")
+	return b.String()
+}
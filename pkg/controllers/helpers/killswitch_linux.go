@@ -0,0 +1,64 @@
+//go:build linux
+
+package helpers
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// killSwitchTable holds the kill switch rules, so removing them never
+// touches anyone else's nftables setup.
+const killSwitchTable = "lazyopenconnect_killswitch"
+
+const killSwitchDisableCmd = "nft delete table inet " + killSwitchTable
+
+// EnableKillSwitch installs, or atomically replaces, the nftables rules
+// that drop all outgoing traffic k does not allow.
+func EnableKillSwitch(k KillSwitch) error {
+	cmd := exec.Command("nft", "-f", "-")
+	cmd.Stdin = strings.NewReader(killSwitchRuleset(k))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("nft: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// DisableKillSwitch removes the kill switch rules.
+func DisableKillSwitch() error {
+	if !KillSwitchActive() {
+		return nil
+	}
+	return runCmd("nft", "delete", "table", "inet", killSwitchTable)
+}
+
+// KillSwitchActive reports whether the kill switch rules are installed.
+func KillSwitchActive() bool {
+	return exec.Command("nft", "list", "table", "inet", killSwitchTable).Run() == nil
+}
+
+func killSwitchRuleset(k KillSwitch) string {
+	var b strings.Builder
+	// Creating the table first lets the delete succeed when it is new.
+	fmt.Fprintf(&b, "table inet %s\ndelete table inet %s\n", killSwitchTable, killSwitchTable)
+	fmt.Fprintf(&b, "table inet %s {\n", killSwitchTable)
+	b.WriteString("\tchain output {\n")
+	b.WriteString("\t\ttype filter hook output priority filter; policy drop;\n")
+	b.WriteString("\t\toifname \"lo\" accept\n")
+	for _, tunnel := range k.validTunnels() {
+		fmt.Fprintf(&b, "\t\toifname %q accept\n", tunnel)
+	}
+	v4, v6 := k.validServers()
+	for _, server := range v4 {
+		fmt.Fprintf(&b, "\t\tip daddr %s accept\n", server)
+	}
+	for _, server := range v6 {
+		fmt.Fprintf(&b, "\t\tip6 daddr %s accept\n", server)
+	}
+	b.WriteString("\t\tudp sport 68 udp dport 67 accept\n")
+	b.WriteString("\t\tudp sport 546 udp dport 547 accept\n")
+	b.WriteString("\t\ticmpv6 type { nd-router-solicit, nd-neighbor-solicit, nd-neighbor-advert } accept\n")
+	b.WriteString("\t}\n}\n")
+	return b.String()
+}
@@ -0,0 +1,32 @@
+package helpers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKillSwitchRuleset(t *testing.T) {
+	ruleset := killSwitchRuleset(KillSwitch{
+		Tunnels: []string{"tun0", `bad"name`},
+		Servers: []string{"203.0.113.7", "2001:db8::7", "vpn.example.com"},
+	})
+
+	for _, want := range []string{
+		"delete table inet " + killSwitchTable + "\n",
+		"policy drop;",
+		`oifname "lo" accept`,
+		`oifname "tun0" accept`,
+		"ip daddr 203.0.113.7 accept",
+		"ip6 daddr 2001:db8::7 accept",
+		"udp sport 68 udp dport 67 accept",
+	} {
+		if !strings.Contains(ruleset, want) {
+			t.Fatalf("ruleset misses %q:\n%s", want, ruleset)
+		}
+	}
+	for _, unwanted := range []string{"bad", "vpn.example.com"} {
+		if strings.Contains(ruleset, unwanted) {
+			t.Fatalf("ruleset contains invalid entry %q:\n%s", unwanted, ruleset)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package helpers
+
+import (
+	"errors"
+	"strings"
+	"syscall"
+)
+
+// WatchRoutes reports changes to links, addresses and routes until stop is
+// closed. Bursts collapse into a single pending signal; the caller looks at
+// the routing table itself. The channel closes when watching fails.
+func WatchRoutes(stop <-chan struct{}) (<-chan struct{}, error) {
+	f, err := openRouteSocket()
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(chan struct{}, 1)
+	go func() {
+		<-stop
+		f.Close()
+	}()
+	go func() {
+		defer close(changes)
+		buf := make([]byte, 1<<16)
+		for {
+			// The kernel drops messages when we fall behind; that still
+			// means something changed.
+			if _, err := f.Read(buf); err != nil && !errors.Is(err, syscall.ENOBUFS) {
+				return
+			}
+			select {
+			case changes <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return changes, nil
+}
+
+// parseIPRoute reads the device and gateway of a route printed by
+// `ip -o route`.
+func parseIPRoute(out string) (iface, gateway string) {
+	line, _, _ := strings.Cut(strings.TrimSpace(out), "\n")
+	fields := strings.Fields(line)
+	for i := 0; i+1 < len(fields); i++ {
+		switch fields[i] {
+		case "dev":
+			iface = fields[i+1]
+		case "via":
+			gateway = fields[i+1]
+		}
+	}
+	return iface, gateway
+}
+
+// parseRouteGet reads the interface and gateway printed by `route -n get`.
+func parseRouteGet(out string) (iface, gateway string) {
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "interface":
+			iface = strings.TrimSpace(value)
+		case "gateway":
+			gateway = strings.TrimSpace(value)
+		}
+	}
+	return iface, gateway
+}
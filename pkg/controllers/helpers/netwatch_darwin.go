@@ -0,0 +1,35 @@
+//go:build darwin
+
+package helpers
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+func openRouteSocket() (*os.File, error) {
+	fd, err := syscall.Socket(syscall.AF_ROUTE, syscall.SOCK_RAW, syscall.AF_UNSPEC)
+	if err != nil {
+		return nil, fmt.Errorf("routing socket: %w", err)
+	}
+	syscall.CloseOnExec(fd)
+	// Non-blocking, so closing the file interrupts a pending read.
+	if err := syscall.SetNonblock(fd, true); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	return os.NewFile(uintptr(fd), "route"), nil
+}
+
+// DetectRouteTo returns the interface and gateway that traffic to ip
+// leaves through.
+func DetectRouteTo(ip string) (iface, gateway string, err error) {
+	out, err := exec.Command("route", "-n", "get", ip).Output()
+	if err != nil {
+		return "", "", err
+	}
+	iface, gateway = parseRouteGet(string(out))
+	return iface, gateway, nil
+}
@@ -0,0 +1,46 @@
+//go:build linux
+
+package helpers
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// routeGroups are the rtnetlink multicast groups for link, address and
+// route changes.
+const routeGroups = 1<<(syscall.RTNLGRP_LINK-1) |
+	1<<(syscall.RTNLGRP_IPV4_IFADDR-1) |
+	1<<(syscall.RTNLGRP_IPV4_ROUTE-1) |
+	1<<(syscall.RTNLGRP_IPV6_IFADDR-1) |
+	1<<(syscall.RTNLGRP_IPV6_ROUTE-1)
+
+func openRouteSocket() (*os.File, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.NETLINK_ROUTE)
+	if err != nil {
+		return nil, fmt.Errorf("netlink socket: %w", err)
+	}
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK, Groups: routeGroups}); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("netlink bind: %w", err)
+	}
+	// Non-blocking, so closing the file interrupts a pending read.
+	if err := syscall.SetNonblock(fd, true); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	return os.NewFile(uintptr(fd), "netlink"), nil
+}
+
+// DetectRouteTo returns the interface and gateway that traffic to ip
+// leaves through.
+func DetectRouteTo(ip string) (iface, gateway string, err error) {
+	out, err := exec.Command("ip", "-o", "route", "get", ip).Output()
+	if err != nil {
+		return "", "", err
+	}
+	iface, gateway = parseIPRoute(string(out))
+	return iface, gateway, nil
+}
@@ -0,0 +1,53 @@
+package helpers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseIPRoute(t *testing.T) {
+	out := "203.0.113.7 via 192.168.1.1 dev wlp2s0 src 192.168.1.20 uid 0 \\    cache \n"
+	iface, gateway := parseIPRoute(out)
+	if iface != "wlp2s0" || gateway != "192.168.1.1" {
+		t.Fatalf("parseIPRoute = %q, %q; want wlp2s0, 192.168.1.1", iface, gateway)
+	}
+
+	iface, gateway = parseIPRoute("default dev tun0 scope link \n")
+	if iface != "tun0" || gateway != "" {
+		t.Fatalf("parseIPRoute = %q, %q; want tun0 without gateway", iface, gateway)
+	}
+}
+
+func TestParseRouteGet(t *testing.T) {
+	out := `   route to: 203.0.113.7
+destination: 203.0.113.7
+    gateway: 192.168.1.1
+  interface: en0
+      flags: <UP,GATEWAY,HOST,DONE,STATIC>
+`
+	iface, gateway := parseRouteGet(out)
+	if iface != "en0" || gateway != "192.168.1.1" {
+		t.Fatalf("parseRouteGet = %q, %q; want en0, 192.168.1.1", iface, gateway)
+	}
+}
+
+func TestWatchRoutesClosesOnStop(t *testing.T) {
+	stop := make(chan struct{})
+	changes, err := WatchRoutes(stop)
+	if err != nil {
+		t.Skipf("route socket unavailable: %v", err)
+	}
+	close(stop)
+
+	deadline := time.After(2 * time.Second)
+	for {
+		select {
+		case _, ok := <-changes:
+			if !ok {
+				return
+			}
+		case <-deadline:
+			t.Fatal("changes still open after stop")
+		}
+	}
+}
@@ -11,6 +11,14 @@ type NetworkSnapshot struct {
 	DNSServers       []string `json:"dns_servers"`
 	DefaultGateway   string   `json:"default_gateway"`
 	TunnelInterface  string   `json:"tunnel_interface"`
+	// IncludeRoutes and ExcludeRoutes are the split routes the tunnel
+	// added, which cleanup removes again.
+	IncludeRoutes []string `json:"include_routes,omitempty"`
+	ExcludeRoutes []string `json:"exclude_routes,omitempty"`
+	// SplitDNSDomains and SplitDNSServers are the split DNS setup of the
+	// tunnel.
+	SplitDNSDomains []string `json:"split_dns_domains,omitempty"`
+	SplitDNSServers []string `json:"split_dns_servers,omitempty"`
 }
 
 func CaptureNetworkSnapshot() *NetworkSnapshot {
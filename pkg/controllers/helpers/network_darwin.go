@@ -72,3 +72,8 @@ func DetectDefaultGateway() (string, error) {
 	}
 	return "", nil
 }
+
+// DNSBackend describes how DNS servers are managed on this system.
+func DNSBackend() string {
+	return "networksetup"
+}
@@ -49,6 +49,14 @@ func DetectDefaultGateway() (string, error) {
 	return "", nil
 }
 
+// DNSBackend describes how DNS servers are managed on this system.
+func DNSBackend() string {
+	if isSystemdResolved() {
+		return "systemd-resolved (resolvectl)"
+	}
+	return "/etc/resolv.conf"
+}
+
 func isSystemdResolved() bool {
 	_, err := exec.LookPath("resolvectl")
 	return err == nil
@@ -0,0 +1,34 @@
+package helpers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ParseBackoff reads a backoff schedule of whole seconds separated by
+// spaces or commas, e.g. "2 5 10". Blank means the default schedule.
+func ParseBackoff(s string) ([]int, error) {
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	backoff := make([]int, 0, len(fields))
+	for _, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 {
+			return nil, errors.New("use whole seconds, e.g. 2 5 10")
+		}
+		backoff = append(backoff, n)
+	}
+	return backoff, nil
+}
+
+// FormatBackoff writes a schedule the way ParseBackoff reads it.
+func FormatBackoff(backoff []int) string {
+	parts := make([]string, len(backoff))
+	for i, n := range backoff {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,220 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+const (
+	ServiceUnit = "lazyopenconnect.service"
+	SocketUnit  = "lazyopenconnect.socket"
+
+	systemdUnitDir = "/etc/systemd/system"
+)
+
+// ServiceConfig describes the systemd units written by InstallService.
+type ServiceConfig struct {
+	Executable string
+	Debug      bool
+	// Socket adds a .socket unit, so the daemon starts on the first client
+	// connection instead of at boot.
+	Socket     bool
+	SocketPath string
+	// User owns the config the daemon reads and the socket. Nil runs the
+	// daemon for root.
+	User *user.User
+}
+
+// ServiceState is what systemd reports about the installed units.
+type ServiceState struct {
+	ServiceInstalled bool
+	ServiceEnabled   string
+	ServiceActive    string
+	SocketInstalled  bool
+	SocketEnabled    string
+	SocketActive     string
+}
+
+func ServiceUnitPath() string {
+	return filepath.Join(systemdUnitDir, ServiceUnit)
+}
+
+func SocketUnitPath() string {
+	return filepath.Join(systemdUnitDir, SocketUnit)
+}
+
+// ServiceSupported reports why the service cannot be installed here, or
+// nil on a host booted with systemd.
+func ServiceSupported() error {
+	if runtime.GOOS != "linux" {
+		return fmt.Errorf("the service needs systemd, which %s does not have", runtime.GOOS)
+	}
+	if _, err := os.Stat("/run/systemd/system"); err != nil {
+		return errors.New("this system was not booted with systemd")
+	}
+	if _, err := exec.LookPath("systemctl"); err != nil {
+		return errors.New("systemctl not found in PATH")
+	}
+	return nil
+}
+
+// ServiceInstalled reports whether the daemon should be started through
+// systemd rather than spawned directly.
+func ServiceInstalled() bool {
+	if runtime.GOOS != "linux" {
+		return false
+	}
+	_, err := os.Stat(ServiceUnitPath())
+	return err == nil
+}
+
+// ServiceUnitContent renders the service unit. SUDO_USER and friends are
+// set as if the daemon had been started with sudo, so it finds the user's
+// config and hands them the socket.
+func ServiceUnitContent(cfg ServiceConfig) string {
+	execStart := cfg.Executable + " daemon run"
+	if cfg.Debug {
+		execStart += " --debug"
+	}
+
+	var b strings.Builder
+	b.WriteString("[Unit]\n")
+	b.WriteString("Description=lazyopenconnect VPN daemon\n")
+	b.WriteString("Wants=network-online.target\n")
+	b.WriteString("After=network-online.target\n")
+	if cfg.Socket {
+		b.WriteString("Requires=" + SocketUnit + "\n")
+		b.WriteString("After=" + SocketUnit + "\n")
+	}
+	b.WriteString("\n[Service]\n")
+	b.WriteString("Type=simple\n")
+	b.WriteString("ExecStart=" + execStart + "\n")
+	if cfg.User != nil {
+		fmt.Fprintf(&b, "Environment=SUDO_USER=%s SUDO_UID=%s SUDO_GID=%s\n", cfg.User.Username, cfg.User.Uid, cfg.User.Gid)
+	}
+	b.WriteString("Restart=on-failure\n")
+	// The daemon stops openconnect itself on SIGTERM; the rest of the
+	// cgroup is only killed if that takes too long.
+	b.WriteString("KillMode=mixed\n")
+	b.WriteString("TimeoutStopSec=30\n")
+	b.WriteString("\n[Install]\n")
+	b.WriteString("WantedBy=multi-user.target\n")
+	return b.String()
+}
+
+// SocketUnitContent renders the socket unit for cfg.SocketPath.
+func SocketUnitContent(cfg ServiceConfig) string {
+	var b strings.Builder
+	b.WriteString("[Unit]\n")
+	b.WriteString("Description=lazyopenconnect daemon socket\n")
+	b.WriteString("\n[Socket]\n")
+	b.WriteString("ListenStream=" + cfg.SocketPath + "\n")
+	b.WriteString("SocketMode=0600\n")
+	if cfg.User != nil {
+		b.WriteString("SocketUser=" + cfg.User.Username + "\n")
+		if group, err := user.LookupGroupId(cfg.User.Gid); err == nil {
+			b.WriteString("SocketGroup=" + group.Name + "\n")
+		}
+	}
+	b.WriteString("RemoveOnStop=yes\n")
+	b.WriteString("\n[Install]\n")
+	b.WriteString("WantedBy=sockets.target\n")
+	return b.String()
+}
+
+// InstallService writes the units and enables them. With start set the
+// daemon (or its socket) is started right away.
+func InstallService(cfg ServiceConfig, start bool) error {
+	if err := os.WriteFile(ServiceUnitPath(), []byte(ServiceUnitContent(cfg)), 0o644); err != nil {
+		return err
+	}
+
+	unit := ServiceUnit
+	if cfg.Socket {
+		if err := os.WriteFile(SocketUnitPath(), []byte(SocketUnitContent(cfg)), 0o644); err != nil {
+			return err
+		}
+		unit = SocketUnit
+		// The socket starts the service; it must not also start at boot.
+		_ = systemctl("disable", ServiceUnit)
+	} else if _, err := os.Stat(SocketUnitPath()); err == nil {
+		_ = systemctl("disable", "--now", SocketUnit)
+		if err := os.Remove(SocketUnitPath()); err != nil {
+			return err
+		}
+	}
+
+	if err := systemctl("daemon-reload"); err != nil {
+		return err
+	}
+	args := []string{"enable", unit}
+	if start {
+		args = []string{"enable", "--now", unit}
+	}
+	return systemctl(args...)
+}
+
+// RemoveService disables and deletes the units, if installed.
+func RemoveService() error {
+	var paths []string
+	for _, path := range []string{SocketUnitPath(), ServiceUnitPath()} {
+		if _, err := os.Stat(path); err == nil {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+
+	_ = systemctl("disable", "--now", SocketUnit, ServiceUnit)
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil {
+			if os.IsPermission(err) {
+				return fmt.Errorf("permission denied removing %s\nRun: sudo lazyopenconnect service uninstall", path)
+			}
+			return err
+		}
+		fmt.Printf("Removed %s\n", path)
+	}
+	return systemctl("daemon-reload")
+}
+
+// GetServiceState asks systemd about the installed units.
+func GetServiceState() ServiceState {
+	var state ServiceState
+	if _, err := os.Stat(ServiceUnitPath()); err == nil {
+		state.ServiceInstalled = true
+		state.ServiceEnabled = systemctlQuery("is-enabled", ServiceUnit)
+		state.ServiceActive = systemctlQuery("is-active", ServiceUnit)
+	}
+	if _, err := os.Stat(SocketUnitPath()); err == nil {
+		state.SocketInstalled = true
+		state.SocketEnabled = systemctlQuery("is-enabled", SocketUnit)
+		state.SocketActive = systemctlQuery("is-active", SocketUnit)
+	}
+	return state
+}
+
+func systemctl(args ...string) error {
+	out, err := exec.Command("systemctl", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("systemctl %s: %s", strings.Join(args, " "), strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// systemctlQuery returns the answer of an is-enabled or is-active query,
+// which exits non-zero for answers like "disabled" or "inactive".
+func systemctlQuery(args ...string) string {
+	out, _ := exec.Command("systemctl", args...).Output()
+	if answer := strings.TrimSpace(string(out)); answer != "" {
+		return answer
+	}
+	return "unknown"
+}
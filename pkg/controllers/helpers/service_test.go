@@ -0,0 +1,56 @@
+package helpers
+
+import (
+	"os/user"
+	"strings"
+	"testing"
+)
+
+func TestServiceUnitContent(t *testing.T) {
+	cfg := ServiceConfig{
+		Executable: "/usr/local/bin/lazyopenconnect",
+		Debug:      true,
+		User:       &user.User{Username: "alice", Uid: "1000", Gid: "1000"},
+	}
+
+	unit := ServiceUnitContent(cfg)
+	for _, want := range []string{
+		"ExecStart=/usr/local/bin/lazyopenconnect daemon run --debug\n",
+		"Environment=SUDO_USER=alice SUDO_UID=1000 SUDO_GID=1000\n",
+		"WantedBy=multi-user.target\n",
+	} {
+		if !strings.Contains(unit, want) {
+			t.Errorf("service unit missing %q:\n%s", want, unit)
+		}
+	}
+	if strings.Contains(unit, SocketUnit) {
+		t.Errorf("service unit without socket references %s:\n%s", SocketUnit, unit)
+	}
+}
+
+func TestServiceUnitContentWithSocket(t *testing.T) {
+	cfg := ServiceConfig{
+		Executable: "/usr/local/bin/lazyopenconnect",
+		Socket:     true,
+		SocketPath: "/home/alice/.config/lazyopenconnect/daemon.sock",
+	}
+
+	unit := ServiceUnitContent(cfg)
+	if !strings.Contains(unit, "Requires="+SocketUnit+"\n") {
+		t.Errorf("service unit does not require %s:\n%s", SocketUnit, unit)
+	}
+	if strings.Contains(unit, "Environment=") {
+		t.Errorf("root service unit sets SUDO_USER:\n%s", unit)
+	}
+
+	socket := SocketUnitContent(cfg)
+	for _, want := range []string{
+		"ListenStream=/home/alice/.config/lazyopenconnect/daemon.sock\n",
+		"SocketMode=0600\n",
+		"WantedBy=sockets.target\n",
+	} {
+		if !strings.Contains(socket, want) {
+			t.Errorf("socket unit missing %q:\n%s", want, socket)
+		}
+	}
+}
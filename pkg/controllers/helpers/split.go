@@ -0,0 +1,240 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+)
+
+// vpncScripts are where distributions and Homebrew install the
+// vpnc-script openconnect runs by default.
+var vpncScripts = []string{
+	"/usr/share/vpnc-scripts/vpnc-script",
+	"/etc/vpnc/vpnc-script",
+	"/etc/openconnect/vpnc-script",
+	"/usr/local/etc/vpnc-script",
+	"/usr/local/etc/vpnc/vpnc-script",
+	"/opt/homebrew/etc/vpnc/vpnc-script",
+}
+
+// FindVpncScript returns the installed vpnc-script the split tunnel
+// wrapper hands over to.
+func FindVpncScript() (string, error) {
+	for _, path := range vpncScripts {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+	}
+	return "", errors.New("vpnc-script not found; set its path with --script in the connection's flags")
+}
+
+// ParseRoute parses a CIDR, or a single address as a host route, and
+// returns it with the host bits cleared.
+func ParseRoute(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, "/") {
+		addr, err := netip.ParseAddr(s)
+		if err != nil || addr.Zone() != "" {
+			return "", fmt.Errorf("invalid route %q: want an address or CIDR", s)
+		}
+		return netip.PrefixFrom(addr, addr.BitLen()).String(), nil
+	}
+	prefix, err := netip.ParsePrefix(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid route %q: want an address or CIDR", s)
+	}
+	return prefix.Masked().String(), nil
+}
+
+// ParseRoutes parses routes separated by commas or spaces, dropping
+// duplicates.
+func ParseRoutes(s string) ([]string, error) {
+	return parseList(s, ParseRoute)
+}
+
+// parseList parses the values of a list separated by commas or spaces,
+// dropping duplicates.
+func parseList(s string, parse func(string) (string, error)) ([]string, error) {
+	var values []string
+	seen := make(map[string]bool)
+	for _, field := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		value, err := parse(field)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[value] {
+			seen[value] = true
+			values = append(values, value)
+		}
+	}
+	return values, nil
+}
+
+// FormatList joins values the way parseList reads them.
+func FormatList(values []string) string {
+	return strings.Join(values, ", ")
+}
+
+// splitScriptDir holds the vpnc-script wrappers. openconnect runs them as
+// root, so they live in a directory only the daemon can write to rather
+// than in the user's config directory. Tests replace it.
+var splitScriptDir = "/var/run/lazyopenconnect"
+
+// SplitScriptPath returns where the vpnc-script wrapper of a connection is
+// written.
+func SplitScriptPath(connID string) string {
+	return filepath.Join(splitScriptDir, "vpnc-"+connID+".sh")
+}
+
+// WriteSplitScript writes the vpnc-script wrapper of conn and returns its
+// path. The wrapper goes to a fresh file that is renamed into place, so a
+// symlink or file planted at the path is replaced rather than written
+// through.
+func WriteSplitScript(conn *models.Connection, vpncScript string) (string, error) {
+	if err := ensurePrivateDir(splitScriptDir); err != nil {
+		return "", err
+	}
+	path := SplitScriptPath(conn.ID)
+
+	// CreateTemp opens with O_EXCL, which never follows a symlink.
+	f, err := os.CreateTemp(splitScriptDir, ".vpnc-"+conn.ID+"-*")
+	if err != nil {
+		return "", err
+	}
+	tmp := f.Name()
+	_, err = f.WriteString(SplitScript(conn, vpncScript))
+	if err == nil {
+		err = f.Chmod(0o755)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+
+	if err := checkOwned(path, false); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// ensurePrivateDir creates dir for the daemon's own use and checks nobody
+// else could have planted it.
+func ensurePrivateDir(dir string) error {
+	if err := os.Mkdir(dir, 0o755); err != nil && !errors.Is(err, os.ErrExist) {
+		return err
+	}
+	return checkOwned(dir, true)
+}
+
+// checkOwned refuses path unless it is a real directory or regular file,
+// as wantDir says, that belongs to the daemon's user and that no one else
+// may write to.
+func checkOwned(path string, wantDir bool) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	switch {
+	case wantDir && !info.IsDir(), !wantDir && !info.Mode().IsRegular():
+		return fmt.Errorf("%s: unexpected file type %s", path, info.Mode().Type())
+	case info.Mode().Perm()&0o022 != 0:
+		return fmt.Errorf("%s: writable by group or others (mode %s)", path, info.Mode().Perm())
+	}
+	if st, ok := info.Sys().(*syscall.Stat_t); ok && int(st.Uid) != os.Geteuid() {
+		return fmt.Errorf("%s: owned by uid %d, not %d", path, st.Uid, os.Geteuid())
+	}
+	return nil
+}
+
+// SplitScript returns a vpnc-script wrapper that adds the split routes of
+// conn to the ones the server pushed, then runs vpncScript. Include routes
+// only apply to a split tunnel; with the server's default route in place
+// they already go through the tunnel.
+func SplitScript(conn *models.Connection, vpncScript string) string {
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	fmt.Fprintf(&b, "# Written by lazyopenconnect for connection %s on every connect.\n", conn.ID)
+	b.WriteString(`
+add_route() {
+	eval "n=\${$1:-0}"
+	export "$1_${n}_ADDR=$2" "$1_${n}_MASK=$3" "$1_${n}_MASKLEN=$4"
+	export "$1=$((n + 1))"
+}
+`)
+
+	if conn.NoDefaultRoute {
+		b.WriteString("\n# Ignore the server's default route.\n")
+		b.WriteString("export CISCO_SPLIT_INC=\"${CISCO_SPLIT_INC:-0}\"\n")
+		b.WriteString("export CISCO_IPV6_SPLIT_INC=\"${CISCO_IPV6_SPLIT_INC:-0}\"\n")
+	}
+
+	var inc4, inc6, exc []string
+	for _, route := range conn.IncludeRoutes {
+		if line, v6 := addRouteLine("INC", route); v6 {
+			inc6 = append(inc6, line)
+		} else if line != "" {
+			inc4 = append(inc4, line)
+		}
+	}
+	for _, route := range conn.ExcludeRoutes {
+		if line, _ := addRouteLine("EXC", route); line != "" {
+			exc = append(exc, line)
+		}
+	}
+	if len(inc4) > 0 {
+		b.WriteString("\nif [ -n \"$CISCO_SPLIT_INC\" ]; then\n")
+		for _, line := range inc4 {
+			b.WriteString("\t" + line + "\n")
+		}
+		b.WriteString("fi\n")
+	}
+	if len(inc6) > 0 {
+		b.WriteString("\nif [ -n \"$CISCO_IPV6_SPLIT_INC\" ]; then\n")
+		for _, line := range inc6 {
+			b.WriteString("\t" + line + "\n")
+		}
+		b.WriteString("fi\n")
+	}
+	if len(exc) > 0 {
+		b.WriteString("\n")
+		for _, line := range exc {
+			b.WriteString(line + "\n")
+		}
+	}
+
+	fmt.Fprintf(&b, "\nexec %s\n", shellQuote(vpncScript))
+	return b.String()
+}
+
+// addRouteLine returns the add_route call for route in the split list kind,
+// "INC" or "EXC", and whether it is an IPv6 route. Routes that do not parse
+// give an empty line.
+func addRouteLine(kind, route string) (string, bool) {
+	prefix, err := netip.ParsePrefix(route)
+	if err != nil {
+		return "", false
+	}
+	prefix = prefix.Masked()
+	if prefix.Addr().Is6() {
+		return fmt.Sprintf("add_route CISCO_IPV6_SPLIT_%s %s '' %d", kind, prefix.Addr(), prefix.Bits()), true
+	}
+	mask := net.IP(net.CIDRMask(prefix.Bits(), 32)).String()
+	return fmt.Sprintf("add_route CISCO_SPLIT_%s %s %s %d", kind, prefix.Addr(), mask, prefix.Bits()), false
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
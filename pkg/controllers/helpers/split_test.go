@@ -0,0 +1,166 @@
+package helpers
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+)
+
+func TestParseRoutes(t *testing.T) {
+	routes, err := ParseRoutes("10.1.2.3/8, 192.168.1.5 10.0.0.0/8,2001:db8::1/32")
+	if err != nil {
+		t.Fatalf("ParseRoutes returned error: %v", err)
+	}
+	want := []string{"10.0.0.0/8", "192.168.1.5/32", "2001:db8::/32"}
+	if !slices.Equal(routes, want) {
+		t.Fatalf("routes = %q, want %q", routes, want)
+	}
+
+	for _, bad := range []string{"10.0.0.0/33", "intranet", "fe80::1%eth0"} {
+		if _, err := ParseRoutes(bad); err == nil {
+			t.Fatalf("ParseRoutes(%q) accepted an invalid route", bad)
+		}
+	}
+}
+
+// runSplitScript runs the wrapper of conn with env around a vpnc-script
+// that prints its environment, and returns the variables that script saw.
+func runSplitScript(t *testing.T, conn *models.Connection, env ...string) map[string]string {
+	t.Helper()
+	dir := t.TempDir()
+	inner := filepath.Join(dir, "vpnc script")
+	if err := os.WriteFile(inner, []byte("#!/bin/sh\nenv\n"), 0o755); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	wrapper := filepath.Join(dir, "wrapper.sh")
+	if err := os.WriteFile(wrapper, []byte(SplitScript(conn, inner)), 0o755); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+
+	cmd := exec.Command("/bin/sh", wrapper)
+	cmd.Env = env
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("wrapper failed: %v", err)
+	}
+	vars := make(map[string]string)
+	for _, line := range strings.Split(string(out), "\n") {
+		if name, value, ok := strings.Cut(line, "="); ok {
+			vars[name] = value
+		}
+	}
+	return vars
+}
+
+func TestSplitScriptAppendsToServerRoutes(t *testing.T) {
+	conn := &models.Connection{
+		ID:            "work",
+		IncludeRoutes: []string{"10.20.0.0/16", "2001:db8::/32"},
+		ExcludeRoutes: []string{"192.168.1.0/24"},
+	}
+	vars := runSplitScript(t, conn,
+		"reason=connect",
+		"CISCO_SPLIT_INC=1",
+		"CISCO_SPLIT_INC_0_ADDR=10.0.0.0",
+	)
+
+	for name, want := range map[string]string{
+		"CISCO_SPLIT_INC":           "2",
+		"CISCO_SPLIT_INC_0_ADDR":    "10.0.0.0",
+		"CISCO_SPLIT_INC_1_ADDR":    "10.20.0.0",
+		"CISCO_SPLIT_INC_1_MASK":    "255.255.0.0",
+		"CISCO_SPLIT_INC_1_MASKLEN": "16",
+		"CISCO_SPLIT_EXC":           "1",
+		"CISCO_SPLIT_EXC_0_ADDR":    "192.168.1.0",
+		"CISCO_SPLIT_EXC_0_MASK":    "255.255.255.0",
+	} {
+		if vars[name] != want {
+			t.Fatalf("%s = %q, want %q", name, vars[name], want)
+		}
+	}
+	if _, ok := vars["CISCO_IPV6_SPLIT_INC"]; ok {
+		t.Fatal("IPv6 include added to a full IPv6 tunnel")
+	}
+}
+
+func TestSplitScriptNoDefaultRoute(t *testing.T) {
+	conn := &models.Connection{
+		ID:             "work",
+		IncludeRoutes:  []string{"2001:db8::/32"},
+		NoDefaultRoute: true,
+	}
+	vars := runSplitScript(t, conn, "reason=connect")
+
+	for name, want := range map[string]string{
+		"CISCO_SPLIT_INC":                "0",
+		"CISCO_IPV6_SPLIT_INC":           "1",
+		"CISCO_IPV6_SPLIT_INC_0_ADDR":    "2001:db8::",
+		"CISCO_IPV6_SPLIT_INC_0_MASKLEN": "32",
+	} {
+		if vars[name] != want {
+			t.Fatalf("%s = %q, want %q", name, vars[name], want)
+		}
+	}
+}
+
+func stubSplitScriptDir(t *testing.T) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "run")
+	orig := splitScriptDir
+	splitScriptDir = dir
+	t.Cleanup(func() { splitScriptDir = orig })
+	return dir
+}
+
+func TestWriteSplitScriptReplacesPlantedSymlink(t *testing.T) {
+	dir := stubSplitScriptDir(t)
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(t.TempDir(), "target")
+	if err := os.WriteFile(target, []byte("keep"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	conn := &models.Connection{ID: "work", IncludeRoutes: []string{"10.20.0.0/16"}}
+	if err := os.Symlink(target, SplitScriptPath(conn.ID)); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := WriteSplitScript(conn, "/bin/true")
+	if err != nil {
+		t.Fatalf("WriteSplitScript returned error: %v", err)
+	}
+	info, err := os.Lstat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Mode().IsRegular() || info.Mode().Perm() != 0o755 {
+		t.Fatalf("wrapper mode = %s, want a regular 0755 file", info.Mode())
+	}
+	if data, _ := os.ReadFile(target); string(data) != "keep" {
+		t.Fatalf("symlink target was written through: %q", data)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("directory holds %d entries, want only the wrapper", len(entries))
+	}
+}
+
+func TestWriteSplitScriptRefusesWritableDir(t *testing.T) {
+	dir := stubSplitScriptDir(t)
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(dir, 0o777); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := WriteSplitScript(&models.Connection{ID: "work", NoDefaultRoute: true}, "/bin/true"); err == nil {
+		t.Fatal("WriteSplitScript wrote into a world-writable directory")
+	}
+}
@@ -0,0 +1,60 @@
+package helpers
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// resolverMarker starts the /etc/resolver files lazyopenconnect writes, so
+// cleanup leaves files it did not write alone.
+const resolverMarker = "# Written by lazyopenconnect"
+
+// ParseDNSDomain checks a split DNS domain and returns it lowercased,
+// without a trailing dot. A leading "~" marks a routing-only domain.
+func ParseDNSDomain(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(s), "."))
+	name := strings.TrimPrefix(s, "~")
+	if name == "" || len(name) > 253 {
+		return "", fmt.Errorf("invalid DNS domain %q", s)
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return "", fmt.Errorf("invalid DNS domain %q", s)
+		}
+		for _, r := range label {
+			if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' && r != '_' {
+				return "", fmt.Errorf("invalid DNS domain %q", s)
+			}
+		}
+	}
+	return s, nil
+}
+
+// ParseDNSDomains parses domains separated by commas or spaces, dropping
+// duplicates.
+func ParseDNSDomains(s string) ([]string, error) {
+	return parseList(s, ParseDNSDomain)
+}
+
+// ParseDNSServers parses server addresses separated by commas or spaces,
+// dropping duplicates.
+func ParseDNSServers(s string) ([]string, error) {
+	return parseList(s, func(field string) (string, error) {
+		addr, err := netip.ParseAddr(field)
+		if err != nil {
+			return "", fmt.Errorf("invalid DNS server %q: want an IP address", field)
+		}
+		return addr.String(), nil
+	})
+}
+
+// RunSplitDNSSteps sends the split DNS domains of snap to its servers.
+func RunSplitDNSSteps(snap *NetworkSnapshot) []CleanupResult {
+	return runSteps(PlatformSplitDNSSteps(snap))
+}
+
+// RunSplitDNSCleanupSteps undoes what RunSplitDNSSteps did for snap.
+func RunSplitDNSCleanupSteps(snap *NetworkSnapshot) []CleanupResult {
+	return runSteps(PlatformSplitDNSCleanupSteps(snap))
+}
@@ -0,0 +1,68 @@
+//go:build darwin
+
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const resolverDir = "/etc/resolver"
+
+// PlatformSplitDNSSteps writes an /etc/resolver file for each split DNS
+// domain of snap, which macOS asks instead of the system resolvers for
+// names in that domain.
+func PlatformSplitDNSSteps(snap *NetworkSnapshot) []CleanupStep {
+	servers := snap.SplitDNSServers
+
+	var steps []CleanupStep
+	for _, raw := range snap.SplitDNSDomains {
+		// The domain names a file, so it must not reach the path unchecked.
+		domain, err := ParseDNSDomain(raw)
+		path := filepath.Join(resolverDir, strings.TrimPrefix(domain, "~"))
+		steps = append(steps, CleanupStep{
+			Name: "Routing DNS for " + strings.TrimPrefix(raw, "~"),
+			Cmd:  "write " + path,
+			Fn: func() error {
+				if err != nil {
+					return err
+				}
+				if len(servers) == 0 {
+					return errors.New("no DNS servers set for split DNS")
+				}
+				return writeResolverFile(path, servers)
+			},
+		})
+	}
+
+	steps = append(steps, CleanupStep{
+		Name: "Flushing DNS cache",
+		Cmd:  "dscacheutil -flushcache && killall -HUP mDNSResponder",
+		Fn: func() error {
+			runCmd("dscacheutil", "-flushcache")
+			return runCmd("killall", "-HUP", "mDNSResponder")
+		},
+	})
+	return steps
+}
+
+// writeResolverFile writes a resolver file pointing at servers, unless a
+// file lazyopenconnect did not write is already in its place.
+func writeResolverFile(path string, servers []string) error {
+	if data, err := os.ReadFile(path); err == nil && !strings.HasPrefix(string(data), resolverMarker) {
+		return fmt.Errorf("%s exists and was not written by lazyopenconnect", path)
+	}
+	if err := os.MkdirAll(resolverDir, 0o755); err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(resolverMarker + "\n")
+	for _, s := range servers {
+		sb.WriteString("nameserver " + s + "\n")
+	}
+	return os.WriteFile(path, []byte(sb.String()), 0o644)
+}
@@ -0,0 +1,68 @@
+//go:build linux
+
+package helpers
+
+import (
+	"errors"
+	"strings"
+)
+
+// PlatformSplitDNSSteps hands the split DNS domains of snap to
+// systemd-resolved on the tunnel link and takes the link off the default
+// DNS route, so other names keep resolving locally.
+func PlatformSplitDNSSteps(snap *NetworkSnapshot) []CleanupStep {
+	tunnelIface := snap.TunnelInterface
+	if tunnelIface == "" {
+		tunnelIface = "tun0"
+	}
+	domains := strings.Join(snap.SplitDNSDomains, " ")
+
+	if !isSystemdResolved() {
+		return []CleanupStep{{
+			Name: "Routing DNS for " + domains,
+			Cmd:  "resolvectl domain " + tunnelIface + " " + domains,
+			Fn: func() error {
+				return errors.New("split DNS needs systemd-resolved")
+			},
+		}}
+	}
+
+	var steps []CleanupStep
+	if len(snap.SplitDNSServers) > 0 {
+		servers := strings.Join(snap.SplitDNSServers, " ")
+		args := append([]string{"dns", tunnelIface}, snap.SplitDNSServers...)
+		steps = append(steps, CleanupStep{
+			Name: "Setting tunnel DNS to " + servers,
+			Cmd:  "resolvectl dns " + tunnelIface + " " + servers,
+			Fn: func() error {
+				return runCmd("resolvectl", args...)
+			},
+		})
+	}
+
+	args := append([]string{"domain", tunnelIface}, snap.SplitDNSDomains...)
+	steps = append(steps,
+		CleanupStep{
+			Name: "Routing DNS for " + domains,
+			Cmd:  "resolvectl domain " + tunnelIface + " " + domains,
+			Fn: func() error {
+				return runCmd("resolvectl", args...)
+			},
+		},
+		CleanupStep{
+			Name: "Keeping other DNS queries local",
+			Cmd:  "resolvectl default-route " + tunnelIface + " false",
+			Fn: func() error {
+				return runCmd("resolvectl", "default-route", tunnelIface, "false")
+			},
+		},
+		CleanupStep{
+			Name: "Flushing DNS cache",
+			Cmd:  "resolvectl flush-caches",
+			Fn: func() error {
+				return runCmd("resolvectl", "flush-caches")
+			},
+		},
+	)
+	return steps
+}
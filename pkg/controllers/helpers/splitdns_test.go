@@ -0,0 +1,35 @@
+package helpers
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseDNSDomains(t *testing.T) {
+	domains, err := ParseDNSDomains("Corp.Example., ~lab.corp.example corp.example")
+	if err != nil {
+		t.Fatalf("ParseDNSDomains returned error: %v", err)
+	}
+	if want := []string{"corp.example", "~lab.corp.example"}; !slices.Equal(domains, want) {
+		t.Fatalf("domains = %q, want %q", domains, want)
+	}
+
+	for _, bad := range []string{"~", "../etc", "corp..example", "-corp.example", "corp/example"} {
+		if _, err := ParseDNSDomains(bad); err == nil {
+			t.Fatalf("ParseDNSDomains(%q) accepted an invalid domain", bad)
+		}
+	}
+}
+
+func TestParseDNSServers(t *testing.T) {
+	servers, err := ParseDNSServers("10.0.0.53,2001:db8::53")
+	if err != nil {
+		t.Fatalf("ParseDNSServers returned error: %v", err)
+	}
+	if want := []string{"10.0.0.53", "2001:db8::53"}; !slices.Equal(servers, want) {
+		t.Fatalf("servers = %q, want %q", servers, want)
+	}
+	if _, err := ParseDNSServers("dns.corp.example"); err == nil {
+		t.Fatal("ParseDNSServers accepted a host name")
+	}
+}
@@ -0,0 +1,75 @@
+package helpers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// TrafficCounters are the cumulative counters of a network interface.
+type TrafficCounters struct {
+	RxBytes   uint64
+	TxBytes   uint64
+	RxPackets uint64
+	TxPackets uint64
+	RxErrors  uint64
+	TxErrors  uint64
+}
+
+// readSysfsCounters reads a Linux statistics directory such as
+// /sys/class/net/tun0/statistics.
+func readSysfsCounters(dir string) (TrafficCounters, error) {
+	var c TrafficCounters
+	for name, dst := range map[string]*uint64{
+		"rx_bytes":   &c.RxBytes,
+		"tx_bytes":   &c.TxBytes,
+		"rx_packets": &c.RxPackets,
+		"tx_packets": &c.TxPackets,
+		"rx_errors":  &c.RxErrors,
+		"tx_errors":  &c.TxErrors,
+	} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return TrafficCounters{}, err
+		}
+		v, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+		if err != nil {
+			return TrafficCounters{}, fmt.Errorf("%s: %w", name, err)
+		}
+		*dst = v
+	}
+	return c, nil
+}
+
+// parseNetstatIB reads the link row of iface from `netstat -ib` output.
+// The address column is blank for utun devices, so the counters are taken
+// from the right: Ipkts Ierrs Ibytes Opkts Oerrs Obytes Coll.
+func parseNetstatIB(out, iface string) (TrafficCounters, error) {
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 9 || fields[0] != iface || !strings.HasPrefix(fields[2], "<Link#") {
+			continue
+		}
+
+		tail := fields[len(fields)-7:]
+		values := make([]uint64, 6)
+		for i := range values {
+			v, err := strconv.ParseUint(tail[i], 10, 64)
+			if err != nil {
+				return TrafficCounters{}, fmt.Errorf("netstat column %d: %w", i, err)
+			}
+			values[i] = v
+		}
+		return TrafficCounters{
+			RxPackets: values[0],
+			RxErrors:  values[1],
+			RxBytes:   values[2],
+			TxPackets: values[3],
+			TxErrors:  values[4],
+			TxBytes:   values[5],
+		}, nil
+	}
+	return TrafficCounters{}, fmt.Errorf("interface %s not found", iface)
+}
@@ -0,0 +1,14 @@
+//go:build darwin
+
+package helpers
+
+import "os/exec"
+
+// ReadTrafficCounters returns the counters of iface.
+func ReadTrafficCounters(iface string) (TrafficCounters, error) {
+	out, err := exec.Command("netstat", "-ib", "-I", iface).Output()
+	if err != nil {
+		return TrafficCounters{}, err
+	}
+	return parseNetstatIB(string(out), iface)
+}
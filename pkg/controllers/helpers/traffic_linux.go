@@ -0,0 +1,10 @@
+//go:build linux
+
+package helpers
+
+import "path/filepath"
+
+// ReadTrafficCounters returns the counters of iface.
+func ReadTrafficCounters(iface string) (TrafficCounters, error) {
+	return readSysfsCounters(filepath.Join("/sys/class/net", iface, "statistics"))
+}
@@ -0,0 +1,58 @@
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadSysfsCounters(t *testing.T) {
+	dir := t.TempDir()
+	for name, value := range map[string]string{
+		"rx_bytes":   "1048576\n",
+		"tx_bytes":   "2048\n",
+		"rx_packets": "900\n",
+		"tx_packets": "40\n",
+		"rx_errors":  "0\n",
+		"tx_errors":  "3\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(value), 0o644); err != nil {
+			t.Fatalf("WriteFile returned error: %v", err)
+		}
+	}
+
+	got, err := readSysfsCounters(dir)
+	if err != nil {
+		t.Fatalf("readSysfsCounters returned error: %v", err)
+	}
+	want := TrafficCounters{RxBytes: 1048576, TxBytes: 2048, RxPackets: 900, TxPackets: 40, TxErrors: 3}
+	if got != want {
+		t.Fatalf("counters = %+v, want %+v", got, want)
+	}
+}
+
+func TestReadSysfsCountersMissingInterface(t *testing.T) {
+	if _, err := readSysfsCounters(filepath.Join(t.TempDir(), "tun9")); err == nil {
+		t.Fatal("readSysfsCounters succeeded for a missing interface")
+	}
+}
+
+func TestParseNetstatIB(t *testing.T) {
+	out := `Name       Mtu   Network       Address            Ipkts Ierrs     Ibytes    Opkts Oerrs     Obytes  Coll
+utun4      1390  <Link#21>                          5120     1    6815744     3072     0     409600     0
+utun4      1390  10.8.0/24     10.8.0.2             5120     -    6815744     3072     -     409600     -
+`
+
+	got, err := parseNetstatIB(out, "utun4")
+	if err != nil {
+		t.Fatalf("parseNetstatIB returned error: %v", err)
+	}
+	want := TrafficCounters{RxPackets: 5120, RxErrors: 1, RxBytes: 6815744, TxPackets: 3072, TxBytes: 409600}
+	if got != want {
+		t.Fatalf("counters = %+v, want %+v", got, want)
+	}
+
+	if _, err := parseNetstatIB(out, "utun5"); err == nil {
+		t.Fatal("parseNetstatIB found an interface that is not listed")
+	}
+}
@@ -0,0 +1,52 @@
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// listenFDsStart is the first file descriptor systemd passes to a
+// socket-activated service (SD_LISTEN_FDS_START).
+const listenFDsStart = 3
+
+// listenFDs returns how many sockets systemd passed to this process.
+func listenFDs() int {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return 0
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// activationListener returns the socket systemd opened on daemon.sock, or
+// nil when the daemon was started any other way. The environment is
+// cleared so openconnect does not mistake itself for activated.
+func activationListener() (net.Listener, error) {
+	n := listenFDs()
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+	if n == 0 {
+		return nil, nil
+	}
+	if n > 1 {
+		return nil, fmt.Errorf("expected one socket from systemd, got %d", n)
+	}
+
+	syscall.CloseOnExec(listenFDsStart)
+	f := os.NewFile(uintptr(listenFDsStart), "daemon.sock")
+	defer f.Close()
+
+	listener, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("inherited socket: %w", err)
+	}
+	return listener, nil
+}
@@ -0,0 +1,43 @@
+package daemon
+
+import (
+	"os"
+	"strconv"
+	"testing"
+)
+
+func TestListenFDs(t *testing.T) {
+	pid := strconv.Itoa(os.Getpid())
+
+	tests := []struct {
+		name      string
+		listenPID string
+		listenFDs string
+		want      int
+	}{
+		{name: "not activated", want: 0},
+		{name: "activated", listenPID: pid, listenFDs: "1", want: 1},
+		{name: "meant for another process", listenPID: "1", listenFDs: "1", want: 0},
+		{name: "malformed count", listenPID: pid, listenFDs: "x", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LISTEN_PID", tt.listenPID)
+			t.Setenv("LISTEN_FDS", tt.listenFDs)
+			if got := listenFDs(); got != tt.want {
+				t.Fatalf("listenFDs() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestActivationListenerWithoutSystemd(t *testing.T) {
+	t.Setenv("LISTEN_PID", "")
+	t.Setenv("LISTEN_FDS", "")
+
+	listener, err := activationListener()
+	if err != nil || listener != nil {
+		t.Fatalf("activationListener() = %v, %v, want nil, nil", listener, err)
+	}
+}
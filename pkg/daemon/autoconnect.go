@@ -0,0 +1,68 @@
+package daemon
+
+import (
+	"github.com/Nybkox/lazyopenconnect/pkg/controllers/helpers"
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+)
+
+// loadConfig reads the config from disk, so the daemon knows its
+// connections and settings before any client sends config_update.
+func (d *Daemon) loadConfig() {
+	cfg, err := helpers.LoadConfig()
+	if err != nil {
+		d.logger.Error("failed to load config, waiting for a client to send it", "err", err)
+		return
+	}
+
+	cfg = sanitizeConfig(*cfg)
+	d.stateMu.Lock()
+	d.state.Config = cfg
+	d.stateMu.Unlock()
+	d.logger.Info("config loaded", "connections", len(cfg.Connections))
+}
+
+// autoConnectTargets returns the connections to bring up at startup, in
+// config order.
+func autoConnectTargets(cfg *models.Config) []models.Connection {
+	var targets []models.Connection
+	for _, conn := range cfg.Connections {
+		if conn.AutoConnect {
+			targets = append(targets, conn)
+		}
+	}
+	return targets
+}
+
+// autoConnect starts every connection marked autoConnect. Without a client
+// attached, the outcome only shows up in daemon.log; reconnects follow the
+// configured settings like for any other tunnel.
+func (d *Daemon) autoConnect() {
+	d.stateMu.RLock()
+	targets := autoConnectTargets(d.state.Config)
+	d.stateMu.RUnlock()
+
+	for _, conn := range targets {
+		d.logger.Info("auto-connecting", "conn_id", conn.ID, "name", conn.Name, "host", conn.Host)
+		d.handleConnect(nil, ConnectCmd{
+			Type:     "connect",
+			ConnID:   conn.ID,
+			Password: d.autoConnectPassword(conn),
+		})
+	}
+}
+
+// autoConnectPassword falls back to the keychain for connections without a
+// password file. A daemon started at boot usually cannot reach it, in which
+// case openconnect prompts and the prompt waits for a client.
+func (d *Daemon) autoConnectPassword(conn models.Connection) string {
+	if conn.PasswordFile != "" || !conn.HasPassword {
+		return ""
+	}
+	password, err := helpers.GetPassword(conn.ID)
+	if err != nil {
+		d.logger.Warn("keychain not available to the daemon, set a password file for auto-connect",
+			"conn_id", conn.ID, "err", err)
+		return ""
+	}
+	return password
+}
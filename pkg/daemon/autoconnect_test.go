@@ -0,0 +1,75 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+)
+
+func TestLoadConfigReadsConfigFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SUDO_USER", "")
+	dir := filepath.Join(home, ".config", "lazyopenconnect")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("MkdirAll returned error: %v", err)
+	}
+	data := `{"connections":[{"id":"work","name":"Work","host":"vpn.example.com","autoConnect":true}],"settings":{"reconnect":true}}`
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(data), 0o600); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+
+	d := newTestDaemon()
+	d.loadConfig()
+
+	if len(d.state.Config.Connections) != 1 || !d.state.Config.Connections[0].AutoConnect {
+		t.Fatalf("Connections = %+v, want the auto-connect connection", d.state.Config.Connections)
+	}
+	assertBool(t, "Reconnect", d.state.Config.Settings.Reconnect, true)
+}
+
+func TestLoadConfigKeepsConfigOnError(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SUDO_USER", "")
+	dir := filepath.Join(home, ".config", "lazyopenconnect")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("MkdirAll returned error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte("{broken"), 0o600); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+
+	d := newTestDaemon()
+	original := d.state.Config
+	d.loadConfig()
+
+	if d.state.Config != original {
+		t.Fatal("config should not change when the file cannot be parsed")
+	}
+}
+
+func TestAutoConnectTargets(t *testing.T) {
+	cfg := &models.Config{Connections: []models.Connection{
+		{ID: "a", AutoConnect: true},
+		{ID: "b"},
+		{ID: "c", AutoConnect: true},
+	}}
+
+	targets := autoConnectTargets(cfg)
+
+	if len(targets) != 2 || targets[0].ID != "a" || targets[1].ID != "c" {
+		t.Fatalf("targets = %+v, want a and c", targets)
+	}
+}
+
+func TestAutoConnectPasswordSkipsKeychainWithPasswordFile(t *testing.T) {
+	d := newTestDaemon()
+
+	conn := models.Connection{ID: "work", HasPassword: true, PasswordFile: "/etc/lazyopenconnect/work.pass"}
+	if got := d.autoConnectPassword(conn); got != "" {
+		t.Fatalf("password = %q, want empty so the daemon reads the file", got)
+	}
+}
@@ -7,8 +7,12 @@ import (
 	"net"
 	"os"
 	"os/exec"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/controllers/helpers"
 )
 
 type DaemonConnStatus int
@@ -21,13 +25,21 @@ const (
 
 var ErrDaemonPrivilegeRequired = errors.New("daemon restart requires sudo")
 
+// VersionMismatchError reports a daemon speaking another protocol version.
+// With Tunnels at zero the daemon is already shutting down; otherwise it
+// keeps running until a client requests the shutdown.
 type VersionMismatchError struct {
-	ClientVersion string
-	DaemonVersion string
+	ClientVersion  string
+	DaemonVersion  string
+	ClientProtocol int
+	DaemonProtocol int
+	Tunnels        int
+	Clients        int
 }
 
 func (e *VersionMismatchError) Error() string {
-	return fmt.Sprintf("daemon version mismatch (client: %s, daemon: %s)", e.ClientVersion, e.DaemonVersion)
+	return fmt.Sprintf("daemon protocol mismatch (client: %s protocol %d, daemon: %s protocol %d)",
+		e.ClientVersion, e.ClientProtocol, e.DaemonVersion, e.DaemonProtocol)
 }
 
 type HelloResult struct {
@@ -58,6 +70,63 @@ func DaemonStatus(socketPath string) DaemonConnStatus {
 	return DaemonReachable
 }
 
+// DaemonFiles describes the daemon's runtime files on disk.
+type DaemonFiles struct {
+	SocketPath   string
+	SocketExists bool
+	PidPath      string
+	Pid          int
+	PidAlive     bool
+	LockPath     string
+	LockHeld     bool
+}
+
+// Stale reports whether files were left behind by a daemon that is gone.
+// It is only meaningful when DaemonStatus reports DaemonNotRunning.
+func (f DaemonFiles) Stale() bool {
+	return f.SocketExists || (f.Pid != 0 && !f.PidAlive) || f.LockHeld
+}
+
+// InspectFiles reports which daemon runtime files exist and whether the
+// process and lock they point at are still alive.
+func InspectFiles(socketPath string) DaemonFiles {
+	files := DaemonFiles{SocketPath: socketPath}
+	if _, err := os.Stat(socketPath); err == nil {
+		files.SocketExists = true
+	}
+
+	if path, err := pidPath(); err == nil {
+		files.PidPath = path
+		if data, err := os.ReadFile(path); err == nil {
+			files.Pid, _ = strconv.Atoi(strings.TrimSpace(string(data)))
+			files.PidAlive = files.Pid > 0 && processAlive(files.Pid)
+		}
+	}
+
+	if path, err := daemonLockPath(); err == nil {
+		files.LockPath = path
+		if f, err := os.Open(path); err == nil {
+			if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err == syscall.EWOULDBLOCK {
+				files.LockHeld = true
+			} else if err == nil {
+				_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+			}
+			f.Close()
+		}
+	}
+
+	return files
+}
+
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
 func WaitForDaemonStart(socketPath string, timeout time.Duration) DaemonConnStatus {
 	deadline := time.Now().Add(timeout)
 	for time.Now().Before(deadline) {
@@ -92,7 +161,12 @@ func ConnectAndHello(socketPath, clientVersion string, timeout time.Duration) (H
 		}
 
 		reader := bufio.NewReader(conn)
-		if err := WriteMsg(conn, HelloCmd{Type: "hello", Version: clientVersion}); err != nil {
+		if err := WriteMsg(conn, HelloCmd{
+			Type:         "hello",
+			Version:      clientVersion,
+			Protocol:     ProtocolVersion,
+			Capabilities: Capabilities(),
+		}); err != nil {
 			_ = conn.Close()
 			return HelloResult{}, err
 		}
@@ -114,7 +188,14 @@ func ConnectAndHello(socketPath, clientVersion string, timeout time.Duration) (H
 		}
 		if !hello.Compatible {
 			_ = conn.Close()
-			return HelloResult{}, &VersionMismatchError{ClientVersion: clientVersion, DaemonVersion: hello.Version}
+			return HelloResult{}, &VersionMismatchError{
+				ClientVersion:  clientVersion,
+				DaemonVersion:  hello.Version,
+				ClientProtocol: ProtocolVersion,
+				DaemonProtocol: hello.Protocol,
+				Tunnels:        hello.Tunnels,
+				Clients:        hello.Clients,
+			}
 		}
 
 		return HelloResult{Conn: conn, Reader: reader, Hello: hello}, nil
@@ -123,7 +204,14 @@ func ConnectAndHello(socketPath, clientVersion string, timeout time.Duration) (H
 	return HelloResult{}, errors.New("timeout connecting to daemon")
 }
 
+// SpawnDaemon starts the daemon: through systemd when the service is
+// installed, otherwise as a detached process, with sudo unless already
+// root.
 func SpawnDaemon(cfg SpawnConfig) error {
+	if helpers.ServiceInstalled() {
+		return startService(cfg)
+	}
+
 	exe, err := os.Executable()
 	if err != nil {
 		return err
@@ -159,6 +247,26 @@ func SpawnDaemon(cfg SpawnConfig) error {
 	return cmd.Run()
 }
 
+// startService starts the installed unit, so the daemon runs under
+// systemd just like after boot. Debug logging follows the unit's flags.
+func startService(cfg SpawnConfig) error {
+	args := []string{"systemctl", "start", helpers.ServiceUnit}
+	if os.Geteuid() == 0 {
+		return exec.Command(args[0], args[1:]...).Run()
+	}
+
+	if !cfg.Interactive {
+		return ErrDaemonPrivilegeRequired
+	}
+
+	fmt.Fprintln(cfg.Stdout, "Starting lazyopenconnect service (sudo required)...")
+	cmd := exec.Command("sudo", args...)
+	cmd.Stdin = cfg.Stdin
+	cmd.Stdout = cfg.Stdout
+	cmd.Stderr = cfg.Stderr
+	return cmd.Run()
+}
+
 func RequestShutdown(socketPath string) error {
 	conn, err := net.Dial("unix", socketPath)
 	if err != nil {
@@ -0,0 +1,177 @@
+package daemon
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// clientWriteTimeout bounds how long a stuck client can hold up a
+// broadcast before it is dropped.
+const clientWriteTimeout = 2 * time.Second
+
+// client is one connection to the daemon. Every client receives broadcasts;
+// only the owner receives prompts and may answer them.
+type client struct {
+	conn    net.Conn
+	writeMu sync.Mutex
+}
+
+func (c *client) send(msg any) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	_ = c.conn.SetWriteDeadline(time.Now().Add(clientWriteTimeout))
+	return WriteMsg(c.conn, msg)
+}
+
+func (d *Daemon) addClient(conn net.Conn) *client {
+	c := &client{conn: conn}
+
+	d.clientMu.Lock()
+	if d.clients == nil {
+		d.clients = make(map[*client]struct{})
+	}
+	d.clients[c] = struct{}{}
+	count := len(d.clients)
+	d.clientMu.Unlock()
+
+	d.logger.Debug("client added", "clients", count)
+	return c
+}
+
+func (d *Daemon) removeClient(c *client) {
+	d.clientMu.Lock()
+	if _, ok := d.clients[c]; !ok {
+		d.clientMu.Unlock()
+		return
+	}
+	delete(d.clients, c)
+	ownerLeft := d.owner == c
+	if ownerLeft {
+		d.owner = nil
+	}
+	count := len(d.clients)
+	d.clientMu.Unlock()
+
+	_ = c.conn.Close()
+	d.logger.Debug("client removed", "clients", count)
+
+	if ownerLeft {
+		d.logger.Info("owner disconnected, prompts wait for a new owner")
+		d.broadcast(OwnershipMsg{Type: "ownership", Owner: false, Free: true, Reason: "the owning client detached"})
+	}
+}
+
+// broadcast sends msg to every client. Clients that cannot keep up are
+// dropped.
+func (d *Daemon) broadcast(msg any) {
+	d.clientMu.Lock()
+	clients := make([]*client, 0, len(d.clients))
+	for c := range d.clients {
+		clients = append(clients, c)
+	}
+	d.clientMu.Unlock()
+
+	for _, c := range clients {
+		if err := c.send(msg); err != nil {
+			d.logger.Warn("failed to send message, dropping client", "err", err)
+			d.removeClient(c)
+		}
+	}
+}
+
+// reply answers the client that sent a request. Requests the daemon makes
+// on its own behalf have no client; their replies are broadcast.
+func (d *Daemon) reply(c *client, msg any) {
+	if c == nil {
+		d.broadcast(msg)
+		return
+	}
+	if err := c.send(msg); err != nil {
+		d.logger.Warn("failed to send reply, dropping client", "err", err)
+		d.removeClient(c)
+	}
+}
+
+// ack tells c that the request id was accepted and is still running.
+// Requests without an ID are not acknowledged.
+func (d *Daemon) ack(c *client, id string) {
+	if id != "" {
+		d.reply(c, AckMsg{Type: "ack", ID: id})
+	}
+}
+
+// succeed reports that the request id finished.
+func (d *Daemon) succeed(c *client, id string) {
+	if id != "" {
+		d.reply(c, ResultMsg{Type: "result", ID: id, OK: true})
+	}
+}
+
+// fail reports that a request failed: as its result when it carried an ID,
+// otherwise as an error event for clients that predate request IDs.
+func (d *Daemon) fail(c *client, id, connID, code, message string) {
+	if id != "" {
+		d.reply(c, ResultMsg{Type: "result", ID: id, Code: code, Message: message})
+		return
+	}
+	d.reply(c, ErrorMsg{Type: "error", ConnID: connID, Code: code, Message: message})
+}
+
+func (d *Daemon) isOwner(c *client) bool {
+	d.clientMu.Lock()
+	defer d.clientMu.Unlock()
+	return c != nil && d.owner == c
+}
+
+// sendToOwner delivers a prompt to the owning client. The session keeps
+// the prompt, so a client taking ownership later still gets to answer it.
+func (d *Daemon) sendToOwner(msg PromptMsg) {
+	d.clientMu.Lock()
+	owner := d.owner
+	d.clientMu.Unlock()
+
+	if owner == nil {
+		d.logger.Info("prompt waiting for an owner", "conn_id", msg.ConnID)
+		return
+	}
+	d.reply(owner, msg)
+}
+
+// handleTakeOwnership makes c the owner when nobody owns the session or
+// when force is set. The previous owner is told it lost ownership but stays
+// attached.
+func (d *Daemon) handleTakeOwnership(c *client, msg TakeOwnershipCmd) {
+	d.clientMu.Lock()
+	previous := d.owner
+	if previous != nil && previous != c && !msg.Force {
+		d.clientMu.Unlock()
+		d.reply(c, OwnershipMsg{Type: "ownership", Owner: false, Reason: "another client owns the session"})
+		if msg.ID != "" {
+			d.fail(c, msg.ID, "", "not_owner", "another client owns the session")
+		}
+		return
+	}
+	d.owner = c
+	d.clientMu.Unlock()
+
+	d.logger.Info("ownership taken", "forced", previous != nil && previous != c)
+	if previous != nil && previous != c {
+		d.reply(previous, OwnershipMsg{Type: "ownership", Owner: false, Reason: "taken over by another client"})
+	}
+	d.reply(c, OwnershipMsg{Type: "ownership", Owner: true})
+	d.succeed(c, msg.ID)
+
+	var prompts []PromptMsg
+	d.stateMu.RLock()
+	for _, s := range d.state.Sessions {
+		if s.status == StatusPrompting && s.prompt != nil {
+			prompts = append(prompts, *s.prompt)
+		}
+	}
+	d.stateMu.RUnlock()
+	for _, prompt := range prompts {
+		d.reply(c, prompt)
+	}
+}
@@ -0,0 +1,202 @@
+package daemon
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestBroadcastReachesEveryClient(t *testing.T) {
+	d := newTestDaemon()
+	_, first := attachTestClient(t, d)
+	_, second := attachTestClient(t, d)
+
+	go d.broadcast(DisconnectedMsg{Type: "disconnected"})
+
+	results := make(chan string, 2)
+	for _, conn := range []net.Conn{first, second} {
+		go func(conn net.Conn) {
+			_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+			msg, err := ReadMsg(bufio.NewReader(conn))
+			if err != nil {
+				results <- err.Error()
+				return
+			}
+			results <- msg.Type
+		}(conn)
+	}
+	for range 2 {
+		if got := <-results; got != "disconnected" {
+			t.Fatalf("got %q, want %q", got, "disconnected")
+		}
+	}
+}
+
+func TestBroadcastDropsDeadClient(t *testing.T) {
+	d := newTestDaemon()
+	_, conn := attachTestClient(t, d)
+	_ = conn.Close()
+
+	d.broadcast(DisconnectedMsg{Type: "disconnected"})
+
+	d.clientMu.Lock()
+	count := len(d.clients)
+	d.clientMu.Unlock()
+	if count != 0 {
+		t.Fatalf("clients = %d, want 0", count)
+	}
+}
+
+func TestPromptGoesToOwnerOnly(t *testing.T) {
+	d := newTestDaemon()
+	owner, ownerConn := attachTestClient(t, d)
+	_, otherConn := attachTestClient(t, d)
+	d.owner = owner
+	s := &session{connID: "work"}
+	d.state.Sessions["work"] = s
+
+	go d.sendPrompt(s, "Password:")
+
+	msg := readTestMsg(t, ownerConn)
+	var prompt PromptMsg
+	if err := msg.Decode(&prompt); err != nil {
+		t.Fatalf("Decode returned error: %v", err)
+	}
+	assertString(t, "Type", prompt.Type, "prompt")
+	assertString(t, "ConnID", prompt.ConnID, "work")
+	assertBool(t, "IsPassword", prompt.IsPassword, true)
+
+	// Other clients may see the prompting state, never the prompt itself,
+	// so read everything they get until the deadline.
+	_ = otherConn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	r := bufio.NewReader(otherConn)
+	for {
+		msg, err := ReadMsg(r)
+		if err != nil {
+			break
+		}
+		if msg.Type == "prompt" {
+			t.Fatal("non-owner received the prompt")
+		}
+	}
+}
+
+func TestInputFromNonOwnerRejected(t *testing.T) {
+	d := newTestDaemon()
+	owner, _ := attachTestClient(t, d)
+	other, otherConn := attachTestClient(t, d)
+	d.owner = owner
+
+	go d.handleInput(other, InputCmd{Type: "input", Value: "secret"})
+
+	msg := readTestMsg(t, otherConn)
+	var errMsg ErrorMsg
+	if err := msg.Decode(&errMsg); err != nil {
+		t.Fatalf("Decode returned error: %v", err)
+	}
+	assertString(t, "Code", errMsg.Code, "not_owner")
+}
+
+func TestInputWithIDFromNonOwnerFailsRequest(t *testing.T) {
+	d := newTestDaemon()
+	owner, _ := attachTestClient(t, d)
+	other, otherConn := attachTestClient(t, d)
+	d.owner = owner
+
+	go d.handleInput(other, InputCmd{Type: "input", ID: "5", Value: "secret"})
+
+	msg := readTestMsg(t, otherConn)
+	assertString(t, "Type", msg.Type, "result")
+	var res ResultMsg
+	if err := msg.Decode(&res); err != nil {
+		t.Fatalf("Decode returned error: %v", err)
+	}
+	assertString(t, "ID", res.ID, "5")
+	assertBool(t, "OK", res.OK, false)
+	assertString(t, "Code", res.Code, "not_owner")
+}
+
+func TestTakeOwnershipRefusedWithoutForce(t *testing.T) {
+	d := newTestDaemon()
+	owner, _ := attachTestClient(t, d)
+	other, otherConn := attachTestClient(t, d)
+	d.owner = owner
+
+	go d.handleTakeOwnership(other, TakeOwnershipCmd{Type: "take_ownership"})
+
+	msg := readTestMsg(t, otherConn)
+	var ownership OwnershipMsg
+	if err := msg.Decode(&ownership); err != nil {
+		t.Fatalf("Decode returned error: %v", err)
+	}
+	assertBool(t, "Owner", ownership.Owner, false)
+	if !d.isOwner(owner) {
+		t.Fatal("ownership changed without force")
+	}
+}
+
+func TestForcedTakeOwnershipNotifiesPreviousOwnerAndResendsPrompt(t *testing.T) {
+	d := newTestDaemon()
+	owner, ownerConn := attachTestClient(t, d)
+	other, otherConn := attachTestClient(t, d)
+	d.owner = owner
+	d.state.Sessions["work"] = &session{
+		connID: "work",
+		status: StatusPrompting,
+		prompt: &PromptMsg{Type: "prompt", ConnID: "work", IsPassword: true},
+	}
+
+	go d.handleTakeOwnership(other, TakeOwnershipCmd{Type: "take_ownership", Force: true})
+
+	var lost OwnershipMsg
+	if err := readTestMsg(t, ownerConn).Decode(&lost); err != nil {
+		t.Fatalf("Decode returned error: %v", err)
+	}
+	assertBool(t, "previous Owner", lost.Owner, false)
+
+	var granted OwnershipMsg
+	if err := readTestMsg(t, otherConn).Decode(&granted); err != nil {
+		t.Fatalf("Decode returned error: %v", err)
+	}
+	assertBool(t, "new Owner", granted.Owner, true)
+
+	assertString(t, "resent Type", readTestMsg(t, otherConn).Type, "prompt")
+	if !d.isOwner(other) {
+		t.Fatal("ownership was not transferred")
+	}
+}
+
+func TestRemoveClientReleasesOwnership(t *testing.T) {
+	d := newTestDaemon()
+	owner, _ := attachTestClient(t, d)
+	d.owner = owner
+
+	d.removeClient(owner)
+
+	if d.isOwner(owner) {
+		t.Fatal("removed client still owns the session")
+	}
+}
+
+func TestRemoveOwnerTellsOthersOwnershipIsFree(t *testing.T) {
+	d := newTestDaemon()
+	owner, _ := attachTestClient(t, d)
+	other, otherConn := attachTestClient(t, d)
+	d.owner = owner
+
+	go d.removeClient(owner)
+
+	var ownership OwnershipMsg
+	if err := readTestMsg(t, otherConn).Decode(&ownership); err != nil {
+		t.Fatalf("Decode returned error: %v", err)
+	}
+	assertString(t, "Type", ownership.Type, "ownership")
+	assertBool(t, "Free", ownership.Free, true)
+
+	go d.handleTakeOwnership(other, TakeOwnershipCmd{Type: "take_ownership"})
+	if err := readTestMsg(t, otherConn).Decode(&ownership); err != nil {
+		t.Fatalf("Decode returned error: %v", err)
+	}
+	assertBool(t, "Owner", ownership.Owner, true)
+}
@@ -8,9 +8,11 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"reflect"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -31,28 +33,22 @@ const (
 	StatusQuitting     = models.StatusQuitting
 )
 
+// DaemonState is guarded by Daemon.stateMu. Sessions holds one entry per
+// running tunnel, keyed by connection ID.
 type DaemonState struct {
-	Status          ConnStatus
-	ActiveConnID    string
-	IP              string
-	PID             int
-	LogLineCount    int
-	Config          *models.Config
-	NetworkSnapshot *helpers.NetworkSnapshot
-	ExternalHost    string
-	ExternalPID     int
+	Sessions     map[string]*session
+	Config       *models.Config
+	ExternalHost string
+	ExternalPID  int
 }
 
 type Daemon struct {
 	listener    net.Listener
-	client      net.Conn
+	clients     map[*client]struct{}
+	owner       *client
 	clientMu    sync.Mutex
 	stateMu     sync.RWMutex
-	vpnMu       sync.Mutex
-	vpnLogMu    sync.Mutex
 	state       *DaemonState
-	vpnProcess  *VPNProcess
-	vpnLogFile  *os.File
 	version     string
 	shutdown    chan struct{}
 	socketPath  string
@@ -63,17 +59,26 @@ type Daemon struct {
 	logger      *slog.Logger
 	debug       bool
 	socketOwned bool
+	// historyPath is the session journal; empty disables it.
+	historyPath string
 
-	reconnectMu          sync.Mutex
-	reconnecting         bool
-	reconnectCancel      chan struct{}
-	disconnectRequested  bool
-	stoppingForReconnect bool
-	passwordCache        map[string]string
+	reconnectMu   sync.Mutex
+	passwordCache map[string]string
 
+	// cleanupSerial runs cleanups one at a time, so the last tunnel to go
+	// restores routes and DNS after the others only dropped their devices.
+	cleanupSerial  sync.Mutex
 	cleanupMu      sync.Mutex
 	cleanupRunning bool
 	shutdownOnce   sync.Once
+
+	// killSwitch is the spec of the rules in place, nil when there are
+	// none. killSwitchHeld keeps them after the guarded tunnels ended, and
+	// killSwitchOn mirrors killSwitch != nil for state messages.
+	killSwitchMu   sync.Mutex
+	killSwitch     *helpers.KillSwitch
+	killSwitchHeld bool
+	killSwitchOn   atomic.Bool
 }
 
 func SocketPath() (string, error) {
@@ -124,16 +129,22 @@ func New(debug bool) (*Daemon, error) {
 		return nil, err
 	}
 
+	historyFile, err := helpers.HistoryPath()
+	if err != nil {
+		return nil, err
+	}
+
 	return &Daemon{
 		state: &DaemonState{
-			Status: StatusDisconnected,
-			Config: models.NewConfig(),
+			Sessions: make(map[string]*session),
+			Config:   models.NewConfig(),
 		},
 		version:       version.Current,
 		shutdown:      make(chan struct{}),
 		socketPath:    socketPath,
 		pidPath:       pidFile,
 		lockPath:      lockFile,
+		historyPath:   historyFile,
 		debug:         debug,
 		passwordCache: make(map[string]string),
 	}, nil
@@ -157,8 +168,12 @@ func Run(debug bool) error {
 	}
 	defer d.releaseLock()
 
-	if err := d.killOldDaemon(); err != nil {
-		d.logger.Warn("failed to kill old daemon", "err", err)
+	// systemd runs one instance per socket, and its socket file outlives
+	// every daemon, so there is nothing to kill when activated.
+	if listenFDs() == 0 {
+		if err := d.killOldDaemon(); err != nil {
+			d.logger.Warn("failed to kill old daemon", "err", err)
+		}
 	}
 
 	if err := d.writePID(); err != nil {
@@ -166,6 +181,9 @@ func Run(debug bool) error {
 	}
 	defer d.removePID()
 
+	d.loadConfig()
+	d.restoreKillSwitch()
+
 	if err := d.listen(); err != nil {
 		return fmt.Errorf("failed to listen: %w", err)
 	}
@@ -173,7 +191,13 @@ func Run(debug bool) error {
 
 	d.logger.Info("daemon listening", "socket", d.socketPath, "pid", os.Getpid(), "version", d.version)
 
+	go d.autoConnect()
+
 	go d.wakeMonitor()
+	go d.statsMonitor()
+	go d.healthMonitor()
+	go d.timeoutMonitor()
+	go d.networkMonitor()
 	go d.externalVPNMonitor()
 
 	sigChan := make(chan os.Signal, 1)
@@ -318,7 +342,19 @@ func (d *Daemon) removePID() {
 	os.Remove(d.pidPath)
 }
 
+// listen takes the socket systemd passed in, or creates daemon.sock. An
+// inherited socket belongs to systemd and is left in place on exit.
 func (d *Daemon) listen() error {
+	listener, err := activationListener()
+	if err != nil {
+		return err
+	}
+	if listener != nil {
+		d.logger.Info("using socket passed by systemd")
+		d.listener = listener
+		return nil
+	}
+
 	if err := os.MkdirAll(filepath.Dir(d.socketPath), 0o755); err != nil {
 		return err
 	}
@@ -327,7 +363,7 @@ func (d *Daemon) listen() error {
 		return err
 	}
 
-	listener, err := net.Listen("unix", d.socketPath)
+	listener, err = net.Listen("unix", d.socketPath)
 	if err != nil {
 		return err
 	}
@@ -401,43 +437,22 @@ func (d *Daemon) acceptLoop() {
 }
 
 func (d *Daemon) handleNewClient(conn net.Conn) {
-	d.clientMu.Lock()
-	if d.client != nil {
-		d.logger.Info("kicking previous client")
-		WriteMsg(d.client, KickedMsg{Type: "kicked"})
-		d.client.Close()
-	}
-	d.client = conn
-	d.clientMu.Unlock()
-
-	go d.readLoop(conn)
+	c := d.addClient(conn)
+	go d.readLoop(c)
 }
 
-func (d *Daemon) readLoop(conn net.Conn) {
-	reader := bufio.NewReader(conn)
+func (d *Daemon) readLoop(c *client) {
+	reader := bufio.NewReader(c.conn)
 
 	for {
 		msg, err := ReadMsg(reader)
 		if err != nil {
 			d.logger.Debug("read error", "err", err)
-			d.clientMu.Lock()
-			if d.client == conn {
-				d.client.Close()
-				d.client = nil
-			}
-			d.clientMu.Unlock()
+			d.removeClient(c)
 			return
 		}
 
-		d.clientMu.Lock()
-		if d.client != conn {
-			d.logger.Debug("connection superseded, exiting readLoop")
-			d.clientMu.Unlock()
-			return
-		}
-		d.clientMu.Unlock()
-
-		d.handleMessage(msg)
+		d.handleMessage(c, msg)
 	}
 }
 
@@ -447,8 +462,20 @@ func decodeIncoming[T any](msg IncomingMsg) (T, error) {
 	return decoded, err
 }
 
-func (d *Daemon) handleMessage(msg IncomingMsg) {
-	d.logger.Debug("message received", "type", msg.Type)
+// rejectInvalid logs a message that failed to decode and, when it carried
+// a request ID, fails that request.
+func (d *Daemon) rejectInvalid(c *client, msg IncomingMsg, err error) {
+	d.logger.Warn("invalid "+msg.Type+" message", "err", err)
+	if msg.ID != "" {
+		d.fail(c, msg.ID, "", "invalid_message", err.Error())
+	}
+}
+
+// handleMessage handles a message from c. Replies go to c only; state
+// changes are broadcast to every client. Commands carrying an ID get a
+// result once they finish, preceded by an ack when they run long.
+func (d *Daemon) handleMessage(c *client, msg IncomingMsg) {
+	d.logger.Debug("message received", "type", msg.Type, "id", msg.ID)
 
 	switch msg.Type {
 	case "hello":
@@ -457,72 +484,126 @@ func (d *Daemon) handleMessage(msg IncomingMsg) {
 			d.logger.Warn("invalid hello message", "err", err)
 			return
 		}
-		d.handleHello(decoded)
+		d.handleHello(c, decoded)
 	case "get_state":
-		d.handleGetState()
+		d.handleGetState(c, msg.ID)
 	case "get_logs":
 		decoded, err := decodeIncoming[GetLogsCmd](msg)
 		if err != nil {
-			d.logger.Warn("invalid get_logs message", "err", err)
+			d.rejectInvalid(c, msg, err)
 			return
 		}
-		d.handleGetLogs(decoded)
+		d.handleGetLogs(c, decoded)
 	case "clear_logs":
-		d.handleClearLogs()
+		decoded, err := decodeIncoming[ClearLogsCmd](msg)
+		if err != nil {
+			d.rejectInvalid(c, msg, err)
+			return
+		}
+		d.handleClearLogs(c, decoded)
 	case "connect":
 		decoded, err := decodeIncoming[ConnectCmd](msg)
 		if err != nil {
-			d.logger.Warn("invalid connect message", "err", err)
+			d.rejectInvalid(c, msg, err)
 			return
 		}
-		d.handleConnect(decoded)
+		d.handleConnect(c, decoded)
 	case "disconnect":
-		d.handleDisconnect()
+		decoded, err := decodeIncoming[DisconnectCmd](msg)
+		if err != nil {
+			d.rejectInvalid(c, msg, err)
+			return
+		}
+		d.handleDisconnect(c, decoded)
 	case "input":
 		decoded, err := decodeIncoming[InputCmd](msg)
 		if err != nil {
-			d.logger.Warn("invalid input message", "err", err)
+			d.rejectInvalid(c, msg, err)
 			return
 		}
-		d.handleInput(decoded)
+		d.handleInput(c, decoded)
+	case "take_ownership":
+		decoded, err := decodeIncoming[TakeOwnershipCmd](msg)
+		if err != nil {
+			d.rejectInvalid(c, msg, err)
+			return
+		}
+		d.handleTakeOwnership(c, decoded)
 	case "config_update":
 		decoded, err := decodeIncoming[ConfigUpdateCmd](msg)
 		if err != nil {
-			d.logger.Warn("invalid config_update message", "err", err)
+			d.rejectInvalid(c, msg, err)
+			return
+		}
+		d.handleConfigUpdate(c, decoded)
+	case "killswitch_off":
+		decoded, err := decodeIncoming[KillSwitchOffCmd](msg)
+		if err != nil {
+			d.rejectInvalid(c, msg, err)
 			return
 		}
-		d.handleConfigUpdate(decoded)
+		d.handleKillSwitchOff(c, decoded)
 	case "cleanup":
 		d.cleanupMu.Lock()
 		if d.cleanupRunning {
 			d.cleanupMu.Unlock()
-			d.sendToClient(ErrorMsg{Type: "error", Code: "cleanup_running", Message: "cleanup already running"})
+			d.fail(c, msg.ID, "", "cleanup_running", "cleanup already running")
 			return
 		}
 		d.cleanupRunning = true
 		d.cleanupMu.Unlock()
-		go d.handleCleanup()
+		d.ack(c, msg.ID)
+		go d.handleCleanup(c, msg.ID)
 
 	case "shutdown":
+		d.succeed(c, msg.ID)
 		d.Shutdown()
 	default:
 		d.logger.Warn("unknown message type", "type", msg.Type)
+		if msg.ID != "" {
+			d.fail(c, msg.ID, "", "unknown_command", "unknown message type "+msg.Type)
+		}
 	}
 }
 
-func (d *Daemon) handleHello(msg HelloCmd) {
-	compatible := msg.Version == d.version
+// handleHello accepts any client speaking the same protocol version,
+// whatever its release. An incompatible daemon only shuts itself down when
+// no tunnel is up and no other client is attached, so a one-shot command
+// from another build cannot pull it from under a TUI; otherwise the client
+// has to ask the user and request the shutdown.
+func (d *Daemon) handleHello(c *client, msg HelloCmd) {
+	compatible := msg.Protocol == ProtocolVersion
+	tunnels := len(d.sessions())
 
-	d.logger.Info("hello from client", "client_version", msg.Version, "compatible", compatible)
+	d.clientMu.Lock()
+	others := len(d.clients)
+	if _, ok := d.clients[c]; ok {
+		others--
+	}
+	d.clientMu.Unlock()
 
-	d.sendToClient(HelloResponse{
-		Type:       "hello_response",
-		Version:    d.version,
-		Compatible: compatible,
+	d.logger.Info("hello from client",
+		"client_version", msg.Version,
+		"client_protocol", msg.Protocol,
+		"client_capabilities", msg.Capabilities,
+		"compatible", compatible,
+	)
+
+	d.reply(c, HelloResponse{
+		Type:         "hello_response",
+		Version:      d.version,
+		Protocol:     ProtocolVersion,
+		Capabilities: Capabilities(),
+		Compatible:   compatible,
+		Tunnels:      tunnels,
+		Clients:      others,
 	})
+	if !compatible && (tunnels > 0 || others > 0) {
+		d.logger.Warn("protocol mismatch, keeping daemon running", "tunnels", tunnels, "clients", others)
+	}
 
-	if !compatible {
-		d.logger.Warn("version mismatch, shutting down daemon")
+	if !compatible && tunnels == 0 && others == 0 {
+		d.logger.Warn("protocol mismatch and no tunnels up, shutting down daemon")
 		go func() {
 			time.Sleep(100 * time.Millisecond)
 			d.Shutdown()
@@ -530,38 +611,70 @@ func (d *Daemon) handleHello(msg HelloCmd) {
 	}
 }
 
-func (d *Daemon) handleGetState() {
-	d.stateMu.RLock()
-	status := d.state.Status
-	connID := d.state.ActiveConnID
-	ip := d.state.IP
-	pid := d.state.PID
-	logLineCount := d.state.LogLineCount
-	externalHost := d.state.ExternalHost
-	d.stateMu.RUnlock()
+// handleGetState answers with the state itself; it carries the request ID
+// in place of a separate result.
+func (d *Daemon) handleGetState(c *client, id string) {
+	msg := d.stateMsg()
+	msg.ID = id
+	d.reply(c, msg)
+}
 
-	d.logger.Debug("sending state", "status", status, "conn_id", connID)
-	d.sendToClient(StateMsg{
-		Type:          "state",
-		Status:        int(status),
-		ActiveConnID:  connID,
-		IP:            ip,
-		PID:           pid,
-		TotalLogLines: logLineCount,
-		ExternalHost:  externalHost,
-	})
+func (d *Daemon) broadcastState() {
+	d.broadcast(d.stateMsg())
 }
 
-func (d *Daemon) handleConfigUpdate(msg ConfigUpdateCmd) {
+func (d *Daemon) stateMsg() StateMsg {
+	sessions := d.sessions()
+
+	d.stateMu.RLock()
+	defer d.stateMu.RUnlock()
+
+	msg := StateMsg{
+		Type:         "state",
+		Sessions:     make([]SessionState, 0, len(sessions)),
+		ExternalHost: d.state.ExternalHost,
+		ExternalPID:  d.state.ExternalPID,
+		KillSwitch:   d.killSwitchOn.Load(),
+	}
+	for _, s := range sessions {
+		var deadline int64
+		if t := s.activeDeadline(); !t.IsZero() {
+			deadline = t.Unix()
+		}
+		var connectedSince int64
+		if !s.connectedAt.IsZero() {
+			connectedSince = s.connectedAt.Unix()
+		}
+		ss := SessionState{
+			ConnID:        s.connID,
+			Status:        int(s.status),
+			IP:            s.ip,
+			PID:           s.pid,
+			Tunnel:        s.tunnel,
+			TotalLogLines: s.logLines,
+			ConnectedAt:   connectedSince,
+			Deadline:      deadline,
+		}
+		if s.snapshot != nil {
+			ss.IncludeRoutes = s.snapshot.IncludeRoutes
+			ss.ExcludeRoutes = s.snapshot.ExcludeRoutes
+		}
+		msg.Sessions = append(msg.Sessions, ss)
+	}
+	return msg
+}
+
+func (d *Daemon) handleConfigUpdate(c *client, msg ConfigUpdateCmd) {
 	cfg := sanitizeConfig(msg.Config)
 	d.stateMu.Lock()
 	d.state.Config = cfg
 	d.stateMu.Unlock()
 	d.logger.Info("config updated", "connections", len(cfg.Connections))
+	d.succeed(c, msg.ID)
 }
 
 func sanitizeConfig(cfg models.Config) *models.Config {
-	if len(cfg.Connections) == 0 && cfg.Settings == (models.Settings{}) {
+	if len(cfg.Connections) == 0 && reflect.ValueOf(cfg.Settings).IsZero() {
 		return models.NewConfig()
 	}
 
@@ -579,197 +692,100 @@ func sanitizeConfig(cfg models.Config) *models.Config {
 	return clean
 }
 
-func (d *Daemon) sendToClient(msg any) {
-	d.clientMu.Lock()
-	defer d.clientMu.Unlock()
-
-	if d.client != nil {
-		if err := WriteMsg(d.client, msg); err != nil {
-			d.logger.Error("failed to send message", "err", err)
-		}
-	}
-}
-
-func (d *Daemon) addLog(line string) {
-	d.vpnLogMu.Lock()
-	if d.vpnLogFile != nil {
-		d.vpnLogFile.WriteString(line + "\n")
-	}
-	d.vpnLogMu.Unlock()
-
-	d.stateMu.Lock()
-	lineNum := d.state.LogLineCount
-	d.state.LogLineCount++
-	d.stateMu.Unlock()
-
-	d.sendToClient(LogMsg{Type: "log", Line: line, LineNumber: lineNum})
-}
-
-func vpnLogPath() (string, error) {
-	dir, err := helpers.GetConfigDir()
-	if err != nil {
-		return "", err
-	}
-	return filepath.Join(dir, "vpn.log"), nil
-}
-
-func (d *Daemon) resetVpnLogFile() error {
-	path, err := vpnLogPath()
-	if err != nil {
-		return err
-	}
-
-	d.vpnLogMu.Lock()
-	defer d.vpnLogMu.Unlock()
-
-	if d.vpnLogFile != nil {
-		d.vpnLogFile.Close()
-	}
-
-	f, err := os.Create(path)
-	if err != nil {
-		return err
-	}
-	d.vpnLogFile = f
-
-	d.stateMu.Lock()
-	d.state.LogLineCount = 0
-	d.stateMu.Unlock()
-
-	return nil
-}
-
-func (d *Daemon) ensureVpnLogFile() error {
-	path, err := vpnLogPath()
-	if err != nil {
-		return err
-	}
-
-	d.vpnLogMu.Lock()
-	defer d.vpnLogMu.Unlock()
-
-	if d.vpnLogFile != nil {
-		return nil
-	}
-
-	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
-	if err != nil {
-		return err
-	}
-	d.vpnLogFile = f
-
-	return nil
-}
-
-func (d *Daemon) closeVpnLogFile() {
-	d.vpnLogMu.Lock()
-	defer d.vpnLogMu.Unlock()
-
-	if d.vpnLogFile != nil {
-		d.vpnLogFile.Close()
-		d.vpnLogFile = nil
-	}
-}
-
-func (d *Daemon) readLogLines(from, to int) []string {
-	path, err := vpnLogPath()
-	if err != nil {
-		return nil
-	}
-
-	f, err := os.Open(path)
-	if err != nil {
-		return nil
-	}
-	defer f.Close()
-
-	var lines []string
-	scanner := bufio.NewScanner(f)
-	lineNum := 0
-
-	for scanner.Scan() {
-		if lineNum >= from && (to < 0 || lineNum < to) {
-			lines = append(lines, scanner.Text())
-		}
-		lineNum++
-		if to >= 0 && lineNum >= to {
-			break
-		}
-	}
-
-	return lines
-}
-
-func (d *Daemon) handleGetLogs(msg GetLogsCmd) {
-	from := msg.From
+func (d *Daemon) handleGetLogs(c *client, msg GetLogsCmd) {
+	from := max(msg.From, 0)
 	to := msg.To
 
-	d.stateMu.RLock()
-	totalLines := d.state.LogLineCount
-	d.stateMu.RUnlock()
-
-	if from < 0 {
-		from = 0
+	var totalLines int
+	if s := d.session(msg.ConnID); s != nil {
+		d.stateMu.RLock()
+		totalLines = s.logLines
+		d.stateMu.RUnlock()
+	} else {
+		totalLines = len(readLogLines(msg.ConnID, 0, -1))
 	}
+
 	if to > totalLines {
 		to = totalLines
 	}
 
-	lines := d.readLogLines(from, to)
+	lines := readLogLines(msg.ConnID, from, to)
 
-	d.sendToClient(LogRangeMsg{
+	d.reply(c, LogRangeMsg{
 		Type:       "log_range",
+		ID:         msg.ID,
+		ConnID:     msg.ConnID,
 		From:       from,
 		Lines:      lines,
 		TotalLines: totalLines,
 	})
 }
 
-func (d *Daemon) handleClearLogs() {
-	if err := d.resetVpnLogFile(); err != nil {
-		d.sendToClient(ErrorMsg{Type: "error", Code: "clear_logs_failed", Message: err.Error()})
+func (d *Daemon) handleClearLogs(c *client, msg ClearLogsCmd) {
+	var err error
+	if s := d.session(msg.ConnID); s != nil {
+		if err = s.openLog(true); err == nil {
+			d.stateMu.Lock()
+			s.logLines = 0
+			d.stateMu.Unlock()
+		}
+	} else {
+		var path string
+		if path, err = helpers.VpnLogPath(msg.ConnID); err == nil {
+			if err = os.Truncate(path, 0); os.IsNotExist(err) {
+				err = nil
+			}
+		}
+	}
+	if err != nil {
+		d.fail(c, msg.ID, msg.ConnID, "clear_logs_failed", err.Error())
 		return
 	}
-	d.handleGetState()
+	d.broadcastState()
+	d.succeed(c, msg.ID)
 }
 
+// cleanupSnapshot is what a manual cleanup restores: the baseline captured
+// by a running session, or the current network state.
 func (d *Daemon) cleanupSnapshot() *helpers.NetworkSnapshot {
-	d.stateMu.RLock()
-	snap := d.state.NetworkSnapshot
-	settings := d.state.Config.Settings
-	d.stateMu.RUnlock()
+	return d.baselineSnapshot()
+}
 
-	if snap == nil {
-		snap = helpers.CaptureNetworkSnapshot()
-	}
-	if settings.NetInterface != "" {
-		snap.DefaultInterface = settings.NetInterface
-	}
-	if settings.WifiInterface != "" {
-		snap.WifiServiceName = settings.WifiInterface
-	}
-	if settings.DNS != "" {
-		snap.DNSServers = strings.Fields(settings.DNS)
-	}
-	if settings.TunnelInterface != "" {
-		snap.TunnelInterface = settings.TunnelInterface
+// runManualCleanup undoes the tunnel, split routes and split DNS of every
+// session, then restores the baseline and removes the kill switch.
+func (d *Daemon) runManualCleanup() []helpers.CleanupResult {
+	var snaps []helpers.NetworkSnapshot
+	for _, s := range d.sessions() {
+		d.stateMu.RLock()
+		if s.snapshot != nil {
+			snaps = append(snaps, *s.snapshot)
+		}
+		d.stateMu.RUnlock()
 	}
 
-	return snap
+	var results []helpers.CleanupResult
+	for i := range snaps {
+		results = append(results, helpers.RunTunnelCleanupSteps(&snaps[i])...)
+	}
+	results = append(results, helpers.RunCleanupSteps(d.cleanupSnapshot())...)
+	return append(results, d.cleanupKillSwitch()...)
 }
 
-func (d *Daemon) runCleanup(label string) {
-	d.sendToClient(CleanupStepMsg{Type: "cleanup_step", Line: fmt.Sprintf("--- %s ---", label)})
+func (d *Daemon) runCleanup(label string, run func() []helpers.CleanupResult) {
+	d.broadcast(CleanupStepMsg{Type: "cleanup_step", Line: fmt.Sprintf("--- %s ---", label)})
 
-	results := helpers.RunCleanupSteps(d.cleanupSnapshot())
-	for _, line := range helpers.FormatCleanupResults(results) {
-		d.sendToClient(CleanupStepMsg{Type: "cleanup_step", Line: line})
+	for _, line := range helpers.FormatCleanupResults(run()) {
+		d.broadcast(CleanupStepMsg{Type: "cleanup_step", Line: line})
 	}
 
-	d.sendToClient(CleanupDoneMsg{Type: "cleanup_done"})
+	d.broadcast(CleanupDoneMsg{Type: "cleanup_done"})
 }
 
-func (d *Daemon) handleCleanup() {
+// handleCleanup runs a cleanup requested by c; id is the request to
+// resolve once it is done.
+func (d *Daemon) handleCleanup(c *client, id string) {
+	d.cleanupSerial.Lock()
+	defer d.cleanupSerial.Unlock()
 	defer func() {
 		d.cleanupMu.Lock()
 		d.cleanupRunning = false
@@ -777,30 +793,56 @@ func (d *Daemon) handleCleanup() {
 	}()
 
 	d.logger.Info("running manual cleanup")
-	d.runCleanup("Running cleanup")
+	d.runCleanup("Running cleanup", d.runManualCleanup)
+	d.succeed(c, id)
 }
 
-func (d *Daemon) runCleanupSync(label string) {
+// runCleanupSync cleans up after s. While other tunnels are up only its
+// own interface is torn down; routes and DNS are restored once it is the
+// last one.
+func (d *Daemon) runCleanupSync(label string, s *session) {
+	d.cleanupSerial.Lock()
+	defer d.cleanupSerial.Unlock()
+
 	d.cleanupMu.Lock()
-	if d.cleanupRunning {
-		d.cleanupMu.Unlock()
-		return
-	}
 	d.cleanupRunning = true
 	d.cleanupMu.Unlock()
-
 	defer func() {
 		d.cleanupMu.Lock()
 		d.cleanupRunning = false
 		d.cleanupMu.Unlock()
 	}()
 
-	d.logger.Info("running cleanup", "label", label)
-	d.runCleanup(label)
+	d.stateMu.RLock()
+	var snap *helpers.NetworkSnapshot
+	if s.snapshot != nil {
+		copied := *s.snapshot
+		snap = &copied
+	}
+	others := false
+	for _, other := range d.state.Sessions {
+		if other != s {
+			others = true
+			break
+		}
+	}
+	d.stateMu.RUnlock()
+
+	if snap == nil {
+		snap = d.cleanupSnapshot()
+	}
+
+	d.logger.Info("running cleanup", "label", label, "conn_id", s.connID, "tunnel_only", others)
+	d.runCleanup(label, func() []helpers.CleanupResult {
+		if others {
+			return helpers.RunTunnelCleanupSteps(snap)
+		}
+		return helpers.RunCleanupSteps(snap)
+	})
 }
 
-func (d *Daemon) runAutoCleanup() {
-	go d.runCleanupSync("Auto-cleanup")
+func (d *Daemon) runAutoCleanup(s *session) {
+	go d.runCleanupSync("Auto-cleanup", s)
 }
 
 func (d *Daemon) Shutdown() {
@@ -810,17 +852,11 @@ func (d *Daemon) Shutdown() {
 		}
 		close(d.shutdown)
 
-		d.vpnMu.Lock()
-		hasVPN := d.vpnProcess != nil
-		d.vpnMu.Unlock()
-		if hasVPN {
-			d.disconnectVPN()
-		}
+		d.disconnectAll(models.EndShutdown)
 
 		if d.listener != nil {
 			_ = d.listener.Close()
 		}
-		d.closeVpnLogFile()
 		d.cleanupSocket()
 	})
 }
@@ -830,11 +866,15 @@ func (d *Daemon) Close() {
 		_ = d.listener.Close()
 	}
 	d.clientMu.Lock()
-	if d.client != nil {
-		_ = d.client.Close()
+	for c := range d.clients {
+		_ = c.conn.Close()
 	}
+	d.clients = nil
+	d.owner = nil
 	d.clientMu.Unlock()
-	d.closeVpnLogFile()
+	for _, s := range d.sessions() {
+		s.closeLogFile()
+	}
 	d.cleanupSocket()
 }
 
@@ -167,7 +167,7 @@ func TestHandleMessageInvalidConfigUpdate(t *testing.T) {
 	d := newTestDaemon()
 	original := d.state.Config
 
-	d.handleMessage(IncomingMsg{
+	d.handleMessage(nil, IncomingMsg{
 		Type: "config_update",
 		raw:  json.RawMessage(`{"type":"config_update","config":"bad"}`),
 	})
@@ -177,40 +177,170 @@ func TestHandleMessageInvalidConfigUpdate(t *testing.T) {
 	}
 }
 
-func TestHandleHelloVersionMismatch(t *testing.T) {
+func TestReadMsgParsesRequestID(t *testing.T) {
+	reader := bufio.NewReader(strings.NewReader("{\"type\":\"get_state\",\"id\":\"7\"}\n"))
+
+	msg, err := ReadMsg(reader)
+	if err != nil {
+		t.Fatalf("ReadMsg returned error: %v", err)
+	}
+	assertString(t, "ID", msg.ID, "7")
+}
+
+func TestHandleMessageResultCarriesRequestID(t *testing.T) {
+	d := newTestDaemon()
+	c, conn := attachTestClient(t, d)
+
+	msg := IncomingMsg{
+		Type: "config_update",
+		ID:   "42",
+		raw:  json.RawMessage(`{"type":"config_update","id":"42","config":{}}`),
+	}
+	go d.handleMessage(c, msg)
+
+	var res ResultMsg
+	if err := readTestMsg(t, conn).Decode(&res); err != nil {
+		t.Fatalf("Decode returned error: %v", err)
+	}
+	assertString(t, "Type", res.Type, "result")
+	assertString(t, "ID", res.ID, "42")
+	assertBool(t, "OK", res.OK, true)
+}
+
+func TestHandleMessageRejectsInvalidRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  IncomingMsg
+		code string
+	}{
+		{
+			name: "undecodable",
+			msg: IncomingMsg{
+				Type: "config_update",
+				ID:   "1",
+				raw:  json.RawMessage(`{"type":"config_update","id":"1","config":"bad"}`),
+			},
+			code: "invalid_message",
+		},
+		{
+			name: "unknown type",
+			msg: IncomingMsg{
+				Type: "frobnicate",
+				ID:   "2",
+				raw:  json.RawMessage(`{"type":"frobnicate","id":"2"}`),
+			},
+			code: "unknown_command",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDaemon()
+			c, conn := attachTestClient(t, d)
+
+			go d.handleMessage(c, tt.msg)
+
+			var res ResultMsg
+			if err := readTestMsg(t, conn).Decode(&res); err != nil {
+				t.Fatalf("Decode returned error: %v", err)
+			}
+			assertString(t, "ID", res.ID, tt.msg.ID)
+			assertBool(t, "OK", res.OK, false)
+			assertString(t, "Code", res.Code, tt.code)
+		})
+	}
+}
+
+func TestHandleGetStateEchoesRequestID(t *testing.T) {
+	d := newTestDaemon()
+	c, conn := attachTestClient(t, d)
+
+	go d.handleGetState(c, "9")
+
+	msg := readTestMsg(t, conn)
+	assertString(t, "Type", msg.Type, "state")
+	assertString(t, "ID", msg.ID, "9")
+}
+
+func TestHandleHelloAcceptsOtherReleaseWithSameProtocol(t *testing.T) {
 	d := newTestDaemon()
 	d.version = "server-version"
 
-	server, client := net.Pipe()
-	defer client.Close()
-	d.client = server
-	defer server.Close()
+	hello := helloTestDaemon(t, d, HelloCmd{Type: "hello", Version: "client-version", Protocol: ProtocolVersion})
 
-	done := make(chan struct{})
-	go func() {
-		d.handleHello(HelloCmd{Type: "hello", Version: "client-version"})
-		close(done)
-	}()
+	assertString(t, "Version", hello.Version, "server-version")
+	assertBool(t, "Compatible", hello.Compatible, true)
+	if !hello.Supports(CapSessions) {
+		t.Fatalf("Capabilities = %v, want %q", hello.Capabilities, CapSessions)
+	}
+	assertDaemonRunning(t, d)
+}
 
-	msg, err := ReadMsg(bufio.NewReader(client))
-	if err != nil {
-		t.Fatalf("ReadMsg returned error: %v", err)
+func TestHandleHelloProtocolMismatchIdleShutsDown(t *testing.T) {
+	d := newTestDaemon()
+
+	hello := helloTestDaemon(t, d, HelloCmd{Type: "hello", Version: "old"})
+
+	assertBool(t, "Compatible", hello.Compatible, false)
+	if hello.Tunnels != 0 {
+		t.Fatalf("Tunnels = %d, want 0", hello.Tunnels)
+	}
+	select {
+	case <-d.shutdown:
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("expected idle daemon to shut down after protocol mismatch")
+	}
+}
+
+func TestHandleHelloProtocolMismatchKeepsTunnels(t *testing.T) {
+	d := newTestDaemon()
+	d.state.Sessions["work"] = &session{connID: "work", status: StatusConnected}
+
+	hello := helloTestDaemon(t, d, HelloCmd{Type: "hello", Version: "new", Protocol: ProtocolVersion + 1})
+
+	assertBool(t, "Compatible", hello.Compatible, false)
+	if hello.Tunnels != 1 {
+		t.Fatalf("Tunnels = %d, want 1", hello.Tunnels)
+	}
+	assertDaemonRunning(t, d)
+}
+
+func TestHandleHelloProtocolMismatchKeepsAttachedClients(t *testing.T) {
+	d := newTestDaemon()
+	attachTestClient(t, d)
+
+	hello := helloTestDaemon(t, d, HelloCmd{Type: "hello", Version: "old"})
+
+	assertBool(t, "Compatible", hello.Compatible, false)
+	if hello.Clients != 1 {
+		t.Fatalf("Clients = %d, want 1", hello.Clients)
 	}
-	<-done
+	assertDaemonRunning(t, d)
+}
+
+func helloTestDaemon(t *testing.T, d *Daemon, cmd HelloCmd) HelloResponse {
+	t.Helper()
+
+	c, conn := attachTestClient(t, d)
+	go d.handleHello(c, cmd)
 
 	var hello HelloResponse
-	if err := msg.Decode(&hello); err != nil {
+	if err := readTestMsg(t, conn).Decode(&hello); err != nil {
 		t.Fatalf("Decode returned error: %v", err)
 	}
-
 	assertString(t, "Type", hello.Type, "hello_response")
-	assertString(t, "Version", hello.Version, "server-version")
-	assertBool(t, "Compatible", hello.Compatible, false)
+	if hello.Protocol != ProtocolVersion {
+		t.Fatalf("Protocol = %d, want %d", hello.Protocol, ProtocolVersion)
+	}
+	return hello
+}
 
+func assertDaemonRunning(t *testing.T, d *Daemon) {
+	t.Helper()
 	select {
 	case <-d.shutdown:
-	case <-time.After(500 * time.Millisecond):
-		t.Fatal("expected daemon shutdown after version mismatch")
+		t.Fatal("daemon shut down")
+	case <-time.After(300 * time.Millisecond):
 	}
 }
 
@@ -221,7 +351,7 @@ func TestShutdownIdempotent(t *testing.T) {
 		t.Fatalf("WriteFile returned error: %v", err)
 	}
 
-	logFile, err := os.Create(filepath.Join(tmpDir, "vpn.log"))
+	logFile, err := os.Create(filepath.Join(tmpDir, "vpn-work.log"))
 	if err != nil {
 		t.Fatalf("Create returned error: %v", err)
 	}
@@ -229,7 +359,7 @@ func TestShutdownIdempotent(t *testing.T) {
 	listener := &mockListener{}
 	d := newTestDaemon()
 	d.listener = listener
-	d.vpnLogFile = logFile
+	d.state.Sessions["work"] = &session{connID: "work", logFile: logFile}
 	d.socketPath = socketPath
 	d.socketOwned = true
 
@@ -254,13 +384,20 @@ func TestShutdownIdempotent(t *testing.T) {
 	if _, err := logFile.WriteString("x"); err == nil {
 		t.Fatal("expected vpn log file to be closed")
 	}
+	if len(d.state.Sessions) != 0 {
+		t.Fatalf("sessions = %d, want 0 after shutdown", len(d.state.Sessions))
+	}
 }
 
+// newTestDaemon has auto-cleanup off, so ending a session in a test never
+// touches the host's routes or DNS.
 func newTestDaemon() *Daemon {
+	cfg := models.NewConfig()
+	cfg.Settings.AutoCleanup = false
 	return &Daemon{
 		state: &DaemonState{
-			Status: StatusDisconnected,
-			Config: models.NewConfig(),
+			Sessions: make(map[string]*session),
+			Config:   cfg,
 		},
 		shutdown:      make(chan struct{}),
 		passwordCache: make(map[string]string),
@@ -268,16 +405,16 @@ func newTestDaemon() *Daemon {
 	}
 }
 
-func attachTestClient(t *testing.T, d *Daemon) net.Conn {
+func attachTestClient(t *testing.T, d *Daemon) (*client, net.Conn) {
 	t.Helper()
 
-	server, client := net.Pipe()
-	d.client = server
+	server, conn := net.Pipe()
+	c := d.addClient(server)
 	t.Cleanup(func() {
 		_ = server.Close()
-		_ = client.Close()
+		_ = conn.Close()
 	})
-	return client
+	return c, conn
 }
 
 func readTestMsg(t *testing.T, conn net.Conn) IncomingMsg {
@@ -26,41 +26,30 @@ func (d *Daemon) externalVPNMonitor() {
 }
 
 func (d *Daemon) checkExternalVPN() {
+	own := make(map[int]bool)
 	d.stateMu.RLock()
-	status := d.state.Status
-	d.stateMu.RUnlock()
-
-	if status != StatusDisconnected && status != StatusExternal {
-		d.clearExternal()
-		return
-	}
-
-	d.vpnMu.Lock()
-	var ownVPNPID int
-	if d.vpnProcess != nil && d.vpnProcess.cmd != nil && d.vpnProcess.cmd.Process != nil {
-		ownVPNPID = d.vpnProcess.cmd.Process.Pid
+	for _, s := range d.state.Sessions {
+		if s.process != nil && s.process.cmd != nil && s.process.cmd.Process != nil {
+			own[s.process.cmd.Process.Pid] = true
+		}
 	}
-	d.vpnMu.Unlock()
+	d.stateMu.RUnlock()
 
-	pid, host := d.findExternalOpenconnect(ownVPNPID)
+	pid, host := d.findExternalOpenconnect(own)
 
 	d.stateMu.Lock()
 	if pid != 0 {
-		changed := d.state.Status != StatusExternal || d.state.ExternalPID != pid
-		d.state.Status = StatusExternal
+		changed := d.state.ExternalPID != pid
 		d.state.ExternalHost = host
 		d.state.ExternalPID = pid
-		d.state.PID = pid
 		d.stateMu.Unlock()
 		if changed {
 			d.logger.Info("external openconnect detected", "pid", pid, "host", host)
 			d.broadcastState()
 		}
-	} else if d.state.Status == StatusExternal {
-		d.state.Status = StatusDisconnected
+	} else if d.state.ExternalPID != 0 {
 		d.state.ExternalHost = ""
 		d.state.ExternalPID = 0
-		d.state.PID = 0
 		d.stateMu.Unlock()
 		d.logger.Info("external openconnect gone")
 		d.broadcastState()
@@ -69,20 +58,9 @@ func (d *Daemon) checkExternalVPN() {
 	}
 }
 
-func (d *Daemon) clearExternal() {
-	d.stateMu.Lock()
-	if d.state.ExternalHost != "" {
-		d.state.ExternalHost = ""
-		d.state.ExternalPID = 0
-	}
-	d.stateMu.Unlock()
-}
-
-func (d *Daemon) broadcastState() {
-	d.handleGetState()
-}
-
-func (d *Daemon) findExternalOpenconnect(ownVPNPID int) (int, string) {
+// findExternalOpenconnect returns the first openconnect process that is
+// not one of the daemon's own tunnels.
+func (d *Daemon) findExternalOpenconnect(own map[int]bool) (int, string) {
 	out, err := exec.Command("ps", "-axo", "pid=,comm=,args=").Output()
 	if err != nil {
 		return 0, ""
@@ -109,7 +87,7 @@ func (d *Daemon) findExternalOpenconnect(ownVPNPID int) (int, string) {
 			continue
 		}
 
-		if pid == ownVPNPID && ownVPNPID != 0 {
+		if own[pid] {
 			continue
 		}
 
@@ -120,7 +98,11 @@ func (d *Daemon) findExternalOpenconnect(ownVPNPID int) (int, string) {
 	return 0, ""
 }
 
-func (d *Daemon) killExternalVPN(pid int) {
+func (d *Daemon) killExternalVPN() {
+	d.stateMu.RLock()
+	pid := d.state.ExternalPID
+	d.stateMu.RUnlock()
+
 	if pid == 0 {
 		return
 	}
@@ -136,13 +118,12 @@ func (d *Daemon) killExternalVPN(pid int) {
 	proc.Signal(syscall.SIGTERM)
 
 	d.stateMu.Lock()
-	d.state.Status = StatusDisconnected
 	d.state.ExternalHost = ""
 	d.state.ExternalPID = 0
-	d.state.PID = 0
 	d.stateMu.Unlock()
 
-	d.sendToClient(DisconnectedMsg{Type: "disconnected"})
+	d.broadcast(DisconnectedMsg{Type: "disconnected"})
+	d.broadcastState()
 }
 
 func extractHostFromArgs(args []string) string {
@@ -0,0 +1,154 @@
+package daemon
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/controllers/helpers"
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+	"github.com/Nybkox/lazyopenconnect/pkg/ui"
+)
+
+const (
+	healthTick = time.Second
+	// healthTimeout caps a single probe; shorter intervals cap it further.
+	healthTimeout = 5 * time.Second
+)
+
+// healthState tracks the health checks of one connected run, identified by
+// its connectedAt.
+type healthState struct {
+	run      time.Time
+	probe    helpers.HealthProbe
+	invalid  bool
+	next     time.Time
+	running  bool
+	failures int
+}
+
+// healthMonitor probes connected tunnels that have a health check
+// configured, each at its own interval.
+func (d *Daemon) healthMonitor() {
+	ticker := time.NewTicker(healthTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.shutdown:
+			return
+		case now := <-ticker.C:
+			for _, s := range d.sessions() {
+				if probe, run, timeout, ok := d.dueHealthCheck(s, now); ok {
+					go d.probeHealth(s, probe, run, timeout)
+				}
+			}
+		}
+	}
+}
+
+// dueHealthCheck reports whether s should be probed now and marks the
+// probe as running. The first probe of a run waits one interval so the
+// routes are in place.
+func (d *Daemon) dueHealthCheck(s *session, now time.Time) (helpers.HealthProbe, time.Time, time.Duration, bool) {
+	d.stateMu.Lock()
+	defer d.stateMu.Unlock()
+
+	conn := connectionByID(d.state.Config, s.connID)
+	if s.status != StatusConnected || s.connectedAt.IsZero() || conn == nil || conn.HealthCheck == "" {
+		return helpers.HealthProbe{}, time.Time{}, 0, false
+	}
+
+	interval := conn.HealthEvery()
+	if !s.health.run.Equal(s.connectedAt) {
+		probe, err := helpers.ParseHealthCheck(conn.HealthCheck)
+		s.health = healthState{
+			run:     s.connectedAt,
+			probe:   probe,
+			invalid: err != nil,
+			next:    now.Add(interval),
+		}
+		if err != nil {
+			d.logger.Warn("invalid health check, not probing", "conn_id", s.connID, "check", conn.HealthCheck, "err", err)
+		}
+	}
+	if s.health.invalid || s.health.running || now.Before(s.health.next) {
+		return helpers.HealthProbe{}, time.Time{}, 0, false
+	}
+
+	s.health.running = true
+	s.health.next = now.Add(interval)
+	return s.health.probe, s.health.run, min(interval, healthTimeout), true
+}
+
+func (d *Daemon) probeHealth(s *session, probe helpers.HealthProbe, run time.Time, timeout time.Duration) {
+	rtt, err := probe.Run(timeout)
+	d.healthResult(s, run, rtt, err)
+}
+
+// healthResult counts a probe of run and reconnects the tunnel once the
+// failures in a row reach the connection's threshold. Results for a run
+// that has since ended are dropped.
+func (d *Daemon) healthResult(s *session, run time.Time, rtt time.Duration, probeErr error) {
+	d.stateMu.Lock()
+	if !s.health.run.Equal(run) || s.status != StatusConnected {
+		d.stateMu.Unlock()
+		return
+	}
+	threshold := models.DefaultHealthFailures
+	if conn := connectionByID(d.state.Config, s.connID); conn != nil {
+		threshold = conn.HealthThreshold()
+	}
+	reconnectEnabled := d.reconnectsOn(s, models.EndHealth)
+
+	s.health.running = false
+	recovered := probeErr == nil && s.health.failures > 0
+	if probeErr == nil {
+		s.health.failures = 0
+	} else {
+		s.health.failures++
+	}
+	failures := s.health.failures
+	dead := failures >= threshold
+	if dead {
+		s.health.failures = 0
+	}
+	d.stateMu.Unlock()
+
+	msg := HealthMsg{
+		Type:      "health",
+		ConnID:    s.connID,
+		OK:        probeErr == nil,
+		Failures:  failures,
+		Threshold: threshold,
+	}
+	if probeErr == nil {
+		msg.RTTMs = float64(rtt.Microseconds()) / 1000
+	} else {
+		msg.Error = probeErr.Error()
+	}
+	d.broadcast(msg)
+
+	switch {
+	case recovered:
+		d.addLog(s, ui.LogOK("Health check passed again"))
+	case probeErr != nil:
+		d.logger.Debug("health check failed", "conn_id", s.connID, "failures", failures, "threshold", threshold, "err", probeErr)
+		d.addLog(s, ui.LogWarning(fmt.Sprintf("Health check failed (%d/%d): %v", failures, threshold, probeErr)))
+	}
+	if !dead {
+		return
+	}
+
+	if !reconnectEnabled {
+		d.logger.Warn("tunnel failed health checks, reconnect on health disabled", "conn_id", s.connID)
+		d.addLog(s, ui.LogError("--- Tunnel looks dead; reconnect on health failure is off ---"))
+		return
+	}
+
+	d.logger.Info("tunnel failed health checks, initiating reconnect", "conn_id", s.connID, "failures", failures)
+	d.addLog(s, ui.LogWarning("--- Tunnel looks dead, will reconnect ---"))
+
+	d.recordHistory(s, models.EndHealth)
+	d.stopForReconnect(s)
+	go d.startAutoReconnect(s, "health")
+}
@@ -0,0 +1,144 @@
+package daemon
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+)
+
+func newHealthTestDaemon(check string, failures int) (*Daemon, *session) {
+	d := newTestDaemon()
+	d.state.Config.Connections = []models.Connection{{
+		ID:             "work",
+		Name:           "Work",
+		Host:           "vpn.example.com",
+		HealthCheck:    check,
+		HealthInterval: 10,
+		HealthFailures: failures,
+	}}
+	s := &session{connID: "work", status: StatusConnected, connectedAt: time.Now()}
+	d.state.Sessions["work"] = s
+	return d, s
+}
+
+func TestDueHealthCheckWaitsOneInterval(t *testing.T) {
+	d, s := newHealthTestDaemon("tcp:10.0.0.1:22", 3)
+	now := time.Now()
+
+	if _, _, _, ok := d.dueHealthCheck(s, now); ok {
+		t.Fatal("probe due right after connecting")
+	}
+	probe, run, timeout, ok := d.dueHealthCheck(s, now.Add(10*time.Second))
+	if !ok {
+		t.Fatal("probe not due after one interval")
+	}
+	assertString(t, "Target", probe.Target, "10.0.0.1:22")
+	if !run.Equal(s.connectedAt) {
+		t.Fatalf("run = %v, want connectedAt %v", run, s.connectedAt)
+	}
+	if timeout != healthTimeout {
+		t.Fatalf("timeout = %v, want %v", timeout, healthTimeout)
+	}
+	if _, _, _, ok := d.dueHealthCheck(s, now.Add(30*time.Second)); ok {
+		t.Fatal("second probe started while the first is running")
+	}
+}
+
+func TestDueHealthCheckSkipsInvalidAndUnconfigured(t *testing.T) {
+	later := time.Now().Add(time.Hour)
+
+	d, s := newHealthTestDaemon("", 3)
+	if _, _, _, ok := d.dueHealthCheck(s, later); ok {
+		t.Fatal("probe due without a health check")
+	}
+
+	d, s = newHealthTestDaemon("http:intranet", 3)
+	d.dueHealthCheck(s, time.Now())
+	if _, _, _, ok := d.dueHealthCheck(s, later); ok {
+		t.Fatal("probe due for an invalid health check")
+	}
+}
+
+func TestHealthResultCountsFailures(t *testing.T) {
+	d, s := newHealthTestDaemon("tcp:10.0.0.1:22", 3)
+	_, conn := attachTestClient(t, d)
+	d.dueHealthCheck(s, time.Now())
+	run := s.health.run
+
+	go d.healthResult(s, run, 0, errors.New("i/o timeout"))
+	failed := readHealthMsg(t, conn)
+	assertBool(t, "OK", failed.OK, false)
+	assertString(t, "Error", failed.Error, "i/o timeout")
+	if failed.Failures != 1 || failed.Threshold != 3 {
+		t.Fatalf("failures = %d/%d, want 1/3", failed.Failures, failed.Threshold)
+	}
+
+	go d.healthResult(s, run, 23*time.Millisecond, nil)
+	passed := readHealthMsg(t, conn)
+	assertBool(t, "OK", passed.OK, true)
+	if passed.RTTMs != 23 || passed.Failures != 0 {
+		t.Fatalf("rtt/failures = %v/%d, want 23/0", passed.RTTMs, passed.Failures)
+	}
+}
+
+// readHealthMsg skips the log lines a probe result writes.
+func readHealthMsg(t *testing.T, conn net.Conn) HealthMsg {
+	t.Helper()
+	for {
+		msg := readTestMsg(t, conn)
+		if msg.Type != "health" {
+			continue
+		}
+		var health HealthMsg
+		if err := msg.Decode(&health); err != nil {
+			t.Fatalf("Decode returned error: %v", err)
+		}
+		assertString(t, "ConnID", health.ConnID, "work")
+		return health
+	}
+}
+
+func TestHealthResultIgnoresEndedRun(t *testing.T) {
+	d, s := newHealthTestDaemon("tcp:10.0.0.1:22", 1)
+	d.dueHealthCheck(s, time.Now())
+	run := s.health.run
+
+	s.connectedAt = run.Add(time.Minute)
+	d.healthResult(s, run, 0, errors.New("i/o timeout"))
+
+	if s.status != StatusConnected {
+		t.Fatalf("status = %v, want connected", s.status)
+	}
+}
+
+func TestHealthResultReconnectsDeadTunnel(t *testing.T) {
+	d, s := newHealthTestDaemon("tcp:10.0.0.1:22", 2)
+	d.state.Config.Settings.Reconnect = true
+	d.dueHealthCheck(s, time.Now())
+	run := s.health.run
+	// Stop the reconnect before it dials anything.
+	close(d.shutdown)
+
+	d.healthResult(s, run, 0, errors.New("i/o timeout"))
+	if status := d.sessionStatus(s); status != StatusConnected {
+		t.Fatalf("status after one failure = %v, want connected", status)
+	}
+
+	d.healthResult(s, run, 0, errors.New("i/o timeout"))
+	if status := d.sessionStatus(s); status != StatusReconnecting {
+		t.Fatalf("status after reaching the threshold = %v, want reconnecting", status)
+	}
+}
+
+func TestHealthResultKeepsTunnelWithoutReconnect(t *testing.T) {
+	d, s := newHealthTestDaemon("tcp:10.0.0.1:22", 1)
+	d.dueHealthCheck(s, time.Now())
+
+	d.healthResult(s, s.health.run, 0, errors.New("i/o timeout"))
+	if status := d.sessionStatus(s); status != StatusConnected {
+		t.Fatalf("status = %v, want connected while reconnect is disabled", status)
+	}
+}
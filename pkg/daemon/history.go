@@ -0,0 +1,130 @@
+package daemon
+
+import (
+	"regexp"
+	"syscall"
+	"time"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/controllers/helpers"
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+)
+
+var (
+	errorLinePattern = regexp.MustCompile(`(?i)\b(error|fail(ed|ure)?|unable|refused|denied|cannot|expired|invalid)\b`)
+	// killedPattern matches openconnect acknowledging a signal it got from
+	// outside the daemon.
+	killedPattern = regexp.MustCompile(`(?i)user cancelled|caught signal`)
+)
+
+// noteHistoryLine keeps what the history record needs from a line of
+// openconnect output.
+func (d *Daemon) noteHistoryLine(s *session, line string) {
+	isError := errorLinePattern.MatchString(line)
+	killed := killedPattern.MatchString(line)
+	if !isError && !killed {
+		return
+	}
+
+	d.stateMu.Lock()
+	if isError {
+		s.lastError = line
+	}
+	if killed {
+		s.killed = true
+	}
+	d.stateMu.Unlock()
+}
+
+// startRun opens a history record for s unless one is already open, as
+// when a dropped tunnel starts to reconnect.
+func (d *Daemon) startRun(s *session) {
+	d.stateMu.Lock()
+	if s.startedAt.IsZero() {
+		s.startedAt = time.Now()
+		s.attempts = 0
+		s.lastError = ""
+		s.killed = false
+	}
+	d.stateMu.Unlock()
+}
+
+// countAttempt adds a reconnect attempt to the open history record.
+func (d *Daemon) countAttempt(s *session) {
+	d.stateMu.Lock()
+	s.attempts++
+	d.stateMu.Unlock()
+}
+
+// recordHistory closes the open history record of s with reason and
+// appends it to the journal. A run is recorded once; later calls for the
+// same run do nothing.
+func (d *Daemon) recordHistory(s *session, reason string) {
+	d.stateMu.Lock()
+	if s.startedAt.IsZero() {
+		d.stateMu.Unlock()
+		return
+	}
+	rec := models.HistoryRecord{
+		ConnID:            s.connID,
+		StartedAt:         s.startedAt,
+		ConnectedAt:       s.connectedAt,
+		EndedAt:           time.Now(),
+		IP:                s.ip,
+		Tunnel:            s.tunnel,
+		EndReason:         reason,
+		ReconnectAttempts: s.attempts,
+		LastError:         s.lastError,
+	}
+	if conn := connectionByID(d.state.Config, s.connID); conn != nil {
+		rec.Name = conn.Name
+		rec.Host = conn.Host
+	}
+	s.startedAt = time.Time{}
+	d.stateMu.Unlock()
+
+	d.logger.Info("session ended", "conn_id", rec.ConnID, "reason", reason, "uptime", rec.Uptime().Round(time.Second))
+	if d.historyPath == "" {
+		return
+	}
+	if err := helpers.AppendHistory(d.historyPath, rec); err != nil {
+		d.logger.Warn("failed to write session history", "err", err)
+	}
+}
+
+// exitReason tells an openconnect that quit on its own from one stopped by
+// a signal the daemon did not send, e.g. `sudo pkill openconnect`.
+func (d *Daemon) exitReason(s *session, proc *VPNProcess) string {
+	d.stateMu.RLock()
+	killed := s.killed
+	d.stateMu.RUnlock()
+
+	if killed || processSignaled(proc) {
+		return models.EndExternalKill
+	}
+	return models.EndExit
+}
+
+// processSignaled reaps proc and reports whether a signal ended it.
+func processSignaled(proc *VPNProcess) bool {
+	if proc == nil || proc.cmd == nil || proc.cmd.Process == nil {
+		return false
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		_ = proc.cmd.Wait()
+		close(exited)
+	}()
+
+	select {
+	case <-exited:
+	case <-time.After(2 * time.Second):
+		return false
+	}
+
+	if proc.cmd.ProcessState == nil {
+		return false
+	}
+	status, ok := proc.cmd.ProcessState.Sys().(syscall.WaitStatus)
+	return ok && status.Signaled()
+}
@@ -0,0 +1,92 @@
+package daemon
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/controllers/helpers"
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+)
+
+func TestRecordHistory(t *testing.T) {
+	d := newTestDaemon()
+	d.historyPath = filepath.Join(t.TempDir(), "history.jsonl")
+	d.state.Config.Connections = []models.Connection{{ID: "berlin", Name: "Berlin", Host: "vpn.berlin.example"}}
+
+	started := time.Now().Add(-time.Hour)
+	s := &session{
+		connID:      "berlin",
+		status:      StatusConnected,
+		ip:          "10.8.0.2",
+		tunnel:      "tun0",
+		startedAt:   started,
+		connectedAt: started.Add(3 * time.Second),
+	}
+	d.state.Sessions["berlin"] = s
+
+	d.noteHistoryLine(s, "Got CONNECT response: HTTP/1.1 200 OK")
+	d.noteHistoryLine(s, "Failed to read from SSL socket: The transmission has been interrupted")
+	d.recordHistory(s, models.EndExit)
+	// A run is journaled once, whatever path reaches it next.
+	d.endSession(s, models.EndUserDisconnect)
+
+	records, err := helpers.LoadHistory(d.historyPath)
+	if err != nil {
+		t.Fatalf("LoadHistory returned error: %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("records = %d, want 1", len(records))
+	}
+
+	rec := records[0]
+	assertString(t, "Name", rec.Name, "Berlin")
+	assertString(t, "EndReason", rec.EndReason, models.EndExit)
+	assertString(t, "IP", rec.IP, "10.8.0.2")
+	assertString(t, "Tunnel", rec.Tunnel, "tun0")
+	assertString(t, "LastError", rec.LastError, "Failed to read from SSL socket: The transmission has been interrupted")
+	if !rec.Connected() {
+		t.Fatal("record lost connectedAt")
+	}
+}
+
+func TestReconnectRunCountsAttempts(t *testing.T) {
+	d := newTestDaemon()
+	d.historyPath = filepath.Join(t.TempDir(), "history.jsonl")
+	s := &session{connID: "berlin", status: StatusReconnecting}
+	d.state.Sessions["berlin"] = s
+
+	d.startRun(s)
+	d.countAttempt(s)
+	d.countAttempt(s)
+	d.endSession(s, models.EndReconnectFailed)
+
+	records, err := helpers.LoadHistory(d.historyPath)
+	if err != nil {
+		t.Fatalf("LoadHistory returned error: %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("records = %d, want 1", len(records))
+	}
+	if records[0].ReconnectAttempts != 2 {
+		t.Fatalf("ReconnectAttempts = %d, want 2", records[0].ReconnectAttempts)
+	}
+	assertString(t, "EndReason", records[0].EndReason, models.EndReconnectFailed)
+}
+
+func TestDisconnectReasonTimeout(t *testing.T) {
+	d := newTestDaemon()
+	d.historyPath = filepath.Join(t.TempDir(), "history.jsonl")
+	d.state.Sessions["work"] = &session{connID: "work", status: StatusConnecting, startedAt: time.Now()}
+
+	d.handleDisconnect(nil, DisconnectCmd{Type: "disconnect", ConnID: "work", Reason: models.EndTimeout})
+
+	records, err := helpers.LoadHistory(d.historyPath)
+	if err != nil {
+		t.Fatalf("LoadHistory returned error: %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("records = %d, want 1", len(records))
+	}
+	assertString(t, "EndReason", records[0].EndReason, models.EndTimeout)
+}
@@ -0,0 +1,100 @@
+package daemon
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/controllers/helpers"
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+	"github.com/Nybkox/lazyopenconnect/pkg/ui"
+)
+
+// Reasons hooks get for the connect events; disconnect and reconnect hooks
+// get the end reason instead.
+const (
+	hookReasonConnect   = "connect"
+	hookReasonReconnect = "reconnect"
+)
+
+// preDisconnectBudget is how long the pre_disconnect hooks of a tunnel may
+// run together, whatever their timeouts. With the grace openconnect gets
+// after SIGTERM it keeps a disconnect within the CLI's default timeout.
+const preDisconnectBudget = 5 * time.Second
+
+// runHook runs one hook command; tests replace it.
+var runHook = helpers.RunHook
+
+// hasHooks reports whether a hook is set for event, globally or for the
+// connection of s.
+func (d *Daemon) hasHooks(s *session, event string) bool {
+	d.stateMu.RLock()
+	defer d.stateMu.RUnlock()
+	if d.state.Config.Settings.Hooks.Command(event) != "" {
+		return true
+	}
+	conn := connectionByID(d.state.Config, s.connID)
+	return conn != nil && conn.Hooks.Command(event) != ""
+}
+
+// runHooks runs the global hook for event, then the connection's own, and
+// streams their output into the session log. It reports whether any hook
+// ran.
+func (d *Daemon) runHooks(s *session, event, reason string) bool {
+	d.stateMu.RLock()
+	global := d.state.Config.Settings.Hooks
+	var own models.Hooks
+	hc := helpers.HookContext{
+		Event:  event,
+		Reason: reason,
+		ConnID: s.connID,
+		Server: s.serverIP,
+		IP:     s.ip,
+		Tunnel: s.tunnel,
+	}
+	if conn := connectionByID(d.state.Config, s.connID); conn != nil {
+		own = conn.Hooks
+		hc.ConnName = conn.Name
+		hc.Host = conn.Host
+	}
+	if s.snapshot != nil {
+		hc.DefaultGateway = s.snapshot.DefaultGateway
+		hc.DefaultInterface = s.snapshot.DefaultInterface
+	}
+	d.stateMu.RUnlock()
+
+	var deadline time.Time
+	if event == models.HookPreDisconnect {
+		deadline = time.Now().Add(preDisconnectBudget)
+	}
+
+	ran := false
+	for _, hooks := range []models.Hooks{global, own} {
+		command := hooks.Command(event)
+		if command == "" {
+			continue
+		}
+		ran = true
+
+		timeout := hooks.RunTimeout()
+		if !deadline.IsZero() {
+			left := time.Until(deadline)
+			if left <= 0 {
+				d.logger.Warn("hook skipped, no time left before disconnect", "conn_id", s.connID, "event", event)
+				d.addLog(s, ui.LogWarning(fmt.Sprintf("%s hook skipped: the disconnect cannot wait longer", event)))
+				continue
+			}
+			timeout = min(timeout, left)
+		}
+
+		d.logger.Info("running hook", "conn_id", s.connID, "event", event, "reason", reason)
+		d.addLog(s, ui.LogCommand(command))
+		err := runHook(command, hc.Env(), timeout, func(line string) {
+			d.addLog(s, line)
+		})
+		if err != nil {
+			d.logger.Warn("hook failed", "conn_id", s.connID, "event", event, "err", err)
+			d.addLog(s, ui.LogWarning(fmt.Sprintf("%s hook failed: %v", event, err)))
+		}
+	}
+	return ran
+}
@@ -0,0 +1,147 @@
+package daemon
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/controllers/helpers"
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+)
+
+type hookCall struct {
+	command string
+	env     []string
+	timeout time.Duration
+}
+
+func stubRunHook(t *testing.T) *[]hookCall {
+	t.Helper()
+	var calls []hookCall
+	orig := runHook
+	runHook = func(command string, env []string, timeout time.Duration, output func(string)) error {
+		calls = append(calls, hookCall{command: command, env: env, timeout: timeout})
+		return nil
+	}
+	t.Cleanup(func() { runHook = orig })
+	return &calls
+}
+
+func TestRunHooksRunsGlobalThenConnection(t *testing.T) {
+	calls := stubRunHook(t)
+	d := newTestDaemon()
+	d.state.Config.Settings.Hooks = models.Hooks{PostConnect: "notify-chat"}
+	d.state.Config.Connections = []models.Connection{{
+		ID:    "work",
+		Name:  "Work",
+		Host:  "vpn.example.com",
+		Hooks: models.Hooks{PostConnect: "mount-shares", Timeout: 90},
+	}}
+	s := &session{
+		connID:   "work",
+		status:   StatusConnected,
+		ip:       "10.0.0.2",
+		tunnel:   "tun0",
+		snapshot: &helpers.NetworkSnapshot{DefaultGateway: "192.168.1.1", DefaultInterface: "eth0"},
+	}
+	d.state.Sessions["work"] = s
+
+	if !d.runHooks(s, models.HookPostConnect, hookReasonConnect) {
+		t.Fatal("runHooks reported no hook ran")
+	}
+
+	if len(*calls) != 2 {
+		t.Fatalf("ran %d hooks, want 2", len(*calls))
+	}
+	global, own := (*calls)[0], (*calls)[1]
+	assertString(t, "first hook", global.command, "notify-chat")
+	assertString(t, "second hook", own.command, "mount-shares")
+	if global.timeout != 30*time.Second || own.timeout != 90*time.Second {
+		t.Fatalf("timeouts = %v/%v, want 30s/90s", global.timeout, own.timeout)
+	}
+	for _, want := range []string{
+		"LAZYOPENCONNECT_EVENT=post_connect",
+		"LAZYOPENCONNECT_REASON=connect",
+		"LAZYOPENCONNECT_CONN_NAME=Work",
+		"LAZYOPENCONNECT_HOST=vpn.example.com",
+		"LAZYOPENCONNECT_IP=10.0.0.2",
+		"LAZYOPENCONNECT_TUNNEL=tun0",
+		"LAZYOPENCONNECT_DEFAULT_GATEWAY=192.168.1.1",
+	} {
+		if !slices.Contains(own.env, want) {
+			t.Fatalf("env %q lacks %s", own.env, want)
+		}
+	}
+}
+
+func TestRunHooksWithoutHooks(t *testing.T) {
+	calls := stubRunHook(t)
+	d := newTestDaemon()
+	s := &session{connID: "work"}
+
+	if d.runHooks(s, models.HookPreConnect, hookReasonConnect) || d.hasHooks(s, models.HookPreConnect) {
+		t.Fatal("hooks reported without any configured")
+	}
+	if len(*calls) != 0 {
+		t.Fatalf("ran %d hooks, want none", len(*calls))
+	}
+}
+
+func TestPreDisconnectHooksShareBudget(t *testing.T) {
+	calls := stubRunHook(t)
+	d := newTestDaemon()
+	d.state.Config.Settings.Hooks = models.Hooks{PreDisconnect: "sync-notes"}
+	d.state.Config.Connections = []models.Connection{{
+		ID:    "work",
+		Name:  "Work",
+		Host:  "vpn.example.com",
+		Hooks: models.Hooks{PreDisconnect: "umount /mnt/share", Timeout: 90},
+	}}
+	s := &session{connID: "work", status: StatusConnected}
+	d.state.Sessions["work"] = s
+
+	d.runHooks(s, models.HookPreDisconnect, models.EndUserDisconnect)
+
+	if len(*calls) != 2 {
+		t.Fatalf("ran %d hooks, want 2", len(*calls))
+	}
+	for _, call := range *calls {
+		if call.timeout <= 0 || call.timeout > preDisconnectBudget {
+			t.Fatalf("%s timeout = %v, want at most %v", call.command, call.timeout, preDisconnectBudget)
+		}
+	}
+}
+
+func TestPostDisconnectHookGetsIPAfterExit(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("SUDO_USER", "")
+	envs := make(chan []string, 1)
+	orig := runHook
+	runHook = func(command string, env []string, timeout time.Duration, output func(string)) error {
+		envs <- env
+		return nil
+	}
+	t.Cleanup(func() { runHook = orig })
+
+	d := newTestDaemon()
+	d.state.Config.Settings.Reconnect = false
+	d.state.Config.Connections = []models.Connection{{
+		ID:    "work",
+		Name:  "Work",
+		Host:  "vpn.example.com",
+		Hooks: models.Hooks{PostDisconnect: "notify-chat"},
+	}}
+	s := &session{connID: "work", status: StatusConnected, ip: "10.0.0.2", tunnel: "tun0", connectedAt: time.Now()}
+	d.state.Sessions["work"] = s
+
+	d.handleVPNExit(s, nil)
+
+	select {
+	case env := <-envs:
+		if !slices.Contains(env, "LAZYOPENCONNECT_IP=10.0.0.2") {
+			t.Fatalf("env %q lacks the tunnel address", env)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("post_disconnect hook did not run")
+	}
+}
@@ -0,0 +1,223 @@
+package daemon
+
+import (
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/controllers/helpers"
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+	"github.com/Nybkox/lazyopenconnect/pkg/ui"
+)
+
+// Test seams for the firewall rules of the kill switch.
+var (
+	enableKillSwitch          = helpers.EnableKillSwitch
+	disableKillSwitch         = helpers.DisableKillSwitch
+	killSwitchActive          = helpers.KillSwitchActive
+	runKillSwitchCleanupSteps = helpers.RunKillSwitchCleanupSteps
+)
+
+// killSwitchSpec returns what the kill switch lets out, nil when no guarded
+// session reached its server yet. Every tunnel and server is allowed, so
+// the kill switch of one connection leaves the others working. Called
+// with stateMu held.
+func (d *Daemon) killSwitchSpec() *helpers.KillSwitch {
+	var spec helpers.KillSwitch
+	guarded := false
+	for _, s := range d.state.Sessions {
+		if s.guarded && s.serverIP != "" {
+			guarded = true
+		}
+		if s.tunnel != "" {
+			spec.Tunnels = append(spec.Tunnels, s.tunnel)
+		}
+		if s.serverIP != "" {
+			spec.Servers = append(spec.Servers, s.serverIP)
+		}
+	}
+	if !guarded {
+		return nil
+	}
+	slices.Sort(spec.Tunnels)
+	spec.Tunnels = slices.Compact(spec.Tunnels)
+	slices.Sort(spec.Servers)
+	spec.Servers = slices.Compact(spec.Servers)
+	return &spec
+}
+
+// syncKillSwitch installs, updates or removes the kill switch rules to
+// match the sessions. Rules held after a guarded tunnel went down for good
+// stay until the user disconnects, connects again, cleans up or turns the
+// kill switch off.
+func (d *Daemon) syncKillSwitch() {
+	d.killSwitchMu.Lock()
+	d.stateMu.RLock()
+	spec := d.killSwitchSpec()
+	d.stateMu.RUnlock()
+
+	changed := false
+	switch {
+	case spec != nil:
+		if d.killSwitch != nil && d.killSwitch.Equal(*spec) {
+			break
+		}
+		if err := enableKillSwitch(*spec); err != nil {
+			d.logger.Error("failed to enable kill switch", "err", err)
+			d.killSwitchMu.Unlock()
+			d.killSwitchFailed("Kill switch failed: " + err.Error())
+			return
+		}
+		d.logger.Info("kill switch enabled", "tunnels", spec.Tunnels, "servers", spec.Servers)
+		d.killSwitch = spec
+		changed = true
+	case d.killSwitch != nil && !d.killSwitchHeld:
+		if err := disableKillSwitch(); err != nil {
+			d.logger.Error("failed to disable kill switch", "err", err)
+			d.killSwitchMu.Unlock()
+			d.killSwitchFailed("Removing the kill switch failed: " + err.Error())
+			return
+		}
+		d.logger.Info("kill switch disabled")
+		d.killSwitch = nil
+		changed = true
+	}
+	d.killSwitchOn.Store(d.killSwitch != nil)
+	d.killSwitchMu.Unlock()
+
+	if changed {
+		d.broadcastState()
+	}
+}
+
+// killSwitchFailed reports a kill switch error to the guarded sessions.
+func (d *Daemon) killSwitchFailed(message string) {
+	for _, s := range d.sessions() {
+		d.stateMu.RLock()
+		guarded := s.guarded
+		d.stateMu.RUnlock()
+		if guarded {
+			d.addLog(s, ui.LogError(message))
+			d.broadcast(ErrorMsg{Type: "error", ConnID: s.connID, Code: "killswitch_failed", Message: message})
+		}
+	}
+}
+
+// holdKillSwitch decides whether the rules outlive s, which ended for
+// reason. A guarded tunnel that went down on its own keeps blocking until
+// the user acts; one the user stopped releases them.
+func (d *Daemon) holdKillSwitch(s *session, reason string) {
+	d.stateMu.RLock()
+	guarded := s.guarded
+	d.stateMu.RUnlock()
+
+	d.killSwitchMu.Lock()
+	switch {
+	case reason == models.EndUserDisconnect || reason == models.EndShutdown:
+		d.killSwitchHeld = false
+	case guarded && d.killSwitch != nil:
+		if !d.killSwitchHeld {
+			d.logger.Warn("guarded tunnel ended, kill switch stays on", "conn_id", s.connID, "reason", reason)
+		}
+		d.killSwitchHeld = true
+	}
+	d.killSwitchMu.Unlock()
+}
+
+// releaseKillSwitch removes rules held from an earlier tunnel, so a manual
+// connect can reach its server; the new tunnel installs them again once it
+// is up.
+func (d *Daemon) releaseKillSwitch() {
+	d.killSwitchMu.Lock()
+	held := d.killSwitchHeld
+	d.killSwitchHeld = false
+	d.killSwitchMu.Unlock()
+	if held {
+		d.syncKillSwitch()
+	}
+}
+
+// handleKillSwitchOff removes the kill switch rules, stale ones included,
+// and leaves the tunnels that are up unguarded until they connect again.
+func (d *Daemon) handleKillSwitchOff(c *client, msg KillSwitchOffCmd) {
+	d.stateMu.Lock()
+	for _, s := range d.state.Sessions {
+		s.guarded = false
+	}
+	d.stateMu.Unlock()
+
+	d.killSwitchMu.Lock()
+	d.killSwitchHeld = false
+	d.killSwitch = nil
+	d.killSwitchOn.Store(false)
+	err := disableKillSwitch()
+	d.killSwitchMu.Unlock()
+
+	d.broadcastState()
+	if err != nil {
+		d.logger.Error("failed to disable kill switch", "err", err)
+		d.fail(c, msg.ID, "", "killswitch_failed", err.Error())
+		return
+	}
+	d.logger.Info("kill switch turned off")
+	d.succeed(c, msg.ID)
+}
+
+// cleanupKillSwitch removes the kill switch rules as part of a manual
+// cleanup. Guarded tunnels install them again once they reconnect.
+func (d *Daemon) cleanupKillSwitch() []helpers.CleanupResult {
+	d.killSwitchMu.Lock()
+	d.killSwitchHeld = false
+	d.killSwitch = nil
+	d.killSwitchOn.Store(false)
+	results := runKillSwitchCleanupSteps()
+	d.killSwitchMu.Unlock()
+
+	d.broadcastState()
+	return results
+}
+
+// restoreKillSwitch keeps rules a previous daemon left in place, so a
+// crash does not open the leak the kill switch closed.
+func (d *Daemon) restoreKillSwitch() {
+	if !killSwitchActive() {
+		return
+	}
+	d.logger.Warn("kill switch rules found at startup, keeping them")
+	d.killSwitchMu.Lock()
+	d.killSwitch = &helpers.KillSwitch{}
+	d.killSwitchHeld = true
+	d.killSwitchOn.Store(true)
+	d.killSwitchMu.Unlock()
+}
+
+// resolveArg pins host to the server address openconnect reached before,
+// since the kill switch blocks the DNS lookup of a reconnect. It returns
+// "" when host is an address already.
+func resolveArg(host, serverIP string) string {
+	name, _, err := net.SplitHostPort(extractProbeAddr(host))
+	if err != nil || serverIP == "" {
+		return ""
+	}
+	name, _, _ = strings.Cut(name, "/")
+	if _, err := netip.ParseAddr(name); err == nil {
+		return ""
+	}
+	return "--resolve=" + name + ":" + serverIP
+}
+
+// serverProbeAddr is where a guarded reconnect checks the network: the
+// server's address on the port of host, as its name does not resolve
+// behind the kill switch.
+func serverProbeAddr(host, serverIP string) string {
+	addr := extractProbeAddr(host)
+	if serverIP == "" {
+		return addr
+	}
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return net.JoinHostPort(serverIP, port)
+}
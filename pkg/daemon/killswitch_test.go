@@ -0,0 +1,125 @@
+package daemon
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/controllers/helpers"
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+)
+
+// stubKillSwitch records the specs the kill switch is enabled with and
+// counts how often it is disabled.
+func stubKillSwitch(t *testing.T) (enabled *[]helpers.KillSwitch, disabled *int) {
+	t.Helper()
+	enabled, disabled = new([]helpers.KillSwitch), new(int)
+	origEnable, origDisable := enableKillSwitch, disableKillSwitch
+	enableKillSwitch = func(k helpers.KillSwitch) error {
+		*enabled = append(*enabled, k)
+		return nil
+	}
+	disableKillSwitch = func() error {
+		*disabled++
+		return nil
+	}
+	t.Cleanup(func() {
+		enableKillSwitch, disableKillSwitch = origEnable, origDisable
+	})
+	return enabled, disabled
+}
+
+func newKillSwitchTestDaemon() (*Daemon, *session) {
+	d := newTestDaemon()
+	s := &session{connID: "work", status: StatusConnected, tunnel: "tun0", serverIP: "203.0.113.7", guarded: true}
+	d.state.Sessions["work"] = s
+	d.state.Sessions["lab"] = &session{connID: "lab", status: StatusConnected, tunnel: "tun1", serverIP: "198.51.100.9"}
+	return d, s
+}
+
+func TestKillSwitchFollowsGuardedSession(t *testing.T) {
+	enabled, disabled := stubKillSwitch(t)
+	d, s := newKillSwitchTestDaemon()
+
+	d.postConnect(s)
+	if len(*enabled) != 1 {
+		t.Fatalf("kill switch enabled %d times, want once", len(*enabled))
+	}
+	got := (*enabled)[0]
+	if !slices.Equal(got.Tunnels, []string{"tun0", "tun1"}) || !slices.Equal(got.Servers, []string{"198.51.100.9", "203.0.113.7"}) {
+		t.Fatalf("kill switch = %+v, want both tunnels and servers allowed", got)
+	}
+	assertBool(t, "state kill switch", d.stateMsg().KillSwitch, true)
+
+	d.postConnect(s)
+	if len(*enabled) != 1 {
+		t.Fatalf("unchanged rules enabled again")
+	}
+
+	d.endSession(s, models.EndUserDisconnect)
+	if *disabled != 1 {
+		t.Fatalf("kill switch disabled %d times after user disconnect, want once", *disabled)
+	}
+	assertBool(t, "state kill switch", d.stateMsg().KillSwitch, false)
+}
+
+func TestKillSwitchHeldAfterReconnectFailed(t *testing.T) {
+	_, disabled := stubKillSwitch(t)
+	d, s := newKillSwitchTestDaemon()
+
+	d.postConnect(s)
+	d.endSession(s, models.EndReconnectFailed)
+	d.endSession(d.session("lab"), models.EndExit)
+	if *disabled != 0 {
+		t.Fatal("kill switch removed after the guarded tunnel failed")
+	}
+	assertBool(t, "state kill switch", d.stateMsg().KillSwitch, true)
+
+	c, conn := attachTestClient(t, d)
+	go d.handleKillSwitchOff(c, KillSwitchOffCmd{Type: "killswitch_off", ID: "3"})
+
+	var result ResultMsg
+	for result.ID == "" {
+		msg := readTestMsg(t, conn)
+		if msg.Type != "result" {
+			continue
+		}
+		if err := msg.Decode(&result); err != nil {
+			t.Fatalf("Decode returned error: %v", err)
+		}
+	}
+	assertString(t, "result id", result.ID, "3")
+	assertBool(t, "result ok", result.OK, true)
+	if *disabled != 1 {
+		t.Fatalf("killswitch_off disabled the kill switch %d times, want once", *disabled)
+	}
+	assertBool(t, "state kill switch", d.stateMsg().KillSwitch, false)
+}
+
+func TestKillSwitchSkipsUnguardedSessions(t *testing.T) {
+	enabled, disabled := stubKillSwitch(t)
+	d, s := newKillSwitchTestDaemon()
+	s.guarded = false
+
+	d.postConnect(s)
+	d.endSession(s, models.EndExit)
+	if len(*enabled) != 0 || *disabled != 0 {
+		t.Fatalf("enabled = %d, disabled = %d without a guarded session", len(*enabled), *disabled)
+	}
+}
+
+func TestResolveArg(t *testing.T) {
+	for _, tc := range []struct{ host, server, want string }{
+		{"vpn.example.com", "203.0.113.7", "--resolve=vpn.example.com:203.0.113.7"},
+		{"https://vpn.example.com:8443/group", "2001:db8::7", "--resolve=vpn.example.com:2001:db8::7"},
+		{"203.0.113.7", "203.0.113.7", ""},
+		{"vpn.example.com", "", ""},
+	} {
+		assertString(t, tc.host, resolveArg(tc.host, tc.server), tc.want)
+	}
+}
+
+func TestServerProbeAddr(t *testing.T) {
+	assertString(t, "probe", serverProbeAddr("vpn.example.com:8443", "2001:db8::7"), "[2001:db8::7]:8443")
+	assertString(t, "probe", serverProbeAddr("vpn.example.com", "203.0.113.7"), "203.0.113.7:443")
+	assertString(t, "probe", serverProbeAddr("vpn.example.com", ""), "vpn.example.com:443")
+}
@@ -0,0 +1,170 @@
+package daemon
+
+import (
+	"time"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/controllers/helpers"
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+	"github.com/Nybkox/lazyopenconnect/pkg/ui"
+)
+
+// networkSettle is how long the routing table must stay quiet before it is
+// compared with the snapshot; a roam or DHCP renewal changes it in bursts.
+const networkSettle = 3 * time.Second
+
+// detectRouteTo and detectDefaultRoute are swapped out by tests.
+var (
+	detectRouteTo      = helpers.DetectRouteTo
+	detectDefaultRoute = func() (string, string) {
+		iface, _ := helpers.DetectDefaultInterface()
+		gateway, _ := helpers.DetectDefaultGateway()
+		return iface, gateway
+	}
+)
+
+// networkMonitor watches the routing table and reconnects tunnels whose
+// physical route moved to another interface or gateway, e.g. when
+// switching from Wi-Fi to Ethernet.
+func (d *Daemon) networkMonitor() {
+	changes, err := helpers.WatchRoutes(d.shutdown)
+	if err != nil {
+		d.logger.Warn("network change monitor unavailable", "err", err)
+		return
+	}
+
+	var settle <-chan time.Time
+	for {
+		select {
+		case <-d.shutdown:
+			return
+		case _, ok := <-changes:
+			if !ok {
+				d.logger.Warn("network change monitor stopped")
+				return
+			}
+			settle = time.After(networkSettle)
+		case <-settle:
+			settle = nil
+			d.handleNetworkChange()
+		}
+	}
+}
+
+// handleNetworkChange compares the route to each tunnel's server with the
+// one its own snapshot recorded, so a change on the interface one server is
+// reached through leaves tunnels reached through another alone.
+func (d *Daemon) handleNetworkChange() {
+	type candidate struct {
+		s        *session
+		serverIP string
+		snap     helpers.NetworkSnapshot
+	}
+	d.stateMu.RLock()
+	var connected []candidate
+	tunnels := make(map[string]bool)
+	for _, s := range d.state.Sessions {
+		if s.tunnel != "" {
+			tunnels[s.tunnel] = true
+		}
+		if s.status == StatusConnected && s.snapshot != nil {
+			connected = append(connected, candidate{s: s, serverIP: s.serverIP, snap: *s.snapshot})
+		}
+	}
+	pinned := d.state.Config.Settings.NetInterface != ""
+	d.stateMu.RUnlock()
+
+	for _, c := range connected {
+		iface, gateway := physicalRoute(c.serverIP, tunnels)
+		d.handleSessionRoute(c.s, &c.snap, iface, gateway, pinned)
+	}
+}
+
+// handleSessionRoute reconnects s when iface and gateway, the current route
+// to its server, differ from snap, the one recorded when it connected.
+func (d *Daemon) handleSessionRoute(s *session, snap *helpers.NetworkSnapshot, iface, gateway string, pinned bool) {
+	if iface == "" {
+		d.logger.Debug("network change: no route outside the tunnels yet", "conn_id", s.connID)
+		return
+	}
+	if !routeChanged(snap, iface, gateway, pinned) {
+		d.logger.Debug("network change: route unchanged", "conn_id", s.connID, "interface", iface, "gateway", gateway)
+		return
+	}
+
+	d.logger.Info("network change detected", "conn_id", s.connID,
+		"old_interface", snap.DefaultInterface, "old_gateway", snap.DefaultGateway,
+		"interface", iface, "gateway", gateway)
+	d.refreshSnapshot(s, iface, gateway, pinned)
+
+	d.stateMu.RLock()
+	reconnect := d.reconnectsOn(s, models.EndNetworkChange)
+	stillConnected := s.status == StatusConnected
+	d.stateMu.RUnlock()
+	if !stillConnected {
+		return
+	}
+	if !reconnect {
+		d.addLog(s, ui.LogWarning("--- Network changed; tunnel may be stale (reconnect on network change is off) ---"))
+		return
+	}
+
+	d.logger.Info("network change: initiating reconnect", "conn_id", s.connID)
+	d.addLog(s, ui.LogWarning("--- Network changed, will reconnect ---"))
+
+	d.recordHistory(s, models.EndNetworkChange)
+	d.stopForReconnect(s)
+	go d.startAutoReconnect(s, models.EndNetworkChange)
+}
+
+// physicalRoute finds the interface and gateway traffic leaves through
+// outside the tunnels: the route to the VPN server, which connect scripts
+// pin to the physical gateway, else the default route.
+func physicalRoute(serverIP string, tunnels map[string]bool) (iface, gateway string) {
+	if serverIP != "" {
+		if iface, gateway, err := detectRouteTo(serverIP); err == nil && iface != "" && !tunnels[iface] {
+			return iface, gateway
+		}
+	}
+	iface, gateway = detectDefaultRoute()
+	if tunnels[iface] {
+		return "", ""
+	}
+	return iface, gateway
+}
+
+// routeChanged reports whether the physical route differs from the one snap
+// recorded. A pinned interface comes from the settings and only its gateway
+// is compared.
+func routeChanged(snap *helpers.NetworkSnapshot, iface, gateway string, pinned bool) bool {
+	if !pinned && snap.DefaultInterface != "" && iface != snap.DefaultInterface {
+		return true
+	}
+	return snap.DefaultGateway != "" && gateway != "" && gateway != snap.DefaultGateway
+}
+
+// refreshSnapshot records the new physical route in the snapshot of s, so
+// cleanup restores it rather than the one that is gone. DNS is kept: the
+// servers visible now are the tunnels'.
+func (d *Daemon) refreshSnapshot(s *session, iface, gateway string, pinned bool) {
+	wifiService := ""
+	if !pinned {
+		if svc, err := helpers.DetectWifiServiceName(iface); err == nil {
+			wifiService = svc
+		}
+	}
+
+	d.stateMu.Lock()
+	defer d.stateMu.Unlock()
+	if s.snapshot == nil {
+		return
+	}
+	if !pinned {
+		s.snapshot.DefaultInterface = iface
+		if wifiService != "" && d.state.Config.Settings.WifiInterface == "" {
+			s.snapshot.WifiServiceName = wifiService
+		}
+	}
+	if gateway != "" {
+		s.snapshot.DefaultGateway = gateway
+	}
+}
@@ -0,0 +1,159 @@
+package daemon
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/controllers/helpers"
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+)
+
+func stubRoutes(t *testing.T, toServer, byDefault [2]string) {
+	t.Helper()
+	origTo, origDefault := detectRouteTo, detectDefaultRoute
+	detectRouteTo = func(string) (string, string, error) {
+		if toServer[0] == "" {
+			return "", "", errors.New("no route")
+		}
+		return toServer[0], toServer[1], nil
+	}
+	detectDefaultRoute = func() (string, string) { return byDefault[0], byDefault[1] }
+	t.Cleanup(func() { detectRouteTo, detectDefaultRoute = origTo, origDefault })
+}
+
+func TestRouteChanged(t *testing.T) {
+	snap := &helpers.NetworkSnapshot{DefaultInterface: "wlan0", DefaultGateway: "192.168.1.1"}
+	tests := []struct {
+		name    string
+		iface   string
+		gateway string
+		pinned  bool
+		want    bool
+	}{
+		{"same route", "wlan0", "192.168.1.1", false, false},
+		{"new interface", "eth0", "192.168.1.1", false, true},
+		{"new gateway", "wlan0", "10.1.0.1", false, true},
+		{"on-link server", "wlan0", "", false, false},
+		{"pinned interface", "eth0", "192.168.1.1", true, false},
+		{"pinned interface, new gateway", "eth0", "10.1.0.1", true, true},
+	}
+	for _, tt := range tests {
+		if got := routeChanged(snap, tt.iface, tt.gateway, tt.pinned); got != tt.want {
+			t.Fatalf("%s: routeChanged = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPhysicalRouteSkipsTunnels(t *testing.T) {
+	tunnels := map[string]bool{"tun0": true}
+
+	stubRoutes(t, [2]string{"eth0", "10.1.0.1"}, [2]string{"tun0", ""})
+	if iface, gateway := physicalRoute("203.0.113.7", tunnels); iface != "eth0" || gateway != "10.1.0.1" {
+		t.Fatalf("physicalRoute = %q, %q; want the route to the server", iface, gateway)
+	}
+
+	stubRoutes(t, [2]string{"tun0", ""}, [2]string{"wlan0", "192.168.1.1"})
+	if iface, _ := physicalRoute("203.0.113.7", tunnels); iface != "wlan0" {
+		t.Fatalf("physicalRoute = %q, want the default route when the server route is tunneled", iface)
+	}
+
+	stubRoutes(t, [2]string{"tun0", ""}, [2]string{"tun0", ""})
+	if iface, _ := physicalRoute("203.0.113.7", tunnels); iface != "" {
+		t.Fatalf("physicalRoute = %q, want none when every route is tunneled", iface)
+	}
+}
+
+func newNetworkTestDaemon(reconnect bool) (*Daemon, *session) {
+	d := newTestDaemon()
+	d.state.Config.Settings.Reconnect = reconnect
+	d.state.Config.Connections = []models.Connection{{ID: "work", Name: "Work", Host: "vpn.example.com"}}
+	s := &session{
+		connID:      "work",
+		status:      StatusConnected,
+		tunnel:      "tun0",
+		serverIP:    "203.0.113.7",
+		connectedAt: time.Now(),
+		snapshot:    &helpers.NetworkSnapshot{DefaultInterface: "wlan0", DefaultGateway: "192.168.1.1"},
+	}
+	d.state.Sessions["work"] = s
+	// Stop any reconnect before it dials anything.
+	close(d.shutdown)
+	return d, s
+}
+
+func TestHandleNetworkChangeReconnects(t *testing.T) {
+	d, s := newNetworkTestDaemon(true)
+	stubRoutes(t, [2]string{"eth0", "10.1.0.1"}, [2]string{"tun0", ""})
+
+	d.handleNetworkChange()
+
+	if status := d.sessionStatus(s); status != StatusReconnecting {
+		t.Fatalf("status = %v, want reconnecting", status)
+	}
+	d.stateMu.RLock()
+	snap := *s.snapshot
+	d.stateMu.RUnlock()
+	assertString(t, "DefaultInterface", snap.DefaultInterface, "eth0")
+	assertString(t, "DefaultGateway", snap.DefaultGateway, "10.1.0.1")
+}
+
+func TestHandleNetworkChangeIgnoresSameRoute(t *testing.T) {
+	d, s := newNetworkTestDaemon(true)
+	stubRoutes(t, [2]string{"wlan0", "192.168.1.1"}, [2]string{"tun0", ""})
+
+	d.handleNetworkChange()
+
+	if status := d.sessionStatus(s); status != StatusConnected {
+		t.Fatalf("status = %v, want connected", status)
+	}
+}
+
+func TestHandleNetworkChangeRespectsPolicy(t *testing.T) {
+	d, s := newNetworkTestDaemon(true)
+	off := false
+	d.state.Config.Settings.ReconnectPolicy.OnNetworkChange = &off
+	stubRoutes(t, [2]string{"eth0", "10.1.0.1"}, [2]string{"tun0", ""})
+
+	d.handleNetworkChange()
+
+	if status := d.sessionStatus(s); status != StatusConnected {
+		t.Fatalf("status = %v, want connected with reconnect on network change off", status)
+	}
+	assertString(t, "DefaultInterface", s.snapshot.DefaultInterface, "eth0")
+}
+
+func TestHandleNetworkChangeDecidesPerSession(t *testing.T) {
+	d, work := newNetworkTestDaemon(true)
+	d.state.Config.Connections = append(d.state.Config.Connections, models.Connection{ID: "lab", Name: "Lab", Host: "lab.example.com"})
+	lab := &session{
+		connID:      "lab",
+		status:      StatusConnected,
+		tunnel:      "tun1",
+		serverIP:    "198.51.100.9",
+		connectedAt: time.Now(),
+		snapshot:    &helpers.NetworkSnapshot{DefaultInterface: "eth0", DefaultGateway: "10.1.0.1"},
+	}
+	d.state.Sessions["lab"] = lab
+
+	// Only the work server moved to the lab's interface; the lab server
+	// is still reached the way its snapshot recorded.
+	origTo, origDefault := detectRouteTo, detectDefaultRoute
+	detectRouteTo = func(string) (string, string, error) { return "eth0", "10.1.0.1", nil }
+	detectDefaultRoute = func() (string, string) { return "tun0", "" }
+	t.Cleanup(func() { detectRouteTo, detectDefaultRoute = origTo, origDefault })
+
+	d.handleNetworkChange()
+
+	if status := d.sessionStatus(work); status != StatusReconnecting {
+		t.Fatalf("work status = %v, want reconnecting", status)
+	}
+	if status := d.sessionStatus(lab); status != StatusConnected {
+		t.Fatalf("lab status = %v, want connected", status)
+	}
+	d.stateMu.RLock()
+	workIface, labIface := work.snapshot.DefaultInterface, lab.snapshot.DefaultInterface
+	d.stateMu.RUnlock()
+	assertString(t, "work DefaultInterface", workIface, "eth0")
+	assertString(t, "lab DefaultInterface", labIface, "eth0")
+}
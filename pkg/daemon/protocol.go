@@ -5,77 +5,170 @@ import (
 	"encoding/json"
 	"errors"
 	"net"
+	"slices"
+	"strconv"
+	"sync/atomic"
 
 	"github.com/Nybkox/lazyopenconnect/pkg/models"
 )
 
+// ProtocolVersion is bumped only when the wire format changes in a way an
+// older peer cannot handle. Additions that older peers can ignore are
+// advertised as capabilities instead, so the daemon survives upgrades.
+const ProtocolVersion = 1
+
+// Capabilities name optional features; a peer only relies on one when the
+// other side advertised it in the hello exchange.
+const (
+	// CapOwnership: prompts go to one owning client (take_ownership).
+	CapOwnership = "ownership"
+	// CapSessions: several tunnels at once; messages carry conn_id.
+	CapSessions = "sessions"
+	// CapRequestIDs: commands with an id are answered by ack and result.
+	CapRequestIDs = "request_ids"
+	// CapStats: connected tunnels report traffic in stats messages.
+	CapStats = "stats"
+	// CapHealth: tunnels with a health check report each probe in health
+	// messages.
+	CapHealth = "health"
+	// CapTimeouts: the daemon enforces connect deadlines, reports them in
+	// state and fails with connect_timeout.
+	CapTimeouts = "timeouts"
+	// CapKillSwitch: the daemon runs the kill switch of connections that
+	// enable it, reports it in state and accepts killswitch_off.
+	CapKillSwitch = "killswitch"
+)
+
+// Capabilities lists every capability this build supports.
+func Capabilities() []string {
+	return []string{CapOwnership, CapSessions, CapRequestIDs, CapStats, CapHealth, CapTimeouts, CapKillSwitch}
+}
+
+// HelloCmd opens every connection. Version is informational; Protocol
+// decides compatibility.
 type HelloCmd struct {
-	Type    string `json:"type"`
-	Version string `json:"version"`
+	Type         string   `json:"type"`
+	Version      string   `json:"version"`
+	Protocol     int      `json:"protocol"`
+	Capabilities []string `json:"capabilities,omitempty"`
 }
 
+// HelloResponse answers HelloCmd. Tunnels and Clients let an incompatible
+// client warn before restarting a daemon that still carries traffic or
+// serves other clients.
 type HelloResponse struct {
-	Type       string `json:"type"`
-	Version    string `json:"version"`
-	Compatible bool   `json:"compatible"`
+	Type         string   `json:"type"`
+	Version      string   `json:"version"`
+	Protocol     int      `json:"protocol"`
+	Capabilities []string `json:"capabilities,omitempty"`
+	Compatible   bool     `json:"compatible"`
+	Tunnels      int      `json:"tunnels"`
+	Clients      int      `json:"clients"`
+}
+
+// Supports reports whether the daemon advertised capability.
+func (h HelloResponse) Supports(capability string) bool {
+	return slices.Contains(h.Capabilities, capability)
 }
 
 type GetStateCmd struct {
 	Type string `json:"type"`
+	ID   string `json:"id,omitempty"`
 }
 
 type ConnectCmd struct {
 	Type     string `json:"type"`
+	ID       string `json:"id,omitempty"`
 	ConnID   string `json:"conn_id"`
 	Password string `json:"password,omitempty"`
 }
 
+// DisconnectCmd stops the tunnel for ConnID. External stops an openconnect
+// started outside the daemon instead; with neither set every tunnel stops.
+// Reason "timeout" marks a client giving up on a connect, for the session
+// history; anything else counts as a user disconnect.
 type DisconnectCmd struct {
-	Type string `json:"type"`
+	Type     string `json:"type"`
+	ID       string `json:"id,omitempty"`
+	ConnID   string `json:"conn_id,omitempty"`
+	External bool   `json:"external,omitempty"`
+	Reason   string `json:"reason,omitempty"`
 }
 
 type InputCmd struct {
-	Type  string `json:"type"`
-	Value string `json:"value"`
+	Type   string `json:"type"`
+	ID     string `json:"id,omitempty"`
+	ConnID string `json:"conn_id"`
+	Value  string `json:"value"`
 }
 
 type ConfigUpdateCmd struct {
 	Type   string        `json:"type"`
+	ID     string        `json:"id,omitempty"`
 	Config models.Config `json:"config"`
 }
 
 type ShutdownCmd struct {
 	Type string `json:"type"`
+	ID   string `json:"id,omitempty"`
 }
 
 type GetLogsCmd struct {
-	Type string `json:"type"`
-	From int    `json:"from"`
-	To   int    `json:"to"`
+	Type   string `json:"type"`
+	ID     string `json:"id,omitempty"`
+	ConnID string `json:"conn_id"`
+	From   int    `json:"from"`
+	To     int    `json:"to"`
 }
 
 type ClearLogsCmd struct {
-	Type string `json:"type"`
+	Type   string `json:"type"`
+	ID     string `json:"id,omitempty"`
+	ConnID string `json:"conn_id"`
 }
 
-type StateMsg struct {
-	Type          string `json:"type"`
+// SessionState describes one tunnel run by the daemon.
+type SessionState struct {
+	ConnID        string `json:"conn_id"`
 	Status        int    `json:"status"`
-	ActiveConnID  string `json:"active_conn_id"`
-	IP            string `json:"ip"`
-	PID           int    `json:"pid"`
+	IP            string `json:"ip,omitempty"`
+	PID           int    `json:"pid,omitempty"`
+	Tunnel        string `json:"tunnel,omitempty"`
 	TotalLogLines int    `json:"total_log_lines"`
-	ExternalHost  string `json:"external_host,omitempty"`
+	ConnectedAt   int64  `json:"connected_at,omitempty"`
+	// Deadline is when the daemon gives up connecting or waiting for a
+	// prompt's answer, in Unix seconds.
+	Deadline int64 `json:"deadline,omitempty"`
+	// IncludeRoutes and ExcludeRoutes are the split routes the tunnel was
+	// started with.
+	IncludeRoutes []string `json:"include_routes,omitempty"`
+	ExcludeRoutes []string `json:"exclude_routes,omitempty"`
+}
+
+// StateMsg is broadcast on every change. In reply to get_state it carries
+// the request's ID.
+type StateMsg struct {
+	Type         string         `json:"type"`
+	ID           string         `json:"id,omitempty"`
+	Sessions     []SessionState `json:"sessions"`
+	ExternalHost string         `json:"external_host,omitempty"`
+	ExternalPID  int            `json:"external_pid,omitempty"`
+	// KillSwitch is set while the kill switch blocks traffic outside the
+	// tunnels, including after a guarded tunnel went down for good.
+	KillSwitch bool `json:"kill_switch,omitempty"`
 }
 
 type LogMsg struct {
 	Type       string `json:"type"`
+	ConnID     string `json:"conn_id"`
 	Line       string `json:"line"`
 	LineNumber int    `json:"line_number"`
 }
 
 type LogRangeMsg struct {
 	Type       string   `json:"type"`
+	ID         string   `json:"id,omitempty"`
+	ConnID     string   `json:"conn_id"`
 	From       int      `json:"from"`
 	Lines      []string `json:"lines"`
 	TotalLines int      `json:"total_lines"`
@@ -83,29 +176,80 @@ type LogRangeMsg struct {
 
 type PromptMsg struct {
 	Type       string `json:"type"`
+	ConnID     string `json:"conn_id"`
 	IsPassword bool   `json:"is_password"`
 }
 
 type ConnectedMsg struct {
-	Type string `json:"type"`
-	IP   string `json:"ip"`
-	PID  int    `json:"pid"`
+	Type   string `json:"type"`
+	ConnID string `json:"conn_id"`
+	IP     string `json:"ip"`
+	PID    int    `json:"pid"`
+	Tunnel string `json:"tunnel,omitempty"`
+}
+
+// StatsMsg carries the counters of a connected tunnel every few seconds.
+// Rates are bytes per second since the previous sample.
+type StatsMsg struct {
+	Type      string  `json:"type"`
+	ConnID    string  `json:"conn_id"`
+	RxBytes   uint64  `json:"rx_bytes"`
+	TxBytes   uint64  `json:"tx_bytes"`
+	RxPackets uint64  `json:"rx_packets"`
+	TxPackets uint64  `json:"tx_packets"`
+	RxErrors  uint64  `json:"rx_errors"`
+	TxErrors  uint64  `json:"tx_errors"`
+	RxRate    float64 `json:"rx_rate"`
+	TxRate    float64 `json:"tx_rate"`
+}
+
+// HealthMsg reports a health probe of a connected tunnel. Failures counts
+// failed probes in a row; reaching Threshold reconnects the tunnel.
+type HealthMsg struct {
+	Type      string  `json:"type"`
+	ConnID    string  `json:"conn_id"`
+	OK        bool    `json:"ok"`
+	RTTMs     float64 `json:"rtt_ms,omitempty"`
+	Error     string  `json:"error,omitempty"`
+	Failures  int     `json:"failures"`
+	Threshold int     `json:"threshold"`
 }
 
+// DisconnectedMsg reports that the tunnel for ConnID is gone. An empty
+// ConnID means the external openconnect stopped.
 type DisconnectedMsg struct {
-	Type string `json:"type"`
+	Type   string `json:"type"`
+	ConnID string `json:"conn_id,omitempty"`
 }
 
 type ErrorMsg struct {
 	Type    string `json:"type"`
+	ConnID  string `json:"conn_id,omitempty"`
 	Code    string `json:"code"`
 	Message string `json:"message"`
 }
 
-type KickedMsg struct {
-	Type string `json:"type"`
+// TakeOwnershipCmd asks to become the client that receives and answers
+// prompts. Without Force it is refused while another client owns the
+// session.
+type TakeOwnershipCmd struct {
+	Type  string `json:"type"`
+	ID    string `json:"id,omitempty"`
+	Force bool   `json:"force,omitempty"`
+}
+
+// OwnershipMsg answers TakeOwnershipCmd, and tells the previous owner when
+// ownership was taken from it. With Free set it is broadcast because the
+// owner detached, so any client may take ownership without forcing it.
+type OwnershipMsg struct {
+	Type   string `json:"type"`
+	Owner  bool   `json:"owner"`
+	Free   bool   `json:"free,omitempty"`
+	Reason string `json:"reason,omitempty"`
 }
 
+// ReconnectingMsg reports progress of an automatic reconnect. Attempt is 0
+// while waiting for the network; Max is 0 when attempts are unlimited.
 type ReconnectingMsg struct {
 	Type    string `json:"type"`
 	ConnID  string `json:"conn_id"`
@@ -116,6 +260,14 @@ type ReconnectingMsg struct {
 
 type CleanupCmd struct {
 	Type string `json:"type"`
+	ID   string `json:"id,omitempty"`
+}
+
+// KillSwitchOffCmd removes the kill switch rules and stops guarding the
+// tunnels that are up until they are connected again.
+type KillSwitchOffCmd struct {
+	Type string `json:"type"`
+	ID   string `json:"id,omitempty"`
 }
 
 type CleanupStepMsg struct {
@@ -127,13 +279,42 @@ type CleanupDoneMsg struct {
 	Type string `json:"type"`
 }
 
+// AckMsg tells the sender of a command with an ID that it was accepted and
+// is still running; its ResultMsg follows once it finished.
+type AckMsg struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// ResultMsg is the outcome of a command sent with an ID. Commands without
+// an ID get no result, and their errors arrive as ErrorMsg instead.
+type ResultMsg struct {
+	Type    string `json:"type"`
+	ID      string `json:"id"`
+	OK      bool   `json:"ok"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+var requestSeq atomic.Uint64
+
+// NewRequestID returns an ID for a command, unique within this process.
+// IDs only need to be unique per connection, so a counter suffices.
+func NewRequestID() string {
+	return strconv.FormatUint(requestSeq.Add(1), 10)
+}
+
 type IncomingMsg struct {
 	Type string
-	raw  json.RawMessage
+	// ID is the request ID of a command, empty for events and for commands
+	// sent without one.
+	ID  string
+	raw json.RawMessage
 }
 
 type messageEnvelope struct {
 	Type string `json:"type"`
+	ID   string `json:"id"`
 }
 
 func WriteMsg(conn net.Conn, msg any) error {
@@ -158,7 +339,7 @@ func ReadMsg(r *bufio.Reader) (IncomingMsg, error) {
 	if env.Type == "" {
 		return IncomingMsg{}, errors.New("missing message type")
 	}
-	return IncomingMsg{Type: env.Type, raw: json.RawMessage(line)}, nil
+	return IncomingMsg{Type: env.Type, ID: env.ID, raw: json.RawMessage(line)}, nil
 }
 
 func (m IncomingMsg) Decode(dst any) error {
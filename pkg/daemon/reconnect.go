@@ -2,6 +2,7 @@ package daemon
 
 import (
 	"fmt"
+	"math/rand/v2"
 	"net"
 	"net/url"
 	"strings"
@@ -12,16 +13,12 @@ import (
 )
 
 const (
-	wakeTickInterval     = 5 * time.Second
-	wakeThreshold        = 20
-	wakeDebounce         = 10 * time.Second
-	networkWaitInitial   = 3 * time.Second
-	networkProbeMax      = 30 * time.Second
-	maxReconnectAttempts = 3
+	wakeTickInterval   = 5 * time.Second
+	wakeThreshold      = 20
+	wakeDebounce       = 10 * time.Second
+	networkWaitInitial = 3 * time.Second
 )
 
-var reconnectBackoffs = []time.Duration{2 * time.Second, 5 * time.Second, 10 * time.Second}
-
 func (d *Daemon) wakeMonitor() {
 	ticker := time.NewTicker(wakeTickInterval)
 	defer ticker.Stop()
@@ -52,14 +49,20 @@ func (d *Daemon) wakeMonitor() {
 	}
 }
 
+// reconnectsOn reports whether s is reconnected after going down for
+// reason. Called with stateMu held.
+func (d *Daemon) reconnectsOn(s *session, reason string) bool {
+	cfg := d.state.Config
+	return cfg.Settings.Reconnect && cfg.ReconnectPolicyFor(s.connID).ReconnectsOn(reason)
+}
+
 func (d *Daemon) handleWake() {
 	d.stateMu.RLock()
-	status := d.state.Status
-	connID := d.state.ActiveConnID
 	reconnectEnabled := d.state.Config.Settings.Reconnect
 	d.stateMu.RUnlock()
 
-	if connID == "" {
+	sessions := d.sessions()
+	if len(sessions) == 0 {
 		d.logger.Debug("wake: no active connection")
 		return
 	}
@@ -69,35 +72,49 @@ func (d *Daemon) handleWake() {
 		return
 	}
 
-	if status != StatusConnected && status != StatusConnecting && status != StatusPrompting {
-		d.logger.Debug("wake: not in reconnectable state", "status", status)
-		return
-	}
+	for _, s := range sessions {
+		status := d.sessionStatus(s)
+		if status != StatusConnected && status != StatusConnecting && status != StatusPrompting {
+			d.logger.Debug("wake: not in reconnectable state", "conn_id", s.connID, "status", status)
+			continue
+		}
 
-	d.logger.Info("wake: initiating reconnect", "conn_id", connID)
-	d.addLog(ui.LogWarning("--- Wake detected, will reconnect ---"))
+		d.stateMu.RLock()
+		onWake := d.reconnectsOn(s, models.EndWake)
+		d.stateMu.RUnlock()
+		if !onWake {
+			d.logger.Debug("wake: reconnect on wake disabled for connection", "conn_id", s.connID)
+			continue
+		}
 
-	d.stopForReconnect()
-	go d.startAutoReconnect(connID, "wake")
-}
+		d.logger.Info("wake: initiating reconnect", "conn_id", s.connID)
+		d.addLog(s, ui.LogWarning("--- Wake detected, will reconnect ---"))
 
-func (d *Daemon) stopForReconnect() {
-	d.reconnectMu.Lock()
-	d.stoppingForReconnect = true
-	d.reconnectMu.Unlock()
+		d.recordHistory(s, models.EndWake)
+		d.stopForReconnect(s)
+		go d.startAutoReconnect(s, "wake")
+	}
+}
 
-	d.vpnMu.Lock()
-	proc := d.vpnProcess
-	d.vpnMu.Unlock()
+func (d *Daemon) stopForReconnect(s *session) {
+	d.stateMu.Lock()
+	proc := s.process
+	s.process = nil
+	s.status = StatusReconnecting
+	s.ip = ""
+	s.pid = 0
+	s.connectedAt = time.Time{}
+	d.stateMu.Unlock()
 
 	if proc == nil {
-		d.reconnectMu.Lock()
-		d.stoppingForReconnect = false
-		d.reconnectMu.Unlock()
 		return
 	}
 
-	d.logger.Debug("stopping vpn for reconnect")
+	d.reconnectMu.Lock()
+	s.stoppingForReconnect = true
+	d.reconnectMu.Unlock()
+
+	d.logger.Debug("stopping vpn for reconnect", "conn_id", s.connID)
 
 	if proc.cmd != nil && proc.cmd.Process != nil {
 		proc.cmd.Process.Kill()
@@ -105,156 +122,139 @@ func (d *Daemon) stopForReconnect() {
 	if proc.ptmx != nil {
 		proc.ptmx.Close()
 	}
-
-	d.vpnMu.Lock()
-	d.vpnProcess = nil
-	d.vpnMu.Unlock()
-
-	d.stateMu.Lock()
-	d.state.Status = StatusDisconnected
-	d.state.IP = ""
-	d.state.PID = 0
-	d.stateMu.Unlock()
 }
 
-func (d *Daemon) startAutoReconnect(connID string, reason string) {
+func (d *Daemon) startAutoReconnect(s *session, reason string) {
+	connID := s.connID
+
 	d.reconnectMu.Lock()
-	if d.reconnecting {
+	if s.reconnecting {
 		d.reconnectMu.Unlock()
-		d.logger.Debug("reconnect already in progress")
+		d.logger.Debug("reconnect already in progress", "conn_id", connID)
 		return
 	}
-	d.reconnecting = true
-	d.reconnectCancel = make(chan struct{})
-	d.disconnectRequested = false
-	cancelCh := d.reconnectCancel
+	s.reconnecting = true
+	s.reconnectCancel = make(chan struct{})
+	s.disconnectRequested = false
+	cancelCh := s.reconnectCancel
 	d.reconnectMu.Unlock()
 
 	defer func() {
 		d.reconnectMu.Lock()
-		d.reconnecting = false
-		d.reconnectCancel = nil
+		s.reconnecting = false
+		s.reconnectCancel = nil
 		d.reconnectMu.Unlock()
 	}()
 
+	d.startRun(s)
+
 	d.stateMu.RLock()
-	var conn *models.Connection
-	for i := range d.state.Config.Connections {
-		if d.state.Config.Connections[i].ID == connID {
-			conn = &d.state.Config.Connections[i]
-			break
-		}
-	}
+	conn := connectionByID(d.state.Config, connID)
+	policy := d.state.Config.ReconnectPolicyFor(connID)
 	d.stateMu.RUnlock()
+	maxAttempts := policy.Attempts()
 
 	if conn == nil {
 		d.logger.Warn("reconnect: connection not found", "conn_id", connID)
-		d.addLog(ui.LogError("Reconnect failed: connection not found"))
+		d.addLog(s, ui.LogError("Reconnect failed: connection not found"))
+		d.endSession(s, models.EndReconnectFailed)
 		return
 	}
 
-	d.sendToClient(ReconnectingMsg{
+	d.broadcast(ReconnectingMsg{
 		Type:    "reconnecting",
 		ConnID:  connID,
 		Reason:  reason,
 		Attempt: 0,
-		Max:     maxReconnectAttempts,
+		Max:     maxAttempts,
 	})
 
-	d.logger.Debug("waiting initial delay before reconnect pre-connect cleanup")
+	d.runHooks(s, models.HookReconnect, reason)
+
+	d.logger.Debug("waiting initial delay before reconnect pre-connect cleanup", "conn_id", connID)
 
 	select {
 	case <-cancelCh:
-		d.logger.Debug("reconnect cancelled during initial wait")
+		d.logger.Debug("reconnect cancelled during initial wait", "conn_id", connID)
 		return
 	case <-d.shutdown:
 		return
 	case <-time.After(networkWaitInitial):
 	}
 
-	d.runCleanupSync("Reconnect pre-connect cleanup")
+	d.runCleanupSync("Reconnect pre-connect cleanup", s)
 
 	select {
 	case <-cancelCh:
-		d.logger.Debug("reconnect cancelled after pre-connect cleanup")
+		d.logger.Debug("reconnect cancelled after pre-connect cleanup", "conn_id", connID)
 		return
 	case <-d.shutdown:
 		return
 	default:
 	}
 
-	d.reconnectMu.Lock()
-	if d.disconnectRequested {
-		d.reconnectMu.Unlock()
-		d.logger.Debug("reconnect cancelled after pre-connect cleanup: disconnect requested")
+	if d.reconnectCancelled(s) {
+		d.logger.Debug("reconnect cancelled after pre-connect cleanup: disconnect requested", "conn_id", connID)
 		return
 	}
-	d.reconnectMu.Unlock()
 
-	d.addLog(ui.LogWarning("Waiting for network..."))
-
-	if !d.waitForNetwork(conn.Host, cancelCh) {
-		d.logger.Warn("reconnect: network not available")
-		d.addLog(ui.LogError("Reconnect failed: network not available"))
-		d.stateMu.Lock()
-		d.state.Status = StatusDisconnected
-		d.state.ActiveConnID = ""
-		d.state.IP = ""
-		d.state.PID = 0
-		d.stateMu.Unlock()
-		d.sendToClient(DisconnectedMsg{Type: "disconnected"})
+	d.addLog(s, ui.LogWarning("Waiting for network..."))
+
+	probeAddr := extractProbeAddr(conn.Host)
+	if d.killSwitchOn.Load() {
+		d.stateMu.RLock()
+		probeAddr = serverProbeAddr(conn.Host, s.serverIP)
+		d.stateMu.RUnlock()
+	}
+	if !d.waitForNetwork(probeAddr, policy.NetworkTimeout(), cancelCh) {
+		d.logger.Warn("reconnect: network not available", "conn_id", connID)
+		d.addLog(s, ui.LogError("Reconnect failed: network not available"))
+		d.endSession(s, models.EndReconnectFailed)
 		return
 	}
 
-	d.addLog(ui.LogOK("Network available"))
+	d.addLog(s, ui.LogOK("Network available"))
 
 	password := ""
-	if conn.HasPassword {
+	if conn.SendsPassword() {
 		d.reconnectMu.Lock()
 		password = d.passwordCache[connID]
 		d.reconnectMu.Unlock()
 	}
 
-	for attempt := 1; attempt <= maxReconnectAttempts; attempt++ {
+	for attempt := 1; maxAttempts == 0 || attempt <= maxAttempts; attempt++ {
 		select {
 		case <-cancelCh:
-			d.logger.Debug("reconnect cancelled")
+			d.logger.Debug("reconnect cancelled", "conn_id", connID)
 			return
 		case <-d.shutdown:
 			return
 		default:
 		}
 
-		d.reconnectMu.Lock()
-		if d.disconnectRequested {
-			d.reconnectMu.Unlock()
-			d.logger.Debug("reconnect cancelled: disconnect requested")
+		if d.reconnectCancelled(s) {
+			d.logger.Debug("reconnect cancelled: disconnect requested", "conn_id", connID)
 			return
 		}
-		d.reconnectMu.Unlock()
 
-		d.logger.Info("reconnect attempt", "attempt", attempt, "max", maxReconnectAttempts)
-		d.addLog(ui.LogWarning(fmt.Sprintf("Reconnecting... (attempt %d/%d)", attempt, maxReconnectAttempts)))
+		d.logger.Info("reconnect attempt", "conn_id", connID, "attempt", attempt, "max", maxAttempts)
+		d.addLog(s, ui.LogWarning("Reconnecting... ("+attemptLabel(attempt, maxAttempts)+")"))
 
-		d.sendToClient(ReconnectingMsg{
+		d.broadcast(ReconnectingMsg{
 			Type:    "reconnecting",
 			ConnID:  connID,
 			Reason:  reason,
 			Attempt: attempt,
-			Max:     maxReconnectAttempts,
+			Max:     maxAttempts,
 		})
 
-		d.stateMu.Lock()
-		d.state.Status = StatusConnecting
-		d.state.ActiveConnID = connID
-		d.stateMu.Unlock()
-
-		d.doConnect(connID, password)
+		d.countAttempt(s)
+		d.startPhase(s, StatusConnecting, phaseReconnect)
+		d.doConnect(s, conn, password)
 
-		connectTimeout := 30 * time.Second
-		connectDeadline := time.Now().Add(connectTimeout)
-
-		for time.Now().Before(connectDeadline) {
+		// timeoutMonitor stops an attempt that overruns its deadline,
+		// which ends this wait like any other failed try.
+		for d.sessionStatus(s) == StatusConnecting {
 			select {
 			case <-cancelCh:
 				return
@@ -263,38 +263,33 @@ func (d *Daemon) startAutoReconnect(connID string, reason string) {
 			case <-time.After(500 * time.Millisecond):
 			}
 
-			d.stateMu.RLock()
-			status := d.state.Status
-			d.stateMu.RUnlock()
+			status := d.sessionStatus(s)
 
 			if status == StatusConnected {
-				d.logger.Info("reconnect successful")
-				d.addLog(ui.LogOK("Reconnected successfully"))
+				d.logger.Info("reconnect successful", "conn_id", connID)
+				d.addLog(s, ui.LogOK("Reconnected successfully"))
 				return
 			}
 
 			if status == StatusPrompting {
-				d.logger.Info("reconnect waiting for user input (prompt)")
+				d.logger.Info("reconnect waiting for user input (prompt)", "conn_id", connID)
 				return
 			}
 
-			if status == StatusDisconnected {
-				d.logger.Debug("reconnect attempt failed, vpn exited")
+			if status == StatusReconnecting {
+				d.logger.Debug("reconnect attempt failed, vpn exited", "conn_id", connID)
 				break
 			}
 		}
 
-		d.stateMu.RLock()
-		status := d.state.Status
-		d.stateMu.RUnlock()
-
+		status := d.sessionStatus(s)
 		if status == StatusConnected || status == StatusPrompting {
 			return
 		}
 
-		if attempt < maxReconnectAttempts {
-			backoff := reconnectBackoffs[attempt-1]
-			d.addLog(ui.LogWarning(fmt.Sprintf("Retrying in %ds...", int(backoff.Seconds()))))
+		if maxAttempts == 0 || attempt < maxAttempts {
+			backoff := policy.Delay(attempt, rand.Float64())
+			d.addLog(s, ui.LogWarning(fmt.Sprintf("Retrying in %ds...", int(backoff.Round(time.Second).Seconds()))))
 			select {
 			case <-cancelCh:
 				return
@@ -305,27 +300,52 @@ func (d *Daemon) startAutoReconnect(connID string, reason string) {
 		}
 	}
 
-	d.logger.Warn("reconnect: all attempts failed")
-	d.addLog(ui.LogError("All reconnect attempts failed"))
+	d.logger.Warn("reconnect: all attempts failed", "conn_id", connID)
+	d.addLog(s, ui.LogError("All reconnect attempts failed"))
 
 	d.stateMu.Lock()
 	autoCleanup := d.state.Config.Settings.AutoCleanup
-	d.state.Status = StatusDisconnected
-	d.state.ActiveConnID = ""
+	proc := s.process
+	s.process = nil
 	d.stateMu.Unlock()
 
+	if proc != nil {
+		d.reconnectMu.Lock()
+		s.stoppingForReconnect = true
+		d.reconnectMu.Unlock()
+		if proc.cmd != nil && proc.cmd.Process != nil {
+			proc.cmd.Process.Kill()
+		}
+		if proc.ptmx != nil {
+			proc.ptmx.Close()
+		}
+	}
+
 	if autoCleanup {
-		d.runCleanupSync("Reconnect cleanup")
+		d.runCleanupSync("Reconnect cleanup", s)
 	}
 
-	d.sendToClient(DisconnectedMsg{Type: "disconnected"})
+	d.endSession(s, models.EndReconnectFailed)
 }
 
-func (d *Daemon) waitForNetwork(host string, cancel <-chan struct{}) bool {
-	probeAddr := extractProbeAddr(host)
-	d.logger.Debug("probing network", "addr", probeAddr)
+// attemptLabel reads "attempt 2/5", or "attempt 2" when unlimited.
+func attemptLabel(attempt, limit int) string {
+	if limit == 0 {
+		return fmt.Sprintf("attempt %d", attempt)
+	}
+	return fmt.Sprintf("attempt %d/%d", attempt, limit)
+}
+
+func (d *Daemon) reconnectCancelled(s *session) bool {
+	d.reconnectMu.Lock()
+	defer d.reconnectMu.Unlock()
+	return s.disconnectRequested
+}
 
-	deadline := time.Now().Add(networkProbeMax)
+func (d *Daemon) waitForNetwork(probeAddr string, timeout time.Duration, cancel <-chan struct{}) bool {
+	d.logger.Debug("probing network", "addr", probeAddr, "timeout", timeout)
+
+	deadline := time.Now().Add(timeout)
 	for time.Now().Before(deadline) {
 		select {
 		case <-cancel:
@@ -369,38 +389,24 @@ func extractProbeAddr(host string) string {
 	return host + ":443"
 }
 
-func (d *Daemon) doConnect(connID, password string) {
-	d.stateMu.RLock()
-	var conn *models.Connection
-	for i := range d.state.Config.Connections {
-		if d.state.Config.Connections[i].ID == connID {
-			conn = &d.state.Config.Connections[i]
-			break
-		}
-	}
-	d.stateMu.RUnlock()
-
-	if conn == nil {
-		return
-	}
-
-	if err := d.ensureVpnLogFile(); err != nil {
-		d.logger.Error("failed to open vpn log file", "err", err)
+func (d *Daemon) doConnect(s *session, conn *models.Connection, password string) {
+	if err := s.openLog(false); err != nil {
+		d.logger.Error("failed to open vpn log file", "conn_id", s.connID, "err", err)
 	}
 
-	go d.startVPN(conn, password)
+	go d.startVPN(s, conn, password)
 }
 
-func (d *Daemon) cancelReconnect() {
+func (d *Daemon) cancelReconnect(s *session) {
 	d.reconnectMu.Lock()
 	defer d.reconnectMu.Unlock()
 
-	d.disconnectRequested = true
-	if d.reconnectCancel != nil {
+	s.disconnectRequested = true
+	if s.reconnectCancel != nil {
 		select {
-		case <-d.reconnectCancel:
+		case <-s.reconnectCancel:
 		default:
-			close(d.reconnectCancel)
+			close(s.reconnectCancel)
 		}
 	}
 }
@@ -0,0 +1,73 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+)
+
+func TestReconnectsOnFollowsPolicy(t *testing.T) {
+	d := newTestDaemon()
+	off, on := false, true
+	d.state.Config.Settings.Reconnect = true
+	d.state.Config.Settings.ReconnectPolicy = models.ReconnectPolicy{OnWake: &off}
+	d.state.Config.Connections = []models.Connection{
+		{ID: "work", Name: "Work", Host: "vpn.example.com"},
+		{ID: "lab", Name: "Lab", Host: "lab.example.com", Reconnect: &models.ReconnectPolicy{OnWake: &on, OnHealth: &off}},
+	}
+	work := &session{connID: "work"}
+	lab := &session{connID: "lab"}
+
+	if d.reconnectsOn(work, models.EndWake) {
+		t.Fatal("work reconnects on wake, want settings to turn it off")
+	}
+	if !d.reconnectsOn(work, models.EndExit) || !d.reconnectsOn(work, models.EndHealth) {
+		t.Fatal("work does not reconnect on exit or health")
+	}
+	if !d.reconnectsOn(lab, models.EndWake) {
+		t.Fatal("lab does not reconnect on wake, want its override to turn it on")
+	}
+	if d.reconnectsOn(lab, models.EndHealth) {
+		t.Fatal("lab reconnects on health, want its override to turn it off")
+	}
+
+	d.state.Config.Settings.Reconnect = false
+	if d.reconnectsOn(lab, models.EndWake) {
+		t.Fatal("reconnect happens with the reconnect setting off")
+	}
+}
+
+func TestReconnectPolicyDelay(t *testing.T) {
+	cfg := models.NewConfig()
+	cfg.Settings.ReconnectPolicy = models.ReconnectPolicy{Backoff: []int{1, 4}, Jitter: 0.5, MaxAttempts: 7}
+	cfg.Connections = []models.Connection{{ID: "work", Reconnect: &models.ReconnectPolicy{MaxAttempts: models.UnlimitedAttempts}}}
+
+	policy := cfg.ReconnectPolicyFor("work")
+	if policy.Attempts() != 0 {
+		t.Fatalf("Attempts = %d, want 0 (unlimited)", policy.Attempts())
+	}
+	if got := policy.Delay(1, 0.5); got != time.Second {
+		t.Fatalf("Delay(1) without jitter offset = %v, want 1s", got)
+	}
+	// The last backoff repeats; r spans ±Jitter.
+	if got := policy.Delay(5, 0); got != 2*time.Second {
+		t.Fatalf("Delay(5, 0) = %v, want 2s", got)
+	}
+	if got := policy.Delay(5, 1); got != 6*time.Second {
+		t.Fatalf("Delay(5, 1) = %v, want 6s", got)
+	}
+
+	defaults := models.ReconnectPolicy{}
+	if defaults.Attempts() != models.DefaultReconnectAttempts || defaults.Delay(3, 0.9) != 10*time.Second {
+		t.Fatalf("default policy = %d attempts, %v third delay", defaults.Attempts(), defaults.Delay(3, 0.9))
+	}
+	if defaults.NetworkTimeout() != 30*time.Second {
+		t.Fatalf("NetworkTimeout = %v, want 30s", defaults.NetworkTimeout())
+	}
+}
+
+func TestAttemptLabel(t *testing.T) {
+	assertString(t, "limited", attemptLabel(2, 5), "attempt 2/5")
+	assertString(t, "unlimited", attemptLabel(12, 0), "attempt 12")
+}
@@ -0,0 +1,353 @@
+package daemon
+
+import (
+	"bufio"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/controllers/helpers"
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+)
+
+// session is one openconnect tunnel run by the daemon. Status fields, the
+// process, the snapshot, the pending prompt and requests are guarded by
+// Daemon.stateMu; the reconnect bookkeeping by Daemon.reconnectMu; the log
+// file by logMu.
+type session struct {
+	connID      string
+	status      ConnStatus
+	ip          string
+	pid         int
+	tunnel      string
+	serverIP    string
+	connectedAt time.Time
+	logLines    int
+	snapshot    *helpers.NetworkSnapshot
+	process     *VPNProcess
+	prompt      *PromptMsg
+	// deadline ends the phase s is in; zero when it has no time limit.
+	phase    phase
+	deadline time.Time
+	// requests are connect commands waiting for the tunnel to come up.
+	requests []pendingRequest
+
+	// traffic is the last counter sample, taken at trafficAt.
+	traffic   helpers.TrafficCounters
+	trafficAt time.Time
+
+	health healthState
+
+	// startedAt opens the run the next history record describes; zero once
+	// it has been recorded.
+	startedAt time.Time
+	attempts  int
+	lastError string
+	killed    bool
+	// guarded is set when the kill switch covers s; killswitch_off clears
+	// it.
+	guarded bool
+
+	logMu   sync.Mutex
+	logFile *os.File
+
+	reconnecting         bool
+	reconnectCancel      chan struct{}
+	disconnectRequested  bool
+	stoppingForReconnect bool
+}
+
+func (d *Daemon) session(connID string) *session {
+	d.stateMu.RLock()
+	defer d.stateMu.RUnlock()
+	return d.state.Sessions[connID]
+}
+
+// sessions returns the running sessions ordered by connection ID.
+func (d *Daemon) sessions() []*session {
+	d.stateMu.RLock()
+	list := make([]*session, 0, len(d.state.Sessions))
+	for _, s := range d.state.Sessions {
+		list = append(list, s)
+	}
+	d.stateMu.RUnlock()
+
+	slices.SortFunc(list, func(a, b *session) int { return strings.Compare(a.connID, b.connID) })
+	return list
+}
+
+func (d *Daemon) sessionStatus(s *session) ConnStatus {
+	d.stateMu.RLock()
+	defer d.stateMu.RUnlock()
+	return s.status
+}
+
+func (d *Daemon) setSessionStatus(s *session, status ConnStatus) {
+	d.stateMu.Lock()
+	s.status = status
+	d.stateMu.Unlock()
+}
+
+// startPhase moves s to status and arms the deadline of p.
+func (d *Daemon) startPhase(s *session, status ConnStatus, p phase) {
+	d.stateMu.Lock()
+	s.status = status
+	d.armDeadline(s, p)
+	d.stateMu.Unlock()
+	d.broadcastState()
+}
+
+// removeSession forgets s and reports whether it was still registered. A
+// session replaced by a newer one for the same connection is left alone.
+func (d *Daemon) removeSession(s *session) bool {
+	d.stateMu.Lock()
+	removed := d.state.Sessions[s.connID] == s
+	if removed {
+		delete(d.state.Sessions, s.connID)
+	}
+	d.stateMu.Unlock()
+
+	s.closeLogFile()
+	return removed
+}
+
+// endSession records why s ended, removes it and tells clients its tunnel
+// is gone.
+func (d *Daemon) endSession(s *session, reason string) {
+	d.recordHistory(s, reason)
+	if d.removeSession(s) {
+		d.broadcast(DisconnectedMsg{Type: "disconnected", ConnID: s.connID})
+		d.revertSplitDNS(s)
+		d.holdKillSwitch(s, reason)
+		d.syncKillSwitch()
+		go d.postDisconnect(s, reason)
+	}
+	d.resolveRequests(s, ResultMsg{Code: "disconnected", Message: "Tunnel closed before it came up"})
+}
+
+// postDisconnect runs the post_disconnect hooks of an ended session, whose
+// log is reopened for their output.
+func (d *Daemon) postDisconnect(s *session, reason string) {
+	if !d.hasHooks(s, models.HookPostDisconnect) {
+		return
+	}
+	if err := s.openLog(false); err != nil {
+		d.logger.Error("failed to open vpn log file", "conn_id", s.connID, "err", err)
+	}
+	defer s.closeLogFile()
+	d.runHooks(s, models.HookPostDisconnect, reason)
+}
+
+// pendingRequest is a command with an ID whose result is sent later.
+type pendingRequest struct {
+	client *client
+	id     string
+}
+
+// resolveRequests sends result to every request waiting on s. Only the
+// first outcome counts; later calls find nothing left to resolve.
+func (d *Daemon) resolveRequests(s *session, result ResultMsg) {
+	d.stateMu.Lock()
+	requests := s.requests
+	s.requests = nil
+	d.stateMu.Unlock()
+
+	for _, req := range requests {
+		msg := result
+		msg.Type = "result"
+		msg.ID = req.id
+		d.reply(req.client, msg)
+	}
+}
+
+func connectionByID(cfg *models.Config, connID string) *models.Connection {
+	for i := range cfg.Connections {
+		if cfg.Connections[i].ID == connID {
+			return &cfg.Connections[i]
+		}
+	}
+	return nil
+}
+
+// tunnelBase is the first interface name handed to openconnect. macOS
+// numbers utun devices itself, so nothing is requested there unless the
+// user configured a name.
+func tunnelBase(settings models.Settings) string {
+	if settings.TunnelInterface != "" {
+		return settings.TunnelInterface
+	}
+	if runtime.GOOS == "darwin" {
+		return ""
+	}
+	return models.DefaultTunnelInterface()
+}
+
+// nextTunnel returns base, or base with the next free number when another
+// session already uses it: tun0, tun1, tun2 and so on.
+func nextTunnel(base string, used map[string]bool) string {
+	if base == "" {
+		return ""
+	}
+
+	prefix := strings.TrimRight(base, "0123456789")
+	n := 0
+	if digits := base[len(prefix):]; digits != "" {
+		n, _ = strconv.Atoi(digits)
+	}
+
+	name := base
+	for used[name] {
+		n++
+		name = prefix + strconv.Itoa(n)
+	}
+	return name
+}
+
+// usedTunnels must be called with stateMu held.
+func (d *Daemon) usedTunnels() map[string]bool {
+	used := make(map[string]bool, len(d.state.Sessions))
+	for _, s := range d.state.Sessions {
+		if s.tunnel != "" {
+			used[s.tunnel] = true
+		}
+	}
+	return used
+}
+
+// baselineSnapshot is the network state to restore once every tunnel is
+// down. Later sessions reuse an earlier session's capture, since by then
+// routes and DNS already point into a tunnel, but not what belongs to that
+// session's tunnel: its interface, split routes and split DNS.
+func (d *Daemon) baselineSnapshot() *helpers.NetworkSnapshot {
+	d.stateMu.RLock()
+	var snap *helpers.NetworkSnapshot
+	for _, s := range d.state.Sessions {
+		if s.snapshot != nil {
+			snap = &helpers.NetworkSnapshot{
+				DefaultInterface: s.snapshot.DefaultInterface,
+				WifiServiceName:  s.snapshot.WifiServiceName,
+				DNSServers:       slices.Clone(s.snapshot.DNSServers),
+				DefaultGateway:   s.snapshot.DefaultGateway,
+			}
+			break
+		}
+	}
+	settings := d.state.Config.Settings
+	d.stateMu.RUnlock()
+
+	if snap == nil {
+		snap = helpers.CaptureNetworkSnapshot()
+		d.logger.Info("network snapshot captured",
+			"interface", snap.DefaultInterface,
+			"gateway", snap.DefaultGateway,
+			"dns", snap.DNSServers,
+			"wifi_service", snap.WifiServiceName,
+		)
+	}
+
+	if settings.NetInterface != "" {
+		snap.DefaultInterface = settings.NetInterface
+	}
+	if settings.WifiInterface != "" {
+		snap.WifiServiceName = settings.WifiInterface
+	}
+	if settings.DNS != "" {
+		snap.DNSServers = strings.Fields(settings.DNS)
+	}
+	if settings.TunnelInterface != "" {
+		snap.TunnelInterface = settings.TunnelInterface
+	}
+
+	return snap
+}
+
+// openLog opens the session's log file, starting it over when truncate is
+// set.
+func (s *session) openLog(truncate bool) error {
+	path, err := helpers.VpnLogPath(s.connID)
+	if err != nil {
+		return err
+	}
+
+	s.logMu.Lock()
+	defer s.logMu.Unlock()
+
+	if s.logFile != nil {
+		if !truncate {
+			return nil
+		}
+		s.logFile.Close()
+		s.logFile = nil
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if truncate {
+		flags |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return err
+	}
+	s.logFile = f
+	return nil
+}
+
+func (s *session) closeLogFile() {
+	s.logMu.Lock()
+	defer s.logMu.Unlock()
+
+	if s.logFile != nil {
+		s.logFile.Close()
+		s.logFile = nil
+	}
+}
+
+func (d *Daemon) addLog(s *session, line string) {
+	s.logMu.Lock()
+	if s.logFile != nil {
+		s.logFile.WriteString(line + "\n")
+	}
+	s.logMu.Unlock()
+
+	d.stateMu.Lock()
+	lineNum := s.logLines
+	s.logLines++
+	d.stateMu.Unlock()
+
+	d.broadcast(LogMsg{Type: "log", ConnID: s.connID, Line: line, LineNumber: lineNum})
+}
+
+// readLogLines reads lines [from, to) of a connection's log; to < 0 reads
+// to the end.
+func readLogLines(connID string, from, to int) []string {
+	path, err := helpers.VpnLogPath(connID)
+	if err != nil {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+
+	for scanner.Scan() {
+		if lineNum >= from && (to < 0 || lineNum < to) {
+			lines = append(lines, scanner.Text())
+		}
+		lineNum++
+		if to >= 0 && lineNum >= to {
+			break
+		}
+	}
+
+	return lines
+}
@@ -0,0 +1,88 @@
+package daemon
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/controllers/helpers"
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+)
+
+func TestNextTunnel(t *testing.T) {
+	tests := []struct {
+		name string
+		base string
+		used map[string]bool
+		want string
+	}{
+		{name: "free base", base: "tun0", used: nil, want: "tun0"},
+		{name: "base taken", base: "tun0", used: map[string]bool{"tun0": true}, want: "tun1"},
+		{name: "skips taken numbers", base: "tun0", used: map[string]bool{"tun0": true, "tun1": true}, want: "tun2"},
+		{name: "custom base", base: "vpn3", used: map[string]bool{"vpn3": true}, want: "vpn4"},
+		{name: "base without number", base: "corp", used: map[string]bool{"corp": true}, want: "corp1"},
+		{name: "left to openconnect", base: "", used: map[string]bool{"tun0": true}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextTunnel(tt.base, tt.used); got != tt.want {
+				t.Fatalf("nextTunnel(%q) = %q, want %q", tt.base, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStateMsgListsSessionsInOrder(t *testing.T) {
+	d := newTestDaemon()
+	d.state.Sessions["work"] = &session{connID: "work", status: StatusConnected, ip: "10.0.0.2", tunnel: "tun0"}
+	d.state.Sessions["lab"] = &session{connID: "lab", status: StatusConnecting, tunnel: "tun1"}
+
+	msg := d.stateMsg()
+
+	if len(msg.Sessions) != 2 {
+		t.Fatalf("sessions = %d, want 2", len(msg.Sessions))
+	}
+	assertString(t, "first ConnID", msg.Sessions[0].ConnID, "lab")
+	assertString(t, "second ConnID", msg.Sessions[1].ConnID, "work")
+	assertString(t, "second IP", msg.Sessions[1].IP, "10.0.0.2")
+	assertString(t, "second Tunnel", msg.Sessions[1].Tunnel, "tun0")
+}
+
+func TestEndSessionIgnoresReplacedSession(t *testing.T) {
+	d := newTestDaemon()
+	old := &session{connID: "work"}
+	current := &session{connID: "work"}
+	d.state.Sessions["work"] = current
+
+	d.endSession(old, models.EndExit)
+
+	if d.session("work") != current {
+		t.Fatal("ending a replaced session removed its successor")
+	}
+}
+
+func TestBaselineSnapshotLeavesOutOtherTunnels(t *testing.T) {
+	d := newTestDaemon()
+	d.state.Sessions["work"] = &session{connID: "work", snapshot: &helpers.NetworkSnapshot{
+		DefaultInterface: "wlan0",
+		DefaultGateway:   "192.168.1.1",
+		DNSServers:       []string{"192.168.1.1"},
+		TunnelInterface:  "tun0",
+		IncludeRoutes:    []string{"10.20.0.0/16"},
+		ExcludeRoutes:    []string{"192.168.50.0/24"},
+		SplitDNSDomains:  []string{"corp.example.com"},
+		SplitDNSServers:  []string{"10.20.0.53"},
+	}}
+
+	snap := d.baselineSnapshot()
+
+	assertString(t, "DefaultInterface", snap.DefaultInterface, "wlan0")
+	assertString(t, "DefaultGateway", snap.DefaultGateway, "192.168.1.1")
+	if !slices.Equal(snap.DNSServers, []string{"192.168.1.1"}) {
+		t.Fatalf("DNSServers = %v, want the captured servers", snap.DNSServers)
+	}
+	assertString(t, "TunnelInterface", snap.TunnelInterface, "")
+	if len(snap.IncludeRoutes)+len(snap.ExcludeRoutes)+len(snap.SplitDNSDomains)+len(snap.SplitDNSServers) != 0 {
+		t.Fatalf("baseline kept the work tunnel's split setup: %+v", snap)
+	}
+}
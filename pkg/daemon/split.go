@@ -0,0 +1,45 @@
+package daemon
+
+import (
+	"strings"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/controllers/helpers"
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+)
+
+// splitScript writes the vpnc-script wrapper that applies the split routes
+// of conn and returns its path, or "" when conn takes the server's routes
+// as they are. A --script in the connection's flags is the script the
+// wrapper runs.
+func splitScript(conn *models.Connection) (string, error) {
+	if !conn.SplitTunnel() {
+		return "", nil
+	}
+	script, _ := scriptFlag(strings.Fields(conn.Flags))
+	if script == "" {
+		var err error
+		if script, err = helpers.FindVpncScript(); err != nil {
+			return "", err
+		}
+	}
+	return helpers.WriteSplitScript(conn, script)
+}
+
+// scriptFlag picks the --script or -s option out of flags and returns its
+// value along with the other flags.
+func scriptFlag(flags []string) (string, []string) {
+	var script string
+	rest := make([]string, 0, len(flags))
+	for i := 0; i < len(flags); i++ {
+		switch flag := flags[i]; {
+		case strings.HasPrefix(flag, "--script="):
+			script = strings.TrimPrefix(flag, "--script=")
+		case (flag == "--script" || flag == "-s") && i+1 < len(flags):
+			i++
+			script = flags[i]
+		default:
+			rest = append(rest, flag)
+		}
+	}
+	return script, rest
+}
@@ -0,0 +1,53 @@
+package daemon
+
+import (
+	"github.com/Nybkox/lazyopenconnect/pkg/controllers/helpers"
+	"github.com/Nybkox/lazyopenconnect/pkg/ui"
+)
+
+// Test seams for applying and undoing split DNS.
+var (
+	runSplitDNSSteps        = helpers.RunSplitDNSSteps
+	runSplitDNSCleanupSteps = helpers.RunSplitDNSCleanupSteps
+)
+
+// splitDNSSnapshot returns a copy of the snapshot of s when its tunnel
+// uses split DNS.
+func (d *Daemon) splitDNSSnapshot(s *session) (helpers.NetworkSnapshot, bool) {
+	d.stateMu.RLock()
+	defer d.stateMu.RUnlock()
+	if s.snapshot == nil || len(s.snapshot.SplitDNSDomains) == 0 {
+		return helpers.NetworkSnapshot{}, false
+	}
+	return *s.snapshot, true
+}
+
+// applySplitDNS points the split DNS domains of a tunnel that just came up
+// at its DNS servers, after vpnc-script set up the tunnel's own DNS.
+func (d *Daemon) applySplitDNS(s *session) {
+	snap, ok := d.splitDNSSnapshot(s)
+	if !ok {
+		return
+	}
+	d.logger.Info("applying split dns", "conn_id", s.connID, "tunnel", snap.TunnelInterface, "domains", snap.SplitDNSDomains)
+	for _, r := range runSplitDNSSteps(&snap) {
+		if !r.Success {
+			d.logger.Warn("split dns step failed", "conn_id", s.connID, "step", r.Step, "err", r.Error)
+			d.addLog(s, ui.LogWarning(r.Step+" failed: "+r.Error))
+		}
+	}
+}
+
+// revertSplitDNS undoes the split DNS of a tunnel that ended, whether or
+// not auto-cleanup runs.
+func (d *Daemon) revertSplitDNS(s *session) {
+	snap, ok := d.splitDNSSnapshot(s)
+	if !ok {
+		return
+	}
+	for _, r := range runSplitDNSCleanupSteps(&snap) {
+		if !r.Success {
+			d.logger.Warn("split dns cleanup failed", "conn_id", s.connID, "step", r.Step, "err", r.Error)
+		}
+	}
+}
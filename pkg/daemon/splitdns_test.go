@@ -0,0 +1,72 @@
+package daemon
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/controllers/helpers"
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+)
+
+// stubSplitDNS records the snapshots split DNS is applied to and reverted
+// for.
+func stubSplitDNS(t *testing.T) (applied, reverted *[]helpers.NetworkSnapshot) {
+	t.Helper()
+	applied, reverted = new([]helpers.NetworkSnapshot), new([]helpers.NetworkSnapshot)
+	origApply, origRevert := runSplitDNSSteps, runSplitDNSCleanupSteps
+	runSplitDNSSteps = func(snap *helpers.NetworkSnapshot) []helpers.CleanupResult {
+		*applied = append(*applied, *snap)
+		return []helpers.CleanupResult{{Step: "Routing DNS for corp.example", Success: true}}
+	}
+	runSplitDNSCleanupSteps = func(snap *helpers.NetworkSnapshot) []helpers.CleanupResult {
+		*reverted = append(*reverted, *snap)
+		return nil
+	}
+	t.Cleanup(func() {
+		runSplitDNSSteps, runSplitDNSCleanupSteps = origApply, origRevert
+	})
+	return applied, reverted
+}
+
+func TestSplitDNSAppliedOnConnectAndRevertedOnEnd(t *testing.T) {
+	applied, reverted := stubSplitDNS(t)
+	d := newTestDaemon()
+	s := &session{
+		connID: "work",
+		status: StatusConnecting,
+		snapshot: &helpers.NetworkSnapshot{
+			TunnelInterface: "tun1",
+			SplitDNSDomains: []string{"corp.example", "~lab.example"},
+			SplitDNSServers: []string{"10.0.0.53"},
+		},
+	}
+	d.state.Sessions["work"] = s
+
+	d.postConnect(s)
+	if len(*applied) != 1 {
+		t.Fatalf("split DNS applied %d times, want once", len(*applied))
+	}
+	got := (*applied)[0]
+	assertString(t, "tunnel", got.TunnelInterface, "tun1")
+	if !slices.Equal(got.SplitDNSDomains, []string{"corp.example", "~lab.example"}) {
+		t.Fatalf("domains = %q", got.SplitDNSDomains)
+	}
+
+	d.endSession(s, models.EndUserDisconnect)
+	if len(*reverted) != 1 || (*reverted)[0].TunnelInterface != "tun1" {
+		t.Fatalf("reverted = %+v, want tun1 reverted once", *reverted)
+	}
+}
+
+func TestSplitDNSSkippedWithoutDomains(t *testing.T) {
+	applied, reverted := stubSplitDNS(t)
+	d := newTestDaemon()
+	s := &session{connID: "work", status: StatusConnecting, snapshot: &helpers.NetworkSnapshot{TunnelInterface: "tun0"}}
+	d.state.Sessions["work"] = s
+
+	d.postConnect(s)
+	d.endSession(s, models.EndUserDisconnect)
+	if len(*applied) != 0 || len(*reverted) != 0 {
+		t.Fatalf("applied = %d, reverted = %d without split DNS domains", len(*applied), len(*reverted))
+	}
+}
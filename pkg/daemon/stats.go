@@ -0,0 +1,79 @@
+package daemon
+
+import (
+	"time"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/controllers/helpers"
+)
+
+const statsInterval = 2 * time.Second
+
+// readTrafficCounters is swapped out by tests.
+var readTrafficCounters = helpers.ReadTrafficCounters
+
+// statsMonitor samples the counters of every connected tunnel while a
+// client is attached to show them.
+func (d *Daemon) statsMonitor() {
+	ticker := time.NewTicker(statsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.shutdown:
+			return
+		case now := <-ticker.C:
+			d.clientMu.Lock()
+			attached := len(d.clients) > 0
+			d.clientMu.Unlock()
+			if !attached {
+				continue
+			}
+			for _, s := range d.sessions() {
+				if msg, ok := d.sampleStats(s, now); ok {
+					d.broadcast(msg)
+				}
+			}
+		}
+	}
+}
+
+// sampleStats reads the counters of s and derives rates from the previous
+// sample. Rates start at zero, and again after the tunnel was recreated
+// and its counters restarted.
+func (d *Daemon) sampleStats(s *session, now time.Time) (StatsMsg, bool) {
+	d.stateMu.RLock()
+	tunnel := s.tunnel
+	connected := s.status == StatusConnected
+	d.stateMu.RUnlock()
+	if !connected || tunnel == "" {
+		return StatsMsg{}, false
+	}
+
+	counters, err := readTrafficCounters(tunnel)
+	if err != nil {
+		d.logger.Debug("failed to read tunnel counters", "conn_id", s.connID, "tunnel", tunnel, "err", err)
+		return StatsMsg{}, false
+	}
+
+	d.stateMu.Lock()
+	prev, prevAt := s.traffic, s.trafficAt
+	s.traffic, s.trafficAt = counters, now
+	d.stateMu.Unlock()
+
+	msg := StatsMsg{
+		Type:      "stats",
+		ConnID:    s.connID,
+		RxBytes:   counters.RxBytes,
+		TxBytes:   counters.TxBytes,
+		RxPackets: counters.RxPackets,
+		TxPackets: counters.TxPackets,
+		RxErrors:  counters.RxErrors,
+		TxErrors:  counters.TxErrors,
+	}
+	elapsed := now.Sub(prevAt).Seconds()
+	if !prevAt.IsZero() && elapsed > 0 && counters.RxBytes >= prev.RxBytes && counters.TxBytes >= prev.TxBytes {
+		msg.RxRate = float64(counters.RxBytes-prev.RxBytes) / elapsed
+		msg.TxRate = float64(counters.TxBytes-prev.TxBytes) / elapsed
+	}
+	return msg, true
+}
@@ -0,0 +1,55 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/controllers/helpers"
+)
+
+func TestSampleStats(t *testing.T) {
+	counters := helpers.TrafficCounters{RxBytes: 1000, TxBytes: 500}
+	orig := readTrafficCounters
+	readTrafficCounters = func(iface string) (helpers.TrafficCounters, error) {
+		if iface != "tun0" {
+			t.Fatalf("read counters of %q, want tun0", iface)
+		}
+		return counters, nil
+	}
+	t.Cleanup(func() { readTrafficCounters = orig })
+
+	d := newTestDaemon()
+	s := &session{connID: "work", status: StatusConnected, tunnel: "tun0"}
+	start := time.Now()
+
+	first, ok := d.sampleStats(s, start)
+	if !ok {
+		t.Fatal("no stats for a connected tunnel")
+	}
+	if first.RxRate != 0 || first.TxRate != 0 {
+		t.Fatalf("first sample rates = %v/%v, want 0", first.RxRate, first.TxRate)
+	}
+
+	counters = helpers.TrafficCounters{RxBytes: 5000, TxBytes: 1500}
+	second, _ := d.sampleStats(s, start.Add(2*time.Second))
+	if second.RxRate != 2000 || second.TxRate != 500 {
+		t.Fatalf("rates = %v/%v, want 2000/500", second.RxRate, second.TxRate)
+	}
+	assertString(t, "ConnID", second.ConnID, "work")
+
+	// A recreated tunnel starts its counters over.
+	counters = helpers.TrafficCounters{RxBytes: 10}
+	third, _ := d.sampleStats(s, start.Add(4*time.Second))
+	if third.RxRate != 0 {
+		t.Fatalf("rate after counter reset = %v, want 0", third.RxRate)
+	}
+}
+
+func TestSampleStatsSkipsUnconnected(t *testing.T) {
+	d := newTestDaemon()
+	s := &session{connID: "work", status: StatusConnecting, tunnel: "tun0"}
+
+	if _, ok := d.sampleStats(s, time.Now()); ok {
+		t.Fatal("stats sampled for a tunnel that is not up")
+	}
+}
@@ -0,0 +1,118 @@
+package daemon
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+	"github.com/Nybkox/lazyopenconnect/pkg/ui"
+)
+
+const timeoutTick = time.Second
+
+// phase is what a session with a deadline is waiting for.
+type phase int
+
+const (
+	phaseNone phase = iota
+	phaseConnect
+	phasePrompt
+	phaseReconnect
+)
+
+// timeout returns how long p may last, 0 for no limit.
+func (p phase) timeout(t models.Timeouts) time.Duration {
+	switch p {
+	case phaseConnect:
+		return t.ConnectTimeout()
+	case phasePrompt:
+		return t.PromptTimeout()
+	case phaseReconnect:
+		return t.ReconnectTimeout()
+	}
+	return 0
+}
+
+// armDeadline starts the timeout of p for s, or clears it when the
+// settings put no limit on p. Called with stateMu held.
+func (d *Daemon) armDeadline(s *session, p phase) {
+	s.phase = p
+	s.deadline = time.Time{}
+	if timeout := p.timeout(d.state.Config.Settings.Timeouts); timeout > 0 {
+		s.deadline = time.Now().Add(timeout)
+	}
+}
+
+// activeDeadline returns the deadline s is counting down to, zero when its
+// status has moved on from the phase the deadline was armed for. Called
+// with stateMu held.
+func (s *session) activeDeadline() time.Time {
+	switch {
+	case s.phase == phasePrompt && s.status == StatusPrompting:
+	case (s.phase == phaseConnect || s.phase == phaseReconnect) && s.status == StatusConnecting:
+	default:
+		return time.Time{}
+	}
+	return s.deadline
+}
+
+// timeoutMonitor gives up on sessions that overran their deadline.
+func (d *Daemon) timeoutMonitor() {
+	ticker := time.NewTicker(timeoutTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.shutdown:
+			return
+		case now := <-ticker.C:
+			for _, s := range d.sessions() {
+				if p, timeout, ok := d.expiredPhase(s, now); ok {
+					d.phaseTimedOut(s, p, timeout)
+				}
+			}
+		}
+	}
+}
+
+// expiredPhase reports whether the deadline of s passed by now and clears
+// it, so each deadline fires once. The timeout is the length of the phase.
+func (d *Daemon) expiredPhase(s *session, now time.Time) (phase, time.Duration, bool) {
+	d.stateMu.Lock()
+	defer d.stateMu.Unlock()
+
+	deadline := s.activeDeadline()
+	if deadline.IsZero() || now.Before(deadline) {
+		return phaseNone, 0, false
+	}
+
+	p := s.phase
+	timeout := p.timeout(d.state.Config.Settings.Timeouts)
+	s.phase = phaseNone
+	s.deadline = time.Time{}
+	return p, timeout, true
+}
+
+// phaseTimedOut acts on an overrun deadline. A reconnect attempt that
+// timed out is stopped so the reconnect moves on to its next try; any
+// other phase ends the session with a connect_timeout error.
+func (d *Daemon) phaseTimedOut(s *session, p phase, timeout time.Duration) {
+	if p == phaseReconnect {
+		d.logger.Warn("reconnect attempt timed out", "conn_id", s.connID, "timeout", timeout)
+		d.addLog(s, ui.LogWarning(fmt.Sprintf("Reconnect attempt timed out after %s", timeout)))
+		d.stopForReconnect(s)
+		return
+	}
+
+	message := fmt.Sprintf("Connection timed out after %s", timeout)
+	if p == phasePrompt {
+		message = fmt.Sprintf("No answer to the prompt after %s", timeout)
+	}
+	d.logger.Warn("connect timed out", "conn_id", s.connID, "prompting", p == phasePrompt, "timeout", timeout)
+	d.stateMu.Lock()
+	s.lastError = message
+	d.stateMu.Unlock()
+	d.broadcast(ErrorMsg{Type: "error", ConnID: s.connID, Code: "connect_timeout", Message: message})
+	d.resolveRequests(s, ResultMsg{Code: "connect_timeout", Message: message})
+	go d.disconnectSession(s, models.EndTimeout)
+}
@@ -0,0 +1,104 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+)
+
+func newTimeoutTestSession(d *Daemon, status ConnStatus, p phase) *session {
+	s := &session{connID: "work", status: status}
+	d.stateMu.Lock()
+	d.armDeadline(s, p)
+	d.state.Sessions["work"] = s
+	d.stateMu.Unlock()
+	return s
+}
+
+func TestExpiredPhaseFiresOnce(t *testing.T) {
+	d := newTestDaemon()
+	s := newTimeoutTestSession(d, StatusConnecting, phaseConnect)
+
+	if _, _, ok := d.expiredPhase(s, time.Now()); ok {
+		t.Fatal("deadline expired right after connecting")
+	}
+	if deadline := d.stateMsg().Sessions[0].Deadline; deadline != s.deadline.Unix() {
+		t.Fatalf("state deadline = %d, want %d", deadline, s.deadline.Unix())
+	}
+
+	later := time.Now().Add(31 * time.Second)
+	p, timeout, ok := d.expiredPhase(s, later)
+	if !ok || p != phaseConnect {
+		t.Fatalf("expiredPhase = %v, %v, want phaseConnect, true", p, ok)
+	}
+	if timeout != 30*time.Second {
+		t.Fatalf("timeout = %v, want 30s", timeout)
+	}
+	if _, _, ok := d.expiredPhase(s, later); ok {
+		t.Fatal("deadline fired twice")
+	}
+}
+
+func TestExpiredPhaseIgnoresFinishedPhase(t *testing.T) {
+	d := newTestDaemon()
+	s := newTimeoutTestSession(d, StatusConnecting, phaseConnect)
+	d.setSessionStatus(s, StatusConnected)
+
+	if _, _, ok := d.expiredPhase(s, time.Now().Add(time.Hour)); ok {
+		t.Fatal("deadline fired for a connected session")
+	}
+	if deadline := d.stateMsg().Sessions[0].Deadline; deadline != 0 {
+		t.Fatalf("state deadline = %d for a connected session", deadline)
+	}
+}
+
+func TestArmDeadlineUsesSettings(t *testing.T) {
+	d := newTestDaemon()
+	d.state.Config.Settings.Timeouts.Prompt = -1
+	d.state.Config.Settings.Timeouts.Reconnect = 90
+
+	s := newTimeoutTestSession(d, StatusPrompting, phasePrompt)
+	if !s.deadline.IsZero() {
+		t.Fatalf("prompt deadline = %v with the timeout disabled", s.deadline)
+	}
+
+	s = newTimeoutTestSession(d, StatusConnecting, phaseReconnect)
+	if left := time.Until(s.deadline); left < 89*time.Second || left > 90*time.Second {
+		t.Fatalf("reconnect deadline in %v, want 90s", left)
+	}
+}
+
+func TestPhaseTimedOutFailsConnect(t *testing.T) {
+	d := newTestDaemon()
+	c, conn := attachTestClient(t, d)
+	s := newTimeoutTestSession(d, StatusConnecting, phaseConnect)
+	s.requests = []pendingRequest{{client: c, id: "7"}}
+
+	go d.phaseTimedOut(s, phaseConnect, 30*time.Second)
+
+	var errMsg ErrorMsg
+	var result ResultMsg
+	for {
+		msg := readTestMsg(t, conn)
+		switch msg.Type {
+		case "error":
+			if err := msg.Decode(&errMsg); err != nil {
+				t.Fatalf("Decode returned error: %v", err)
+			}
+		case "result":
+			if err := msg.Decode(&result); err != nil {
+				t.Fatalf("Decode returned error: %v", err)
+			}
+		}
+		if msg.Type == "disconnected" {
+			break
+		}
+	}
+
+	assertString(t, "error code", errMsg.Code, "connect_timeout")
+	assertString(t, "error message", errMsg.Message, "Connection timed out after 30s")
+	assertString(t, "result ID", result.ID, "7")
+	assertString(t, "result code", result.Code, "connect_timeout")
+	if d.session("work") != nil {
+		t.Fatal("timed out session still registered")
+	}
+}
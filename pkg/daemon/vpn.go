@@ -5,8 +5,10 @@ import (
 	"os"
 	"os/exec"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -22,6 +24,9 @@ var (
 	ipPattern     = regexp.MustCompile(`Configured as (\d+\.\d+\.\d+\.\d+)`)
 	pidPattern    = regexp.MustCompile(`pid (\d+)`)
 	tunDevPattern = regexp.MustCompile(`(?i)(?:set up|using) (?:tun|DTLS) (?:device|connection) (\S+)`)
+	// serverPattern matches openconnect reaching the VPN server, e.g.
+	// "Connected to 203.0.113.7:443" or "Connected to [2001:db8::7]:443".
+	serverPattern = regexp.MustCompile(`Connected to (?:\[([0-9A-Fa-f:]+)\]|(\d+\.\d+\.\d+\.\d+)):\d+`)
 
 	connectedPatterns = []string{
 		"continuing in background",
@@ -39,143 +44,172 @@ type VPNProcess struct {
 	ptmx *os.File
 }
 
-func (d *Daemon) handleConnect(msg ConnectCmd) {
+func (d *Daemon) handleConnect(c *client, msg ConnectCmd) {
 	connID := msg.ConnID
 	password := msg.Password
 
-	d.cancelReconnect()
-
 	d.stateMu.Lock()
-	if d.state.Status != StatusDisconnected {
+	existing := d.state.Sessions[connID]
+	if existing != nil && existing.status != StatusReconnecting {
 		d.stateMu.Unlock()
-		d.logger.Warn("connect rejected, already connected", "status", d.state.Status)
-		d.sendToClient(ErrorMsg{
-			Type:    "error",
-			Code:    "already_connected",
-			Message: "Already connected or connecting",
-		})
+		d.logger.Warn("connect rejected, already connected", "conn_id", connID, "status", existing.status)
+		d.fail(c, msg.ID, connID, "already_connected", "Already connected or connecting")
 		return
 	}
 
-	var conn *models.Connection
-	for i := range d.state.Config.Connections {
-		if d.state.Config.Connections[i].ID == connID {
-			conn = &d.state.Config.Connections[i]
-			break
-		}
-	}
-
+	conn := connectionByID(d.state.Config, connID)
 	if conn == nil {
 		d.stateMu.Unlock()
 		d.logger.Warn("connect rejected, connection not found", "conn_id", connID)
-		d.sendToClient(ErrorMsg{
-			Type:    "error",
-			Code:    "invalid_conn",
-			Message: "Connection not found",
-		})
+		d.fail(c, msg.ID, connID, "invalid_conn", "Connection not found")
 		return
 	}
 
-	d.state.Status = StatusConnecting
-	d.state.ActiveConnID = connID
-	settings := d.state.Config.Settings
+	if d.state.Sessions == nil {
+		d.state.Sessions = make(map[string]*session)
+	}
+	if existing != nil {
+		delete(d.state.Sessions, connID)
+	}
+	s := &session{
+		connID:    connID,
+		status:    StatusConnecting,
+		tunnel:    nextTunnel(tunnelBase(d.state.Config.Settings), d.usedTunnels()),
+		startedAt: time.Now(),
+		guarded:   conn.KillSwitch,
+	}
+	if msg.ID != "" {
+		s.requests = []pendingRequest{{client: c, id: msg.ID}}
+	}
+	d.armDeadline(s, phaseConnect)
+	d.state.Sessions[connID] = s
 	d.stateMu.Unlock()
+	d.ack(c, msg.ID)
+
+	if existing != nil {
+		d.logger.Info("manual connect replaces pending reconnect", "conn_id", connID)
+		d.cancelReconnect(existing)
+		d.recordHistory(existing, models.EndReplaced)
+		existing.closeLogFile()
+		d.resolveRequests(existing, ResultMsg{Code: "superseded", Message: "Replaced by a new connect"})
+	}
+
+	password = d.connectPassword(conn, password)
 
 	d.reconnectMu.Lock()
-	d.disconnectRequested = false
-	if password != "" && conn.HasPassword {
+	if password != "" && conn.SendsPassword() {
 		d.passwordCache[connID] = password
 	}
 	d.reconnectMu.Unlock()
 
-	d.logger.Info("connecting", "conn_id", connID, "host", conn.Host, "protocol", conn.Protocol)
+	d.logger.Info("connecting", "conn_id", connID, "host", conn.Host, "protocol", conn.Protocol, "tunnel", s.tunnel)
 
-	snap := helpers.CaptureNetworkSnapshot()
-	d.logger.Info("network snapshot captured",
-		"interface", snap.DefaultInterface,
-		"gateway", snap.DefaultGateway,
-		"dns", snap.DNSServers,
-		"wifi_service", snap.WifiServiceName,
-	)
+	snap := d.baselineSnapshot()
+	if s.tunnel != "" {
+		snap.TunnelInterface = s.tunnel
+	}
+	d.stateMu.Lock()
+	s.snapshot = snap
+	d.stateMu.Unlock()
 
-	if settings.NetInterface != "" {
-		snap.DefaultInterface = settings.NetInterface
+	if err := s.openLog(true); err != nil {
+		d.logger.Error("failed to open vpn log file", "conn_id", connID, "err", err)
 	}
-	if settings.WifiInterface != "" {
-		snap.WifiServiceName = settings.WifiInterface
+
+	d.broadcastState()
+	go d.preConnect(s, conn, password)
+}
+
+// connectPassword returns the password sent to openconnect: the one the
+// client sent, else the connection's password file. An unreadable file
+// leaves it empty, so openconnect prompts instead.
+func (d *Daemon) connectPassword(conn *models.Connection, password string) string {
+	if password != "" || conn.PasswordFile == "" {
+		return password
 	}
-	if settings.DNS != "" {
-		snap.DNSServers = strings.Fields(settings.DNS)
+	password, err := helpers.ReadPasswordFile(conn.PasswordFile)
+	if err != nil {
+		d.logger.Warn("failed to read password file, openconnect will prompt", "conn_id", conn.ID, "err", err)
+		return ""
 	}
-	if settings.TunnelInterface != "" {
-		snap.TunnelInterface = settings.TunnelInterface
+	return password
+}
+
+// preConnect releases a kill switch held from an earlier tunnel, runs the
+// pre_connect hooks, then starts openconnect unless s was disconnected
+// meanwhile. The connect timeout starts over once hooks ran.
+func (d *Daemon) preConnect(s *session, conn *models.Connection, password string) {
+	d.releaseKillSwitch()
+	if d.runHooks(s, models.HookPreConnect, hookReasonConnect) {
+		if d.session(s.connID) != s || d.reconnectCancelled(s) {
+			d.logger.Debug("disconnected during pre_connect hooks", "conn_id", s.connID)
+			return
+		}
+		d.startPhase(s, StatusConnecting, phaseConnect)
 	}
+	d.startVPN(s, conn, password)
+}
+
+func (d *Daemon) startVPN(s *session, conn *models.Connection, password string) {
+	d.stateMu.RLock()
+	tunnel := s.tunnel
+	serverIP := s.serverIP
+	d.stateMu.RUnlock()
 
+	script, err := splitScript(conn)
+	if err != nil {
+		d.startFailed(s, "split_tunnel_failed", err)
+		return
+	}
 	d.stateMu.Lock()
-	d.state.NetworkSnapshot = snap
+	if s.snapshot != nil {
+		s.snapshot.IncludeRoutes = slices.Clone(conn.IncludeRoutes)
+		s.snapshot.ExcludeRoutes = slices.Clone(conn.ExcludeRoutes)
+		s.snapshot.SplitDNSDomains = slices.Clone(conn.DNSDomains)
+		s.snapshot.SplitDNSServers = slices.Clone(conn.DNSServers)
+	}
 	d.stateMu.Unlock()
-
-	if err := d.resetVpnLogFile(); err != nil {
-		d.logger.Error("failed to open vpn log file", "err", err)
+	if password == "" && conn.SendsPassword() {
+		d.addLog(s, ui.LogWarning("Saved password not available, openconnect will prompt"))
+	}
+	args := buildArgs(conn, tunnel, script, password != "")
+	if d.killSwitchOn.Load() {
+		if resolve := resolveArg(conn.Host, serverIP); resolve != "" {
+			args = append(args, resolve)
+		}
 	}
-
-	go d.startVPN(conn, password)
-}
-
-func (d *Daemon) startVPN(conn *models.Connection, password string) {
-	args := buildArgs(conn)
 
 	cmdStr := "openconnect " + strings.Join(args, " ")
-	d.addLog(ui.LogCommand(cmdStr))
-	d.logger.Debug("executing openconnect", "args", args)
+	d.addLog(s, ui.LogCommand(cmdStr))
+	d.logger.Debug("executing openconnect", "conn_id", s.connID, "args", args)
 
 	cmd := exec.Command("openconnect", args...)
 
 	ptmx, err := pty.Start(cmd)
 	if err != nil {
-		d.logger.Error("pty start failed", "err", err)
-		d.sendToClient(ErrorMsg{
-			Type:    "error",
-			Code:    "start_failed",
-			Message: err.Error(),
-		})
-		d.sendToClient(DisconnectedMsg{Type: "disconnected"})
-		d.stateMu.Lock()
-		d.state.Status = StatusDisconnected
-		d.state.ActiveConnID = ""
-		d.stateMu.Unlock()
+		d.startFailed(s, "start_failed", err)
 		return
 	}
 
 	_, err = term.MakeRaw(int(ptmx.Fd()))
 	if err != nil {
-		d.logger.Error("pty make raw failed", "err", err)
-		d.sendToClient(ErrorMsg{
-			Type:    "error",
-			Code:    "pty_failed",
-			Message: err.Error(),
-		})
-		d.sendToClient(DisconnectedMsg{Type: "disconnected"})
-		d.stateMu.Lock()
-		d.state.Status = StatusDisconnected
-		d.state.ActiveConnID = ""
-		d.stateMu.Unlock()
+		_ = cmd.Process.Kill()
+		_ = ptmx.Close()
+		d.startFailed(s, "pty_failed", err)
 		return
 	}
 
-	d.vpnMu.Lock()
-	d.vpnProcess = &VPNProcess{
+	proc := &VPNProcess{
 		cmd:  cmd,
 		ptmx: ptmx,
 	}
-	d.vpnMu.Unlock()
-
 	pid := cmd.Process.Pid
 	d.stateMu.Lock()
-	d.state.PID = pid
+	s.process = proc
+	s.pid = pid
 	d.stateMu.Unlock()
 
-	d.logger.Info("vpn process started", "pid", pid)
+	d.logger.Info("vpn process started", "conn_id", s.connID, "pid", pid)
 
 	if password != "" {
 		d.logger.Debug("sending password to stdin")
@@ -185,20 +219,54 @@ func (d *Daemon) startVPN(conn *models.Connection, password string) {
 		}()
 	}
 
-	d.streamPTYOutput(ptmx)
+	d.streamPTYOutput(s, proc)
 }
 
-func buildArgs(conn *models.Connection) []string {
+// startFailed reports that openconnect could not be started. A reconnect
+// in progress retries on its own; otherwise the session ends.
+func (d *Daemon) startFailed(s *session, code string, err error) {
+	d.logger.Error("vpn start failed", "conn_id", s.connID, "code", code, "err", err)
+	d.broadcast(ErrorMsg{
+		Type:    "error",
+		ConnID:  s.connID,
+		Code:    code,
+		Message: err.Error(),
+	})
+	d.resolveRequests(s, ResultMsg{Code: code, Message: err.Error()})
+
+	d.stateMu.Lock()
+	s.lastError = err.Error()
+	d.stateMu.Unlock()
+
+	d.reconnectMu.Lock()
+	reconnecting := s.reconnecting
+	d.reconnectMu.Unlock()
+	if reconnecting {
+		d.setSessionStatus(s, StatusReconnecting)
+		return
+	}
+	d.endSession(s, models.EndStartFailed)
+}
+
+// buildArgs returns the openconnect arguments for conn. A split tunnel
+// wrapper given as script replaces any --script in the flags, which it
+// runs itself. sendPassword is set when the password goes to stdin;
+// otherwise openconnect prompts for it.
+func buildArgs(conn *models.Connection, tunnel, script string, sendPassword bool) []string {
 	args := []string{
 		"--protocol=" + conn.Protocol,
 		conn.Host,
 	}
 
+	if tunnel != "" {
+		args = append(args, "--interface="+tunnel)
+	}
+
 	if conn.Username != "" {
 		args = append(args, "--user="+conn.Username)
 	}
 
-	if conn.HasPassword {
+	if sendPassword {
 		args = append(args, "--passwd-on-stdin")
 	}
 
@@ -206,25 +274,27 @@ func buildArgs(conn *models.Connection) []string {
 		args = append(args, "--servercert="+conn.ServerCert)
 	}
 
-	if conn.Flags != "" {
-		flags := strings.Fields(conn.Flags)
-		args = append(args, flags...)
+	flags := strings.Fields(conn.Flags)
+	if script != "" {
+		_, flags = scriptFlag(flags)
+		args = append(args, "--script="+script)
 	}
+	args = append(args, flags...)
 
 	return args
 }
 
-func (d *Daemon) streamPTYOutput(ptmx *os.File) {
+func (d *Daemon) streamPTYOutput(s *session, proc *VPNProcess) {
 	buf := make([]byte, 1024)
 	var lineBuf strings.Builder
 
 	for {
-		n, err := ptmx.Read(buf)
+		n, err := proc.ptmx.Read(buf)
 		if err != nil {
 			if lineBuf.Len() > 0 {
-				d.addLog(lineBuf.String())
+				d.addLog(s, lineBuf.String())
 			}
-			d.handleVPNExit()
+			d.handleVPNExit(s, proc)
 			return
 		}
 
@@ -233,8 +303,8 @@ func (d *Daemon) streamPTYOutput(ptmx *os.File) {
 			if ch == '\n' || ch == '\r' {
 				if lineBuf.Len() > 0 {
 					line := lineBuf.String()
-					d.addLog(line)
-					d.checkLineForEvents(line)
+					d.addLog(s, line)
+					d.checkLineForEvents(s, line)
 					lineBuf.Reset()
 				}
 			} else {
@@ -244,8 +314,8 @@ func (d *Daemon) streamPTYOutput(ptmx *os.File) {
 
 		partial := lineBuf.String()
 		if isPrompt(partial) {
-			d.addLog(partial)
-			d.sendPrompt(partial)
+			d.addLog(s, partial)
+			d.sendPrompt(s, partial)
 			lineBuf.Reset()
 		}
 	}
@@ -302,19 +372,26 @@ func isPasswordPrompt(line string) bool {
 	return false
 }
 
-func (d *Daemon) sendPrompt(line string) {
+func (d *Daemon) sendPrompt(s *session, line string) {
 	isPassword := isPasswordPrompt(line)
-	d.logger.Debug("prompt detected", "is_password", isPassword)
-	d.stateMu.Lock()
-	d.state.Status = StatusPrompting
-	d.stateMu.Unlock()
-	d.sendToClient(PromptMsg{
+	d.logger.Debug("prompt detected", "conn_id", s.connID, "is_password", isPassword)
+	prompt := PromptMsg{
 		Type:       "prompt",
+		ConnID:     s.connID,
 		IsPassword: isPassword,
-	})
+	}
+	d.stateMu.Lock()
+	s.status = StatusPrompting
+	s.prompt = &prompt
+	d.armDeadline(s, phasePrompt)
+	d.stateMu.Unlock()
+	d.sendToOwner(prompt)
+	d.broadcastState()
 }
 
-func (d *Daemon) checkLineForEvents(line string) {
+func (d *Daemon) checkLineForEvents(s *session, line string) {
+	d.noteHistoryLine(s, line)
+
 	ip := ""
 	pid := 0
 
@@ -328,122 +405,211 @@ func (d *Daemon) checkLineForEvents(line string) {
 
 	if match := tunDevPattern.FindStringSubmatch(line); len(match) > 1 {
 		d.stateMu.Lock()
-		if d.state.NetworkSnapshot != nil {
-			d.state.NetworkSnapshot.TunnelInterface = match[1]
-			d.logger.Info("tunnel interface detected", "device", match[1])
+		s.tunnel = match[1]
+		if s.snapshot != nil {
+			s.snapshot.TunnelInterface = match[1]
 		}
 		d.stateMu.Unlock()
+		d.logger.Info("tunnel interface detected", "conn_id", s.connID, "device", match[1])
+	}
+
+	if match := serverPattern.FindStringSubmatch(line); len(match) > 2 {
+		d.stateMu.Lock()
+		s.serverIP = match[1] + match[2]
+		d.stateMu.Unlock()
 	}
 
 	lineLower := strings.ToLower(line)
 	for _, pattern := range connectedPatterns {
 		if strings.Contains(lineLower, pattern) {
 			d.stateMu.Lock()
-			d.state.Status = StatusConnected
+			cameUp := s.status != StatusConnected || s.connectedAt.IsZero()
+			if cameUp {
+				s.connectedAt = time.Now()
+			}
+			s.status = StatusConnected
 			if ip != "" {
-				d.state.IP = ip
+				s.ip = ip
 			}
 			if pid != 0 {
-				d.state.PID = pid
+				s.pid = pid
+			}
+			msg := ConnectedMsg{
+				Type:   "connected",
+				ConnID: s.connID,
+				IP:     s.ip,
+				PID:    s.pid,
+				Tunnel: s.tunnel,
 			}
-			currentIP := d.state.IP
-			currentPID := d.state.PID
 			d.stateMu.Unlock()
-			d.logger.Info("vpn connected", "ip", currentIP, "pid", currentPID, "pattern", pattern)
-			d.sendToClient(ConnectedMsg{
-				Type: "connected",
-				IP:   currentIP,
-				PID:  currentPID,
-			})
+			d.logger.Info("vpn connected", "conn_id", s.connID, "ip", msg.IP, "pid", msg.PID, "pattern", pattern)
+			d.broadcast(msg)
+			d.resolveRequests(s, ResultMsg{OK: true})
+			if cameUp {
+				go d.postConnect(s)
+			}
 			break
 		}
 	}
 }
 
-func (d *Daemon) handleVPNExit() {
-	d.vpnMu.Lock()
-	d.vpnProcess = nil
-	d.vpnMu.Unlock()
+// postConnect brings the kill switch up to date with a tunnel that just
+// came up, applies its split DNS, then runs its post_connect hooks.
+func (d *Daemon) postConnect(s *session) {
+	d.syncKillSwitch()
+	d.applySplitDNS(s)
+	d.reconnectMu.Lock()
+	reason := hookReasonConnect
+	if s.reconnecting {
+		reason = hookReasonReconnect
+	}
+	d.reconnectMu.Unlock()
+	d.runHooks(s, models.HookPostConnect, reason)
+}
+
+func (d *Daemon) handleVPNExit(s *session, proc *VPNProcess) {
+	d.stateMu.Lock()
+	if s.process == proc {
+		s.process = nil
+	}
+	d.stateMu.Unlock()
 
 	d.reconnectMu.Lock()
-	stoppingForReconnect := d.stoppingForReconnect
-	d.stoppingForReconnect = false
-	disconnectRequested := d.disconnectRequested
+	stoppingForReconnect := s.stoppingForReconnect
+	s.stoppingForReconnect = false
+	disconnectRequested := s.disconnectRequested
+	reconnecting := s.reconnecting
 	d.reconnectMu.Unlock()
 
 	if stoppingForReconnect {
-		d.logger.Debug("vpn exit due to reconnect, skipping cleanup")
+		d.logger.Debug("vpn exit due to reconnect, skipping cleanup", "conn_id", s.connID)
 		return
 	}
 
 	if disconnectRequested {
-		d.logger.Debug("vpn exit due to user disconnect, handled by disconnectVPN()")
+		d.logger.Debug("vpn exit due to user disconnect, handled by disconnectVPN()", "conn_id", s.connID)
 		return
 	}
 
+	// An exit during a reconnect attempt is a failed try, counted in the
+	// record of that reconnect rather than a run of its own.
+	reason := models.EndExit
+	if !reconnecting {
+		reason = d.exitReason(s, proc)
+		d.recordHistory(s, reason)
+	}
+
 	d.stateMu.Lock()
-	wasConnected := d.state.Status == StatusConnected
-	wasConnecting := d.state.Status == StatusConnecting
-	wasPrompting := d.state.Status == StatusPrompting
-	reconnectEnabled := d.state.Config.Settings.Reconnect
+	status := s.status
+	shouldReconnect := !reconnecting && d.reconnectsOn(s, models.EndExit) &&
+		(status == StatusConnected || status == StatusConnecting || status == StatusPrompting)
 	autoCleanup := d.state.Config.Settings.AutoCleanup
-	connID := d.state.ActiveConnID
-	d.state.Status = StatusDisconnected
-	d.state.ActiveConnID = ""
-	d.state.IP = ""
-	d.state.PID = 0
+	// A session that ends keeps its address for the post_disconnect hooks.
+	if reconnecting || shouldReconnect {
+		s.ip = ""
+		s.pid = 0
+		s.connectedAt = time.Time{}
+	}
+	if reconnecting {
+		s.status = StatusReconnecting
+	}
 	d.stateMu.Unlock()
 
-	d.logger.Info("vpn process exited", "was_connected", wasConnected, "disconnect_requested", disconnectRequested)
+	d.logger.Info("vpn process exited", "conn_id", s.connID, "status", status)
 
-	shouldReconnect := reconnectEnabled && !disconnectRequested && connID != "" &&
-		(wasConnected || wasConnecting || wasPrompting)
+	if reconnecting {
+		d.logger.Debug("vpn exit during reconnect attempt", "conn_id", s.connID)
+		return
+	}
 
 	if shouldReconnect {
-		d.logger.Info("vpn exit: initiating auto-reconnect", "conn_id", connID)
-		d.addLog(ui.LogWarning("--- Connection lost, will reconnect ---"))
-		go d.startAutoReconnect(connID, "exit")
+		d.logger.Info("vpn exit: initiating auto-reconnect", "conn_id", s.connID)
+		d.setSessionStatus(s, StatusReconnecting)
+		d.addLog(s, ui.LogWarning("--- Connection lost, will reconnect ---"))
+		go d.startAutoReconnect(s, "exit")
 		return
 	}
 
-	d.sendToClient(DisconnectedMsg{Type: "disconnected"})
+	d.endSession(s, reason)
 
 	if autoCleanup {
-		d.runAutoCleanup()
+		d.runAutoCleanup(s)
 	}
 }
 
-func (d *Daemon) handleDisconnect() {
-	d.cancelReconnect()
-
-	d.stateMu.RLock()
-	status := d.state.Status
-	externalPID := d.state.ExternalPID
-	d.stateMu.RUnlock()
-
-	if status == StatusDisconnected {
-		return
-	}
+// handleDisconnect stops one tunnel, the external openconnect, or
+// everything when the command names neither. Its result follows once the
+// tunnels are down.
+func (d *Daemon) handleDisconnect(c *client, msg DisconnectCmd) {
+	reason := models.EndUserDisconnect
+	if msg.Reason == models.EndTimeout {
+		reason = models.EndTimeout
+	}
+
+	switch {
+	case msg.ConnID != "":
+		s := d.session(msg.ConnID)
+		if s == nil {
+			if msg.ID != "" {
+				d.fail(c, msg.ID, msg.ConnID, "not_connected", "Not connected")
+			}
+			return
+		}
+		d.ack(c, msg.ID)
+		d.disconnectSession(s, reason)
+	case msg.External:
+		d.stateMu.RLock()
+		external := d.state.ExternalPID != 0
+		d.stateMu.RUnlock()
+		if !external {
+			if msg.ID != "" {
+				d.fail(c, msg.ID, "", "not_connected", "No external openconnect running")
+			}
+			return
+		}
+		d.ack(c, msg.ID)
+		d.killExternalVPN()
+	default:
+		d.ack(c, msg.ID)
+		d.disconnectAll(reason)
+		d.killExternalVPN()
+	}
+	d.succeed(c, msg.ID)
+}
 
-	if status == StatusExternal {
-		d.killExternalVPN(externalPID)
-		return
-	}
+// disconnectAll stops every tunnel, recording reason in the history.
+func (d *Daemon) disconnectAll(reason string) {
+	var wg sync.WaitGroup
+	for _, s := range d.sessions() {
+		wg.Add(1)
+		go func(s *session) {
+			defer wg.Done()
+			d.disconnectSession(s, reason)
+		}(s)
+	}
+	wg.Wait()
+}
 
-	d.disconnectVPN()
+func (d *Daemon) disconnectSession(s *session, reason string) {
+	d.cancelReconnect(s)
+	d.disconnectVPN(s, reason)
 }
 
-func (d *Daemon) disconnectVPN() {
-	d.logger.Info("disconnecting vpn")
+func (d *Daemon) disconnectVPN(s *session, reason string) {
+	d.logger.Info("disconnecting vpn", "conn_id", s.connID)
 
-	d.vpnMu.Lock()
-	proc := d.vpnProcess
-	d.vpnProcess = nil
-	d.vpnMu.Unlock()
+	if d.sessionStatus(s) == StatusConnected {
+		d.runHooks(s, models.HookPreDisconnect, reason)
+	}
+
+	d.stateMu.Lock()
+	proc := s.process
+	s.process = nil
+	d.stateMu.Unlock()
 
 	if proc != nil && proc.cmd != nil && proc.cmd.Process != nil {
 		pid := proc.cmd.Process.Pid
-		d.addLog(ui.LogCommand(fmt.Sprintf("kill -TERM %d", pid)))
+		d.addLog(s, ui.LogCommand(fmt.Sprintf("kill -TERM %d", pid)))
 		proc.cmd.Process.Signal(syscall.SIGTERM)
 
 		exited := make(chan struct{})
@@ -454,10 +620,10 @@ func (d *Daemon) disconnectVPN() {
 
 		select {
 		case <-exited:
-			d.logger.Debug("vpn process exited gracefully after SIGTERM")
+			d.logger.Debug("vpn process exited gracefully after SIGTERM", "conn_id", s.connID)
 		case <-time.After(8 * time.Second):
-			d.logger.Warn("vpn process did not exit after SIGTERM, sending SIGKILL")
-			d.addLog(ui.LogCommand(fmt.Sprintf("kill -KILL %d", pid)))
+			d.logger.Warn("vpn process did not exit after SIGTERM, sending SIGKILL", "conn_id", s.connID)
+			d.addLog(s, ui.LogCommand(fmt.Sprintf("kill -KILL %d", pid)))
 			proc.cmd.Process.Kill()
 			<-exited
 		}
@@ -467,33 +633,44 @@ func (d *Daemon) disconnectVPN() {
 		}
 	}
 
-	d.stateMu.Lock()
-	d.state.Status = StatusDisconnected
-	d.state.ActiveConnID = ""
-	d.state.IP = ""
-	d.state.PID = 0
+	d.stateMu.RLock()
 	autoCleanup := d.state.Config.Settings.AutoCleanup
-	d.stateMu.Unlock()
+	d.stateMu.RUnlock()
 
-	d.sendToClient(DisconnectedMsg{Type: "disconnected"})
+	d.endSession(s, reason)
 
 	if autoCleanup {
-		d.runAutoCleanup()
+		d.runAutoCleanup(s)
 	}
 }
 
-func (d *Daemon) handleInput(msg InputCmd) {
+func (d *Daemon) handleInput(c *client, msg InputCmd) {
 	value := msg.Value
 
-	d.vpnMu.Lock()
-	proc := d.vpnProcess
-	d.vpnMu.Unlock()
+	if !d.isOwner(c) {
+		d.logger.Warn("input rejected, client does not own the session")
+		d.fail(c, msg.ID, msg.ConnID, "not_owner", "Another client owns the session; take ownership to answer prompts")
+		return
+	}
+
+	s := d.session(msg.ConnID)
+	if s == nil {
+		d.logger.Warn("input for unknown session", "conn_id", msg.ConnID)
+		if msg.ID != "" {
+			d.fail(c, msg.ID, msg.ConnID, "invalid_conn", "No tunnel for this connection")
+		}
+		return
+	}
+
+	d.stateMu.Lock()
+	proc := s.process
+	s.prompt = nil
+	d.stateMu.Unlock()
 
 	if proc != nil && proc.ptmx != nil {
-		d.logger.Debug("sending input to vpn")
+		d.logger.Debug("sending input to vpn", "conn_id", s.connID)
 		proc.ptmx.Write([]byte(value + "\n"))
-		d.stateMu.Lock()
-		d.state.Status = StatusConnecting
-		d.stateMu.Unlock()
+		d.startPhase(s, StatusConnecting, phaseConnect)
 	}
+	d.succeed(c, msg.ID)
 }
@@ -1,9 +1,14 @@
 package daemon
 
 import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/Nybkox/lazyopenconnect/pkg/controllers/helpers"
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
 )
 
 func TestIsPrompt(t *testing.T) {
@@ -37,12 +42,13 @@ func TestIsPasswordPrompt(t *testing.T) {
 
 func TestCheckLineForEventsMarksConnected(t *testing.T) {
 	d := newTestDaemon()
-	d.state.Status = StatusConnecting
-	client := attachTestClient(t, d)
+	s := &session{connID: "work", status: StatusConnecting}
+	d.state.Sessions["work"] = s
+	_, client := attachTestClient(t, d)
 
 	done := make(chan struct{})
 	go func() {
-		d.checkLineForEvents("Configured as 10.10.10.5 with pid 4321")
+		d.checkLineForEvents(s, "Configured as 10.10.10.5 with pid 4321")
 		close(done)
 	}()
 
@@ -54,6 +60,9 @@ func TestCheckLineForEventsMarksConnected(t *testing.T) {
 	if connected.Type != "connected" {
 		t.Fatalf("Type = %q, want %q", connected.Type, "connected")
 	}
+	if connected.ConnID != "work" {
+		t.Fatalf("ConnID = %q, want %q", connected.ConnID, "work")
+	}
 	if connected.IP != "10.10.10.5" {
 		t.Fatalf("IP = %q, want %q", connected.IP, "10.10.10.5")
 	}
@@ -63,14 +72,18 @@ func TestCheckLineForEventsMarksConnected(t *testing.T) {
 	<-done
 
 	d.stateMu.RLock()
-	status := d.state.Status
-	ip := d.state.IP
-	pid := d.state.PID
+	status := s.status
+	ip := s.ip
+	pid := s.pid
+	connectedAt := s.connectedAt
 	d.stateMu.RUnlock()
 
 	if status != StatusConnected {
 		t.Fatalf("status = %v, want %v", status, StatusConnected)
 	}
+	if connectedAt.IsZero() {
+		t.Fatal("ConnectedAt was not set")
+	}
 	if ip != "10.10.10.5" {
 		t.Fatalf("IP = %q, want %q", ip, "10.10.10.5")
 	}
@@ -81,15 +94,187 @@ func TestCheckLineForEventsMarksConnected(t *testing.T) {
 
 func TestCheckLineForEventsUpdatesTunnelInterface(t *testing.T) {
 	d := newTestDaemon()
-	d.state.NetworkSnapshot = &helpers.NetworkSnapshot{}
+	s := &session{connID: "work", snapshot: &helpers.NetworkSnapshot{}}
 
-	d.checkLineForEvents("Using tun device utun9")
+	d.checkLineForEvents(s, "Using tun device utun9")
 
 	d.stateMu.RLock()
-	tunnelInterface := d.state.NetworkSnapshot.TunnelInterface
+	tunnelInterface := s.snapshot.TunnelInterface
+	tunnel := s.tunnel
 	d.stateMu.RUnlock()
 
 	if tunnelInterface != "utun9" {
 		t.Fatalf("TunnelInterface = %q, want %q", tunnelInterface, "utun9")
 	}
+	if tunnel != "utun9" {
+		t.Fatalf("tunnel = %q, want %q", tunnel, "utun9")
+	}
+}
+
+func TestCheckLineForEventsRecordsServerIP(t *testing.T) {
+	d := newTestDaemon()
+	s := &session{connID: "work"}
+
+	for _, tt := range []struct{ line, want string }{
+		{"Connected to 203.0.113.7:443", "203.0.113.7"},
+		{"Connected to [2001:db8::7]:443", "2001:db8::7"},
+		{"Connected to HTTPS on vpn.local", "2001:db8::7"},
+	} {
+		d.checkLineForEvents(s, tt.line)
+		d.stateMu.RLock()
+		got := s.serverIP
+		d.stateMu.RUnlock()
+		if got != tt.want {
+			t.Fatalf("after %q serverIP = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestBuildArgsRequestsTunnelInterface(t *testing.T) {
+	conn := &models.Connection{Protocol: "anyconnect", Host: "vpn.example.com"}
+
+	args := buildArgs(conn, "tun1", "", false)
+	if !slices.Contains(args, "--interface=tun1") {
+		t.Fatalf("args = %v, want --interface=tun1", args)
+	}
+
+	args = buildArgs(conn, "", "", false)
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "--interface") {
+			t.Fatalf("args = %v, want no --interface without a tunnel", args)
+		}
+	}
+}
+
+func TestBuildArgsPasswordFileUsesStdin(t *testing.T) {
+	pass := filepath.Join(t.TempDir(), "work.pass")
+	if err := os.WriteFile(pass, []byte("secret\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	conn := &models.Connection{Protocol: "gp", Host: "vpn.example.com", PasswordFile: pass}
+
+	password := newTestDaemon().connectPassword(conn, "")
+	assertString(t, "password", password, "secret")
+	if args := buildArgs(conn, "", "", password != ""); !slices.Contains(args, "--passwd-on-stdin") {
+		t.Fatalf("args = %v, want --passwd-on-stdin", args)
+	}
+}
+
+func TestUnreadablePasswordFileLetsOpenconnectPrompt(t *testing.T) {
+	conn := &models.Connection{Protocol: "gp", Host: "vpn.example.com", PasswordFile: filepath.Join(t.TempDir(), "missing.pass")}
+
+	password := newTestDaemon().connectPassword(conn, "")
+	assertString(t, "password", password, "")
+	if args := buildArgs(conn, "", "", password != ""); slices.Contains(args, "--passwd-on-stdin") {
+		t.Fatalf("args = %v, want openconnect to prompt without --passwd-on-stdin", args)
+	}
+}
+
+func TestBuildArgsSplitScriptReplacesScriptFlag(t *testing.T) {
+	conn := &models.Connection{Protocol: "gp", Host: "vpn.example.com", Flags: "--no-dtls -s /opt/my-script --reconnect-timeout 10"}
+
+	args := buildArgs(conn, "", "/home/alice/.config/lazyopenconnect/vpnc-work.sh", false)
+	want := []string{"--protocol=gp", "vpn.example.com", "--script=/home/alice/.config/lazyopenconnect/vpnc-work.sh", "--no-dtls", "--reconnect-timeout", "10"}
+	if !slices.Equal(args, want) {
+		t.Fatalf("args = %q, want %q", args, want)
+	}
+
+	if args := buildArgs(conn, "", "", false); !slices.Contains(args, "/opt/my-script") {
+		t.Fatalf("args = %q, want the flags' own script without a split tunnel", args)
+	}
+}
+
+func TestScriptFlag(t *testing.T) {
+	for _, flags := range []string{"--script=/opt/s", "--script /opt/s", "-s /opt/s"} {
+		script, rest := scriptFlag(strings.Fields("--no-dtls " + flags))
+		if script != "/opt/s" || !slices.Equal(rest, []string{"--no-dtls"}) {
+			t.Fatalf("scriptFlag(%q) = %q, %q", flags, script, rest)
+		}
+	}
+}
+
+func TestHandleConnectRejectsRunningSession(t *testing.T) {
+	d := newTestDaemon()
+	d.state.Config.Connections = []models.Connection{{ID: "work", Host: "vpn.example.com"}}
+	d.state.Sessions["work"] = &session{connID: "work", status: StatusConnected}
+	c, conn := attachTestClient(t, d)
+
+	go d.handleConnect(c, ConnectCmd{Type: "connect", ConnID: "work"})
+
+	var errMsg ErrorMsg
+	if err := readTestMsg(t, conn).Decode(&errMsg); err != nil {
+		t.Fatalf("Decode returned error: %v", err)
+	}
+	if errMsg.Code != "already_connected" {
+		t.Fatalf("Code = %q, want %q", errMsg.Code, "already_connected")
+	}
+	if errMsg.ConnID != "work" {
+		t.Fatalf("ConnID = %q, want %q", errMsg.ConnID, "work")
+	}
+}
+
+func TestHandleConnectWithIDFailsRequest(t *testing.T) {
+	d := newTestDaemon()
+	c, conn := attachTestClient(t, d)
+
+	go d.handleConnect(c, ConnectCmd{Type: "connect", ID: "3", ConnID: "missing"})
+
+	var res ResultMsg
+	if err := readTestMsg(t, conn).Decode(&res); err != nil {
+		t.Fatalf("Decode returned error: %v", err)
+	}
+	assertString(t, "Type", res.Type, "result")
+	assertString(t, "ID", res.ID, "3")
+	assertBool(t, "OK", res.OK, false)
+	assertString(t, "Code", res.Code, "invalid_conn")
+}
+
+func TestConnectRequestResolvedWhenTunnelComesUp(t *testing.T) {
+	d := newTestDaemon()
+	c, conn := attachTestClient(t, d)
+	s := &session{
+		connID:   "work",
+		status:   StatusConnecting,
+		requests: []pendingRequest{{client: c, id: "8"}},
+	}
+	d.state.Sessions["work"] = s
+
+	go d.checkLineForEvents(s, "Configured as 10.10.10.5")
+
+	assertString(t, "first Type", readTestMsg(t, conn).Type, "connected")
+	var res ResultMsg
+	if err := readTestMsg(t, conn).Decode(&res); err != nil {
+		t.Fatalf("Decode returned error: %v", err)
+	}
+	assertString(t, "ID", res.ID, "8")
+	assertBool(t, "OK", res.OK, true)
+
+	d.stateMu.RLock()
+	left := len(s.requests)
+	d.stateMu.RUnlock()
+	if left != 0 {
+		t.Fatalf("requests left = %d, want 0", left)
+	}
+}
+
+func TestConnectRequestFailsWhenSessionEnds(t *testing.T) {
+	d := newTestDaemon()
+	c, conn := attachTestClient(t, d)
+	s := &session{
+		connID:   "work",
+		status:   StatusConnecting,
+		requests: []pendingRequest{{client: c, id: "4"}},
+	}
+	d.state.Sessions["work"] = s
+
+	go d.endSession(s, models.EndExit)
+
+	assertString(t, "first Type", readTestMsg(t, conn).Type, "disconnected")
+	var res ResultMsg
+	if err := readTestMsg(t, conn).Decode(&res); err != nil {
+		t.Fatalf("Decode returned error: %v", err)
+	}
+	assertString(t, "ID", res.ID, "4")
+	assertBool(t, "OK", res.OK, false)
+	assertString(t, "Code", res.Code, "disconnected")
 }
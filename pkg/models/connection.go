@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type Connection struct {
 	ID          string `json:"id"`
 	Name        string `json:"name"`
@@ -9,4 +11,103 @@ type Connection struct {
 	HasPassword bool   `json:"hasPassword"`
 	ServerCert  string `json:"serverCert,omitempty"`
 	Flags       string `json:"flags"`
+	// AutoConnect brings the tunnel up when the daemon starts.
+	AutoConnect bool `json:"autoConnect,omitempty"`
+	// PasswordFile holds the password for the daemon, which cannot reach
+	// the user's keychain when it starts at boot.
+	PasswordFile string `json:"passwordFile,omitempty"`
+	// HealthCheck probes a target inside the VPN while connected, e.g.
+	// "tcp:10.0.0.1:22", "icmp:10.0.0.1" or "dns:intranet.corp@10.0.0.53".
+	HealthCheck string `json:"healthCheck,omitempty"`
+	// HealthInterval is the number of seconds between probes.
+	HealthInterval int `json:"healthInterval,omitempty"`
+	// HealthFailures is how many failed probes in a row mark the tunnel
+	// dead.
+	HealthFailures int `json:"healthFailures,omitempty"`
+	// Reconnect overrides fields of the settings' reconnect policy for
+	// this connection.
+	Reconnect *ReconnectPolicy `json:"reconnect,omitempty"`
+	// Hooks run after the global hooks in Settings.
+	Hooks Hooks `json:"hooks,omitzero"`
+	// IncludeRoutes are CIDRs sent through the tunnel on top of the
+	// routes the server pushes.
+	IncludeRoutes []string `json:"includeRoutes,omitempty"`
+	// ExcludeRoutes are CIDRs kept off the tunnel.
+	ExcludeRoutes []string `json:"excludeRoutes,omitempty"`
+	// NoDefaultRoute ignores a default route pushed by the server, so only
+	// split routes use the tunnel.
+	NoDefaultRoute bool `json:"noDefaultRoute,omitempty"`
+	// DNSDomains are the only names resolved through the tunnel; a leading
+	// "~" routes a domain without adding it to the search list.
+	DNSDomains []string `json:"dnsDomains,omitempty"`
+	// DNSServers answer for DNSDomains. Empty uses the servers the VPN
+	// pushed, which macOS cannot do.
+	DNSServers []string `json:"dnsServers,omitempty"`
+	// KillSwitch blocks all traffic outside the tunnel while it is up or
+	// reconnecting, except to the VPN server, DHCP and loopback.
+	KillSwitch bool `json:"killSwitch,omitempty"`
+}
+
+const (
+	DefaultHealthInterval = 30
+	DefaultHealthFailures = 3
+)
+
+// SendsPassword reports whether openconnect reads the password from stdin
+// instead of prompting for it.
+func (c Connection) SendsPassword() bool {
+	return c.HasPassword || c.PasswordFile != ""
+}
+
+// SplitTunnel reports whether the connection changes the routes the
+// server pushes.
+func (c Connection) SplitTunnel() bool {
+	return len(c.IncludeRoutes) > 0 || len(c.ExcludeRoutes) > 0 || c.NoDefaultRoute
+}
+
+// SplitDNS reports whether only some domains are resolved through the
+// tunnel.
+func (c Connection) SplitDNS() bool {
+	return len(c.DNSDomains) > 0
+}
+
+// HealthEvery returns the time between health probes.
+func (c Connection) HealthEvery() time.Duration {
+	if c.HealthInterval <= 0 {
+		return DefaultHealthInterval * time.Second
+	}
+	return time.Duration(c.HealthInterval) * time.Second
+}
+
+// HealthThreshold returns how many failed probes in a row trigger a
+// reconnect.
+func (c Connection) HealthThreshold() int {
+	if c.HealthFailures <= 0 {
+		return DefaultHealthFailures
+	}
+	return c.HealthFailures
+}
+
+type Protocol struct {
+	Name  string
+	Label string
+}
+
+var Protocols = []Protocol{
+	{Name: "fortinet", Label: "Fortinet"},
+	{Name: "gp", Label: "GlobalProtect"},
+	{Name: "anyconnect", Label: "AnyConnect"},
+	{Name: "nc", Label: "Juniper"},
+	{Name: "pulse", Label: "Pulse"},
+	{Name: "f5", Label: "F5"},
+	{Name: "array", Label: "Array"},
+}
+
+func IsSupportedProtocol(name string) bool {
+	for _, p := range Protocols {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
 }
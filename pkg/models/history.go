@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// Why a tunnel went down, as recorded in the session history.
+const (
+	EndUserDisconnect  = "user_disconnect"
+	EndExit            = "exit"
+	EndWake            = "wake"
+	EndHealth          = "health"
+	EndNetworkChange   = "network_change"
+	EndTimeout         = "timeout"
+	EndExternalKill    = "external_kill"
+	EndStartFailed     = "start_failed"
+	EndReconnectFailed = "reconnect_failed"
+	EndReplaced        = "replaced"
+	EndShutdown        = "shutdown"
+)
+
+// HistoryRecord is one run of a tunnel: from a connect, or the start of a
+// reconnect, until the tunnel went down. A dropped tunnel that reconnects
+// therefore leaves two records.
+type HistoryRecord struct {
+	ConnID      string    `json:"connId"`
+	Name        string    `json:"name,omitempty"`
+	Host        string    `json:"host,omitempty"`
+	StartedAt   time.Time `json:"startedAt"`
+	ConnectedAt time.Time `json:"connectedAt,omitzero"`
+	EndedAt     time.Time `json:"endedAt"`
+	IP          string    `json:"ip,omitempty"`
+	Tunnel      string    `json:"tunnel,omitempty"`
+	EndReason   string    `json:"endReason"`
+	// ReconnectAttempts counts the tries it took to bring a reconnect up,
+	// or the tries made before giving up.
+	ReconnectAttempts int    `json:"reconnectAttempts,omitempty"`
+	LastError         string `json:"lastError,omitempty"`
+}
+
+// Connected reports whether the tunnel came up during this run.
+func (r HistoryRecord) Connected() bool {
+	return !r.ConnectedAt.IsZero()
+}
+
+// Uptime is how long the tunnel was up.
+func (r HistoryRecord) Uptime() time.Duration {
+	if !r.Connected() || r.EndedAt.Before(r.ConnectedAt) {
+		return 0
+	}
+	return r.EndedAt.Sub(r.ConnectedAt)
+}
+
+// Dropped reports whether the tunnel went down without anyone asking.
+func (r HistoryRecord) Dropped() bool {
+	switch r.EndReason {
+	case EndExit, EndWake, EndHealth, EndNetworkChange, EndExternalKill:
+		return r.Connected()
+	}
+	return false
+}
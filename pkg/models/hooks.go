@@ -0,0 +1,82 @@
+package models
+
+import "time"
+
+// Hook events, in the order a tunnel's life reaches them.
+const (
+	HookPreConnect     = "pre_connect"
+	HookPostConnect    = "post_connect"
+	HookReconnect      = "reconnect"
+	HookPreDisconnect  = "pre_disconnect"
+	HookPostDisconnect = "post_disconnect"
+)
+
+var HookEvents = []string{HookPreConnect, HookPostConnect, HookReconnect, HookPreDisconnect, HookPostDisconnect}
+
+// DefaultHookTimeout is how many seconds a hook may run when Timeout is
+// zero.
+const DefaultHookTimeout = 30
+
+// Hooks are shell commands the daemon runs as root at points of a tunnel's
+// life. The hooks in Settings run for every connection, before the
+// connection's own.
+type Hooks struct {
+	// PreConnect runs before openconnect starts.
+	PreConnect string `json:"preConnect,omitempty"`
+	// PostConnect runs each time the tunnel comes up, including after a
+	// reconnect.
+	PostConnect string `json:"postConnect,omitempty"`
+	// Reconnect runs when a dropped tunnel starts to reconnect.
+	Reconnect string `json:"reconnect,omitempty"`
+	// PreDisconnect runs before a tunnel that is being disconnected is
+	// stopped.
+	PreDisconnect string `json:"preDisconnect,omitempty"`
+	// PostDisconnect runs once the tunnel is gone, however it ended.
+	PostDisconnect string `json:"postDisconnect,omitempty"`
+	// Timeout is how many seconds each hook may run before it is killed.
+	Timeout int `json:"timeout,omitempty"`
+}
+
+// Command returns the hook for event, empty when none is set.
+func (h Hooks) Command(event string) string {
+	switch event {
+	case HookPreConnect:
+		return h.PreConnect
+	case HookPostConnect:
+		return h.PostConnect
+	case HookReconnect:
+		return h.Reconnect
+	case HookPreDisconnect:
+		return h.PreDisconnect
+	case HookPostDisconnect:
+		return h.PostDisconnect
+	}
+	return ""
+}
+
+// SetCommand sets the hook for event and reports whether event is known.
+func (h *Hooks) SetCommand(event, command string) bool {
+	switch event {
+	case HookPreConnect:
+		h.PreConnect = command
+	case HookPostConnect:
+		h.PostConnect = command
+	case HookReconnect:
+		h.Reconnect = command
+	case HookPreDisconnect:
+		h.PreDisconnect = command
+	case HookPostDisconnect:
+		h.PostDisconnect = command
+	default:
+		return false
+	}
+	return true
+}
+
+// RunTimeout returns how long each hook may run.
+func (h Hooks) RunTimeout() time.Duration {
+	if h.Timeout <= 0 {
+		return DefaultHookTimeout * time.Second
+	}
+	return time.Duration(h.Timeout) * time.Second
+}
@@ -0,0 +1,148 @@
+package models
+
+import "time"
+
+// Reconnect policy defaults, used for fields left at zero.
+const (
+	DefaultReconnectAttempts = 3
+	DefaultNetworkWait       = 30
+	// UnlimitedAttempts as MaxAttempts retries until the user disconnects.
+	UnlimitedAttempts = -1
+)
+
+var DefaultReconnectBackoff = []int{2, 5, 10}
+
+// ReconnectTriggers are the end reasons a reconnect policy can act on.
+var ReconnectTriggers = []string{EndWake, EndExit, EndHealth, EndNetworkChange}
+
+// ReconnectPolicy decides how a dropped tunnel is brought back. Zero fields
+// use the defaults. The policy in Settings applies to every connection; a
+// connection's own policy overrides it field by field.
+type ReconnectPolicy struct {
+	// MaxAttempts caps the tries per reconnect; UnlimitedAttempts never
+	// gives up.
+	MaxAttempts int `json:"maxAttempts,omitempty"`
+	// Backoff is the wait in seconds after each failed try; the last entry
+	// repeats.
+	Backoff []int `json:"backoff,omitempty"`
+	// Jitter spreads every wait by up to this fraction either way, e.g.
+	// 0.2 for ±20%.
+	Jitter float64 `json:"jitter,omitempty"`
+	// NetworkWait is how many seconds to wait for the VPN server to become
+	// reachable before giving up.
+	NetworkWait int `json:"networkWait,omitempty"`
+	// OnWake, OnExit, OnHealth and OnNetworkChange pick the events that
+	// reconnect: waking from sleep, openconnect exiting, failed health
+	// checks, a new default route. Unset means yes.
+	OnWake          *bool `json:"onWake,omitempty"`
+	OnExit          *bool `json:"onExit,omitempty"`
+	OnHealth        *bool `json:"onHealth,omitempty"`
+	OnNetworkChange *bool `json:"onNetworkChange,omitempty"`
+}
+
+// Override returns p with the fields set in o replacing its own.
+func (p ReconnectPolicy) Override(o *ReconnectPolicy) ReconnectPolicy {
+	if o == nil {
+		return p
+	}
+	if o.MaxAttempts != 0 {
+		p.MaxAttempts = o.MaxAttempts
+	}
+	if len(o.Backoff) > 0 {
+		p.Backoff = o.Backoff
+	}
+	if o.Jitter != 0 {
+		p.Jitter = o.Jitter
+	}
+	if o.NetworkWait != 0 {
+		p.NetworkWait = o.NetworkWait
+	}
+	if o.OnWake != nil {
+		p.OnWake = o.OnWake
+	}
+	if o.OnExit != nil {
+		p.OnExit = o.OnExit
+	}
+	if o.OnHealth != nil {
+		p.OnHealth = o.OnHealth
+	}
+	if o.OnNetworkChange != nil {
+		p.OnNetworkChange = o.OnNetworkChange
+	}
+	return p
+}
+
+// Attempts returns the tries per reconnect, 0 when unlimited.
+func (p ReconnectPolicy) Attempts() int {
+	switch {
+	case p.MaxAttempts < 0:
+		return 0
+	case p.MaxAttempts == 0:
+		return DefaultReconnectAttempts
+	}
+	return p.MaxAttempts
+}
+
+// Delay returns the wait after failed try attempt (from 1). r in [0, 1)
+// picks where in the jitter range it falls.
+func (p ReconnectPolicy) Delay(attempt int, r float64) time.Duration {
+	backoff := p.Backoff
+	if len(backoff) == 0 {
+		backoff = DefaultReconnectBackoff
+	}
+	secs := backoff[min(max(attempt, 1), len(backoff))-1]
+	d := time.Duration(secs) * time.Second
+
+	if jitter := min(max(p.Jitter, 0), 1); jitter > 0 {
+		d = time.Duration(float64(d) * (1 + jitter*(2*r-1)))
+	}
+	return d
+}
+
+// NetworkTimeout returns how long to wait for the network.
+func (p ReconnectPolicy) NetworkTimeout() time.Duration {
+	if p.NetworkWait <= 0 {
+		return DefaultNetworkWait * time.Second
+	}
+	return time.Duration(p.NetworkWait) * time.Second
+}
+
+// ReconnectsOn reports whether a tunnel that went down for reason, one of
+// ReconnectTriggers, is reconnected.
+func (p ReconnectPolicy) ReconnectsOn(reason string) bool {
+	on := p.trigger(reason)
+	return on == nil || *on == nil || **on
+}
+
+// SetReconnectsOn sets the trigger for reason; nil leaves the decision to
+// the policy it overrides.
+func (p *ReconnectPolicy) SetReconnectsOn(reason string, on *bool) {
+	if field := p.trigger(reason); field != nil {
+		*field = on
+	}
+}
+
+func (p *ReconnectPolicy) trigger(reason string) **bool {
+	switch reason {
+	case EndWake:
+		return &p.OnWake
+	case EndExit:
+		return &p.OnExit
+	case EndHealth:
+		return &p.OnHealth
+	case EndNetworkChange:
+		return &p.OnNetworkChange
+	}
+	return nil
+}
+
+// ReconnectPolicyFor returns the policy of the connection connID.
+func (c *Config) ReconnectPolicyFor(connID string) ReconnectPolicy {
+	policy := c.Settings.ReconnectPolicy
+	for i := range c.Connections {
+		if c.Connections[i].ID == connID {
+			return policy.Override(c.Connections[i].Reconnect)
+		}
+	}
+	return policy
+}
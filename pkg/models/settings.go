@@ -10,6 +10,10 @@ type Settings struct {
 	NetInterface      string `json:"netInterface"`
 	TunnelInterface   string `json:"tunnelInterface"`
 	SkipVersionUpdate string `json:"skipVersionUpdate"`
+	// ReconnectPolicy applies when Reconnect is on.
+	ReconnectPolicy ReconnectPolicy `json:"reconnectPolicy,omitzero"`
+	Timeouts        Timeouts        `json:"timeouts,omitzero"`
+	Hooks           Hooks           `json:"hooks,omitzero"`
 }
 
 func DefaultWifiInterface() string {
@@ -11,3 +11,24 @@ const (
 	StatusReconnecting
 	StatusQuitting
 )
+
+func (s ConnStatus) String() string {
+	switch s {
+	case StatusDisconnected:
+		return "disconnected"
+	case StatusConnecting:
+		return "connecting"
+	case StatusPrompting:
+		return "prompting"
+	case StatusConnected:
+		return "connected"
+	case StatusExternal:
+		return "external"
+	case StatusReconnecting:
+		return "reconnecting"
+	case StatusQuitting:
+		return "quitting"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// Timeout defaults in seconds, used for fields left at zero.
+const (
+	DefaultConnectTimeout   = 30
+	DefaultPromptTimeout    = 300
+	DefaultReconnectTimeout = 30
+	// NoTimeout as a timeout waits for as long as it takes.
+	NoTimeout = -1
+)
+
+// Timeouts bound how long the daemon waits in each phase of bringing a
+// tunnel up before it gives up. Values are seconds; zero uses the default.
+type Timeouts struct {
+	// Connect runs from starting openconnect, or answering a prompt, until
+	// the tunnel is up.
+	Connect int `json:"connect,omitempty"`
+	// Prompt is how long openconnect may wait for an answer.
+	Prompt int `json:"prompt,omitempty"`
+	// Reconnect limits each attempt of a reconnect; a timed out attempt
+	// counts as failed.
+	Reconnect int `json:"reconnect,omitempty"`
+}
+
+// ConnectTimeout returns the connect deadline, 0 for none.
+func (t Timeouts) ConnectTimeout() time.Duration {
+	return timeoutOrDefault(t.Connect, DefaultConnectTimeout)
+}
+
+// PromptTimeout returns the prompt deadline, 0 for none.
+func (t Timeouts) PromptTimeout() time.Duration {
+	return timeoutOrDefault(t.Prompt, DefaultPromptTimeout)
+}
+
+// ReconnectTimeout returns the deadline of one reconnect attempt, 0 for
+// none.
+func (t Timeouts) ReconnectTimeout() time.Duration {
+	return timeoutOrDefault(t.Reconnect, DefaultReconnectTimeout)
+}
+
+func timeoutOrDefault(seconds, def int) time.Duration {
+	switch {
+	case seconds < 0:
+		return 0
+	case seconds == 0:
+		seconds = def
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,145 @@
+package presentation
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/app"
+	"github.com/Nybkox/lazyopenconnect/pkg/controllers/helpers"
+	"github.com/Nybkox/lazyopenconnect/pkg/models"
+)
+
+func overlayHistory(base string, state *app.State, width, height int) string {
+	maxModalHeight := height - 4
+	content := renderHistoryContent(state, min(width-10, 84), maxModalHeight)
+	styled := FormOverlayStyle.Render(content)
+	dimmed := dimContent(base, height)
+	return compositeOverlay(dimmed, styled, width, height)
+}
+
+func renderHistoryContent(state *app.State, historyWidth, maxHeight int) string {
+	records := state.VisibleHistory()
+
+	scope := "All connections"
+	if state.HistoryConnID != "" {
+		scope = state.ConnectionName(state.HistoryConnID)
+	}
+	header := TitleStyle.Render("History") + "  " + MutedStyle.Render(scope)
+
+	var lines []string
+	if len(records) == 0 {
+		lines = append(lines, MutedStyle.Render("No sessions recorded"))
+	} else {
+		lines = append(lines, MutedStyle.Render(historyTotals(records)))
+		lines = append(lines, "")
+		for _, rec := range records {
+			lines = append(lines, renderHistoryRecord(state, rec))
+			if rec.LastError != "" {
+				lines = append(lines, "    "+DimStyle.Render(truncateText(rec.LastError, historyWidth-4)))
+			}
+		}
+	}
+
+	footer := MutedStyle.Render("[a] all / selected connection  [esc] close")
+	padLen := max((historyWidth-lipgloss.Width(footer))/2, 0)
+	footer = strings.Repeat(" ", padLen) + footer
+
+	visibleLines := max(maxHeight-8, 1)
+	body := strings.Join(lines, "\n")
+	if len(lines) > visibleLines {
+		maxScroll := len(lines) - visibleLines
+		scroll := max(min(state.HistoryScroll, maxScroll), 0)
+		body = addScrollbar(strings.Join(lines[scroll:scroll+visibleLines], "\n"), visibleLines, historyWidth, scroll, len(lines), visibleLines)
+	}
+
+	return lipgloss.NewStyle().Width(historyWidth).Render(header + "\n\n" + body + "\n\n" + footer)
+}
+
+// historyTotals sums up records, e.g. "12 sessions · 3 drops · up 5h12m".
+func historyTotals(records []models.HistoryRecord) string {
+	var total helpers.HistorySummary
+	for _, sum := range helpers.SummarizeHistory(records) {
+		total.Sessions += sum.Sessions
+		total.Drops += sum.Drops
+		total.Failed += sum.Failed
+		total.Uptime += sum.Uptime
+	}
+
+	parts := []string{
+		plural(total.Sessions, "session"),
+		plural(total.Drops, "drop"),
+	}
+	if total.Failed > 0 {
+		parts = append(parts, fmt.Sprintf("%d failed", total.Failed))
+	}
+	parts = append(parts, "up "+total.Uptime.Round(time.Minute).String())
+	return strings.Join(parts, " · ")
+}
+
+func renderHistoryRecord(state *app.State, rec models.HistoryRecord) string {
+	name := rec.Name
+	if conn := state.FindConnectionByID(rec.ConnID); conn != nil {
+		name = conn.Name
+	}
+	if name == "" {
+		name = rec.ConnID
+	}
+
+	uptime := "-"
+	if rec.Connected() {
+		uptime = rec.Uptime().Round(time.Second).String()
+	}
+
+	reason := fmt.Sprintf("%-16s", rec.EndReason)
+	switch {
+	case !rec.Connected() && rec.EndReason != models.EndUserDisconnect:
+		reason = DangerStyle.Render(reason)
+	case rec.Dropped():
+		reason = WarningStyle.Render(reason)
+	default:
+		reason = MutedStyle.Render(reason)
+	}
+
+	line := fmt.Sprintf("%s  %-16s %9s  %s",
+		rec.StartedAt.Local().Format("01-02 15:04"),
+		truncateText(name, 16),
+		uptime,
+		reason,
+	)
+
+	var details []string
+	if rec.ReconnectAttempts > 0 {
+		details = append(details, plural(rec.ReconnectAttempts, "retry"))
+	}
+	if rec.Tunnel != "" {
+		details = append(details, rec.Tunnel)
+	}
+	if rec.IP != "" {
+		details = append(details, rec.IP)
+	}
+	if len(details) > 0 {
+		line += " " + MutedStyle.Render(strings.Join(details, " "))
+	}
+	return line
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return "1 " + word
+	}
+	if strings.HasSuffix(word, "y") {
+		word = strings.TrimSuffix(word, "y") + "ie"
+	}
+	return fmt.Sprintf("%d %ss", n, word)
+}
+
+func truncateText(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n || n < 1 {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
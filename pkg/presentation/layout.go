@@ -3,6 +3,7 @@ package presentation
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
 
@@ -32,7 +33,7 @@ func Render(state *app.State, spinnerFrame int) string {
 	inputHeight := 5
 	outputHeight := totalHeight - inputHeight
 
-	statusPane := renderPane("Status", "1", renderStatusContent(state, spinnerFrame), leftWidth, statusHeight, state.FocusedPane == app.PaneStatus, state.ActiveForm != nil)
+	statusPane := renderPane("Status", "1", renderStatusContent(state, spinnerFrame, leftWidth-2), leftWidth, statusHeight, state.FocusedPane == app.PaneStatus, state.ActiveForm != nil)
 	connectionsPane := renderPane("Connections", "2", renderConnectionsContent(state, connectionsHeight-3, leftWidth-2, spinnerFrame), leftWidth, connectionsHeight, state.FocusedPane == app.PaneConnections, state.ActiveForm != nil)
 
 	settingsTitle := "Settings"
@@ -57,6 +58,8 @@ func Render(state *app.State, spinnerFrame int) string {
 
 	if state.ShowingHelp {
 		main = overlayHelp(main, state, state.Width, totalHeight)
+	} else if state.ShowingHistory {
+		main = overlayHistory(main, state, state.Width, totalHeight)
 	} else if state.ActiveForm != nil {
 		main = overlayForm(main, state.ActiveForm.View(), state.Width, totalHeight)
 	}
@@ -82,37 +85,82 @@ func renderPane(title, key, content string, width, height int, focused, formActi
 	return style.Render(inner)
 }
 
-func renderStatusContent(state *app.State, spinnerFrame int) string {
-	switch state.Status {
-	case app.StatusConnected:
-		conn := state.ActiveConnection()
-		name := ""
-		if conn != nil {
-			name = conn.Name + " "
+func renderStatusContent(state *app.State, spinnerFrame int, width int) string {
+	connID := state.StatusConnID()
+	if connID == "" {
+		if state.HasExternal() {
+			// Try to match external connection to saved connection
+			name := ""
+			if conn := state.MatchConnectionByHost(state.ExternalHost); conn != nil {
+				name = conn.Name + " "
+			} else if state.ExternalHost != "" {
+				name = state.ExternalHost + " "
+			}
+			return fmt.Sprintf("%s External %s(pid %d)",
+				WarningStyle.Render("●"), name, state.ExternalPID)
 		}
-		return fmt.Sprintf("%s Connected %s(%s) pid %d",
-			SuccessStyle.Render("●"), name, state.IP, state.PID)
-	case app.StatusExternal:
-		// Try to match external connection to saved connection
-		name := ""
-		if conn := state.MatchConnectionByHost(state.ExternalHost); conn != nil {
-			name = conn.Name + " "
-		} else if state.ExternalHost != "" {
-			name = state.ExternalHost + " "
+		line := fmt.Sprintf("%s Disconnected", MutedStyle.Render("○"))
+		if state.KillSwitch {
+			line += "\n" + DangerStyle.Render("Kill switch on, traffic blocked") +
+				MutedStyle.Render("  lazyopenconnect killswitch off")
 		}
-		return fmt.Sprintf("%s External %s(pid %d)",
-			WarningStyle.Render("●"), name, state.PID)
-	case app.StatusConnecting:
-		frame := SpinnerFrames[spinnerFrame%len(SpinnerFrames)]
-		return fmt.Sprintf("%s Connecting...", WarningStyle.Render(frame))
+		return line
+	}
+
+	sess := state.Sessions[connID]
+	name := state.ConnectionName(connID)
+
+	var line string
+	switch sess.Status {
+	case app.StatusConnected:
+		line = fmt.Sprintf("%s Connected %s (%s) pid %d",
+			SuccessStyle.Render("●"), name, sess.IP, sess.PID)
 	case app.StatusPrompting:
-		return fmt.Sprintf("%s Awaiting input...", WarningStyle.Render("○"))
+		line = fmt.Sprintf("%s %s awaiting input...", WarningStyle.Render("○"), name)
 	case app.StatusReconnecting:
-		return fmt.Sprintf("%s Reconnecting... (attempt %d/3)",
-			WarningStyle.Render("◐"), state.ReconnectAttempts)
+		line = fmt.Sprintf("%s Reconnecting %s... (%s)",
+			WarningStyle.Render("◐"), name, reconnectAttempt(sess))
 	default:
-		return fmt.Sprintf("%s Disconnected", MutedStyle.Render("○"))
+		frame := SpinnerFrames[spinnerFrame%len(SpinnerFrames)]
+		if sess.ReconnectAttempts > 0 {
+			line = fmt.Sprintf("%s Reconnecting %s... (%s)",
+				WarningStyle.Render(frame), name, reconnectAttempt(sess))
+		} else {
+			line = fmt.Sprintf("%s Connecting %s...", WarningStyle.Render(frame), name)
+		}
+	}
+	if !sess.Deadline.IsZero() {
+		line += MutedStyle.Render("  " + formatCountdown(time.Until(sess.Deadline)) + " left")
+	}
+
+	if others := len(state.Sessions) - 1; others > 0 {
+		line += MutedStyle.Render(fmt.Sprintf("  +%d more", others))
 	}
+	if state.KillSwitch {
+		line += "  " + WarningStyle.Render("kill switch")
+	}
+	if sess.Status == app.StatusConnected {
+		line += "\n" + renderTraffic(sess, width)
+	}
+	return line
+}
+
+// reconnectAttempt reads "attempt 2/5", or "attempt 2" when unlimited.
+func reconnectAttempt(sess *app.Session) string {
+	attempt := fmt.Sprintf("attempt %d", sess.ReconnectAttempts)
+	if sess.ReconnectMax > 0 {
+		attempt += fmt.Sprintf("/%d", sess.ReconnectMax)
+	}
+	return attempt
+}
+
+// formatCountdown shows time left as "42s" or "4:05".
+func formatCountdown(left time.Duration) string {
+	secs := max(int(left.Round(time.Second).Seconds()), 0)
+	if secs < 60 {
+		return fmt.Sprintf("%ds", secs)
+	}
+	return fmt.Sprintf("%d:%02d", secs/60, secs%60)
 }
 
 func renderConnectionsContent(state *app.State, maxLines int, paneWidth int, frame int) string {
@@ -153,7 +201,7 @@ func renderConnectionsContent(state *app.State, maxLines int, paneWidth int, fra
 			continue
 		}
 		conn := state.Config.Connections[realIdx]
-		lines = append(lines, renderConnectionItem(state, &conn, i, frame))
+		lines = append(lines, renderConnectionItem(state, &conn, i, frame, paneWidth))
 	}
 
 	content := strings.Join(lines, "\n")
@@ -205,22 +253,23 @@ func addScrollbar(content string, height, paneWidth, scroll, total, visible int)
 	return strings.Join(lines, "\n")
 }
 
-func renderConnectionItem(state *app.State, conn *models.Connection, idx int, spinnerFrame int) string {
+func renderConnectionItem(state *app.State, conn *models.Connection, idx int, spinnerFrame int, paneWidth int) string {
 	isSelected := idx == state.Selected
-	isActive := conn.ID == state.ActiveConnID
-	isExternal := state.Status == app.StatusExternal && conn.Host == state.ExternalHost
+	sess := state.Sessions[conn.ID]
 
 	var indicator string
-	if isActive {
-		switch state.Status {
+	if sess != nil {
+		switch sess.Status {
 		case app.StatusConnected:
 			indicator = StatusConnected
-		case app.StatusConnecting:
+		case app.StatusConnecting, app.StatusReconnecting:
 			indicator = StatusConnecting.Render(SpinnerFrames[spinnerFrame%len(SpinnerFrames)])
+		case app.StatusPrompting:
+			indicator = WarningStyle.Render("○")
 		default:
 			indicator = "  "
 		}
-	} else if isExternal {
+	} else if state.IsExternal(conn) {
 		// Yellow indicator for external-matched connection
 		indicator = WarningStyle.Render("●") + " "
 	} else {
@@ -243,11 +292,39 @@ func renderConnectionItem(state *app.State, conn *models.Connection, idx int, sp
 		}
 		detailStr += " · cert:" + certShort
 	}
-	detail := ConnectionDetailStyle.Render(detailStr)
+	if conn.AutoConnect {
+		detailStr += " · auto"
+	}
+	if sess != nil && sess.Status == app.StatusConnected {
+		if sess.IP != "" {
+			detailStr += " · " + sess.IP
+		}
+		if sess.Tunnel != "" {
+			detailStr += " · " + sess.Tunnel
+		}
+		if routes := splitRoutes(sess); routes != "" {
+			detailStr += " · " + routes
+		}
+	}
+	// Leave room for the style's padding and the scrollbar.
+	detail := ConnectionDetailStyle.Render(truncateText(detailStr, paneWidth-6))
 
 	return fmt.Sprintf("%s %s\n%s", name, indicator, detail)
 }
 
+// splitRoutes lists the split routes of a tunnel, includes marked + and
+// excludes -.
+func splitRoutes(sess *app.Session) string {
+	var routes []string
+	for _, route := range sess.IncludeRoutes {
+		routes = append(routes, "+"+route)
+	}
+	for _, route := range sess.ExcludeRoutes {
+		routes = append(routes, "-"+route)
+	}
+	return strings.Join(routes, " ")
+}
+
 func renderSettingsContent(state *app.State) string {
 	dns := state.Config.Settings.DNS
 	if dns == "" {
@@ -288,6 +365,8 @@ func renderStatusBar(state *app.State, width int) string {
 	var help string
 	if state.ShowingHelp {
 		help = "[j/k] scroll  [esc/?] close  [Q] quit"
+	} else if state.ShowingHistory {
+		help = "[j/k] scroll  [a] all/selected  [esc/h] close  [Q] quit"
 	} else if state.ActiveForm != nil {
 		help = "[tab] next  [enter] save  [esc] cancel"
 	} else {
@@ -297,21 +376,23 @@ func renderStatusBar(state *app.State, width int) string {
 				help = "[R] confirm restart  [any] cancel"
 				break
 			}
-			switch state.Status {
-			case app.StatusReconnecting:
+			sess := state.Sessions[state.StatusConnID()]
+			switch {
+			case sess != nil && sess.Status == app.StatusReconnecting:
 				help = "[d] cancel  [c] cleanup  [R][R] restart  [q] detach  [Q] quit  [?] help"
-			case app.StatusExternal, app.StatusConnected:
+			case sess != nil, state.HasExternal():
 				help = "[d] disconnect  [c] cleanup  [R][R] restart  [q] detach  [Q] quit  [?] help"
 			default:
 				help = "[1-5] pane  [c] cleanup  [R][R] restart  [q] detach  [Q] quit  [?] help"
 			}
 		case app.PaneConnections:
+			selected := state.SelectedConnection()
 			if state.FilterActive {
 				help = "[j/k] nav  [enter] select  [esc] clear filter"
-			} else if state.Status == app.StatusExternal {
-				help = "[j/k] nav  [d] disconnect  [/] search  [J/K] move  [n] new  [e] edit  [x] del [q] detach  [Q] quit  [?] help"
+			} else if selected != nil && (state.Sessions[selected.ID] != nil || state.IsExternal(selected)) {
+				help = "[j/k] nav  [d] disconnect  [/] search  [J/K] move  [n] new  [i] import  [e] edit  [x] del  [h] history [q] detach  [Q] quit  [?] help"
 			} else {
-				help = "[j/k] nav  [enter] connect  [/] search  [J/K] move  [n] new  [e] edit  [x] del [q] detach  [Q] quit  [?] help"
+				help = "[j/k] nav  [enter] connect  [/] search  [J/K] move  [n] new  [i] import  [e] edit  [x] del  [h] history [q] detach  [Q] quit  [?] help"
 			}
 		case app.PaneSettings:
 			if state.ResetPending {
@@ -326,7 +407,7 @@ func renderStatusBar(state *app.State, width int) string {
 				help = "[j/k] scroll  [g/G] top/end  [x][x] clear  [E] export  [C] copy  [?] help"
 			}
 		case app.PaneInput:
-			if state.Status == app.StatusConnected || state.Status == app.StatusExternal || state.Status == app.StatusReconnecting {
+			if len(state.Sessions) > 0 || state.HasExternal() {
 				help = "[enter] submit  [ctrl+d] disconnect  [q] detach  [Q] quit  [?] help"
 			} else {
 				help = "[enter] submit  [q] detach  [Q] quit  [?] help"
@@ -443,6 +524,7 @@ func renderHelpContent(state *app.State, maxHeight int) string {
 	sections = append(sections, helpLine("?", "Toggle help"))
 	sections = append(sections, helpLine("Q", "Quit (stop daemon)"))
 	sections = append(sections, helpLine("q", "Detach (keep daemon)"))
+	sections = append(sections, helpLine("o", "Take prompts from another client"))
 
 	sections = append(sections, "")
 	sections = append(sections, TitleStyle.Render("── Status [1] ──"))
@@ -456,10 +538,13 @@ func renderHelpContent(state *app.State, maxHeight int) string {
 	sections = append(sections, helpLine("d", "Disconnect"))
 	sections = append(sections, helpLine("c", "Cleanup stale processes/DNS"))
 	sections = append(sections, helpLine("n", "New connection"))
+	sections = append(sections, helpLine("i", "Import from other VPN clients"))
+	sections = append(sections, helpLine("E", "Share connections (no secrets)"))
 	sections = append(sections, helpLine("e", "Edit connection"))
 	sections = append(sections, helpLine("x", "Delete connection"))
 	sections = append(sections, helpLine("/", "Search/filter connections"))
 	sections = append(sections, helpLine("J/K", "Move connection up/down"))
+	sections = append(sections, helpLine("h", "Session history (a: all connections)"))
 
 	sections = append(sections, "")
 	sections = append(sections, TitleStyle.Render("── Settings [3] ──"))
@@ -68,6 +68,7 @@ var (
 
 	SuccessStyle = lipgloss.NewStyle().Foreground(ui.ColorSuccess)
 	WarningStyle = lipgloss.NewStyle().Foreground(ui.ColorWarning)
+	DangerStyle  = lipgloss.NewStyle().Foreground(ui.ColorDanger)
 	MutedStyle   = lipgloss.NewStyle().Foreground(ui.ColorMuted)
 )
 
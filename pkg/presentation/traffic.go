@@ -0,0 +1,119 @@
+package presentation
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/Nybkox/lazyopenconnect/pkg/app"
+)
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// renderTraffic is the Status pane's second line for a connected tunnel:
+// uptime, health, throughput and a sparkline of recent throughput.
+func renderTraffic(sess *app.Session, width int) string {
+	var parts []string
+	if !sess.ConnectedAt.IsZero() {
+		parts = append(parts, "up "+formatUptime(time.Since(sess.ConnectedAt)))
+	}
+
+	line := "  " + MutedStyle.Render(strings.Join(parts, " · "))
+	if h := sess.Health; h != nil {
+		if len(parts) > 0 {
+			line += MutedStyle.Render(" · ")
+		}
+		line += renderHealth(h)
+	}
+
+	t := sess.Traffic
+	if t == nil {
+		return line
+	}
+
+	line += MutedStyle.Render(fmt.Sprintf(" · ↓ %s ↑ %s", formatRate(t.RxRate), formatRate(t.TxRate)))
+	if errs := t.RxErrors + t.TxErrors; errs > 0 {
+		line += " " + WarningStyle.Render(fmt.Sprintf("%d err", errs))
+	}
+
+	if room := width - lipgloss.Width(line) - 1; room >= 4 {
+		line += " " + SuccessStyle.Render(sparkline(t.Rates, room))
+	}
+	return line
+}
+
+// renderHealth shows the last probe: "✓ 23ms", or the failures in a row
+// counting towards a reconnect.
+func renderHealth(h *app.Health) string {
+	if h.OK {
+		return SuccessStyle.Render("✓ " + formatRTT(h.RTT))
+	}
+	text := fmt.Sprintf("✗ %d/%d", h.Failures, h.Threshold)
+	if h.Error != "" {
+		text += " " + truncateText(h.Error, 24)
+	}
+	return DangerStyle.Render(text)
+}
+
+func formatRTT(d time.Duration) string {
+	if d < 10*time.Millisecond {
+		return fmt.Sprintf("%.1fms", float64(d.Microseconds())/1000)
+	}
+	return fmt.Sprintf("%dms", d.Milliseconds())
+}
+
+// sparkline draws the last width values, scaled to the largest of them.
+func sparkline(values []float64, width int) string {
+	if len(values) > width {
+		values = values[len(values)-width:]
+	}
+
+	peak := 0.0
+	for _, v := range values {
+		peak = max(peak, v)
+	}
+
+	var b strings.Builder
+	for _, v := range values {
+		idx := 0
+		if peak > 0 {
+			idx = int(v / peak * float64(len(sparkBlocks)-1))
+		}
+		b.WriteRune(sparkBlocks[idx])
+	}
+	return b.String()
+}
+
+func formatRate(bytesPerSec float64) string {
+	return formatBytes(bytesPerSec) + "/s"
+}
+
+func formatBytes(n float64) string {
+	units := []string{"B", "KB", "MB", "GB"}
+	i := 0
+	for n >= 1024 && i < len(units)-1 {
+		n /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%.0f %s", n, units[i])
+	}
+	return fmt.Sprintf("%.1f %s", n, units[i])
+}
+
+func formatUptime(d time.Duration) string {
+	d = d.Round(time.Second)
+	h := int(d.Hours())
+	m := int(d.Minutes()) % 60
+	s := int(d.Seconds()) % 60
+	switch {
+	case h > 0:
+		return fmt.Sprintf("%dh%02dm", h, m)
+	case m > 0:
+		return fmt.Sprintf("%dm%02ds", m, s)
+	default:
+		return fmt.Sprintf("%ds", s)
+	}
+}